@@ -28,6 +28,9 @@ import (
 	"github.com/ai-chat/backend/internal/pkg/jwt"
 	"github.com/ai-chat/backend/internal/pkg/oauth2"
 	"github.com/ai-chat/backend/internal/pkg/ratelimit"
+	"github.com/ai-chat/backend/internal/pkg/storage"
+	"github.com/ai-chat/backend/internal/pkg/tokenizer"
+	"github.com/ai-chat/backend/internal/pkg/tools"
 	"github.com/ai-chat/backend/internal/repository"
 	"github.com/ai-chat/backend/internal/service"
 )
@@ -137,37 +140,131 @@ func main() {
 	convRepo := repository.NewConversationRepository(db.DB)
 	msgRepo := repository.NewMessageRepository(db.DB)
 	memoryRepo := repository.NewMemoryRepository(db.DB)
+	memoryVersionRepo := repository.NewMemoryVersionRepository(db.DB)
 	modelRepo := repository.NewAIModelRepository(db.DB)
 	providerRepo := repository.NewAIProviderRepository(db.DB)
+	providerKeyRepo := repository.NewProviderAPIKeyRepository(db.DB)
 	settingsRepo := repository.NewUserSettingsRepository(db.DB)
 	auditRepo := repository.NewAuditLogRepository(db.DB)
 	tokenUsageRepo := repository.NewTokenUsageRepository(db.DB)
 	systemSettingsRepo := repository.NewSystemSettingsRepository(db.DB)
 	resetTokenRepo := repository.NewPasswordResetTokenRepository(db.DB)
+	attachmentRepo := repository.NewAttachmentRepository(db.DB)
+	documentRepo := repository.NewDocumentRepository(db.DB)
+	memoryJobRepo := repository.NewMemoryJobRepository(db.DB)
+
+	// Initialize attachment storage
+	var attachmentStore storage.Storage
+	if cfg.Attachments.Storage == "s3" {
+		attachmentStore, err = storage.NewS3Storage(storage.S3Config{
+			Endpoint:        cfg.Attachments.S3Endpoint,
+			Region:          cfg.Attachments.S3Region,
+			Bucket:          cfg.Attachments.S3Bucket,
+			AccessKeyID:     cfg.Attachments.S3AccessKey,
+			SecretAccessKey: cfg.Attachments.S3SecretKey,
+			UsePathStyle:    cfg.Attachments.S3PathStyle,
+		})
+	} else {
+		attachmentStore, err = storage.NewLocalStorage(cfg.Attachments.LocalDir)
+	}
+	if err != nil {
+		log.Fatalf("Failed to initialize attachment storage: %v", err)
+	}
+	log.Printf("Attachment storage initialized (%s)", cfg.Attachments.Storage)
 
 	// Initialize services
-	aiProxyService := service.NewAIProxyService(modelRepo, providerRepo, cfg.Encryption.Key)
+	if err := tokenizer.Load(cfg.AI.TokenizerDataDir); err != nil {
+		if cfg.AI.TokenizerRequired {
+			log.Fatalf("Failed to load tokenizer rank files from %s (run scripts/fetch-tokenizer.sh, or set TOKENIZER_REQUIRED=false to estimate token counts): %v", cfg.AI.TokenizerDataDir, err)
+		}
+		log.Printf("WARNING: tokenizer rank files unavailable, token counts are estimates (run scripts/fetch-tokenizer.sh to install them): %v", err)
+	}
+	aiProxyService := service.NewAIProxyService(modelRepo, providerRepo, providerKeyRepo, cfg.Encryption.Key)
+	embeddingService := service.NewEmbeddingService(
+		modelRepo,
+		tokenUsageRepo,
+		aiProxyService,
+		cfg.AI.EmbeddingModel,
+	)
 	memoryService := service.NewMemoryService(
 		memoryRepo,
+		memoryVersionRepo,
 		msgRepo,
 		aiProxyService,
+		embeddingService,
 		modelRepo,
 		cfg.AI.MemoryExtractionEnabled,
 		cfg.AI.DefaultMemoryModel,
 	)
+	memoryJobService := service.NewMemoryJobService(
+		memoryJobRepo,
+		memoryService,
+		cfg.AI.MemoryExtractionTurns,
+		cfg.AI.MemoryExtractionIdle,
+		cfg.AI.MemoryExtractionWorkers,
+	)
+	memoryJobService.Start(ctx)
+	memoryService.StartVersionCleanup(ctx, cfg.AI.MemoryVersionRetention, time.Hour)
+	summaryService := service.NewSummaryService(
+		convRepo,
+		msgRepo,
+		modelRepo,
+		tokenUsageRepo,
+		aiProxyService,
+		cfg.AI.SummaryModel,
+	)
+	settingsService := service.NewUserSettingsService(settingsRepo)
+
+	// Built-in tools offered to models that support function calling
+	toolRegistry := tools.NewRegistry()
+	for _, tool := range []tools.Tool{
+		tools.NewDateTimeTool(settingsService.Timezone),
+		tools.NewCalculatorTool(),
+		tools.NewUnitConvertTool(),
+		tools.NewSearchTool(memoryRepo, msgRepo),
+	} {
+		if err := toolRegistry.Register(tool); err != nil {
+			log.Fatalf("Failed to register tool: %v", err)
+		}
+	}
+
+	attachmentService := service.NewAttachmentService(
+		attachmentRepo,
+		convRepo,
+		attachmentStore,
+		cfg.Attachments.MaxFileSize,
+		cfg.Attachments.UserQuota,
+	)
+	attachmentService.StartCleanup(ctx, 10*time.Minute)
+
+	documentService := service.NewDocumentService(
+		documentRepo,
+		convRepo,
+		attachmentService,
+		embeddingService,
+	)
+	documentService.ResumeProcessing(ctx)
+
 	chatService := service.NewChatService(
 		convRepo,
 		msgRepo,
 		modelRepo,
 		tokenUsageRepo,
+		settingsRepo,
 		aiProxyService,
 		memoryService,
+		memoryJobService,
+		summaryService,
+		toolRegistry,
+		attachmentService,
+		documentService,
+		embeddingService,
 	)
+	chatService.StartEphemeralCleanup(ctx, 10*time.Minute)
 	authService := service.NewAuthService(userRepo, jwtManager, twitterOAuth2)
 	emailService := service.NewEmailService(emailSender, userRepo, resetTokenRepo, cfg.Server.FrontendURL)
-	settingsService := service.NewUserSettingsService(settingsRepo)
 	systemSettingsService := service.NewSystemSettingsService(systemSettingsRepo, cfg.Encryption.Key)
-	adminService := service.NewAdminService(userRepo, modelRepo, providerRepo, auditRepo, tokenUsageRepo, convRepo, msgRepo, cfg.Encryption.Key)
+	adminService := service.NewAdminService(userRepo, modelRepo, providerRepo, providerKeyRepo, auditRepo, tokenUsageRepo, convRepo, msgRepo, aiProxyService, toolRegistry, cfg.Encryption.Key)
 
 	// Load default rate limit from database (override env var if exists)
 	if defaultLimit, err := systemSettingsService.GetRateLimitDefault(ctx); err == nil && defaultLimit > 0 {
@@ -180,7 +277,9 @@ func main() {
 	chatHandler := handlers.NewChatHandler(chatService)
 	memoryHandler := handlers.NewMemoryHandler(memoryService)
 	settingsHandler := handlers.NewSettingsHandler(settingsService)
-	adminHandler := handlers.NewAdminHandler(adminService, systemSettingsService)
+	adminHandler := handlers.NewAdminHandler(adminService, systemSettingsService, memoryJobService)
+	attachmentHandler := handlers.NewAttachmentHandler(attachmentService, cfg.Attachments.MaxFileSize)
+	documentHandler := handlers.NewDocumentHandler(documentService)
 
 	// Setup router
 	routerConfig := &api.RouterConfig{
@@ -198,11 +297,13 @@ func main() {
 		AuditRepo:      auditRepo,
 		TokenUsageRepo: tokenUsageRepo,
 
-		AuthHandler:     authHandler,
-		ChatHandler:     chatHandler,
-		MemoryHandler:   memoryHandler,
-		AdminHandler:    adminHandler,
-		SettingsHandler: settingsHandler,
+		AuthHandler:       authHandler,
+		ChatHandler:       chatHandler,
+		MemoryHandler:     memoryHandler,
+		AdminHandler:      adminHandler,
+		SettingsHandler:   settingsHandler,
+		AttachmentHandler: attachmentHandler,
+		DocumentHandler:   documentHandler,
 	}
 
 	router := setupRouter(cfg, routerConfig)
@@ -291,6 +392,10 @@ func setupRouter(cfg *config.Config, routerCfg *api.RouterConfig) *gin.Engine {
 				middleware.RateLimitMiddleware(routerCfg.RateLimiter),
 				routerCfg.ChatHandler.SendMessage,
 			)
+			conversations.POST("/:id/messages/stream",
+				middleware.RateLimitMiddleware(routerCfg.RateLimiter),
+				routerCfg.ChatHandler.StreamSSE,
+			)
 		}
 
 		// Memories
@@ -298,8 +403,34 @@ func setupRouter(cfg *config.Config, routerCfg *api.RouterConfig) *gin.Engine {
 		{
 			memories.GET("", routerCfg.MemoryHandler.List)
 			memories.POST("", routerCfg.MemoryHandler.Create)
+			memories.DELETE("", routerCfg.MemoryHandler.BulkDelete)
+			memories.GET("/search", routerCfg.MemoryHandler.Search)
+			memories.GET("/export", routerCfg.MemoryHandler.Export)
+			memories.POST("/import", routerCfg.MemoryHandler.Import)
 			memories.PUT("/:id", routerCfg.MemoryHandler.Update)
 			memories.DELETE("/:id", routerCfg.MemoryHandler.Delete)
+			memories.GET("/:id/source", routerCfg.MemoryHandler.Source)
+			memories.GET("/:id/history", routerCfg.MemoryHandler.History)
+			memories.POST("/:id/rollback", routerCfg.MemoryHandler.Rollback)
+		}
+
+		// Attachments
+		attachments := protected.Group("/attachments")
+		{
+			attachments.GET("", routerCfg.AttachmentHandler.List)
+			attachments.POST("", routerCfg.AttachmentHandler.Upload)
+			attachments.GET("/:id", routerCfg.AttachmentHandler.Get)
+			attachments.GET("/:id/content", routerCfg.AttachmentHandler.Download)
+			attachments.DELETE("/:id", routerCfg.AttachmentHandler.Delete)
+		}
+
+		// Documents (retrieval over uploaded files)
+		documents := protected.Group("/documents")
+		{
+			documents.GET("", routerCfg.DocumentHandler.List)
+			documents.POST("", routerCfg.DocumentHandler.Create)
+			documents.GET("/:id", routerCfg.DocumentHandler.Get)
+			documents.DELETE("/:id", routerCfg.DocumentHandler.Delete)
 		}
 
 		// User settings
@@ -337,12 +468,20 @@ func setupRouter(cfg *config.Config, routerCfg *api.RouterConfig) *gin.Engine {
 				models.PUT("/:id/default", routerCfg.AdminHandler.SetDefaultModel)
 			}
 
+			admin.GET("/tools", routerCfg.AdminHandler.ListTools)
+
 			providers := admin.Group("/providers")
 			{
 				providers.GET("", routerCfg.AdminHandler.ListProviders)
 				providers.POST("", routerCfg.AdminHandler.CreateProvider)
 				providers.PUT("/:id", routerCfg.AdminHandler.UpdateProvider)
 				providers.DELETE("/:id", routerCfg.AdminHandler.DeleteProvider)
+				providers.GET("/:id/models/discover", routerCfg.AdminHandler.DiscoverProviderModels)
+				providers.POST("/:id/models/import", routerCfg.AdminHandler.ImportProviderModels)
+				providers.GET("/:id/keys", routerCfg.AdminHandler.ListProviderKeys)
+				providers.POST("/:id/keys", routerCfg.AdminHandler.CreateProviderKey)
+				providers.PUT("/:id/keys/:keyId", routerCfg.AdminHandler.UpdateProviderKey)
+				providers.DELETE("/:id/keys/:keyId", routerCfg.AdminHandler.DeleteProviderKey)
 			}
 
 			stats := admin.Group("/statistics")
@@ -353,6 +492,10 @@ func setupRouter(cfg *config.Config, routerCfg *api.RouterConfig) *gin.Engine {
 
 			admin.GET("/audit-logs", routerCfg.AdminHandler.ListAuditLogs)
 
+			// Memory extraction queue
+			admin.GET("/memory-jobs", routerCfg.AdminHandler.ListMemoryJobs)
+			admin.POST("/memory-jobs/:id/retry", routerCfg.AdminHandler.RetryMemoryJob)
+
 			// System settings
 			admin.GET("/system/settings", routerCfg.AdminHandler.GetSystemSettings)
 			admin.PUT("/system/settings", routerCfg.AdminHandler.UpdateSystemSettings)
@@ -366,8 +509,9 @@ func setupRouter(cfg *config.Config, routerCfg *api.RouterConfig) *gin.Engine {
 			superAdmin.PUT("/users/:id/role", routerCfg.AdminHandler.ChangeUserRole)
 		}
 
-		// WebSocket streaming - without rate limiting (rate limit only on message send)
+		// WebSocket streaming - each connection sends one message, so it is rate limited like message send
 		protected.GET("/chat/stream",
+			middleware.RateLimitMiddleware(routerCfg.RateLimiter),
 			routerCfg.ChatHandler.StreamChat,
 		)
 	}
@@ -3,6 +3,7 @@ module github.com/ai-chat/backend
 go 1.21
 
 require (
+	github.com/dlclark/regexp2 v1.11.4
 	github.com/gin-contrib/cors v1.5.0
 	github.com/gin-gonic/gin v1.9.1
 	github.com/golang-jwt/jwt/v5 v5.2.0
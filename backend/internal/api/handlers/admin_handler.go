@@ -15,13 +15,15 @@ import (
 type AdminHandler struct {
 	adminService           *service.AdminService
 	systemSettingsService *service.SystemSettingsService
+	memoryJobService       *service.MemoryJobService
 }
 
 // NewAdminHandler creates a new admin handler
-func NewAdminHandler(adminService *service.AdminService, systemSettingsService *service.SystemSettingsService) *AdminHandler {
+func NewAdminHandler(adminService *service.AdminService, systemSettingsService *service.SystemSettingsService, memoryJobService *service.MemoryJobService) *AdminHandler {
 	return &AdminHandler{
 		adminService:           adminService,
 		systemSettingsService: systemSettingsService,
+		memoryJobService:       memoryJobService,
 	}
 }
 
@@ -322,6 +324,13 @@ func (h *AdminHandler) SetDefaultModel(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Default model set successfully"})
 }
 
+// ListTools lists the server-side tools that can be enabled per model
+func (h *AdminHandler) ListTools(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"tools": h.adminService.ListTools(),
+	})
+}
+
 // TokenLeaderboard retrieves token usage leaderboard
 func (h *AdminHandler) TokenLeaderboard(c *gin.Context) {
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
@@ -379,6 +388,55 @@ func (h *AdminHandler) ListAuditLogs(c *gin.Context) {
 	})
 }
 
+// ListMemoryJobs lists memory extraction jobs, optionally filtered by status,
+// with the number of jobs in each status
+func (h *AdminHandler) ListMemoryJobs(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	status := model.MemoryJobStatus(c.Query("status"))
+
+	jobs, counts, err := h.memoryJobService.ListJobs(c.Request.Context(), status, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if jobs == nil {
+		jobs = []*model.MemoryExtractionJob{}
+	}
+
+	total := counts[status]
+	if status == "" {
+		total = 0
+		for _, n := range counts {
+			total += n
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"jobs":   jobs,
+		"counts": counts,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// RetryMemoryJob makes a failed memory extraction job run again
+func (h *AdminHandler) RetryMemoryJob(c *gin.Context) {
+	jobID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+		return
+	}
+
+	if err := h.memoryJobService.RetryJob(c.Request.Context(), jobID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Job queued for retry"})
+}
+
 // SetUserRateLimit sets custom rate limit for a user
 func (h *AdminHandler) SetUserRateLimit(c *gin.Context) {
 	adminUser := h.getAdminUser(c)
@@ -585,3 +643,156 @@ func (h *AdminHandler) DeleteProvider(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Provider deleted successfully"})
 }
+
+// ListProviderKeys lists the keys in a provider's pool
+func (h *AdminHandler) ListProviderKeys(c *gin.Context) {
+	providerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid provider ID"})
+		return
+	}
+
+	keys, err := h.adminService.ListProviderKeys(c.Request.Context(), providerID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if keys == nil {
+		keys = []*model.ProviderAPIKey{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"keys": keys})
+}
+
+// CreateProviderKey adds a key to a provider's pool
+func (h *AdminHandler) CreateProviderKey(c *gin.Context) {
+	adminUserID := h.getAdminUserID(c)
+	if adminUserID == uuid.Nil {
+		return
+	}
+
+	providerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid provider ID"})
+		return
+	}
+
+	var req model.ProviderAPIKeyCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	key, err := h.adminService.CreateProviderKey(c.Request.Context(), adminUserID, providerID, &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, key)
+}
+
+// UpdateProviderKey updates a key in a provider's pool
+func (h *AdminHandler) UpdateProviderKey(c *gin.Context) {
+	providerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid provider ID"})
+		return
+	}
+	keyID, err := uuid.Parse(c.Param("keyId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid key ID"})
+		return
+	}
+
+	var req model.ProviderAPIKeyUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	key, err := h.adminService.UpdateProviderKey(c.Request.Context(), providerID, keyID, &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, key)
+}
+
+// DeleteProviderKey removes a key from a provider's pool
+func (h *AdminHandler) DeleteProviderKey(c *gin.Context) {
+	providerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid provider ID"})
+		return
+	}
+	keyID, err := uuid.Parse(c.Param("keyId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid key ID"})
+		return
+	}
+
+	if err := h.adminService.DeleteProviderKey(c.Request.Context(), providerID, keyID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "API key deleted successfully"})
+}
+
+// DiscoverProviderModels lists the models available from a provider's upstream API
+func (h *AdminHandler) DiscoverProviderModels(c *gin.Context) {
+	providerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid provider ID"})
+		return
+	}
+
+	models, err := h.adminService.DiscoverProviderModels(c.Request.Context(), providerID)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	if models == nil {
+		models = []model.DiscoveredModel{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"models": models})
+}
+
+// ImportProviderModels creates AI models from discovered provider model identifiers
+func (h *AdminHandler) ImportProviderModels(c *gin.Context) {
+	adminUserID := h.getAdminUserID(c)
+	if adminUserID == uuid.Nil {
+		return
+	}
+
+	providerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid provider ID"})
+		return
+	}
+
+	var req model.ProviderModelImportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	created, err := h.adminService.ImportProviderModels(c.Request.Context(), adminUserID, providerID, req.ModelIdentifiers)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "models": created})
+		return
+	}
+
+	// Set audit action
+	names := make([]string, 0, len(created))
+	for _, m := range created {
+		names = append(names, m.Name)
+	}
+	c.Set("audit_action", model.AuditModelCreated)
+	c.Set("audit_details", map[string]interface{}{"provider_id": providerID, "model_names": names})
+
+	c.JSON(http.StatusCreated, gin.H{"models": created})
+}
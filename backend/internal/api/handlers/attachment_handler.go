@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ai-chat/backend/internal/model"
+	"github.com/ai-chat/backend/internal/service"
+)
+
+// multipartOverhead allows for the multipart envelope around an uploaded file
+const multipartOverhead = 1 << 20
+
+// AttachmentHandler handles file upload and download endpoints
+type AttachmentHandler struct {
+	attachmentService *service.AttachmentService
+	maxFileSize       int64
+}
+
+// NewAttachmentHandler creates a new attachment handler
+func NewAttachmentHandler(attachmentService *service.AttachmentService, maxFileSize int64) *AttachmentHandler {
+	return &AttachmentHandler{
+		attachmentService: attachmentService,
+		maxFileSize:       maxFileSize,
+	}
+}
+
+// Upload stores a file sent as the "file" field of a multipart form. An
+// optional "conversation_id" field scopes it to one of the user's conversations.
+func (h *AttachmentHandler) Upload(c *gin.Context) {
+	userID := h.getUserID(c)
+	if userID == uuid.Nil {
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxFileSize+multipartOverhead)
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		if strings.Contains(err.Error(), "request body too large") {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File is too large"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File is required"})
+		return
+	}
+
+	var conversationID *uuid.UUID
+	if value := c.PostForm("conversation_id"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid conversation ID"})
+			return
+		}
+		conversationID = &id
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+		return
+	}
+	defer file.Close()
+
+	attachment, err := h.attachmentService.Upload(c.Request.Context(), userID, conversationID, &service.UploadedFile{
+		Filename: header.Filename,
+		Size:     header.Size,
+		Content:  file,
+	})
+	if err != nil {
+		attachmentError(c, err, "Failed to upload file")
+		return
+	}
+
+	c.JSON(http.StatusCreated, attachment)
+}
+
+// List lists the user's attachments together with their quota usage
+func (h *AttachmentHandler) List(c *gin.Context) {
+	userID := h.getUserID(c)
+	if userID == uuid.Nil {
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	attachments, err := h.attachmentService.ListAttachments(c.Request.Context(), userID, limit, offset)
+	if err != nil {
+		attachmentError(c, err, "Failed to list attachments")
+		return
+	}
+	if attachments == nil {
+		attachments = []*model.Attachment{}
+	}
+
+	usage, err := h.attachmentService.GetUsage(c.Request.Context(), userID)
+	if err != nil {
+		attachmentError(c, err, "Failed to list attachments")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"attachments": attachments,
+		"usage":       usage,
+		"limit":       limit,
+		"offset":      offset,
+	})
+}
+
+// Get retrieves an attachment's metadata
+func (h *AttachmentHandler) Get(c *gin.Context) {
+	userID := h.getUserID(c)
+	if userID == uuid.Nil {
+		return
+	}
+
+	attachmentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid attachment ID"})
+		return
+	}
+
+	attachment, err := h.attachmentService.GetAttachment(c.Request.Context(), userID, attachmentID)
+	if err != nil {
+		attachmentError(c, err, "Failed to get attachment")
+		return
+	}
+
+	c.JSON(http.StatusOK, attachment)
+}
+
+// Download streams an attachment's content. Images and PDFs are shown inline;
+// everything else is offered as a download.
+func (h *AttachmentHandler) Download(c *gin.Context) {
+	userID := h.getUserID(c)
+	if userID == uuid.Nil {
+		return
+	}
+
+	attachmentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid attachment ID"})
+		return
+	}
+
+	attachment, content, err := h.attachmentService.OpenAttachment(c.Request.Context(), userID, attachmentID)
+	if err != nil {
+		attachmentError(c, err, "Failed to read attachment")
+		return
+	}
+	defer content.Close()
+
+	disposition := "attachment"
+	if strings.HasPrefix(attachment.ContentType, "image/") || attachment.ContentType == "application/pdf" {
+		disposition = "inline"
+	}
+
+	c.DataFromReader(http.StatusOK, attachment.SizeBytes, attachment.ContentType, content, map[string]string{
+		"Content-Disposition":    mime.FormatMediaType(disposition, map[string]string{"filename": attachment.Filename}),
+		"Cache-Control":          "private, max-age=86400",
+		"X-Content-Type-Options": "nosniff",
+	})
+}
+
+// Delete deletes an attachment
+func (h *AttachmentHandler) Delete(c *gin.Context) {
+	userID := h.getUserID(c)
+	if userID == uuid.Nil {
+		return
+	}
+
+	attachmentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid attachment ID"})
+		return
+	}
+
+	if err := h.attachmentService.DeleteAttachment(c.Request.Context(), userID, attachmentID); err != nil {
+		attachmentError(c, err, "Failed to delete attachment")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Attachment deleted"})
+}
+
+// attachmentError answers a failed attachment request. Rejected requests get
+// their reason and missing attachments a 404; anything else, such as a storage
+// failure, is logged and answered with a generic message.
+func attachmentError(c *gin.Context, err error, message string) {
+	var rejected *service.AttachmentRejectedError
+	switch {
+	case errors.As(err, &rejected):
+		c.JSON(http.StatusBadRequest, gin.H{"error": rejected.Reason})
+	case errors.Is(err, service.ErrAttachmentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+	default:
+		log.Printf("Attachment request failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
+
+// getUserID extracts user ID from context
+func (h *AttachmentHandler) getUserID(c *gin.Context) uuid.UUID {
+	userIDStr, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return uuid.Nil
+	}
+
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return uuid.Nil
+	}
+
+	return userID
+}
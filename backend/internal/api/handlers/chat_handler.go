@@ -2,11 +2,15 @@ package handlers
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"github.com/ai-chat/backend/internal/model"
@@ -214,7 +218,7 @@ func (h *ChatHandler) SendMessage(c *gin.Context) {
 
 	userMsg, assistantMsg, err := h.chatService.SendMessage(c.Request.Context(), userID, conversationID, &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": chatError(conversationID, err)})
 		return
 	}
 
@@ -252,10 +256,16 @@ func (h *ChatHandler) StreamChat(c *gin.Context) {
 		Message        model.MessageCreateRequest   `json:"message"`
 	}
 
+	// Same rules as the HTTP handlers' ShouldBindJSON: image- or
+	// attachment-only messages are valid, empty ones are not
 	if err := json.Unmarshal(msg, &wsRequest); err != nil {
 		conn.WriteJSON(gin.H{"error": "Invalid request"})
 		return
 	}
+	if err := binding.Validator.ValidateStruct(&wsRequest.Message); err != nil {
+		conn.WriteJSON(gin.H{"error": "Invalid request"})
+		return
+	}
 
 	conversationID, err := uuid.Parse(wsRequest.ConversationID)
 	if err != nil {
@@ -263,47 +273,36 @@ func (h *ChatHandler) StreamChat(c *gin.Context) {
 		return
 	}
 
-	// Verify user has access to this conversation
-	conv, err := h.chatService.GetConversation(c.Request.Context(), userID, conversationID)
+	// The hijacked connection outlives the request context, so make sure the
+	// streaming goroutine is released when this handler returns
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+
+	// Ownership is verified by the chat service before anything is sent upstream
+	responseChan, errorChan, err := h.chatService.GetStreamingResponse(ctx, userID, conversationID, &wsRequest.Message)
 	if err != nil {
-		conn.WriteJSON(gin.H{"error": "Unauthorized or conversation not found"})
-		return
-	}
-
-	_ = conv // Conversation verified
-
-	// This is a simplified implementation
-	// In production, implement proper streaming with AI service
-
-	// Send chunks
-	response := "This is a streaming response placeholder."
-	for i, char := range response {
-		chunk := model.ChatCompletionStreamResponse{
-			ID:      uuid.New().String(),
-			Object:  "chat.completion.chunk",
-			Created: 0,
-			Model:   "gpt-4",
-			Choices: []model.ChatCompletionStreamChoice{
-				{
-					Index: 0,
-					Delta: model.ChatMessageDelta{
-						Content: string(char),
-					},
-					FinishReason: func() *string {
-						if i == len(response)-1 {
-							s := "stop"
-							return &s
-						}
-						return nil
-					}(),
-				},
-			},
-		}
+		conn.WriteJSON(gin.H{"error": chatError(conversationID, err)})
+		return
+	}
 
+	for chunk := range responseChan {
 		if err := conn.WriteJSON(chunk); err != nil {
 			return
 		}
 	}
+
+	if err, ok := <-errorChan; ok && err != nil {
+		conn.WriteJSON(gin.H{"error": chatError(conversationID, err)})
+	}
+}
+
+// chatError logs a failed chat request and returns the message the client
+// may see; upstream failures are logged in full but only summarized to clients
+func chatError(conversationID uuid.UUID, err error) string {
+	if errors.Is(err, service.ErrUpstream) {
+		log.Printf("Chat request in conversation %s failed: %v", conversationID, err)
+	}
+	return service.ClientErrorMessage(err)
 }
 
 // getUserID extracts user ID from context
@@ -325,10 +324,6 @@ func (h *ChatHandler) getUserID(c *gin.Context) uuid.UUID {
 
 // StreamSSE handles Server-Sent Events streaming (alternative to WebSocket)
 func (h *ChatHandler) StreamSSE(c *gin.Context) {
-	c.Header("Content-Type", "text/event-stream")
-	c.Header("Cache-Control", "no-cache")
-	c.Header("Connection", "keep-alive")
-
 	// Get user ID
 	userID := h.getUserID(c)
 	if userID == uuid.Nil {
@@ -347,14 +342,6 @@ func (h *ChatHandler) StreamSSE(c *gin.Context) {
 		return
 	}
 
-	// Get streaming response
-	responseChan, errorChan, err := h.chatService.GetStreamingResponse(c.Request.Context(), userID, conversationID, &req)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	// Stream responses
 	w := c.Writer
 	flusher, ok := w.(http.Flusher)
 	if !ok {
@@ -362,12 +349,35 @@ func (h *ChatHandler) StreamSSE(c *gin.Context) {
 		return
 	}
 
+	// Get streaming response
+	responseChan, errorChan, err := h.chatService.GetStreamingResponse(c.Request.Context(), userID, conversationID, &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": chatError(conversationID, err)})
+		return
+	}
+
+	// Everything is validated; switch the response to an event stream only
+	// now so the errors above still reach the client as plain JSON
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+
 	writer := bufio.NewWriter(w)
 
 	for {
 		select {
 		case chunk, ok := <-responseChan:
 			if !ok {
+				// Stream finished; report a trailing error if there was one.
+				// JSON keeps a multi-line message inside one data: line.
+				if err, ok := <-errorChan; ok && err != nil {
+					data, _ := json.Marshal(gin.H{"error": chatError(conversationID, err)})
+					writer.WriteString("event: error\ndata: " + string(data) + "\n\n")
+				} else {
+					writer.WriteString("data: [DONE]\n\n")
+				}
+				writer.Flush()
+				flusher.Flush()
 				return
 			}
 
@@ -376,14 +386,6 @@ func (h *ChatHandler) StreamSSE(c *gin.Context) {
 			writer.Flush()
 			flusher.Flush()
 
-		case err := <-errorChan:
-			if err != nil {
-				writer.WriteString("event: error\ndata: " + err.Error() + "\n\n")
-				writer.Flush()
-				flusher.Flush()
-			}
-			return
-
 		case <-c.Request.Context().Done():
 			return
 		}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ai-chat/backend/internal/model"
+	"github.com/ai-chat/backend/internal/service"
+)
+
+// DocumentHandler handles document indexing endpoints
+type DocumentHandler struct {
+	documentService *service.DocumentService
+}
+
+// NewDocumentHandler creates a new document handler
+func NewDocumentHandler(documentService *service.DocumentService) *DocumentHandler {
+	return &DocumentHandler{documentService: documentService}
+}
+
+// Create indexes an uploaded attachment for retrieval
+func (h *DocumentHandler) Create(c *gin.Context) {
+	userID := h.getUserID(c)
+	if userID == uuid.Nil {
+		return
+	}
+
+	var req model.DocumentCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	doc, err := h.documentService.CreateDocument(c.Request.Context(), userID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, doc)
+}
+
+// List lists the user's documents; conversation_id limits it to one conversation
+func (h *DocumentHandler) List(c *gin.Context) {
+	userID := h.getUserID(c)
+	if userID == uuid.Nil {
+		return
+	}
+
+	var conversationID *uuid.UUID
+	if value := c.Query("conversation_id"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid conversation ID"})
+			return
+		}
+		conversationID = &id
+	}
+
+	docs, err := h.documentService.ListDocuments(c.Request.Context(), userID, conversationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if docs == nil {
+		docs = []*model.Document{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"documents": docs})
+}
+
+// Get retrieves a document, including its processing status
+func (h *DocumentHandler) Get(c *gin.Context) {
+	userID := h.getUserID(c)
+	if userID == uuid.Nil {
+		return
+	}
+
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	doc, err := h.documentService.GetDocument(c.Request.Context(), userID, documentID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, doc)
+}
+
+// Delete deletes a document
+func (h *DocumentHandler) Delete(c *gin.Context) {
+	userID := h.getUserID(c)
+	if userID == uuid.Nil {
+		return
+	}
+
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	if err := h.documentService.DeleteDocument(c.Request.Context(), userID, documentID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Document deleted"})
+}
+
+// getUserID extracts user ID from context
+func (h *DocumentHandler) getUserID(c *gin.Context) uuid.UUID {
+	userIDStr, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return uuid.Nil
+	}
+
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return uuid.Nil
+	}
+
+	return userID
+}
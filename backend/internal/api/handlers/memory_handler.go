@@ -1,8 +1,14 @@
 package handlers
 
 import (
+	"fmt"
+	"io"
+	"log"
+	"mime"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -10,6 +16,9 @@ import (
 	"github.com/ai-chat/backend/internal/service"
 )
 
+// maxMemoryImportSize bounds an uploaded memory import
+const maxMemoryImportSize = 5 << 20
+
 // MemoryHandler handles memory-related endpoints
 type MemoryHandler struct {
 	memoryService *service.MemoryService
@@ -116,6 +125,216 @@ func (h *MemoryHandler) Delete(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Memory deleted"})
 }
 
+// Search finds memories containing every word of the "q" query parameter
+func (h *MemoryHandler) Search(c *gin.Context) {
+	userID := h.getUserID(c)
+	if userID == uuid.Nil {
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+
+	memories, err := h.memoryService.SearchMemories(c.Request.Context(), userID, c.Query("q"), limit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if memories == nil {
+		memories = []*model.Memory{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"memories": memories})
+}
+
+// BulkDelete deletes the memories of a category and/or extracted from a
+// conversation, given as the "category" and "conversation_id" query parameters
+func (h *MemoryHandler) BulkDelete(c *gin.Context) {
+	userID := h.getUserID(c)
+	if userID == uuid.Nil {
+		return
+	}
+
+	category := model.MemoryCategory(c.Query("category"))
+	switch category {
+	case "", model.MemoryCategoryPreference, model.MemoryCategoryFact, model.MemoryCategoryContext:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category"})
+		return
+	}
+
+	var conversationID *uuid.UUID
+	if value := c.Query("conversation_id"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid conversation ID"})
+			return
+		}
+		conversationID = &id
+	}
+
+	deleted, err := h.memoryService.DeleteMemories(c.Request.Context(), userID, category, conversationID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deleted": deleted})
+}
+
+// Export downloads all memories as JSON (default) or, with format=markdown, Markdown
+func (h *MemoryHandler) Export(c *gin.Context) {
+	userID := h.getUserID(c)
+	if userID == uuid.Nil {
+		return
+	}
+
+	format := c.DefaultQuery("format", service.MemoryFormatJSON)
+	contentType, ext := "application/json", "json"
+	if format == service.MemoryFormatMarkdown {
+		contentType, ext = "text/markdown; charset=utf-8", "md"
+	}
+
+	data, err := h.memoryService.ExportMemories(c.Request.Context(), userID, format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filename := fmt.Sprintf("memories-%s.%s", time.Now().Format("20060102"), ext)
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// Import adds memories from a JSON or Markdown export, sent either as the
+// "file" field of a multipart form or as the request body
+func (h *MemoryHandler) Import(c *gin.Context) {
+	userID := h.getUserID(c)
+	if userID == uuid.Nil {
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxMemoryImportSize+multipartOverhead)
+
+	var content io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		header, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "File is required"})
+			return
+		}
+		file, err := header.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+			return
+		}
+		defer file.Close()
+		content = file
+	}
+
+	data, err := io.ReadAll(io.LimitReader(content, maxMemoryImportSize+1))
+	if err != nil {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File is too large"})
+		return
+	}
+	if len(data) > maxMemoryImportSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File is too large"})
+		return
+	}
+
+	result, err := h.memoryService.ImportMemories(c.Request.Context(), userID, data)
+	if err != nil && result != nil {
+		// Storing failed midway; the memories imported so far are kept
+		log.Printf("Memory import for user %s failed: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":    "Import stopped before all memories were stored",
+			"imported": result.Imported,
+			"skipped":  result.Skipped,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// Source returns the message a memory was extracted from
+func (h *MemoryHandler) Source(c *gin.Context) {
+	userID := h.getUserID(c)
+	if userID == uuid.Nil {
+		return
+	}
+
+	memoryID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid memory ID"})
+		return
+	}
+
+	source, err := h.memoryService.GetMemorySource(c.Request.Context(), userID, memoryID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, source)
+}
+
+// History lists a memory's versions, newest first
+func (h *MemoryHandler) History(c *gin.Context) {
+	userID := h.getUserID(c)
+	if userID == uuid.Nil {
+		return
+	}
+
+	memoryID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid memory ID"})
+		return
+	}
+
+	versions, err := h.memoryService.GetMemoryHistory(c.Request.Context(), userID, memoryID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"versions": versions})
+}
+
+// Rollback returns a memory to an earlier version, restoring it if deleted
+func (h *MemoryHandler) Rollback(c *gin.Context) {
+	userID := h.getUserID(c)
+	if userID == uuid.Nil {
+		return
+	}
+
+	memoryID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid memory ID"})
+		return
+	}
+
+	var req model.MemoryRollbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	memory, err := h.memoryService.RollbackMemory(c.Request.Context(), userID, memoryID, req.Version)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, memory)
+}
+
 // getUserID extracts user ID from context
 func (h *MemoryHandler) getUserID(c *gin.Context) uuid.UUID {
 	userIDStr, exists := c.Get("user_id")
@@ -31,11 +31,13 @@ type RouterConfig struct {
 	TokenUsageRepo   *repository.TokenUsageRepository
 
 	// Handlers (will be initialized in Stage 4)
-	AuthHandler     *handlers.AuthHandler
-	ChatHandler     *handlers.ChatHandler
-	MemoryHandler   *handlers.MemoryHandler
-	AdminHandler    *handlers.AdminHandler
-	SettingsHandler *handlers.SettingsHandler
+	AuthHandler       *handlers.AuthHandler
+	ChatHandler       *handlers.ChatHandler
+	MemoryHandler     *handlers.MemoryHandler
+	AdminHandler      *handlers.AdminHandler
+	SettingsHandler   *handlers.SettingsHandler
+	AttachmentHandler *handlers.AttachmentHandler
+	DocumentHandler   *handlers.DocumentHandler
 }
 
 // SetupRouter creates and configures the Gin router
@@ -26,6 +26,7 @@ type Config struct {
 	Email    EmailConfig
 	Encryption EncryptionConfig
 	AI       AIConfig
+	Attachments AttachmentConfig
 }
 
 type ServerConfig struct {
@@ -89,6 +90,33 @@ type EncryptionConfig struct {
 type AIConfig struct {
 	DefaultMemoryModel      string
 	MemoryExtractionEnabled bool
+	TokenizerDataDir        string // directory holding <encoding>.tiktoken rank files
+	TokenizerRequired       bool   // refuse to start without the rank files instead of estimating
+	SummaryModel            string // model that condenses old conversation turns
+	EmbeddingModel          string // model that embeds text; empty picks the first active model that supports embeddings
+
+	// Memory extraction runs once a conversation has MemoryExtractionTurns
+	// new turns or has been idle for MemoryExtractionIdle
+	MemoryExtractionTurns   int
+	MemoryExtractionIdle    time.Duration
+	MemoryExtractionWorkers int
+
+	// MemoryVersionRetention is how long superseded memory versions and deleted
+	// memories are kept; zero keeps them forever
+	MemoryVersionRetention time.Duration
+}
+
+type AttachmentConfig struct {
+	Storage     string // "local" or "s3"
+	LocalDir    string
+	MaxFileSize int64 // bytes per upload
+	UserQuota   int64 // total bytes per user
+	S3Endpoint  string
+	S3Region    string
+	S3Bucket    string
+	S3AccessKey string
+	S3SecretKey string
+	S3PathStyle bool
 }
 
 // Load loads configuration from environment variables
@@ -133,6 +161,46 @@ func Load() (*Config, error) {
 		memoryExtractionEnabled = true
 	}
 
+	tokenizerRequired, err := strconv.ParseBool(getEnv("TOKENIZER_REQUIRED", "false"))
+	if err != nil {
+		tokenizerRequired = false
+	}
+
+	memoryExtractionTurns, err := strconv.Atoi(getEnv("MEMORY_EXTRACTION_TURNS", "3"))
+	if err != nil || memoryExtractionTurns < 1 {
+		memoryExtractionTurns = 3
+	}
+
+	memoryExtractionIdleSeconds, err := strconv.Atoi(getEnv("MEMORY_EXTRACTION_IDLE_SECONDS", "120"))
+	if err != nil || memoryExtractionIdleSeconds < 0 {
+		memoryExtractionIdleSeconds = 120
+	}
+
+	memoryExtractionWorkers, err := strconv.Atoi(getEnv("MEMORY_EXTRACTION_WORKERS", "2"))
+	if err != nil || memoryExtractionWorkers < 1 {
+		memoryExtractionWorkers = 2
+	}
+
+	memoryVersionRetentionDays, err := strconv.Atoi(getEnv("MEMORY_VERSION_RETENTION_DAYS", "180"))
+	if err != nil || memoryVersionRetentionDays < 0 {
+		memoryVersionRetentionDays = 180
+	}
+
+	attachmentMaxSizeMB, err := strconv.ParseInt(getEnv("ATTACHMENT_MAX_SIZE_MB", "20"), 10, 64)
+	if err != nil {
+		attachmentMaxSizeMB = 20
+	}
+
+	attachmentQuotaMB, err := strconv.ParseInt(getEnv("ATTACHMENT_USER_QUOTA_MB", "500"), 10, 64)
+	if err != nil {
+		attachmentQuotaMB = 500
+	}
+
+	s3PathStyle, err := strconv.ParseBool(getEnv("S3_USE_PATH_STYLE", "true"))
+	if err != nil {
+		s3PathStyle = true
+	}
+
 	serverPort, err := strconv.Atoi(getEnv("SERVER_PORT", "8080"))
 	if err != nil {
 		serverPort = 8080
@@ -192,6 +260,26 @@ func Load() (*Config, error) {
 		AI: AIConfig{
 			DefaultMemoryModel:      getEnv("DEFAULT_MEMORY_MODEL", "gpt-3.5-turbo"),
 			MemoryExtractionEnabled: memoryExtractionEnabled,
+			TokenizerDataDir:        getEnv("TOKENIZER_DATA_DIR", "./data/tokenizer"),
+			TokenizerRequired:       tokenizerRequired,
+			SummaryModel:            getEnv("SUMMARY_MODEL", "gpt-3.5-turbo"),
+			EmbeddingModel:          getEnv("EMBEDDING_MODEL", ""),
+			MemoryExtractionTurns:   memoryExtractionTurns,
+			MemoryExtractionIdle:    time.Duration(memoryExtractionIdleSeconds) * time.Second,
+			MemoryExtractionWorkers: memoryExtractionWorkers,
+			MemoryVersionRetention:  time.Duration(memoryVersionRetentionDays) * 24 * time.Hour,
+		},
+		Attachments: AttachmentConfig{
+			Storage:     getEnv("ATTACHMENT_STORAGE", "local"),
+			LocalDir:    getEnv("ATTACHMENT_DIR", "./data/attachments"),
+			MaxFileSize: attachmentMaxSizeMB << 20,
+			UserQuota:   attachmentQuotaMB << 20,
+			S3Endpoint:  getEnv("S3_ENDPOINT", ""),
+			S3Region:    getEnv("S3_REGION", "us-east-1"),
+			S3Bucket:    getEnv("S3_BUCKET", ""),
+			S3AccessKey: getEnv("S3_ACCESS_KEY_ID", ""),
+			S3SecretKey: getEnv("S3_SECRET_ACCESS_KEY", ""),
+			S3PathStyle: s3PathStyle,
 		},
 	}
 
@@ -221,6 +309,10 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("ENCRYPTION_KEY must be 32 chars (raw) or 64 chars (hex) for AES-256")
 	}
 
+	if c.Attachments.Storage != "local" && c.Attachments.Storage != "s3" {
+		return fmt.Errorf("ATTACHMENT_STORAGE must be local or s3")
+	}
+
 	if c.Database.Password == "" {
 		return fmt.Errorf("DB_PASSWORD must be set")
 	}
@@ -363,6 +455,12 @@ func (c *Config) LoadDynamicConfig(db *sql.DB) error {
 	if val, ok := settings["ai_memory_extraction_enabled"]; ok {
 		c.AI.MemoryExtractionEnabled = (val == "true")
 	}
+	if val, ok := settings["ai_summary_model"]; ok && val != "" {
+		c.AI.SummaryModel = val
+	}
+	if val, ok := settings["ai_embedding_model"]; ok && val != "" {
+		c.AI.EmbeddingModel = val
+	}
 
 	// 加载速率限制配置
 	if val, ok := settings["rate_limit_default_per_minute"]; ok && val != "" {
@@ -0,0 +1,12 @@
+-- Migration 007: Azure OpenAI providers
+-- Azure needs a resource name, an api-version and a mapping from model
+-- identifiers to deployment names, none of which fit in api_endpoint alone
+
+ALTER TABLE ai_providers
+    ADD COLUMN IF NOT EXISTS azure_resource VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS azure_api_version VARCHAR(50) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS azure_deployments JSONB NOT NULL DEFAULT '{}'::jsonb;  -- {"gpt-4o": "my-gpt4o-deployment"}
+
+-- api_endpoint may be derived from azure_resource
+ALTER TABLE ai_providers
+    ALTER COLUMN api_endpoint SET DEFAULT '';
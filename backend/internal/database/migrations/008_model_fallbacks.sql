@@ -0,0 +1,5 @@
+-- Migration 008: Model failover chains
+-- Ordered list of model IDs to try when a model's upstream is unavailable
+
+ALTER TABLE ai_models
+    ADD COLUMN IF NOT EXISTS fallback_model_ids JSONB NOT NULL DEFAULT '[]'::jsonb;  -- ["<uuid>", ...]
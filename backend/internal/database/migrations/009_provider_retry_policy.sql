@@ -0,0 +1,5 @@
+-- Migration 009: Per-provider retry policy
+-- Empty fields fall back to the built-in defaults
+
+ALTER TABLE ai_providers
+    ADD COLUMN IF NOT EXISTS retry_policy JSONB NOT NULL DEFAULT '{}'::jsonb;  -- {"max_attempts": 3, "initial_backoff_ms": 500, ...}
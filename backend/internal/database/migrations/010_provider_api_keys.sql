@@ -0,0 +1,26 @@
+-- Migration 010: Provider API key pools
+-- A provider may hold several keys; requests are spread across the active ones.
+-- ai_providers.api_key_encrypted stays as the fallback when the pool is empty.
+
+CREATE TABLE IF NOT EXISTS provider_api_keys (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    provider_id UUID NOT NULL REFERENCES ai_providers(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    api_key_encrypted TEXT NOT NULL,
+    key_hint VARCHAR(20) NOT NULL DEFAULT '',
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    disabled_reason TEXT NOT NULL DEFAULT '',
+    disabled_at TIMESTAMP,
+    request_count BIGINT NOT NULL DEFAULT 0,
+    input_tokens BIGINT NOT NULL DEFAULT 0,
+    output_tokens BIGINT NOT NULL DEFAULT 0,
+    last_used_at TIMESTAMP,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_provider_api_keys_provider ON provider_api_keys(provider_id, is_active);
+
+ALTER TABLE ai_providers
+    ADD COLUMN IF NOT EXISTS key_selection VARCHAR(20) NOT NULL DEFAULT 'round_robin';  -- 'round_robin' | 'least_used'
@@ -0,0 +1,5 @@
+-- Migration 011: Per-model tokenizer
+-- BPE encoding used to count a model's tokens; empty picks one from the model identifier
+
+ALTER TABLE ai_models
+    ADD COLUMN IF NOT EXISTS tokenizer VARCHAR(50) NOT NULL DEFAULT '';  -- cl100k_base, o200k_base, heuristic or ''
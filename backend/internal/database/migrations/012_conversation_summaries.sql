@@ -0,0 +1,13 @@
+-- Migration 012: Rolling conversation summaries
+-- Older turns that no longer fit a model's context window are condensed into a
+-- summary that is refreshed incrementally as the conversation grows
+
+ALTER TABLE conversations
+    ADD COLUMN IF NOT EXISTS summary TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS summary_up_to TIMESTAMP,       -- created_at of the last message folded into the summary
+    ADD COLUMN IF NOT EXISTS summary_up_to_id UUID,         -- and its id, which orders messages created in the same instant
+    ADD COLUMN IF NOT EXISTS summary_updated_at TIMESTAMP;
+
+INSERT INTO system_settings (setting_key, setting_value, description, value_type) VALUES
+    ('ai_summary_model', 'gpt-3.5-turbo', '对话摘要模型', 'string')
+ON CONFLICT (setting_key) DO NOTHING;
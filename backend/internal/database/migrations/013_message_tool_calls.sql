@@ -0,0 +1,9 @@
+-- Migration 013: Tool calling
+-- Assistant turns record the tools they called; "tool" turns carry the result
+-- of one call, so conversations replay with their tool exchanges intact
+
+ALTER TABLE messages
+    ADD COLUMN IF NOT EXISTS tool_calls JSONB,               -- [{"id","type","function":{"name","arguments"}}]
+    ADD COLUMN IF NOT EXISTS tool_call_id VARCHAR(100) NOT NULL DEFAULT '';
+
+COMMENT ON COLUMN messages.role IS 'user, assistant, system or tool';
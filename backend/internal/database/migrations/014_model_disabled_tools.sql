@@ -0,0 +1,5 @@
+-- Migration 014: Per-model tool switches
+-- Names of registered server-side tools an admin has turned off for a model
+
+ALTER TABLE ai_models
+    ADD COLUMN IF NOT EXISTS disabled_tools JSONB NOT NULL DEFAULT '[]'::jsonb;  -- ["calculator", ...]
@@ -0,0 +1,9 @@
+-- Migration 015: Image input
+-- Models declare whether they accept images; multi-part user messages keep
+-- their text and image parts so history can be re-sent as it was written
+
+ALTER TABLE ai_models
+    ADD COLUMN IF NOT EXISTS supports_vision BOOLEAN NOT NULL DEFAULT false;
+
+ALTER TABLE messages
+    ADD COLUMN IF NOT EXISTS content_parts JSONB;  -- [{"type":"text",...},{"type":"image_url",...}]
@@ -0,0 +1,40 @@
+-- Migration 016: File attachments
+-- Files live in the configured object storage; this table holds their metadata.
+-- Rows removed by any cascade (conversation, message or user deletion) queue
+-- their storage key in attachment_deletions so the files can be purged too.
+
+CREATE TABLE IF NOT EXISTS attachments (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    conversation_id UUID REFERENCES conversations(id) ON DELETE CASCADE,
+    message_id UUID REFERENCES messages(id) ON DELETE CASCADE,
+    storage_key VARCHAR(512) NOT NULL UNIQUE,
+    filename VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    checksum VARCHAR(64) NOT NULL,  -- SHA-256 hex
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_attachments_user ON attachments(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_attachments_message ON attachments(message_id);
+CREATE INDEX IF NOT EXISTS idx_attachments_conversation ON attachments(conversation_id);
+CREATE INDEX IF NOT EXISTS idx_attachments_unlinked ON attachments(created_at) WHERE message_id IS NULL;
+
+CREATE TABLE IF NOT EXISTS attachment_deletions (
+    storage_key VARCHAR(512) PRIMARY KEY,
+    queued_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE OR REPLACE FUNCTION queue_attachment_deletion()
+RETURNS TRIGGER AS $$
+BEGIN
+    INSERT INTO attachment_deletions (storage_key) VALUES (OLD.storage_key)
+    ON CONFLICT (storage_key) DO NOTHING;
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS queue_attachment_deletion ON attachments;
+CREATE TRIGGER queue_attachment_deletion AFTER DELETE ON attachments
+    FOR EACH ROW EXECUTE FUNCTION queue_attachment_deletion();
@@ -0,0 +1,89 @@
+-- Migration 017: Documents for retrieval-augmented answers
+-- A document is an uploaded attachment whose text has been extracted, split
+-- into chunks and embedded. Documents tied to a conversation are searched
+-- only there; documents without one form the user's knowledge base and are
+-- searched in every conversation.
+--
+-- Embeddings are stored as REAL[] so the schema works on plain Postgres.
+-- When the pgvector extension is available, each chunk also gets a vector
+-- copy with an HNSW index for the common embedding sizes, and the database
+-- ranks; otherwise the server ranks them itself.
+
+DO $$
+BEGIN
+    CREATE EXTENSION IF NOT EXISTS vector;
+EXCEPTION WHEN OTHERS THEN
+    RAISE NOTICE 'pgvector is not available, document search will rank in the application';
+END
+$$;
+
+CREATE TABLE IF NOT EXISTS documents (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    attachment_id UUID NOT NULL UNIQUE REFERENCES attachments(id) ON DELETE CASCADE,
+    conversation_id UUID REFERENCES conversations(id) ON DELETE CASCADE,  -- NULL: knowledge base
+    filename VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',  -- pending, processing, ready, failed
+    error TEXT NOT NULL DEFAULT '',
+    chunk_count INTEGER NOT NULL DEFAULT 0,
+    embedding_model VARCHAR(255) NOT NULL DEFAULT '',  -- model identifier the chunks were embedded with
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_documents_user ON documents(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_documents_conversation ON documents(conversation_id);
+CREATE INDEX IF NOT EXISTS idx_documents_status ON documents(status) WHERE status IN ('pending', 'processing');
+
+CREATE TABLE IF NOT EXISTS document_chunks (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    document_id UUID NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    chunk_index INTEGER NOT NULL,
+    content TEXT NOT NULL,
+    embedding REAL[] NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (document_id, chunk_index)
+);
+
+CREATE INDEX IF NOT EXISTS idx_document_chunks_user ON document_chunks(user_id);
+
+DROP TRIGGER IF EXISTS update_documents_updated_at ON documents;
+CREATE TRIGGER update_documents_updated_at BEFORE UPDATE ON documents
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- pgvector: a vector copy of every embedding, kept in step by a trigger, and
+-- cosine HNSW indexes per embedding size (an index needs a fixed size). The
+-- sizes must match indexedVectorDims in document_repository.go.
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'vector') THEN
+        RETURN;
+    END IF;
+
+    EXECUTE 'ALTER TABLE document_chunks ADD COLUMN IF NOT EXISTS embedding_vector vector';
+    EXECUTE $fn$
+        CREATE OR REPLACE FUNCTION set_document_chunk_vector() RETURNS TRIGGER AS $body$
+        BEGIN
+            NEW.embedding_vector := NEW.embedding::vector;
+            RETURN NEW;
+        END;
+        $body$ LANGUAGE plpgsql
+    $fn$;
+    EXECUTE 'DROP TRIGGER IF EXISTS set_document_chunks_vector ON document_chunks';
+    EXECUTE 'CREATE TRIGGER set_document_chunks_vector BEFORE INSERT OR UPDATE OF embedding ON document_chunks
+        FOR EACH ROW EXECUTE FUNCTION set_document_chunk_vector()';
+    EXECUTE 'UPDATE document_chunks SET embedding_vector = embedding::vector WHERE embedding_vector IS NULL';
+
+    BEGIN
+        EXECUTE 'CREATE INDEX IF NOT EXISTS idx_document_chunks_vector_768 ON document_chunks
+            USING hnsw ((embedding_vector::vector(768)) vector_cosine_ops) WHERE vector_dims(embedding_vector) = 768';
+        EXECUTE 'CREATE INDEX IF NOT EXISTS idx_document_chunks_vector_1024 ON document_chunks
+            USING hnsw ((embedding_vector::vector(1024)) vector_cosine_ops) WHERE vector_dims(embedding_vector) = 1024';
+        EXECUTE 'CREATE INDEX IF NOT EXISTS idx_document_chunks_vector_1536 ON document_chunks
+            USING hnsw ((embedding_vector::vector(1536)) vector_cosine_ops) WHERE vector_dims(embedding_vector) = 1536';
+    EXCEPTION WHEN OTHERS THEN
+        RAISE NOTICE 'pgvector has no HNSW support (0.5 or later), document search will not be indexed';
+    END;
+END
+$$;
@@ -0,0 +1,11 @@
+-- Migration 018: Embedding models
+-- Models declare whether they serve the embeddings API; the setting names the
+-- one used for documents and other semantic features (empty: the first active
+-- model that supports embeddings)
+
+ALTER TABLE ai_models
+    ADD COLUMN IF NOT EXISTS supports_embeddings BOOLEAN NOT NULL DEFAULT false;
+
+INSERT INTO system_settings (setting_key, setting_value, description, value_type) VALUES
+    ('ai_embedding_model', '', '向量嵌入模型', 'string')
+ON CONFLICT (setting_key) DO NOTHING;
@@ -0,0 +1,19 @@
+-- Migration 019: Memory embeddings
+-- Memories are embedded so the ones injected into a conversation can be ranked
+-- by relevance to the current message. The embedding is cleared when the
+-- content changes and is recomputed lazily, also after the embedding model
+-- is switched.
+
+ALTER TABLE memories
+    ADD COLUMN IF NOT EXISTS embedding REAL[],
+    ADD COLUMN IF NOT EXISTS embedding_model VARCHAR(255) NOT NULL DEFAULT '';  -- model identifier the embedding was computed with
+
+-- updated_at marks changes to what a memory says, which ranking treats as
+-- recent; recording its use or its embedding leaves it alone
+DROP TRIGGER IF EXISTS update_memories_updated_at ON memories;
+CREATE TRIGGER update_memories_updated_at BEFORE UPDATE ON memories
+    FOR EACH ROW
+    WHEN (OLD.content IS DISTINCT FROM NEW.content
+        OR OLD.category IS DISTINCT FROM NEW.category
+        OR OLD.importance IS DISTINCT FROM NEW.importance)
+    EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,29 @@
+-- Migration 020: Memory extraction job queue
+-- One job per conversation collects the turns not yet read for memories.
+-- A job becomes due after enough turns or once the conversation has been idle;
+-- workers claim due jobs with SKIP LOCKED, retry failures with backoff and
+-- advance the watermark past the messages they processed.
+
+CREATE TABLE IF NOT EXISTS memory_extraction_jobs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    conversation_id UUID NOT NULL UNIQUE REFERENCES conversations(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',  -- pending, running, done, failed
+    pending_turns INTEGER NOT NULL DEFAULT 0,       -- turns since the last extraction
+    run_after TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    attempts INTEGER NOT NULL DEFAULT 0,            -- consecutive failed attempts
+    last_error TEXT NOT NULL DEFAULT '',
+    watermark_at TIMESTAMP,                         -- created_at of the last message processed
+    watermark_id UUID,                              -- and its id, which orders messages created in the same instant
+    locked_at TIMESTAMP,                            -- when a worker claimed the job
+    finished_at TIMESTAMP,                          -- last successful run
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_memory_extraction_jobs_due ON memory_extraction_jobs(run_after) WHERE status IN ('pending', 'running');
+CREATE INDEX IF NOT EXISTS idx_memory_extraction_jobs_status ON memory_extraction_jobs(status, updated_at DESC);
+
+DROP TRIGGER IF EXISTS update_memory_extraction_jobs_updated_at ON memory_extraction_jobs;
+CREATE TRIGGER update_memory_extraction_jobs_updated_at BEFORE UPDATE ON memory_extraction_jobs
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
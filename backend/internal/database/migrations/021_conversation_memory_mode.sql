@@ -0,0 +1,9 @@
+-- Migration 021: Per-conversation memory mode
+-- full: memories are injected and extracted; read_only: injected only;
+-- off: neither; ephemeral: neither, and the conversation is deleted once idle.
+-- Only full conversations are found by the history search tool.
+
+ALTER TABLE conversations
+    ADD COLUMN IF NOT EXISTS memory_mode VARCHAR(20) NOT NULL DEFAULT 'full';
+
+CREATE INDEX IF NOT EXISTS idx_conversations_ephemeral ON conversations(last_message_at) WHERE memory_mode = 'ephemeral';
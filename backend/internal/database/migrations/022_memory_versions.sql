@@ -0,0 +1,34 @@
+-- Migration 022: Memory version history
+-- Every change to a memory - by the user, extraction, consolidation, import,
+-- a rollback or cleanup - records the memory's state afterwards as a new
+-- version. Deletions are recorded too, and versions are kept after the memory
+-- is deleted, so deleted memories can be restored. memory_id and the source
+-- columns therefore have no foreign keys. History older than
+-- MEMORY_VERSION_RETENTION_DAYS is pruned down to each memory's current state.
+
+CREATE TABLE IF NOT EXISTS memory_versions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    memory_id UUID NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    change_type VARCHAR(20) NOT NULL,  -- create, update, delete, restore
+    changed_by VARCHAR(20) NOT NULL,   -- user, extraction, consolidation, import, rollback, cleanup
+    content TEXT NOT NULL,
+    category memory_category NOT NULL,
+    importance INTEGER NOT NULL,
+    source_conversation_id UUID,
+    source_message_id UUID,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (memory_id, version)
+);
+
+CREATE INDEX IF NOT EXISTS idx_memory_versions_user ON memory_versions(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_memory_versions_created ON memory_versions(created_at);
+
+-- Existing memories start their history at their current state
+INSERT INTO memory_versions (memory_id, user_id, version, change_type, changed_by, content, category, importance,
+    source_conversation_id, source_message_id, created_at)
+SELECT id, user_id, 1, 'create', CASE WHEN source_conversation_id IS NULL THEN 'user' ELSE 'extraction' END,
+    content, category, importance, source_conversation_id, source_message_id, updated_at
+FROM memories
+ON CONFLICT (memory_id, version) DO NOTHING;
@@ -6,40 +6,158 @@ import (
 	"github.com/google/uuid"
 )
 
+// Provider types determine which wire format is used to talk to the upstream API
+const (
+	ProviderTypeOpenAI    = "openai"
+	ProviderTypeAnthropic = "anthropic"
+	ProviderTypeGemini    = "gemini"
+	ProviderTypeAzure     = "azure"
+	ProviderTypeLocal     = "local"  // self-hosted runtime (Ollama, llama.cpp, vLLM), no API key
+	ProviderTypeCustom    = "custom" // any OpenAI-compatible endpoint
+)
+
+// Key selection strategies for providers with several API keys
+const (
+	KeySelectionRoundRobin = "round_robin"
+	KeySelectionLeastUsed  = "least_used"
+)
+
 // AIProvider represents an AI API provider configuration
 type AIProvider struct {
-	ID              uuid.UUID  `json:"id" db:"id"`
-	Name            string     `json:"name" db:"name"`
-	DisplayName     string     `json:"display_name" db:"display_name"`
-	ProviderType    string     `json:"provider_type" db:"provider_type"` // openai | anthropic | custom
-	APIEndpoint     string     `json:"api_endpoint" db:"api_endpoint"`
-	APIKeyEncrypted string     `json:"-" db:"api_key_encrypted"`
-	IsActive        bool       `json:"is_active" db:"is_active"`
-	Description     string     `json:"description,omitempty" db:"description"`
-	CreatedBy       *uuid.UUID `json:"created_by,omitempty" db:"created_by"`
-	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
-	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
+	ID              uuid.UUID `json:"id" db:"id"`
+	Name            string    `json:"name" db:"name"`
+	DisplayName     string    `json:"display_name" db:"display_name"`
+	ProviderType    string    `json:"provider_type" db:"provider_type"` // openai | anthropic | gemini | azure | local | custom
+	APIEndpoint     string    `json:"api_endpoint" db:"api_endpoint"`
+	APIKeyEncrypted string    `json:"-" db:"api_key_encrypted"`
+	IsActive        bool      `json:"is_active" db:"is_active"`
+	Description     string    `json:"description,omitempty" db:"description"`
+
+	// Azure OpenAI
+	AzureResource    string            `json:"azure_resource,omitempty" db:"azure_resource"`
+	AzureAPIVersion  string            `json:"azure_api_version,omitempty" db:"azure_api_version"`
+	AzureDeployments map[string]string `json:"azure_deployments,omitempty" db:"azure_deployments"` // model identifier → deployment name
+
+	// Retry behaviour for transient upstream failures
+	RetryPolicy RetryPolicy `json:"retry_policy" db:"retry_policy"`
+
+	// How a key is picked from the provider's key pool
+	KeySelection string `json:"key_selection" db:"key_selection"` // round_robin | least_used
+
+	CreatedBy *uuid.UUID `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
 
 	// Computed
-	ModelCount int `json:"model_count,omitempty" db:"-"`
+	ModelCount int             `json:"model_count,omitempty" db:"-"`
+	Health     *ProviderHealth `json:"health,omitempty" db:"-"`
+}
+
+// ProviderHealth is the live circuit breaker view of a provider, kept in memory
+type ProviderHealth struct {
+	State          string     `json:"state"` // closed | open | half-open
+	RecentRequests int        `json:"recent_requests"`
+	ErrorRate      float64    `json:"error_rate"`     // 0..1 over the last few minutes
+	P95LatencyMs   int64      `json:"p95_latency_ms"` // time to response headers
+	OpenedAt       *time.Time `json:"opened_at,omitempty"`
+}
+
+// RetryPolicy controls how transient upstream failures (connection errors,
+// 429, 502/503/504) are retried. Zero values fall back to the defaults.
+type RetryPolicy struct {
+	MaxAttempts         int     `json:"max_attempts,omitempty" binding:"omitempty,min=1,max=10"`           // including the first attempt, default 3
+	InitialBackoffMs    int     `json:"initial_backoff_ms,omitempty" binding:"omitempty,min=1,max=60000"`  // default 500
+	MaxBackoffMs        int     `json:"max_backoff_ms,omitempty" binding:"omitempty,min=1,max=300000"`     // default 8000
+	Jitter              float64 `json:"jitter,omitempty" binding:"omitempty,min=0,max=1"`                  // fraction of each backoff randomized, default 0.2
+	TotalTimeoutSeconds int     `json:"total_timeout_seconds,omitempty" binding:"omitempty,min=1,max=600"` // deadline across all attempts, default 120
 }
 
 // AIProviderCreateRequest represents request to create a provider
 type AIProviderCreateRequest struct {
 	Name         string `json:"name" binding:"required,min=1,max=100"`
 	DisplayName  string `json:"display_name" binding:"required,min=1,max=100"`
-	ProviderType string `json:"provider_type" binding:"required"`
-	APIEndpoint  string `json:"api_endpoint" binding:"required"`
-	APIKey       string `json:"api_key" binding:"required"`
+	ProviderType string `json:"provider_type" binding:"required,oneof=openai anthropic gemini azure local custom"`
+	APIEndpoint  string `json:"api_endpoint"` // required unless azure_resource is set
+	APIKey       string `json:"api_key"`      // optional for local runtimes
 	Description  string `json:"description"`
+
+	AzureResource    string            `json:"azure_resource"`
+	AzureAPIVersion  string            `json:"azure_api_version"`
+	AzureDeployments map[string]string `json:"azure_deployments"`
+
+	RetryPolicy  *RetryPolicy `json:"retry_policy"`
+	KeySelection string       `json:"key_selection" binding:"omitempty,oneof=round_robin least_used"`
 }
 
 // AIProviderUpdateRequest represents request to update a provider
 type AIProviderUpdateRequest struct {
 	DisplayName  *string `json:"display_name" binding:"omitempty,min=1,max=100"`
-	ProviderType *string `json:"provider_type"`
+	ProviderType *string `json:"provider_type" binding:"omitempty,oneof=openai anthropic gemini azure local custom"`
 	APIEndpoint  *string `json:"api_endpoint"`
 	APIKey       *string `json:"api_key"`
 	IsActive     *bool   `json:"is_active"`
 	Description  *string `json:"description"`
+
+	AzureResource    *string           `json:"azure_resource"`
+	AzureAPIVersion  *string           `json:"azure_api_version"`
+	AzureDeployments map[string]string `json:"azure_deployments"`
+
+	RetryPolicy  *RetryPolicy `json:"retry_policy"`
+	KeySelection *string      `json:"key_selection" binding:"omitempty,oneof=round_robin least_used"`
+}
+
+// ProviderAPIKey is one key in a provider's key pool
+type ProviderAPIKey struct {
+	ID              uuid.UUID  `json:"id" db:"id"`
+	ProviderID      uuid.UUID  `json:"provider_id" db:"provider_id"`
+	Name            string     `json:"name" db:"name"`
+	APIKeyEncrypted string     `json:"-" db:"api_key_encrypted"`
+	KeyHint         string     `json:"key_hint" db:"key_hint"` // last characters of the key, for display
+	IsActive        bool       `json:"is_active" db:"is_active"`
+	DisabledReason  string     `json:"disabled_reason,omitempty" db:"disabled_reason"`
+	DisabledAt      *time.Time `json:"disabled_at,omitempty" db:"disabled_at"`
+
+	// Usage
+	RequestCount int64      `json:"request_count" db:"request_count"`
+	InputTokens  int64      `json:"input_tokens" db:"input_tokens"`
+	OutputTokens int64      `json:"output_tokens" db:"output_tokens"`
+	LastUsedAt   *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+
+	CreatedBy *uuid.UUID `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// ProviderAPIKeyCreateRequest represents request to add a key to a provider's pool
+type ProviderAPIKeyCreateRequest struct {
+	Name   string `json:"name" binding:"required,min=1,max=100"`
+	APIKey string `json:"api_key" binding:"required"`
+}
+
+// ProviderAPIKeyUpdateRequest represents request to update a pooled key
+type ProviderAPIKeyUpdateRequest struct {
+	Name     *string `json:"name" binding:"omitempty,min=1,max=100"`
+	APIKey   *string `json:"api_key"`
+	IsActive *bool   `json:"is_active"` // re-enabling clears the disabled reason
+}
+
+// DiscoveredModel represents a model reported by a provider's model listing endpoint
+type DiscoveredModel struct {
+	ModelIdentifier string `json:"model_identifier"`
+	DisplayName     string `json:"display_name,omitempty"`
+	MaxTokens       int    `json:"max_tokens,omitempty"` // context length, when the provider reports it
+	AlreadyImported bool   `json:"already_imported"`
+
+	// Capabilities reported by the provider or known from the model catalog
+	SupportsStreaming bool `json:"supports_streaming"`
+	SupportsFunctions bool `json:"supports_functions"`
+
+	// Known list pricing, filled in for well-known models
+	InputPricePer1k  *float64 `json:"input_price_per_1k,omitempty"`
+	OutputPricePer1k *float64 `json:"output_price_per_1k,omitempty"`
+}
+
+// ProviderModelImportRequest represents request to create models from discovered identifiers
+type ProviderModelImportRequest struct {
+	ModelIdentifiers []string `json:"model_identifiers" binding:"required,min=1"`
 }
@@ -0,0 +1,29 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Attachment is an uploaded file. The bytes live in object storage under
+// StorageKey; the row links the file to its owner and, once sent, to a message.
+type Attachment struct {
+	ID             uuid.UUID  `json:"id" db:"id"`
+	UserID         uuid.UUID  `json:"user_id" db:"user_id"`
+	ConversationID *uuid.UUID `json:"conversation_id,omitempty" db:"conversation_id"`
+	MessageID      *uuid.UUID `json:"message_id,omitempty" db:"message_id"`
+	StorageKey     string     `json:"-" db:"storage_key"`
+	Filename       string     `json:"filename" db:"filename"`
+	ContentType    string     `json:"content_type" db:"content_type"`
+	SizeBytes      int64      `json:"size_bytes" db:"size_bytes"`
+	Checksum       string     `json:"checksum" db:"checksum"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+}
+
+// AttachmentUsage summarizes a user's stored files against their quota
+type AttachmentUsage struct {
+	Count      int   `json:"count"`
+	UsedBytes  int64 `json:"used_bytes"`
+	QuotaBytes int64 `json:"quota_bytes"`
+}
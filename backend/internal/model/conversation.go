@@ -1,6 +1,9 @@
 package model
 
 import (
+	"encoding/json"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -15,38 +18,131 @@ type Conversation struct {
 	MessageCount  int        `json:"message_count" db:"message_count"`
 	TotalTokens   int        `json:"total_tokens" db:"total_tokens"`
 	LastMessageAt *time.Time `json:"last_message_at,omitempty" db:"last_message_at"`
-	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
-	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
+	MemoryMode    MemoryMode `json:"memory_mode" db:"memory_mode"`
+
+	// Rolling summary of turns that no longer fit the context window
+	Summary          string     `json:"summary,omitempty" db:"summary"`
+	SummaryUpTo      *time.Time `json:"-" db:"summary_up_to"`    // created_at of the last summarized message
+	SummaryUpToID    *uuid.UUID `json:"-" db:"summary_up_to_id"` // and its ID
+	SummaryUpdatedAt *time.Time `json:"summary_updated_at,omitempty" db:"summary_updated_at"`
+
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// SummaryCursor returns the position of the last summarized message, or nil
+// when nothing has been summarized yet
+func (c *Conversation) SummaryCursor() *MessageCursor {
+	if c.SummaryUpTo == nil || c.SummaryUpToID == nil {
+		return nil
+	}
+	return &MessageCursor{CreatedAt: *c.SummaryUpTo, ID: *c.SummaryUpToID}
+}
+
+// MessageCursor is a position in a conversation's messages. Messages are
+// ordered by creation time and then ID, so a cursor neither skips nor repeats
+// messages created in the same instant.
+type MessageCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// Cursor returns the message's position in its conversation
+func (m *Message) Cursor() MessageCursor {
+	return MessageCursor{CreatedAt: m.CreatedAt, ID: m.ID}
+}
+
+// MemoryMode controls how a conversation uses the user's long-term memory
+type MemoryMode string
+
+const (
+	MemoryModeFull      MemoryMode = "full"      // memories are injected and extracted
+	MemoryModeReadOnly  MemoryMode = "read_only" // memories are injected, nothing is extracted
+	MemoryModeOff       MemoryMode = "off"       // memory is neither read nor written
+	MemoryModeEphemeral MemoryMode = "ephemeral" // like off, and the conversation is deleted once idle
+)
+
+// ReadsMemory reports whether memories are injected into the conversation
+func (m MemoryMode) ReadsMemory() bool {
+	return m == MemoryModeFull || m == MemoryModeReadOnly
+}
+
+// WritesMemory reports whether memories are extracted from the conversation
+func (m MemoryMode) WritesMemory() bool {
+	return m == MemoryModeFull
 }
 
 // ConversationCreateRequest represents request to create a conversation
 type ConversationCreateRequest struct {
-	Title   string     `json:"title" binding:"omitempty,max=255"`
-	ModelID *uuid.UUID `json:"model_id"`
+	Title      string     `json:"title" binding:"omitempty,max=255"`
+	ModelID    *uuid.UUID `json:"model_id"`
+	MemoryMode MemoryMode `json:"memory_mode" binding:"omitempty,oneof=full read_only off ephemeral"`
 }
 
-// ConversationUpdateRequest represents request to update a conversation
+// ConversationUpdateRequest represents request to update a conversation.
+// A conversation cannot be made ephemeral, or stop being ephemeral, after
+// it was created.
 type ConversationUpdateRequest struct {
-	Title *string `json:"title" binding:"omitempty,max=255"`
+	Title      *string     `json:"title" binding:"omitempty,max=255"`
+	MemoryMode *MemoryMode `json:"memory_mode" binding:"omitempty,oneof=full read_only off"`
 }
 
 // Message represents a chat message
 type Message struct {
-	ID             uuid.UUID  `json:"id" db:"id"`
-	ConversationID uuid.UUID  `json:"conversation_id" db:"conversation_id"`
-	Role           string     `json:"role" db:"role"` // "user", "assistant", "system"
-	Content        string     `json:"content" db:"content"`
-	InputTokens    *int       `json:"input_tokens,omitempty" db:"input_tokens"`
-	OutputTokens   *int       `json:"output_tokens,omitempty" db:"output_tokens"`
-	TotalTokens    *int       `json:"total_tokens,omitempty" db:"total_tokens"`
-	ModelID        *uuid.UUID `json:"model_id,omitempty" db:"model_id"`
-	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
-}
-
-// MessageCreateRequest represents request to create a message
+	ID             uuid.UUID     `json:"id" db:"id"`
+	ConversationID uuid.UUID     `json:"conversation_id" db:"conversation_id"`
+	Role           string        `json:"role" db:"role"` // "user", "assistant", "system", "tool"
+	Content        string        `json:"content" db:"content"`
+	ToolCalls      []ToolCall    `json:"tool_calls,omitempty" db:"tool_calls"`       // tools an assistant turn called
+	ToolCallID     string        `json:"tool_call_id,omitempty" db:"tool_call_id"`   // call a tool result answers
+	Parts          []ContentPart `json:"content_parts,omitempty" db:"content_parts"` // text and images of a multi-part message
+	InputTokens    *int          `json:"input_tokens,omitempty" db:"input_tokens"`
+	OutputTokens   *int          `json:"output_tokens,omitempty" db:"output_tokens"`
+	TotalTokens    *int          `json:"total_tokens,omitempty" db:"total_tokens"`
+	ModelID        *uuid.UUID    `json:"model_id,omitempty" db:"model_id"`
+	CreatedAt      time.Time     `json:"created_at" db:"created_at"`
+
+	Attachments []*Attachment `json:"attachments,omitempty" db:"-"` // files linked to the message, loaded separately
+}
+
+// MessageSearchResult is a message matched by a search over a user's conversations
+type MessageSearchResult struct {
+	MessageID         uuid.UUID `json:"message_id"`
+	ConversationID    uuid.UUID `json:"conversation_id"`
+	ConversationTitle string    `json:"conversation_title"`
+	Role              string    `json:"role"`
+	Content           string    `json:"content"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// MessageCreateRequest represents request to create a message. Content is
+// either a string or an array of content parts (text and image_url); for the
+// latter, Parts holds the array and Content its joined text. AttachmentIDs
+// are previously uploaded files to link to the new message.
 type MessageCreateRequest struct {
-	Content string `json:"content" binding:"required,min=1"`
-	ModelID *uuid.UUID `json:"model_id"`
+	Content       string        `json:"-" binding:"required_without_all=Parts AttachmentIDs"`
+	Parts         []ContentPart `json:"-"`
+	ModelID       *uuid.UUID    `json:"model_id"`
+	AttachmentIDs []uuid.UUID   `json:"attachment_ids" binding:"omitempty,max=10"`
+}
+
+// UnmarshalJSON decodes either form of the content field
+func (r *MessageCreateRequest) UnmarshalJSON(data []byte) error {
+	var in struct {
+		Content       json.RawMessage `json:"content"`
+		ModelID       *uuid.UUID      `json:"model_id"`
+		AttachmentIDs []uuid.UUID     `json:"attachment_ids"`
+	}
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+
+	content, parts, err := decodeContent(in.Content)
+	if err != nil {
+		return err
+	}
+	*r = MessageCreateRequest{Content: content, Parts: parts, ModelID: in.ModelID, AttachmentIDs: in.AttachmentIDs}
+	return nil
 }
 
 // ChatCompletionRequest represents OpenAI-compatible chat request
@@ -60,12 +156,219 @@ type ChatCompletionRequest struct {
 	TopP        *float64               `json:"top_p,omitempty"`
 	N           *int                   `json:"n,omitempty"`
 	User        string                 `json:"user,omitempty"`
+	StreamOptions *ChatCompletionStreamOptions `json:"stream_options,omitempty"`
+	Tools       []Tool                 `json:"tools,omitempty"`
+	ToolChoice  *ToolChoice            `json:"tool_choice,omitempty"`
 }
 
-// ChatMessage represents a chat message in OpenAI format
+// ChatCompletionStreamOptions controls extra data sent with streaming responses
+type ChatCompletionStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// ChatMessage represents a chat message in OpenAI format. Content is the
+// message text; a multi-part message (text and images) also carries Parts,
+// which is sent in place of Content.
 type ChatMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role       string        `json:"role"`
+	Content    string        `json:"-"`
+	Parts      []ContentPart `json:"-"`
+	ToolCalls  []ToolCall    `json:"tool_calls,omitempty"`   // set on assistant turns that call tools
+	ToolCallID string        `json:"tool_call_id,omitempty"` // set on "tool" turns carrying a result
+}
+
+// chatMessageFields is ChatMessage without its JSON methods
+type chatMessageFields ChatMessage
+
+// MarshalJSON encodes content as a string, or as an array of parts
+func (m ChatMessage) MarshalJSON() ([]byte, error) {
+	out := struct {
+		chatMessageFields
+		Content interface{} `json:"content"`
+	}{chatMessageFields: chatMessageFields(m), Content: m.Content}
+	if len(m.Parts) > 0 {
+		out.Content = m.Parts
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON decodes content given as a string, an array of parts or null
+func (m *ChatMessage) UnmarshalJSON(data []byte) error {
+	var in struct {
+		chatMessageFields
+		Content json.RawMessage `json:"content"`
+	}
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+
+	content, parts, err := decodeContent(in.Content)
+	if err != nil {
+		return err
+	}
+	*m = ChatMessage(in.chatMessageFields)
+	m.Content, m.Parts = content, parts
+	return nil
+}
+
+// HasImages reports whether the message carries any image parts
+func (m ChatMessage) HasImages() bool {
+	return hasImages(m.Parts)
+}
+
+// Content part types
+const (
+	ContentPartText     = "text"
+	ContentPartImageURL = "image_url"
+)
+
+// ContentPart is one element of multi-part message content
+type ContentPart struct {
+	Type     string    `json:"type"` // "text" or "image_url"
+	Text     string    `json:"text,omitempty"`
+	ImageURL *ImageURL `json:"image_url,omitempty"`
+}
+
+// ImageURL points at an image by http(s) URL or base64 data URL
+type ImageURL struct {
+	URL    string `json:"url"`
+	Detail string `json:"detail,omitempty"` // "auto", "low" or "high"
+}
+
+// DataURL splits a base64 data URL into its media type and payload; ok is
+// false for any other kind of URL
+func (u *ImageURL) DataURL() (mediaType, data string, ok bool) {
+	rest, found := strings.CutPrefix(u.URL, "data:")
+	if !found {
+		return "", "", false
+	}
+	header, data, found := strings.Cut(rest, ",")
+	if !found {
+		return "", "", false
+	}
+	mediaType, found = strings.CutSuffix(header, ";base64")
+	if !found {
+		return "", "", false
+	}
+	return mediaType, data, true
+}
+
+// decodeContent reads a content field that is a string, an array of parts or
+// null. For an array, the returned text is its text parts joined by newlines.
+func decodeContent(raw json.RawMessage) (string, []ContentPart, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return "", nil, nil
+	}
+
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text, nil, nil
+	}
+
+	var parts []ContentPart
+	if err := json.Unmarshal(raw, &parts); err != nil {
+		return "", nil, fmt.Errorf("content must be a string or an array of content parts")
+	}
+	return PartsText(parts), parts, nil
+}
+
+// PartsText joins the text parts of multi-part content
+func PartsText(parts []ContentPart) string {
+	var texts []string
+	for _, part := range parts {
+		if part.Type == ContentPartText && part.Text != "" {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// hasImages reports whether any part is an image
+func hasImages(parts []ContentPart) bool {
+	for _, part := range parts {
+		if part.Type == ContentPartImageURL {
+			return true
+		}
+	}
+	return false
+}
+
+// HasImages reports whether the request carries any image parts
+func (r *MessageCreateRequest) HasImages() bool {
+	return hasImages(r.Parts)
+}
+
+// Tool describes a function the model may call
+type Tool struct {
+	Type     string             `json:"type"` // always "function"
+	Function FunctionDefinition `json:"function"`
+}
+
+// FunctionDefinition is a callable function and the JSON Schema of its arguments
+type FunctionDefinition struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
+
+// ToolCall is a model's request to call a tool
+type ToolCall struct {
+	Index    *int         `json:"index,omitempty"` // position of the call, only in stream deltas
+	ID       string       `json:"id,omitempty"`
+	Type     string       `json:"type,omitempty"`
+	Function FunctionCall `json:"function"`
+}
+
+// FunctionCall names the function to call and carries its JSON-encoded arguments
+type FunctionCall struct {
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments"`
+}
+
+// Tool choice modes
+const (
+	ToolChoiceAuto     = "auto"
+	ToolChoiceNone     = "none"
+	ToolChoiceRequired = "required"
+)
+
+// ToolChoice controls tool use: one of the ToolChoice modes, or a specific
+// function the model must call. On the wire it is either a string or
+// {"type":"function","function":{"name":...}}.
+type ToolChoice struct {
+	Mode     string
+	Function string
+}
+
+// MarshalJSON encodes the choice in OpenAI format
+func (c ToolChoice) MarshalJSON() ([]byte, error) {
+	if c.Function != "" {
+		return json.Marshal(map[string]interface{}{
+			"type":     "function",
+			"function": map[string]string{"name": c.Function},
+		})
+	}
+	return json.Marshal(c.Mode)
+}
+
+// UnmarshalJSON decodes either form of the OpenAI tool_choice field
+func (c *ToolChoice) UnmarshalJSON(data []byte) error {
+	var mode string
+	if err := json.Unmarshal(data, &mode); err == nil {
+		*c = ToolChoice{Mode: mode}
+		return nil
+	}
+
+	var named struct {
+		Function struct {
+			Name string `json:"name"`
+		} `json:"function"`
+	}
+	if err := json.Unmarshal(data, &named); err != nil || named.Function.Name == "" {
+		return fmt.Errorf("invalid tool_choice: %s", data)
+	}
+	*c = ToolChoice{Function: named.Function.Name}
+	return nil
 }
 
 // ChatCompletionResponse represents OpenAI-compatible chat response
@@ -99,6 +402,7 @@ type ChatCompletionStreamResponse struct {
 	Created int64                         `json:"created"`
 	Model   string                        `json:"model"`
 	Choices []ChatCompletionStreamChoice  `json:"choices"`
+	Usage   *ChatCompletionUsage          `json:"usage,omitempty"` // only set on the final chunk when requested
 }
 
 // ChatCompletionStreamChoice represents a choice in streaming response
@@ -110,6 +414,26 @@ type ChatCompletionStreamChoice struct {
 
 // ChatMessageDelta represents incremental message content
 type ChatMessageDelta struct {
-	Role    string `json:"role,omitempty"`
-	Content string `json:"content,omitempty"`
+	Role      string     `json:"role,omitempty"`
+	Content   string     `json:"content,omitempty"`
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"` // fragments keyed by Index; arguments arrive in pieces
+}
+
+// EmbeddingRequest represents an OpenAI-compatible embeddings request
+type EmbeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+// EmbeddingResponse represents an OpenAI-compatible embeddings response
+type EmbeddingResponse struct {
+	Data  []EmbeddingData     `json:"data"`
+	Model string              `json:"model"`
+	Usage ChatCompletionUsage `json:"usage"` // only prompt and total tokens are set
+}
+
+// EmbeddingData is the embedding of one input, identified by its position
+type EmbeddingData struct {
+	Index     int       `json:"index"`
+	Embedding []float32 `json:"embedding"`
 }
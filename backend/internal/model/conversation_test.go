@@ -0,0 +1,44 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+)
+
+func TestMessageCreateRequestValidation(t *testing.T) {
+	ids := make([]string, 11)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("%q", uuid.New())
+	}
+
+	tests := []struct {
+		name  string
+		body  string
+		valid bool
+	}{
+		{"text", `{"content": "hello"}`, true},
+		{"image only", `{"content": [{"type": "image_url", "image_url": {"url": "data:image/png;base64,AA=="}}]}`, true},
+		{"attachment only", `{"attachment_ids": [` + ids[0] + `]}`, true},
+		{"empty", `{"content": ""}`, false},
+		{"nothing", `{}`, false},
+		{"too many attachments", `{"content": "hi", "attachment_ids": [` + strings.Join(ids, ",") + `]}`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req MessageCreateRequest
+			if err := json.Unmarshal([]byte(tt.body), &req); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			err := binding.Validator.ValidateStruct(&req)
+			if (err == nil) != tt.valid {
+				t.Errorf("ValidateStruct() error = %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DocumentStatus tracks a document through text extraction and embedding
+type DocumentStatus string
+
+const (
+	DocumentStatusPending    DocumentStatus = "pending"
+	DocumentStatusProcessing DocumentStatus = "processing"
+	DocumentStatusReady      DocumentStatus = "ready"
+	DocumentStatusFailed     DocumentStatus = "failed"
+)
+
+// Document is an attachment indexed for retrieval. With a ConversationID it
+// is searched only in that conversation; without one it belongs to the
+// user's knowledge base and is searched in all of them.
+type Document struct {
+	ID             uuid.UUID      `json:"id" db:"id"`
+	UserID         uuid.UUID      `json:"user_id" db:"user_id"`
+	AttachmentID   uuid.UUID      `json:"attachment_id" db:"attachment_id"`
+	ConversationID *uuid.UUID     `json:"conversation_id,omitempty" db:"conversation_id"`
+	Filename       string         `json:"filename" db:"filename"`
+	Status         DocumentStatus `json:"status" db:"status"`
+	Error          string         `json:"error,omitempty" db:"error"`
+	ChunkCount     int            `json:"chunk_count" db:"chunk_count"`
+	EmbeddingModel string         `json:"embedding_model" db:"embedding_model"`
+	CreatedAt      time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at" db:"updated_at"`
+}
+
+// DocumentCreateRequest indexes an uploaded attachment. ConversationID scopes
+// the document to a conversation; omit it to add it to the knowledge base.
+type DocumentCreateRequest struct {
+	AttachmentID   uuid.UUID  `json:"attachment_id" binding:"required"`
+	ConversationID *uuid.UUID `json:"conversation_id"`
+}
+
+// DocumentChunk is a passage of a document with its embedding
+type DocumentChunk struct {
+	ID         uuid.UUID `json:"id" db:"id"`
+	DocumentID uuid.UUID `json:"document_id" db:"document_id"`
+	UserID     uuid.UUID `json:"user_id" db:"user_id"`
+	ChunkIndex int       `json:"chunk_index" db:"chunk_index"`
+	Content    string    `json:"content" db:"content"`
+	Embedding  []float32 `json:"-" db:"embedding"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+// DocumentExcerpt is a chunk retrieved for a query, with its source and similarity
+type DocumentExcerpt struct {
+	DocumentID uuid.UUID `json:"document_id"`
+	Filename   string    `json:"filename"`
+	ChunkIndex int       `json:"chunk_index"`
+	Content    string    `json:"content"`
+	Score      float64   `json:"score"` // cosine similarity
+}
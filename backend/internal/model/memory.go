@@ -28,6 +28,10 @@ type Memory struct {
 	LastUsedAt           *time.Time      `json:"last_used_at,omitempty" db:"last_used_at"`
 	CreatedAt            time.Time       `json:"created_at" db:"created_at"`
 	UpdatedAt            time.Time       `json:"updated_at" db:"updated_at"`
+
+	// Embedding of the content, loaded only for ranking; nil when missing or
+	// computed with another model
+	Embedding []float32 `json:"-" db:"embedding"`
 }
 
 // MemoryCreateRequest represents request to create memory
@@ -44,9 +48,106 @@ type MemoryUpdateRequest struct {
 	Importance *int            `json:"importance" binding:"omitempty,min=1,max=10"`
 }
 
-// MemoryExtractionResult represents extracted memories from conversation
+// MemoryExport is the JSON format memories are exported and imported in
+type MemoryExport struct {
+	Version    int                `json:"version"`
+	ExportedAt time.Time          `json:"exported_at"`
+	Memories   []MemoryExportItem `json:"memories"`
+}
+
+// MemoryExportItem is one exported memory
+type MemoryExportItem struct {
+	Content    string         `json:"content"`
+	Category   MemoryCategory `json:"category"`
+	Importance int            `json:"importance"`
+	CreatedAt  *time.Time     `json:"created_at,omitempty"`
+}
+
+// MemoryImportResult reports the outcome of an import. Skipped memories were
+// empty, too long or already known.
+type MemoryImportResult struct {
+	Imported int `json:"imported"`
+	Skipped  int `json:"skipped"`
+}
+
+// MemoryExtractionResult represents extracted memories from conversation.
+// Source is the 1-based number of the message it was taken from, as listed
+// to the extraction model.
 type MemoryExtractionResult struct {
 	Content    string         `json:"content"`
 	Category   MemoryCategory `json:"category"`
 	Importance int            `json:"importance"`
+	Source     int            `json:"source,omitempty"`
+
+	SourceMessageID *uuid.UUID `json:"-"` // message Source refers to, if valid
+}
+
+// MemoryChangeType is what a change did to a memory
+type MemoryChangeType string
+
+const (
+	MemoryChangeCreate  MemoryChangeType = "create"
+	MemoryChangeUpdate  MemoryChangeType = "update"
+	MemoryChangeDelete  MemoryChangeType = "delete"
+	MemoryChangeRestore MemoryChangeType = "restore" // a deleted memory was rolled back
+)
+
+// MemoryChangeSource is who or what changed a memory
+type MemoryChangeSource string
+
+const (
+	MemoryChangedByUser          MemoryChangeSource = "user"
+	MemoryChangedByExtraction    MemoryChangeSource = "extraction"
+	MemoryChangedByConsolidation MemoryChangeSource = "consolidation"
+	MemoryChangedByImport        MemoryChangeSource = "import"
+	MemoryChangedByRollback      MemoryChangeSource = "rollback"
+	MemoryChangedByCleanup       MemoryChangeSource = "cleanup"
+)
+
+// MemoryVersion is a memory's state after a change. A delete version holds
+// the state the memory was deleted in.
+type MemoryVersion struct {
+	ID                   uuid.UUID          `json:"id" db:"id"`
+	MemoryID             uuid.UUID          `json:"memory_id" db:"memory_id"`
+	UserID               uuid.UUID          `json:"user_id" db:"user_id"`
+	Version              int                `json:"version" db:"version"`
+	ChangeType           MemoryChangeType   `json:"change_type" db:"change_type"`
+	ChangedBy            MemoryChangeSource `json:"changed_by" db:"changed_by"`
+	Content              string             `json:"content" db:"content"`
+	Category             MemoryCategory     `json:"category" db:"category"`
+	Importance           int                `json:"importance" db:"importance"`
+	SourceConversationID *uuid.UUID         `json:"source_conversation_id,omitempty" db:"source_conversation_id"`
+	SourceMessageID      *uuid.UUID         `json:"source_message_id,omitempty" db:"source_message_id"`
+	CreatedAt            time.Time          `json:"created_at" db:"created_at"`
+}
+
+// MemoryRollbackRequest represents request to roll a memory back to a version
+type MemoryRollbackRequest struct {
+	Version int `json:"version" binding:"required,min=1"`
+}
+
+// MemorySource is the message a memory was extracted from
+type MemorySource struct {
+	MemoryID       uuid.UUID `json:"memory_id"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	Message        *Message  `json:"message"`
+}
+
+// MemoryAction is how an extracted memory is reconciled with the stored ones
+type MemoryAction string
+
+const (
+	MemoryActionAdd    MemoryAction = "ADD"    // store as a new memory
+	MemoryActionUpdate MemoryAction = "UPDATE" // replace or merge into the target memory
+	MemoryActionDelete MemoryAction = "DELETE" // the target memory is no longer true
+	MemoryActionNoop   MemoryAction = "NOOP"   // already known
+)
+
+// MemoryConsolidationDecision is the model's verdict for one extracted memory.
+// Fact and Target are the 1-based numbers the memories were listed with.
+type MemoryConsolidationDecision struct {
+	Fact    int          `json:"fact"`
+	Action  MemoryAction `json:"action"`
+	Target  int          `json:"target,omitempty"`
+	Content string       `json:"content,omitempty"` // merged content for UPDATE
 }
@@ -0,0 +1,45 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MemoryJobStatus tracks a conversation's memory extraction job
+type MemoryJobStatus string
+
+const (
+	MemoryJobStatusPending MemoryJobStatus = "pending" // waiting for enough turns, idle time or a retry
+	MemoryJobStatusRunning MemoryJobStatus = "running"
+	MemoryJobStatusDone    MemoryJobStatus = "done"   // caught up with the conversation
+	MemoryJobStatusFailed  MemoryJobStatus = "failed" // out of retries until the next turn
+)
+
+// MemoryExtractionJob is a conversation's queued memory extraction. Messages
+// up to the watermark have been processed.
+type MemoryExtractionJob struct {
+	ID             uuid.UUID       `json:"id" db:"id"`
+	UserID         uuid.UUID       `json:"user_id" db:"user_id"`
+	ConversationID uuid.UUID       `json:"conversation_id" db:"conversation_id"`
+	Status         MemoryJobStatus `json:"status" db:"status"`
+	PendingTurns   int             `json:"pending_turns" db:"pending_turns"`
+	RunAfter       time.Time       `json:"run_after" db:"run_after"`
+	Attempts       int             `json:"attempts" db:"attempts"`
+	LastError      string          `json:"last_error,omitempty" db:"last_error"`
+	WatermarkAt    *time.Time      `json:"watermark_at,omitempty" db:"watermark_at"`
+	WatermarkID    *uuid.UUID      `json:"-" db:"watermark_id"`
+	LockedAt       *time.Time      `json:"locked_at,omitempty" db:"locked_at"`
+	FinishedAt     *time.Time      `json:"finished_at,omitempty" db:"finished_at"`
+	CreatedAt      time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at" db:"updated_at"`
+}
+
+// Watermark returns the position of the last processed message, or nil when
+// none has been processed yet
+func (j *MemoryExtractionJob) Watermark() *MessageCursor {
+	if j.WatermarkAt == nil || j.WatermarkID == nil {
+		return nil
+	}
+	return &MessageCursor{CreatedAt: *j.WatermarkAt, ID: *j.WatermarkID}
+}
@@ -72,8 +72,17 @@ type AIModel struct {
 	// Capabilities
 	SupportsStreaming  bool `json:"supports_streaming" db:"supports_streaming"`
 	SupportsFunctions  bool `json:"supports_functions" db:"supports_functions"`
+	SupportsVision     bool `json:"supports_vision" db:"supports_vision"`         // accepts image_url content parts
+	SupportsEmbeddings bool `json:"supports_embeddings" db:"supports_embeddings"` // serves the embeddings API
 	MaxTokens          int  `json:"max_tokens" db:"max_tokens"`
 
+	// Tokenizer is the BPE encoding used to count tokens; empty picks one from the model identifier
+	Tokenizer string `json:"tokenizer" db:"tokenizer"`
+
+	// DisabledTools lists registered tools not offered to this model; the rest
+	// are offered when it supports functions
+	DisabledTools []string `json:"disabled_tools" db:"disabled_tools"`
+
 	// Pricing
 	InputPricePer1k  *float64 `json:"input_price_per_1k,omitempty" db:"input_price_per_1k"`
 	OutputPricePer1k *float64 `json:"output_price_per_1k,omitempty" db:"output_price_per_1k"`
@@ -82,6 +91,9 @@ type AIModel struct {
 	IsActive  bool `json:"is_active" db:"is_active"`
 	IsDefault bool `json:"is_default" db:"is_default"`
 
+	// Failover: models tried in order when this one is unavailable
+	FallbackModelIDs []uuid.UUID `json:"fallback_model_ids" db:"fallback_model_ids"`
+
 	// Metadata
 	Description string     `json:"description,omitempty" db:"description"`
 	CreatedBy   *uuid.UUID `json:"created_by,omitempty" db:"created_by"`
@@ -100,10 +112,15 @@ type AIModelCreateRequest struct {
 	ProviderID       *uuid.UUID `json:"provider_id"`
 	SupportsStreaming bool    `json:"supports_streaming"`
 	SupportsFunctions bool    `json:"supports_functions"`
+	SupportsVision    bool    `json:"supports_vision"`
+	SupportsEmbeddings bool   `json:"supports_embeddings"`
 	MaxTokens        int     `json:"max_tokens" binding:"required,min=1"`
 	InputPricePer1k  *float64 `json:"input_price_per_1k"`
 	OutputPricePer1k *float64 `json:"output_price_per_1k"`
 	Description      string  `json:"description"`
+	FallbackModelIDs []uuid.UUID `json:"fallback_model_ids"`
+	Tokenizer        string      `json:"tokenizer" binding:"omitempty,oneof=cl100k_base o200k_base heuristic"`
+	DisabledTools    []string    `json:"disabled_tools"`
 }
 
 // AIModelUpdateRequest represents request to update an AI model
@@ -113,9 +130,14 @@ type AIModelUpdateRequest struct {
 	APIKey           *string  `json:"api_key"`
 	SupportsStreaming *bool   `json:"supports_streaming"`
 	SupportsFunctions *bool   `json:"supports_functions"`
+	SupportsVision    *bool   `json:"supports_vision"`
+	SupportsEmbeddings *bool  `json:"supports_embeddings"`
 	MaxTokens        *int     `json:"max_tokens" binding:"omitempty,min=1"`
 	InputPricePer1k  *float64 `json:"input_price_per_1k"`
 	OutputPricePer1k *float64 `json:"output_price_per_1k"`
 	Description      *string  `json:"description"`
 	IsActive         *bool    `json:"is_active"`
+	FallbackModelIDs *[]uuid.UUID `json:"fallback_model_ids"`
+	Tokenizer        *string      `json:"tokenizer"`
+	DisabledTools    *[]string    `json:"disabled_tools"`
 }
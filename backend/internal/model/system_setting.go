@@ -44,6 +44,8 @@ type SystemSettingsDTO struct {
 	// AI 配置
 	AIDefaultMemoryModel      string `json:"ai_default_memory_model"`
 	AIMemoryExtractionEnabled bool   `json:"ai_memory_extraction_enabled"`
+	AISummaryModel            string `json:"ai_summary_model"`
+	AIEmbeddingModel          string `json:"ai_embedding_model"`
 }
 
 // MaskSensitiveData 掩码敏感信息，用于API返回
@@ -0,0 +1,227 @@
+package circuitbreaker
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// State is the circuit state of a breaker
+type State string
+
+const (
+	StateClosed   State = "closed"    // requests flow normally
+	StateOpen     State = "open"      // requests fail fast until the cool-down ends
+	StateHalfOpen State = "half-open" // a single probe request decides whether to close again
+)
+
+// ErrOpen is returned by Allow while the circuit is open
+var ErrOpen = errors.New("circuit breaker is open")
+
+// Config tunes when a breaker trips and recovers
+type Config struct {
+	Window       time.Duration // outcomes older than this are ignored
+	MaxSamples   int           // cap on outcomes kept per breaker
+	MinRequests  int           // minimum outcomes in the window before tripping
+	FailureRatio float64       // failure ratio in the window that trips the breaker
+	OpenDuration time.Duration // how long to fail fast before probing
+}
+
+// DefaultConfig returns the settings used for upstream AI providers
+func DefaultConfig() Config {
+	return Config{
+		Window:       2 * time.Minute,
+		MaxSamples:   200,
+		MinRequests:  5,
+		FailureRatio: 0.5,
+		OpenDuration: 30 * time.Second,
+	}
+}
+
+type outcome struct {
+	at      time.Time
+	success bool
+	latency time.Duration
+}
+
+// Breaker tracks recent outcomes for one upstream and decides whether to let
+// requests through
+type Breaker struct {
+	mu       sync.Mutex
+	config   Config
+	state    State
+	openedAt time.Time
+	probing  bool
+	outcomes []outcome
+	now      func() time.Time
+}
+
+// NewBreaker creates a closed breaker
+func NewBreaker(config Config) *Breaker {
+	return &Breaker{config: config, state: StateClosed, now: time.Now}
+}
+
+// Allow reports whether a request may be sent now. In half-open state only
+// one probe is allowed at a time.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateOpen:
+		if b.now().Sub(b.openedAt) < b.config.OpenDuration {
+			return ErrOpen
+		}
+		b.state = StateHalfOpen
+		b.probing = true
+		return nil
+	case StateHalfOpen:
+		if b.probing {
+			return ErrOpen
+		}
+		b.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// Record reports the outcome of a request that Allow let through
+func (b *Breaker) Record(success bool, latency time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.outcomes = append(b.outcomes, outcome{at: now, success: success, latency: latency})
+	b.prune(now)
+
+	switch b.state {
+	case StateHalfOpen:
+		b.probing = false
+		if success {
+			b.state = StateClosed
+			b.outcomes = b.outcomes[:0]
+		} else {
+			b.trip(now)
+		}
+	case StateClosed:
+		if success {
+			return
+		}
+		total, failures := len(b.outcomes), 0
+		for _, o := range b.outcomes {
+			if !o.success {
+				failures++
+			}
+		}
+		if total >= b.config.MinRequests && float64(failures)/float64(total) >= b.config.FailureRatio {
+			b.trip(now)
+		}
+	}
+}
+
+// Abandon releases a request that Allow let through without recording an
+// outcome, e.g. when the caller gave up before the upstream answered
+func (b *Breaker) Abandon() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateHalfOpen {
+		b.probing = false
+	}
+}
+
+func (b *Breaker) trip(now time.Time) {
+	b.state = StateOpen
+	b.openedAt = now
+}
+
+// prune drops outcomes that fell out of the window or exceed the sample cap
+func (b *Breaker) prune(now time.Time) {
+	cutoff := now.Add(-b.config.Window)
+	start := 0
+	for start < len(b.outcomes) && b.outcomes[start].at.Before(cutoff) {
+		start++
+	}
+	if excess := len(b.outcomes) - start - b.config.MaxSamples; excess > 0 {
+		start += excess
+	}
+	if start > 0 {
+		b.outcomes = append(b.outcomes[:0], b.outcomes[start:]...)
+	}
+}
+
+// Snapshot is a point-in-time view of a breaker's health
+type Snapshot struct {
+	State          State      `json:"state"`
+	RecentRequests int        `json:"recent_requests"`
+	ErrorRate      float64    `json:"error_rate"`     // 0..1 over the window
+	P95LatencyMs   int64      `json:"p95_latency_ms"` // over the window
+	OpenedAt       *time.Time `json:"opened_at,omitempty"`
+}
+
+// Snapshot returns the breaker's current health
+func (b *Breaker) Snapshot() Snapshot {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.prune(now)
+
+	state := b.state
+	if state == StateOpen && now.Sub(b.openedAt) >= b.config.OpenDuration {
+		state = StateHalfOpen // the next request will probe
+	}
+
+	snap := Snapshot{State: state, RecentRequests: len(b.outcomes)}
+	if state != StateClosed {
+		openedAt := b.openedAt
+		snap.OpenedAt = &openedAt
+	}
+	if len(b.outcomes) == 0 {
+		return snap
+	}
+
+	failures := 0
+	latencies := make([]time.Duration, 0, len(b.outcomes))
+	for _, o := range b.outcomes {
+		if !o.success {
+			failures++
+		}
+		latencies = append(latencies, o.latency)
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	snap.ErrorRate = float64(failures) / float64(len(b.outcomes))
+	snap.P95LatencyMs = latencies[(len(latencies)*95+99)/100-1].Milliseconds()
+	return snap
+}
+
+// Registry holds one breaker per upstream key
+type Registry struct {
+	config   Config
+	breakers sync.Map // key -> *Breaker
+}
+
+// NewRegistry creates a registry whose breakers share the given config
+func NewRegistry(config Config) *Registry {
+	return &Registry{config: config}
+}
+
+// Get returns the breaker for a key, creating it on first use
+func (r *Registry) Get(key string) *Breaker {
+	if b, ok := r.breakers.Load(key); ok {
+		return b.(*Breaker)
+	}
+	b, _ := r.breakers.LoadOrStore(key, NewBreaker(r.config))
+	return b.(*Breaker)
+}
+
+// Snapshot returns the health for a key; unknown keys report a closed circuit
+func (r *Registry) Snapshot(key string) Snapshot {
+	if b, ok := r.breakers.Load(key); ok {
+		return b.(*Breaker).Snapshot()
+	}
+	return Snapshot{State: StateClosed}
+}
@@ -0,0 +1,152 @@
+package circuitbreaker
+
+import (
+	"testing"
+	"time"
+)
+
+// testBreaker returns a breaker on a clock the test moves with advance
+func testBreaker() (*Breaker, func(time.Duration)) {
+	clock := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := NewBreaker(Config{
+		Window:       time.Minute,
+		MaxSamples:   10,
+		MinRequests:  4,
+		FailureRatio: 0.5,
+		OpenDuration: 30 * time.Second,
+	})
+	b.now = func() time.Time { return clock }
+	return b, func(d time.Duration) { clock = clock.Add(d) }
+}
+
+func record(b *Breaker, outcomes ...bool) {
+	for _, success := range outcomes {
+		b.Record(success, 100*time.Millisecond)
+	}
+}
+
+func TestBreakerTrips(t *testing.T) {
+	b, _ := testBreaker()
+
+	// Failures below MinRequests do not trip the breaker
+	record(b, false, false, false)
+	if err := b.Allow(); err != nil {
+		t.Fatalf("Allow() after 3 failures = %v, want nil", err)
+	}
+
+	record(b, false)
+	if err := b.Allow(); err != ErrOpen {
+		t.Fatalf("Allow() after 4 failures = %v, want ErrOpen", err)
+	}
+	if got := b.Snapshot().State; got != StateOpen {
+		t.Errorf("state = %s, want %s", got, StateOpen)
+	}
+}
+
+func TestBreakerFailureRatio(t *testing.T) {
+	b, _ := testBreaker()
+
+	record(b, true, true, true, false, false)
+	if err := b.Allow(); err != nil {
+		t.Fatalf("Allow() at 2 of 5 failed = %v, want nil", err)
+	}
+	record(b, false)
+	if err := b.Allow(); err != ErrOpen {
+		t.Fatalf("Allow() at 3 of 6 failed = %v, want ErrOpen", err)
+	}
+}
+
+func TestBreakerWindow(t *testing.T) {
+	b, advance := testBreaker()
+
+	record(b, false, false, false)
+	advance(2 * time.Minute)
+	// The old failures fell out of the window
+	record(b, false)
+	if err := b.Allow(); err != nil {
+		t.Fatalf("Allow() = %v, want nil", err)
+	}
+	if got := b.Snapshot().RecentRequests; got != 1 {
+		t.Errorf("RecentRequests = %d, want 1", got)
+	}
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	b, advance := testBreaker()
+	record(b, false, false, false, false)
+
+	advance(29 * time.Second)
+	if err := b.Allow(); err != ErrOpen {
+		t.Fatalf("Allow() before the cool-down = %v, want ErrOpen", err)
+	}
+
+	advance(time.Second)
+	if got := b.Snapshot().State; got != StateHalfOpen {
+		t.Errorf("state after the cool-down = %s, want %s", got, StateHalfOpen)
+	}
+	if err := b.Allow(); err != nil {
+		t.Fatalf("Allow() of the probe = %v, want nil", err)
+	}
+	// Only one probe at a time
+	if err := b.Allow(); err != ErrOpen {
+		t.Fatalf("Allow() during the probe = %v, want ErrOpen", err)
+	}
+
+	// A failed probe opens the circuit again
+	record(b, false)
+	if err := b.Allow(); err != ErrOpen {
+		t.Fatalf("Allow() after a failed probe = %v, want ErrOpen", err)
+	}
+
+	// An abandoned probe lets the next request probe
+	advance(30 * time.Second)
+	if err := b.Allow(); err != nil {
+		t.Fatalf("Allow() of the probe = %v, want nil", err)
+	}
+	b.Abandon()
+	if err := b.Allow(); err != nil {
+		t.Fatalf("Allow() after an abandoned probe = %v, want nil", err)
+	}
+
+	// A successful probe closes it with a clean slate
+	record(b, true)
+	if err := b.Allow(); err != nil {
+		t.Fatalf("Allow() after a successful probe = %v, want nil", err)
+	}
+	snap := b.Snapshot()
+	if snap.State != StateClosed || snap.RecentRequests != 0 {
+		t.Errorf("snapshot after closing = %+v, want closed with no requests", snap)
+	}
+}
+
+func TestBreakerSnapshot(t *testing.T) {
+	b, _ := testBreaker()
+	for i := 1; i <= 20; i++ {
+		b.Record(i%5 != 0, time.Duration(i)*time.Millisecond)
+	}
+
+	// Only the last MaxSamples outcomes are kept: latencies 11-20 ms, two failures
+	snap := b.Snapshot()
+	if snap.RecentRequests != 10 {
+		t.Errorf("RecentRequests = %d, want 10", snap.RecentRequests)
+	}
+	if snap.ErrorRate != 0.2 {
+		t.Errorf("ErrorRate = %v, want 0.2", snap.ErrorRate)
+	}
+	if snap.P95LatencyMs != 20 {
+		t.Errorf("P95LatencyMs = %d, want 20", snap.P95LatencyMs)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry(DefaultConfig())
+	if r.Get("a") != r.Get("a") {
+		t.Error("Get() returned different breakers for one key")
+	}
+	if r.Get("a") == r.Get("b") {
+		t.Error("Get() shared a breaker between keys")
+	}
+	if got := r.Snapshot("unknown").State; got != StateClosed {
+		t.Errorf("Snapshot() of an unknown key = %s, want %s", got, StateClosed)
+	}
+}
@@ -0,0 +1,62 @@
+package docparse
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Chunk splits text into pieces of at most maxRunes runes, repeating about
+// overlap runes of each piece at the start of the next so that passages cut
+// at a boundary stay retrievable. Splits prefer paragraph, then line, then
+// sentence and word boundaries.
+func Chunk(text string, maxRunes, overlap int) []string {
+	if overlap >= maxRunes/2 {
+		overlap = maxRunes / 4
+	}
+
+	var chunks []string
+	for text != "" {
+		if utf8.RuneCountInString(text) <= maxRunes {
+			chunks = append(chunks, text)
+			break
+		}
+
+		end := splitPoint(text, maxRunes)
+		chunks = append(chunks, strings.TrimSpace(text[:end]))
+
+		// Step back into the chunk for the overlap, starting on a word boundary
+		next := end
+		for back := 0; back < overlap && next > 0; back++ {
+			_, size := utf8.DecodeLastRuneInString(text[:next])
+			next -= size
+		}
+		if i := strings.IndexAny(text[next:end], " \n"); i >= 0 && next+i+1 < end {
+			next += i + 1
+		}
+		if next <= 0 {
+			next = end
+		}
+		text = strings.TrimSpace(text[next:])
+	}
+
+	return chunks
+}
+
+// splitPoint returns the byte offset at which to cut text so that the first
+// part has at most maxRunes runes, preferring natural boundaries in its second half
+func splitPoint(text string, maxRunes int) int {
+	limit := 0
+	for i := 0; i < maxRunes; i++ {
+		_, size := utf8.DecodeRuneInString(text[limit:])
+		limit += size
+	}
+	window := text[:limit]
+	min := len(window) / 2
+
+	for _, sep := range []string{"\n\n", "\n", "。", ". ", "！", "? ", "？", "; ", "；", " "} {
+		if i := strings.LastIndex(window, sep); i >= min {
+			return i + len(sep)
+		}
+	}
+	return limit
+}
@@ -0,0 +1,92 @@
+// Package docparse extracts plain text from uploaded documents and splits it
+// into chunks for retrieval. It depends only on the standard library, so its
+// PDF and DOCX support covers the common cases rather than the full formats.
+package docparse
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Content types Extract understands
+const (
+	TypePlain    = "text/plain"
+	TypeMarkdown = "text/markdown"
+	TypeCSV      = "text/csv"
+	TypeJSON     = "application/json"
+	TypePDF      = "application/pdf"
+	TypeDOCX     = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+)
+
+// maxExtractedBytes bounds decompressed data, guarding against zip and flate bombs
+const maxExtractedBytes = 64 << 20
+
+// Supported reports whether Extract can read the content type
+func Supported(contentType string) bool {
+	switch contentType {
+	case TypePlain, TypeMarkdown, TypeCSV, TypeJSON, TypePDF, TypeDOCX:
+		return true
+	}
+	return false
+}
+
+// Extract returns the text of a document
+func Extract(contentType string, data []byte) (string, error) {
+	var text string
+	var err error
+	switch contentType {
+	case TypePlain, TypeMarkdown, TypeCSV, TypeJSON:
+		text = string(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+	case TypeDOCX:
+		text, err = extractDOCX(data)
+	case TypePDF:
+		text, err = extractPDF(data)
+	default:
+		return "", fmt.Errorf("unsupported document type: %s", contentType)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	text = normalize(text)
+	if !strings.ContainsFunc(text, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) {
+		return "", fmt.Errorf("document contains no extractable text")
+	}
+	return text, nil
+}
+
+// normalize makes line endings uniform, drops invalid UTF-8 and control
+// characters, trims trailing spaces and collapses runs of blank lines
+func normalize(text string) string {
+	text = strings.ToValidUTF8(text, "")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+	text = strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\t' {
+			return r
+		}
+		if unicode.IsControl(r) || r == '�' {
+			return -1
+		}
+		return r
+	}, text)
+
+	lines := strings.Split(text, "\n")
+	out := make([]string, 0, len(lines))
+	blank := 0
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t")
+		if line == "" {
+			blank++
+			if blank > 1 {
+				continue
+			}
+		} else {
+			blank = 0
+		}
+		out = append(out, line)
+	}
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
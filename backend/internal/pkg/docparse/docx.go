@@ -0,0 +1,87 @@
+package docparse
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// extractDOCX reads the paragraphs of word/document.xml. Text runs (w:t),
+// tabs and breaks are kept; paragraphs and table rows end lines, and table
+// cells are separated by tabs.
+func extractDOCX(data []byte) (string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("invalid DOCX file: %w", err)
+	}
+
+	var doc *zip.File
+	for _, f := range zr.File {
+		if f.Name == "word/document.xml" {
+			doc = f
+			break
+		}
+	}
+	if doc == nil {
+		return "", fmt.Errorf("invalid DOCX file: word/document.xml is missing")
+	}
+
+	rc, err := doc.Open()
+	if err != nil {
+		return "", fmt.Errorf("invalid DOCX file: %w", err)
+	}
+	defer rc.Close()
+
+	var out strings.Builder
+	decoder := xml.NewDecoder(io.LimitReader(rc, maxExtractedBytes))
+	inText := false
+	cells := 0 // table cell nesting; paragraphs inside cells stay on the row's line
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("invalid DOCX file: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tc":
+				cells++
+			case "tab":
+				out.WriteByte('\t')
+			case "br", "cr":
+				out.WriteByte('\n')
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				if cells > 0 {
+					out.WriteByte(' ')
+				} else {
+					out.WriteByte('\n')
+				}
+			case "tc":
+				cells--
+				out.WriteByte('\t')
+			case "tr":
+				out.WriteByte('\n')
+			}
+		case xml.CharData:
+			if inText {
+				out.Write(t)
+			}
+		}
+	}
+
+	return out.String(), nil
+}
@@ -0,0 +1,104 @@
+package docparse
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// buildDOCX zips the given files into a DOCX package
+func buildDOCX(t testing.TB, files map[string]string) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+const docxNamespace = `xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"`
+
+func TestExtractDOCX(t *testing.T) {
+	body := `<w:document ` + docxNamespace + `><w:body>` +
+		`<w:p><w:r><w:t>First</w:t></w:r><w:r><w:tab/><w:t>paragraph</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>Line</w:t><w:br/><w:t>break</w:t></w:r></w:p>` +
+		`<w:tbl><w:tr><w:tc><w:p><w:r><w:t>A</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>B</w:t></w:r></w:p></w:tc></w:tr></w:tbl>` +
+		`</w:body></w:document>`
+
+	got, err := Extract(TypeDOCX, buildDOCX(t, map[string]string{"word/document.xml": body}))
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	want := "First\tparagraph\nLine\nbreak\nA \tB"
+	if got != want {
+		t.Errorf("Extract() = %q, want %q", got, want)
+	}
+}
+
+func TestExtractDOCXMalformed(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr string
+	}{
+		{name: "empty", data: nil, wantErr: "invalid DOCX"},
+		{name: "not a zip", data: []byte("PK\x03\x04garbage"), wantErr: "invalid DOCX"},
+		{
+			name:    "missing document.xml",
+			data:    buildDOCX(t, map[string]string{"word/styles.xml": "<styles/>"}),
+			wantErr: "document.xml is missing",
+		},
+		{
+			name:    "broken XML",
+			data:    buildDOCX(t, map[string]string{"word/document.xml": "<w:document><w:t>text</w:document"}),
+			wantErr: "invalid DOCX",
+		},
+		{
+			name:    "no text",
+			data:    buildDOCX(t, map[string]string{"word/document.xml": "<w:document " + docxNamespace + "/>"}),
+			wantErr: "no extractable text",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Extract(TypeDOCX, tt.data)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Extract() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestChunk(t *testing.T) {
+	text := strings.Repeat("word ", 100)
+	chunks := Chunk(strings.TrimSpace(text), 50, 10)
+	if len(chunks) < 10 {
+		t.Fatalf("Chunk() returned %d chunks, want at least 10", len(chunks))
+	}
+	for i, chunk := range chunks {
+		if n := len([]rune(chunk)); n > 50 {
+			t.Errorf("chunk %d has %d runes, want at most 50", i, n)
+		}
+		if strings.HasPrefix(chunk, "ord") || strings.HasSuffix(chunk, "wor") {
+			t.Errorf("chunk %d = %q splits a word", i, chunk)
+		}
+	}
+
+	cjk := strings.Repeat("你好。", 40)
+	for i, chunk := range Chunk(cjk, 30, 5) {
+		if n := len([]rune(chunk)); n > 30 {
+			t.Errorf("CJK chunk %d has %d runes, want at most 30", i, n)
+		}
+	}
+}
@@ -0,0 +1,1097 @@
+package docparse
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"encoding/ascii85"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// extractPDF pulls the text out of a PDF's page content streams. It reads
+// objects by scanning for "N G obj" headers rather than trusting the xref
+// table, which also copes with mildly damaged files. Supported: Flate,
+// ASCIIHex and ASCII85 filters, object streams, and ToUnicode CMaps for
+// composite (e.g. CJK) fonts. Scanned and encrypted PDFs are rejected.
+func extractPDF(data []byte) (string, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte("%PDF-")) {
+		return "", fmt.Errorf("invalid PDF file")
+	}
+
+	doc := &pdfDocument{objects: make(map[int]pdfObject), streams: make(map[int][]byte)}
+	doc.load(data)
+	if doc.encrypted {
+		return "", fmt.Errorf("encrypted PDFs are not supported")
+	}
+
+	var out strings.Builder
+	for _, page := range doc.pages() {
+		text := doc.pageText(page)
+		if text != "" {
+			out.WriteString(text)
+			out.WriteString("\n\n")
+		}
+		if out.Len() > maxExtractedBytes {
+			break
+		}
+	}
+	return out.String(), nil
+}
+
+// ─── Objects ─────────────────────────────────────────────────────────────────
+
+type (
+	pdfObject  interface{}
+	pdfName    string
+	pdfString  []byte
+	pdfKeyword string
+	pdfArray   []pdfObject
+	pdfDict    map[pdfName]pdfObject
+	pdfRef     struct{ num, gen int }
+)
+
+// pdfStream is a dictionary with a raw (still encoded) stream
+type pdfStream struct {
+	dict pdfDict
+	raw  []byte
+}
+
+// ─── Lexer / parser ──────────────────────────────────────────────────────────
+
+// maxNesting bounds nested arrays and dictionaries
+const maxNesting = 64
+
+type pdfLexer struct {
+	data  []byte
+	pos   int
+	depth int
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isPDFDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+func (l *pdfLexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if isPDFSpace(c) {
+			l.pos++
+		} else if c == '%' {
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+		} else {
+			return
+		}
+	}
+}
+
+// next parses the next object or keyword; ok is false at the end of input.
+// Array and dictionary terminators are returned as keywords.
+func (l *pdfLexer) next() (obj pdfObject, ok bool) {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, false
+	}
+
+	c := l.data[l.pos]
+	switch {
+	case c == '/':
+		return l.name(), true
+	case c == '(':
+		return l.literalString(), true
+	case c == '<' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '<':
+		l.pos += 2
+		return l.dict(), true
+	case c == '<':
+		return l.hexString(), true
+	case c == '>' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '>':
+		l.pos += 2
+		return pdfKeyword(">>"), true
+	case c == '[':
+		l.pos++
+		return l.array(), true
+	case c == ']' || c == '{' || c == '}' || c == ')' || c == '>':
+		l.pos++
+		return pdfKeyword(string(c)), true
+	case c == '+' || c == '-' || c == '.' || c >= '0' && c <= '9':
+		return l.number(), true
+	}
+
+	start := l.pos
+	for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	word := string(l.data[start:l.pos])
+	switch word {
+	case "true":
+		return true, true
+	case "false":
+		return false, true
+	case "null":
+		return nil, true
+	}
+	return pdfKeyword(word), true
+}
+
+// object parses the next object, folding "N G R" into a reference
+func (l *pdfLexer) object() (pdfObject, bool) {
+	obj, ok := l.next()
+	if !ok {
+		return nil, false
+	}
+	num, isInt := obj.(int)
+	if !isInt {
+		return obj, true
+	}
+
+	// Look ahead at the raw bytes only, so backing out never re-parses a
+	// nested object
+	save := l.pos
+	l.skipSpace()
+	if l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '9' {
+		if gen, isInt := l.number().(int); isInt {
+			l.skipSpace()
+			if l.pos < len(l.data) && l.data[l.pos] == 'R' &&
+				(l.pos+1 == len(l.data) || isPDFSpace(l.data[l.pos+1]) || isPDFDelimiter(l.data[l.pos+1])) {
+				l.pos++
+				return pdfRef{num: num, gen: gen}, true
+			}
+		}
+	}
+	l.pos = save
+	return num, true
+}
+
+func (l *pdfLexer) number() pdfObject {
+	start := l.pos
+	l.pos++
+	for l.pos < len(l.data) && (l.data[l.pos] >= '0' && l.data[l.pos] <= '9' || l.data[l.pos] == '.') {
+		l.pos++
+	}
+	s := string(l.data[start:l.pos])
+	if n, err := strconv.Atoi(s); err == nil {
+		return n
+	}
+	f, _ := strconv.ParseFloat(s, 64)
+	return f
+}
+
+func (l *pdfLexer) name() pdfName {
+	l.pos++ // '/'
+	var b []byte
+	for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+		c := l.data[l.pos]
+		if c == '#' && l.pos+2 < len(l.data) {
+			if v, err := strconv.ParseUint(string(l.data[l.pos+1:l.pos+3]), 16, 8); err == nil {
+				b = append(b, byte(v))
+				l.pos += 3
+				continue
+			}
+		}
+		b = append(b, c)
+		l.pos++
+	}
+	return pdfName(b)
+}
+
+func (l *pdfLexer) literalString() pdfString {
+	l.pos++ // '('
+	var b []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return b
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				return b
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						v = v*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					c = byte(v)
+				} else {
+					c = e
+				}
+			}
+		}
+		b = append(b, c)
+	}
+	return b
+}
+
+func (l *pdfLexer) hexString() pdfString {
+	l.pos++ // '<'
+	var digits []byte
+	for l.pos < len(l.data) && l.data[l.pos] != '>' {
+		c := l.data[l.pos]
+		if c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F' {
+			digits = append(digits, c)
+		}
+		l.pos++
+	}
+	l.pos++ // '>'
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	b := make([]byte, len(digits)/2)
+	hex.Decode(b, digits)
+	return b
+}
+
+func (l *pdfLexer) array() pdfArray {
+	var arr pdfArray
+	if l.depth++; l.depth > maxNesting {
+		l.pos = len(l.data)
+	}
+	defer func() { l.depth-- }()
+	for {
+		obj, ok := l.object()
+		if !ok || obj == pdfKeyword("]") {
+			return arr
+		}
+		arr = append(arr, obj)
+	}
+}
+
+func (l *pdfLexer) dict() pdfDict {
+	d := make(pdfDict)
+	if l.depth++; l.depth > maxNesting {
+		l.pos = len(l.data)
+	}
+	defer func() { l.depth-- }()
+	for {
+		key, ok := l.next()
+		if !ok || key == pdfKeyword(">>") {
+			return d
+		}
+		name, isName := key.(pdfName)
+		if !isName {
+			continue
+		}
+		value, ok := l.object()
+		if !ok || value == pdfKeyword(">>") {
+			return d
+		}
+		d[name] = value
+	}
+}
+
+// ─── Document ────────────────────────────────────────────────────────────────
+
+type pdfDocument struct {
+	objects   map[int]pdfObject
+	streams   map[int][]byte // decoded stream cache
+	encrypted bool
+	decoded   int       // total decoded bytes, bounded by maxExtractedBytes
+	root      pdfObject // the catalog named by the last trailer
+	rootPos   int
+}
+
+// setRoot records the /Root of a trailer or cross-reference stream at pos;
+// the last one in the file wins, as with incremental updates
+func (d *pdfDocument) setRoot(trailer pdfDict, pos int) {
+	if root, ok := trailer["Root"]; ok && pos >= d.rootPos {
+		d.root, d.rootPos = root, pos
+	}
+}
+
+var pdfObjHeader = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+
+// load finds every indirect object in the file. Later definitions of an
+// object number win, as with incremental updates.
+func (d *pdfDocument) load(data []byte) {
+	headers := pdfObjHeader.FindAllSubmatchIndex(data, -1)
+	for i, m := range headers {
+		if m[0] > 0 && !isPDFSpace(data[m[0]-1]) && !isPDFDelimiter(data[m[0]-1]) {
+			continue
+		}
+		num, _ := strconv.Atoi(string(data[m[2]:m[3]]))
+
+		// Parse no further than the next object, so damaged objects stay cheap
+		end := len(data)
+		if i+1 < len(headers) {
+			end = headers[i+1][0]
+		}
+		l := &pdfLexer{data: data[:end], pos: m[1]}
+		obj, ok := l.object()
+		if !ok {
+			continue
+		}
+
+		if dict, isDict := obj.(pdfDict); isDict {
+			save := l.pos
+			if kw, ok := l.next(); ok && kw == pdfKeyword("stream") {
+				obj = &pdfStream{dict: dict, raw: streamData(data, l.pos, dict)}
+				// Cross-reference streams carry the trailer entries
+				if dict["Type"] == pdfName("XRef") {
+					d.setRoot(dict, m[0])
+				}
+			} else {
+				l.pos = save
+			}
+		}
+		d.objects[num] = obj
+	}
+
+	for _, obj := range d.objects {
+		if dict := asDict(obj); dict != nil && dict["Encrypt"] != nil {
+			d.encrypted = true
+		}
+	}
+	for _, trailer := range regexp.MustCompile(`trailer\s*<<`).FindAllIndex(data, -1) {
+		l := &pdfLexer{data: data, pos: trailer[1] - 2}
+		if obj, ok := l.object(); ok {
+			if dict := asDict(obj); dict != nil {
+				if dict["Encrypt"] != nil {
+					d.encrypted = true
+				}
+				d.setRoot(dict, trailer[0])
+			}
+		}
+	}
+
+	// Objects packed into object streams
+	nums := make([]int, 0, len(d.objects))
+	for num := range d.objects {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+	for _, num := range nums {
+		stream, ok := d.objects[num].(*pdfStream)
+		if !ok || stream.dict["Type"] != pdfName("ObjStm") {
+			continue
+		}
+		d.loadObjectStream(num, stream)
+	}
+}
+
+// streamData returns the raw bytes of a stream whose keyword ends at pos
+func streamData(data []byte, pos int, dict pdfDict) []byte {
+	if pos < len(data) && data[pos] == '\r' {
+		pos++
+	}
+	if pos < len(data) && data[pos] == '\n' {
+		pos++
+	}
+	if length, ok := dict["Length"].(int); ok && length >= 0 && pos+length <= len(data) {
+		rest := bytes.TrimLeft(data[pos+length:], " \r\n")
+		if bytes.HasPrefix(rest, []byte("endstream")) {
+			return data[pos : pos+length]
+		}
+	}
+	end := bytes.Index(data[pos:], []byte("endstream"))
+	if end < 0 {
+		return data[pos:]
+	}
+	return bytes.TrimRight(data[pos:pos+end], "\r\n")
+}
+
+// loadObjectStream adds the objects compressed into an object stream
+func (d *pdfDocument) loadObjectStream(num int, stream *pdfStream) {
+	content := d.decodeStream(num, stream)
+	count, _ := stream.dict["N"].(int)
+	first, _ := stream.dict["First"].(int)
+	if content == nil || first < 0 || first > len(content) {
+		return
+	}
+
+	header := &pdfLexer{data: content[:first]}
+	for i := 0; i < count; i++ {
+		numObj, ok1 := header.next()
+		offObj, ok2 := header.next()
+		objNum, isInt1 := numObj.(int)
+		offset, isInt2 := offObj.(int)
+		if !ok1 || !ok2 || !isInt1 || !isInt2 {
+			return
+		}
+		// Offsets come from the file; a negative or overflowing one is damage
+		if _, exists := d.objects[objNum]; exists || first+offset < 0 || first+offset >= len(content) {
+			continue
+		}
+		l := &pdfLexer{data: content, pos: first + offset}
+		if obj, ok := l.object(); ok {
+			d.objects[objNum] = obj
+		}
+	}
+}
+
+// resolve follows references
+func (d *pdfDocument) resolve(obj pdfObject) pdfObject {
+	for i := 0; i < 32; i++ {
+		ref, ok := obj.(pdfRef)
+		if !ok {
+			return obj
+		}
+		obj = d.objects[ref.num]
+	}
+	return nil
+}
+
+func (d *pdfDocument) dict(obj pdfObject) pdfDict {
+	return asDict(d.resolve(obj))
+}
+
+func asDict(obj pdfObject) pdfDict {
+	switch v := obj.(type) {
+	case pdfDict:
+		return v
+	case *pdfStream:
+		return v.dict
+	}
+	return nil
+}
+
+// streamContent returns the decoded content of a referenced stream
+func (d *pdfDocument) streamContent(obj pdfObject) []byte {
+	num := -1
+	if ref, ok := obj.(pdfRef); ok {
+		num = ref.num
+	}
+	stream, ok := d.resolve(obj).(*pdfStream)
+	if !ok {
+		return nil
+	}
+	return d.decodeStream(num, stream)
+}
+
+// decodeStream applies a stream's filters, caching by object number
+func (d *pdfDocument) decodeStream(num int, stream *pdfStream) []byte {
+	if num >= 0 {
+		if cached, ok := d.streams[num]; ok {
+			return cached
+		}
+	}
+
+	var filters []pdfObject
+	switch f := d.resolve(stream.dict["Filter"]).(type) {
+	case pdfName:
+		filters = []pdfObject{f}
+	case pdfArray:
+		filters = f
+	}
+
+	out := stream.raw
+	for _, f := range filters {
+		var err error
+		switch d.resolve(f) {
+		case pdfName("FlateDecode"), pdfName("Fl"):
+			out, err = inflate(out, maxExtractedBytes-d.decoded)
+		case pdfName("ASCIIHexDecode"), pdfName("AHx"):
+			out = (&pdfLexer{data: append(append([]byte("<"), out...), '>')}).hexString()
+		case pdfName("ASCII85Decode"), pdfName("A85"):
+			out, err = decodeASCII85(out)
+		default:
+			err = fmt.Errorf("unsupported filter") // images (DCT, JBIG2, ...) carry no text
+		}
+		if err != nil {
+			out = nil
+			break
+		}
+	}
+
+	d.decoded += len(out)
+	if num >= 0 {
+		d.streams[num] = out
+	}
+	return out
+}
+
+// inflate decompresses zlib data, tolerating a missing header or a truncated end
+func inflate(data []byte, limit int) ([]byte, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("decompressed data too large")
+	}
+	var r io.ReadCloser
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		r = flate.NewReader(bytes.NewReader(data))
+	}
+	defer r.Close()
+
+	out, err := io.ReadAll(io.LimitReader(r, int64(limit)))
+	if len(out) > 0 {
+		return out, nil // keep what decoded before any corruption
+	}
+	return out, err
+}
+
+func decodeASCII85(data []byte) ([]byte, error) {
+	data = bytes.TrimSpace(data)
+	data = bytes.TrimPrefix(data, []byte("<~"))
+	if i := bytes.Index(data, []byte("~>")); i >= 0 {
+		data = data[:i]
+	}
+	out := make([]byte, len(data))
+	n, _, err := ascii85.Decode(out, data, true)
+	return out[:n], err
+}
+
+// pages returns the page dictionaries in order, each with its inherited
+// resources resolved, falling back to every page object when there is no
+// usable page tree
+func (d *pdfDocument) pages() []pdfDict {
+	var pages []pdfDict
+	visited := make(map[int]bool)
+
+	var walk func(node pdfObject, resources pdfObject, depth int)
+	walk = func(node pdfObject, resources pdfObject, depth int) {
+		if ref, ok := node.(pdfRef); ok {
+			if visited[ref.num] {
+				return
+			}
+			visited[ref.num] = true
+		}
+		dict := d.dict(node)
+		if dict == nil || depth > 64 {
+			return
+		}
+		if r, ok := dict["Resources"]; ok {
+			resources = r
+		}
+		if kids, ok := d.resolve(dict["Kids"]).(pdfArray); ok {
+			for _, kid := range kids {
+				walk(kid, resources, depth+1)
+			}
+			return
+		}
+		if dict["Type"] == pdfName("Page") {
+			page := make(pdfDict, len(dict)+1)
+			for k, v := range dict {
+				page[k] = v
+			}
+			page["Resources"] = resources
+			pages = append(pages, page)
+		}
+	}
+
+	if catalog := d.catalog(); catalog != nil {
+		walk(catalog["Pages"], nil, 0)
+	}
+	if len(pages) > 0 {
+		return pages
+	}
+
+	nums := make([]int, 0)
+	for num, obj := range d.objects {
+		if dict := asDict(obj); dict != nil && dict["Type"] == pdfName("Page") {
+			nums = append(nums, num)
+		}
+	}
+	sort.Ints(nums)
+	for _, num := range nums {
+		pages = append(pages, asDict(d.objects[num]))
+	}
+	return pages
+}
+
+// catalog returns the document catalog named by the trailer, or failing that
+// the lowest numbered catalog object
+func (d *pdfDocument) catalog() pdfDict {
+	if dict := d.dict(d.root); dict != nil {
+		return dict
+	}
+	nums := make([]int, 0)
+	for num, obj := range d.objects {
+		if dict := asDict(obj); dict != nil && dict["Type"] == pdfName("Catalog") {
+			nums = append(nums, num)
+		}
+	}
+	if len(nums) == 0 {
+		return nil
+	}
+	sort.Ints(nums)
+	return asDict(d.objects[nums[0]])
+}
+
+// ─── Fonts ───────────────────────────────────────────────────────────────────
+
+// pdfFont decodes the bytes of shown strings into text
+type pdfFont struct {
+	cmap         map[uint32]string  // from ToUnicode
+	codeLen      int                // bytes per character code
+	widths       map[uint32]float64 // glyph advances in thousandths of an em
+	defaultWidth float64
+}
+
+// fonts loads the fonts named in a page's resources
+func (d *pdfDocument) fonts(resources pdfObject, cache map[int]*pdfFont) map[pdfName]*pdfFont {
+	fonts := make(map[pdfName]*pdfFont)
+	fontDict := d.dict(d.dict(resources)["Font"])
+	for name, ref := range fontDict {
+		if r, ok := ref.(pdfRef); ok {
+			if f, ok := cache[r.num]; ok {
+				fonts[name] = f
+				continue
+			}
+		}
+
+		dict := d.dict(ref)
+		font := &pdfFont{codeLen: 1}
+		if dict["Subtype"] == pdfName("Type0") {
+			font.codeLen = 2
+		}
+		if toUnicode, ok := dict["ToUnicode"]; ok {
+			if cmap, codeLen := parseCMap(d.streamContent(toUnicode)); len(cmap) > 0 {
+				font.cmap = cmap
+				if codeLen > 0 {
+					font.codeLen = codeLen
+				}
+			}
+		}
+		d.loadWidths(font, dict)
+
+		fonts[name] = font
+		if r, ok := ref.(pdfRef); ok {
+			cache[r.num] = font
+		}
+	}
+	return fonts
+}
+
+// loadWidths reads glyph advances: /FirstChar and /Widths for simple fonts,
+// /DW and /W of the descendant font for composite ones
+func (d *pdfDocument) loadWidths(font *pdfFont, dict pdfDict) {
+	font.widths = make(map[uint32]float64)
+	if dict["Subtype"] != pdfName("Type0") {
+		first, _ := d.resolve(dict["FirstChar"]).(int)
+		widths, _ := d.resolve(dict["Widths"]).(pdfArray)
+		for i, w := range widths {
+			font.widths[uint32(first+i)] = number(d.resolve(w))
+		}
+		return
+	}
+
+	descendants, _ := d.resolve(dict["DescendantFonts"]).(pdfArray)
+	if len(descendants) == 0 {
+		return
+	}
+	cid := d.dict(descendants[0])
+	font.defaultWidth = 1000
+	if dw, ok := d.resolve(cid["DW"]).(int); ok {
+		font.defaultWidth = float64(dw)
+	}
+
+	w, _ := d.resolve(cid["W"]).(pdfArray)
+	for i := 0; i < len(w); {
+		start, ok := d.resolve(w[i]).(int)
+		if !ok || i+1 >= len(w) {
+			return
+		}
+		if list, ok := d.resolve(w[i+1]).(pdfArray); ok {
+			// c [w1 w2 ...]
+			for j, width := range list {
+				font.widths[uint32(start+j)] = number(d.resolve(width))
+			}
+			i += 2
+			continue
+		}
+		// c_first c_last w
+		end, ok := d.resolve(w[i+1]).(int)
+		if !ok || i+2 >= len(w) || end < start || end-start > maxCMapRange {
+			return
+		}
+		width := number(d.resolve(w[i+2]))
+		for c := start; c <= end; c++ {
+			font.widths[uint32(c)] = width
+		}
+		i += 3
+	}
+}
+
+// width returns the advance of shown bytes in thousandths of an em, falling
+// back to rough per-character estimates when the font has no widths
+func (f *pdfFont) width(s []byte, text string) float64 {
+	if f != nil && len(f.widths) > 0 {
+		var total float64
+		for i := 0; i+f.codeLen <= len(s); i += f.codeLen {
+			w, ok := f.widths[bytesToCode(s[i:i+f.codeLen])]
+			if !ok {
+				w = f.defaultWidth
+			}
+			if w == 0 {
+				w = 500
+			}
+			total += w
+		}
+		return total
+	}
+
+	var total float64
+	for _, r := range text {
+		switch {
+		case r > 0x2e80:
+			total += 1000 // CJK
+		case strings.ContainsRune("fijlrtI.,;:'!|", r):
+			total += 280
+		case strings.ContainsRune("mwMW", r):
+			total += 850
+		case unicode.IsUpper(r):
+			total += 670
+		default:
+			total += 500
+		}
+	}
+	return total
+}
+
+// decode converts shown bytes to text
+func (f *pdfFont) decode(s []byte) string {
+	if f == nil || f.cmap == nil {
+		if f != nil && f.codeLen == 2 {
+			return "" // composite font without ToUnicode: codes are glyph IDs
+		}
+		return decodePDFDocEncoding(s)
+	}
+
+	var out strings.Builder
+	for i := 0; i+f.codeLen <= len(s); i += f.codeLen {
+		var code uint32
+		for _, b := range s[i : i+f.codeLen] {
+			code = code<<8 | uint32(b)
+		}
+		if text, ok := f.cmap[code]; ok {
+			out.WriteString(text)
+		} else if f.codeLen == 1 {
+			out.WriteString(decodePDFDocEncoding(s[i : i+1]))
+		}
+	}
+	return out.String()
+}
+
+// decodePDFDocEncoding maps single-byte text; printable ASCII and Latin-1
+// cover the standard encodings well enough for retrieval
+func decodePDFDocEncoding(s []byte) string {
+	runes := make([]rune, 0, len(s))
+	for _, b := range s {
+		switch {
+		case b == 0x91 || b == 0x92:
+			runes = append(runes, '\'')
+		case b == 0x93 || b == 0x94:
+			runes = append(runes, '"')
+		case b == 0x96 || b == 0x97:
+			runes = append(runes, '-')
+		case b >= 0x20 && b < 0x7f || b >= 0xa0:
+			runes = append(runes, rune(b))
+		case b == '\t' || b == '\n':
+			runes = append(runes, ' ')
+		}
+	}
+	return string(runes)
+}
+
+// maxCMapRange bounds a single bfrange entry
+const maxCMapRange = 1 << 16
+
+// parseCMap reads the bfchar and bfrange mappings of a ToUnicode CMap and
+// the code length from its codespace ranges
+func parseCMap(data []byte) (map[uint32]string, int) {
+	cmap := make(map[uint32]string)
+	codeLen := 0
+	l := &pdfLexer{data: data}
+
+	var operands []pdfObject
+	section := ""
+	for {
+		obj, ok := l.object()
+		if !ok {
+			break
+		}
+		kw, isKeyword := obj.(pdfKeyword)
+		if !isKeyword {
+			operands = append(operands, obj)
+			continue
+		}
+
+		switch kw {
+		case "begincodespacerange", "beginbfchar", "beginbfrange":
+			section = string(kw)
+			operands = nil
+			continue
+		case "endcodespacerange":
+			for _, op := range operands {
+				if s, ok := op.(pdfString); ok && len(s) > codeLen {
+					codeLen = len(s)
+				}
+			}
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				src, ok1 := operands[i].(pdfString)
+				dst, ok2 := operands[i+1].(pdfString)
+				if ok1 && ok2 {
+					cmap[bytesToCode(src)] = utf16BEString(dst)
+				}
+			}
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				lo, ok1 := operands[i].(pdfString)
+				hi, ok2 := operands[i+1].(pdfString)
+				if !ok1 || !ok2 {
+					continue
+				}
+				start, end := bytesToCode(lo), bytesToCode(hi)
+				if end < start || end-start > maxCMapRange {
+					continue
+				}
+				switch dst := operands[i+2].(type) {
+				case pdfString:
+					base := utf16.Decode(utf16BE(dst))
+					for code := start; code <= end && len(base) > 0; code++ {
+						runes := append([]rune(nil), base...)
+						runes[len(runes)-1] += rune(code - start)
+						cmap[code] = string(runes)
+					}
+				case pdfArray:
+					for j, item := range dst {
+						if s, ok := item.(pdfString); ok && start+uint32(j) <= end {
+							cmap[start+uint32(j)] = utf16BEString(s)
+						}
+					}
+				}
+			}
+		}
+		if strings.HasPrefix(string(kw), "end") && section != "" {
+			section = ""
+		}
+		operands = nil
+	}
+
+	return cmap, codeLen
+}
+
+func bytesToCode(b []byte) uint32 {
+	var code uint32
+	for _, c := range b {
+		code = code<<8 | uint32(c)
+	}
+	return code
+}
+
+func utf16BE(b []byte) []uint16 {
+	units := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+	}
+	return units
+}
+
+func utf16BEString(b []byte) string {
+	if len(b) == 1 {
+		return string(rune(b[0]))
+	}
+	return string(utf16.Decode(utf16BE(b)))
+}
+
+// ─── Content streams ─────────────────────────────────────────────────────────
+
+// pageText interprets the text operators of a page's content streams
+func (d *pdfDocument) pageText(page pdfDict) string {
+	var content []byte
+	switch c := d.resolve(page["Contents"]).(type) {
+	case *pdfStream:
+		content = d.streamContent(page["Contents"])
+	case pdfArray:
+		for _, part := range c {
+			content = append(content, d.streamContent(part)...)
+			content = append(content, '\n')
+		}
+	}
+	if len(content) == 0 {
+		return ""
+	}
+
+	fonts := d.fonts(page["Resources"], make(map[int]*pdfFont))
+	w := &pageWriter{fontSize: 12}
+	l := &pdfLexer{data: content}
+	var operands []pdfObject
+	for {
+		obj, ok := l.next()
+		if !ok {
+			break
+		}
+		op, isOp := obj.(pdfKeyword)
+		if !isOp {
+			operands = append(operands, obj)
+			continue
+		}
+
+		switch op {
+		case "Tf":
+			if len(operands) >= 2 {
+				if name, ok := operands[len(operands)-2].(pdfName); ok {
+					w.font = fonts[name]
+				}
+				if size := number(operands[len(operands)-1]); size != 0 {
+					w.fontSize = math.Abs(size)
+				}
+			}
+		case "Tj":
+			if len(operands) >= 1 {
+				if s, ok := operands[len(operands)-1].(pdfString); ok {
+					w.show(s)
+				}
+			}
+		case "'", "\"":
+			w.newline()
+			if len(operands) >= 1 {
+				if s, ok := operands[len(operands)-1].(pdfString); ok {
+					w.show(s)
+				}
+			}
+		case "TJ":
+			if len(operands) >= 1 {
+				if arr, ok := operands[len(operands)-1].(pdfArray); ok {
+					for _, item := range arr {
+						if s, ok := item.(pdfString); ok {
+							w.show(s)
+						} else {
+							w.kern(number(item))
+						}
+					}
+				}
+			}
+		case "T*":
+			w.newline()
+		case "Td", "TD":
+			if len(operands) >= 2 {
+				w.move(number(operands[len(operands)-2]), number(operands[len(operands)-1]))
+			}
+		case "Tm":
+			if len(operands) >= 6 {
+				w.moveTo(number(operands[len(operands)-2]), number(operands[len(operands)-1]))
+			}
+		case "ET":
+			w.space()
+		case "ID":
+			// Inline image data runs to "EI"; skip it
+			if end := bytes.Index(content[l.pos:], []byte("EI")); end >= 0 {
+				l.pos += end + 2
+			} else {
+				l.pos = len(content)
+			}
+		}
+		operands = nil
+	}
+
+	return w.out.String()
+}
+
+// pageWriter lays out shown text. It follows the width of what has been shown
+// on the current line, so moves that jump clearly past it become spaces and
+// vertical moves become line breaks.
+type pageWriter struct {
+	out      strings.Builder
+	last     byte
+	font     *pdfFont
+	fontSize float64
+	lineX    float64 // x of the current line start
+	lineY    float64
+	shown    float64 // estimated width shown since the line start
+}
+
+func (w *pageWriter) write(s string) {
+	if s == "" {
+		return
+	}
+	w.out.WriteString(s)
+	w.last = s[len(s)-1]
+}
+
+func (w *pageWriter) newline() {
+	if w.out.Len() > 0 && w.last != '\n' {
+		w.write("\n")
+	}
+	w.shown = 0
+}
+
+func (w *pageWriter) space() {
+	if w.out.Len() > 0 && w.last != ' ' && w.last != '\n' {
+		w.write(" ")
+	}
+}
+
+func (w *pageWriter) show(s []byte) {
+	text := w.font.decode(s)
+	w.write(text)
+	w.shown += w.font.width(s, text) / 1000 * w.fontSize
+}
+
+// kern applies a TJ adjustment, given in thousandths of the font size
+func (w *pageWriter) kern(adjust float64) {
+	if adjust < -200 {
+		w.space()
+	}
+	w.shown -= adjust / 1000 * w.fontSize
+}
+
+// move starts a new line offset from the current line start (Td)
+func (w *pageWriter) move(dx, dy float64) {
+	if dy != 0 {
+		w.newline()
+	} else if dx-w.shown > w.fontSize*0.15 {
+		w.space()
+	}
+	w.lineX += dx
+	w.lineY += dy
+	w.shown = 0
+}
+
+// moveTo sets the line start absolutely (Tm)
+func (w *pageWriter) moveTo(x, y float64) {
+	w.move(x-w.lineX, y-w.lineY)
+}
+
+func number(obj pdfObject) float64 {
+	switch v := obj.(type) {
+	case int:
+		return float64(v)
+	case float64:
+		return v
+	}
+	return 0
+}
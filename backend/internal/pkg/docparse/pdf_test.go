@@ -0,0 +1,236 @@
+package docparse
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// buildPDF assembles a PDF from object bodies numbered from 1. The parser
+// scans for object headers, so no xref table is needed.
+func buildPDF(objects ...string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	for i, obj := range objects {
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	buf.WriteString("trailer\n<< /Root 1 0 R >>\n%%EOF\n")
+	return buf.Bytes()
+}
+
+func stream(dict, data string) string {
+	return fmt.Sprintf("<< %s /Length %d >>\nstream\n%s\nendstream", dict, len(data), data)
+}
+
+func deflate(t testing.TB, data string) string {
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	if _, err := w.Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	return buf.String()
+}
+
+// simplePDF is a one-page document showing the given content stream
+func simplePDF(content string) []byte {
+	return buildPDF(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /Resources << /Font << /F1 5 0 R >> >> /Contents 4 0 R >>",
+		stream("", content),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	)
+}
+
+func TestExtractPDF(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{
+			name: "text operators",
+			data: simplePDF("BT /F1 12 Tf 72 720 Td (Hello World) Tj 0 -14 Td (Second line) Tj ET"),
+			want: "Hello World\nSecond line",
+		},
+		{
+			name: "TJ kerning becomes spaces",
+			data: simplePDF("BT /F1 12 Tf [(Hello) -500 (World)] TJ ET"),
+			want: "Hello World",
+		},
+		{
+			name: "escapes in literal strings",
+			data: simplePDF(`BT /F1 12 Tf (a \(b\) \101) Tj ET`),
+			want: "a (b) A",
+		},
+		{
+			name: "hex string",
+			data: simplePDF("BT /F1 12 Tf <48656C6C6F> Tj ET"),
+			want: "Hello",
+		},
+		{
+			name: "flate compressed content",
+			data: buildPDF(
+				"<< /Type /Catalog /Pages 2 0 R >>",
+				"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+				"<< /Type /Page /Parent 2 0 R /Contents 4 0 R >>",
+				stream("/Filter /FlateDecode", deflate(t, "BT (Compressed) Tj ET")),
+			),
+			want: "Compressed",
+		},
+		{
+			name: "ToUnicode CMap for a composite font",
+			data: buildPDF(
+				"<< /Type /Catalog /Pages 2 0 R >>",
+				"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+				"<< /Type /Page /Parent 2 0 R /Resources << /Font << /F1 5 0 R >> >> /Contents 4 0 R >>",
+				stream("", "BT /F1 12 Tf <00010002> Tj ET"),
+				"<< /Type /Font /Subtype /Type0 /ToUnicode 6 0 R >>",
+				stream("", "1 begincodespacerange <0000> <FFFF> endcodespacerange\n"+
+					"2 beginbfchar <0001> <4F60> <0002> <597D> endbfchar"),
+			),
+			want: "你好",
+		},
+		{
+			name: "page from an object stream",
+			data: buildPDF(
+				"<< /Type /Catalog /Pages 2 0 R >>",
+				"<< /Type /Pages /Kids [5 0 R] /Count 1 >>",
+				stream("/Type /ObjStm /N 1 /First 5", "5 0 \n<< /Type /Page /Contents 4 0 R >>"),
+				stream("", "BT (Packed) Tj ET"),
+			),
+			want: "Packed",
+		},
+		{
+			name: "catalog named by the last trailer",
+			// An incremental update replaces the catalog and its page tree
+			data: append(simplePDF("BT (Old) Tj ET"), []byte("6 0 obj\n<< /Type /Catalog /Pages 7 0 R >>\nendobj\n"+
+				"7 0 obj\n<< /Type /Pages /Kids [8 0 R] /Count 1 >>\nendobj\n"+
+				"8 0 obj\n<< /Type /Page /Contents 9 0 R >>\nendobj\n"+
+				"9 0 obj\n"+stream("", "BT (New) Tj ET")+"\nendobj\n"+
+				"trailer\n<< /Root 6 0 R /Prev 0 >>\n%%EOF\n")...),
+			want: "New",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Extract(TypePDF, tt.data)
+			if err != nil {
+				t.Fatalf("Extract() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Extract() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractPDFMalformed(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr string
+	}{
+		{name: "empty", data: nil, wantErr: "invalid PDF"},
+		{name: "not a PDF", data: []byte("hello"), wantErr: "invalid PDF"},
+		{name: "header only", data: []byte("%PDF-1.7"), wantErr: "no extractable text"},
+		{
+			name:    "encrypted",
+			data:    append(simplePDF("BT (Secret) Tj ET"), []byte("trailer << /Encrypt 9 0 R >>")...),
+			wantErr: "encrypted",
+		},
+		{
+			name: "negative object stream /First",
+			data: buildPDF(
+				stream("/Type /ObjStm /N 1 /First -1", "5 0 << /Type /Page >>"),
+			),
+			wantErr: "no extractable text",
+		},
+		{
+			name: "negative object stream offset",
+			data: buildPDF(
+				stream("/Type /ObjStm /N 1 /First 6", "5 -8 \n<< /Type /Page >>"),
+			),
+			wantErr: "no extractable text",
+		},
+		{
+			name: "overflowing object stream offset",
+			data: buildPDF(
+				stream("/Type /ObjStm /N 1 /First 4", "5 9223372036854775807 \n<< >>"),
+			),
+			wantErr: "no extractable text",
+		},
+		{
+			name:    "truncated stream",
+			data:    []byte("%PDF-1.4\n1 0 obj << /Type /Page /Contents 2 0 R >> endobj 2 0 obj << /Length 500 >> stream\nBT (Cut"),
+			wantErr: "no extractable text",
+		},
+		{
+			name:    "corrupt flate data",
+			data:    simplePDF("x\x9c\xff\xff\xff"),
+			wantErr: "no extractable text",
+		},
+		{
+			name:    "unterminated nesting",
+			data:    simplePDF(strings.Repeat("[<<", 10000)),
+			wantErr: "no extractable text",
+		},
+		{
+			name: "self-referencing page tree",
+			data: buildPDF(
+				"<< /Type /Catalog /Pages 2 0 R >>",
+				"<< /Type /Pages /Kids [2 0 R 1 0 R] >>",
+			),
+			wantErr: "no extractable text",
+		},
+		{
+			name: "huge bfrange",
+			data: buildPDF(
+				"<< /Type /Catalog /Pages 2 0 R >>",
+				"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+				"<< /Type /Page /Resources << /Font << /F1 5 0 R >> >> /Contents 4 0 R >>",
+				stream("", "BT /F1 12 Tf <0001> Tj ET"),
+				"<< /Type /Font /Subtype /Type0 /ToUnicode 6 0 R >>",
+				stream("", "1 beginbfrange <0000> <FFFFFFFF> <0041> endbfrange"),
+			),
+			wantErr: "no extractable text",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Extract(TypePDF, tt.data)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Extract() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func FuzzExtractPDF(f *testing.F) {
+	f.Add(simplePDF("BT /F1 12 Tf (Hello) Tj ET"))
+	f.Add(simplePDF("BT [(A) -300 (B)] TJ 0 -12 Td <4142> Tj ET"))
+	f.Add(buildPDF(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [5 0 R] /Count 1 >>",
+		stream("/Type /ObjStm /N 1 /First 5", "5 0 \n<< /Type /Page /Contents 4 0 R >>"),
+		stream("/Filter /FlateDecode", deflate(f, "BT (Packed) Tj ET")),
+	))
+	f.Add(buildPDF(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Resources << /Font << /F1 5 0 R >> >> /Contents 4 0 R >>",
+		stream("/Filter [/ASCIIHexDecode]", "4254203C303030313E20546A204554"),
+		"<< /Type /Font /Subtype /Type0 /ToUnicode 6 0 R /DescendantFonts [<< /W [1 [500] 2 9 600] >>] >>",
+		stream("", "1 begincodespacerange <0000> <FFFF> endcodespacerange 1 beginbfrange <0000> <00FF> <0041> endbfrange"),
+	))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Only panics and hangs are failures; errors are expected
+		Extract(TypePDF, data)
+	})
+}
@@ -0,0 +1,90 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/ai-chat/backend/internal/model"
+)
+
+// Target describes the upstream API a request is sent to
+type Target struct {
+	ProviderType string
+	Endpoint     string
+	APIKey       string
+
+	// Azure OpenAI
+	Resource    string
+	APIVersion  string
+	Deployments map[string]string // model identifier → deployment name
+}
+
+// Adapter translates between our OpenAI-shaped chat types and a provider's wire format
+type Adapter interface {
+	// NewChatRequest builds the HTTP request for a (streaming or non-streaming) chat completion
+	NewChatRequest(ctx context.Context, target *Target, request *model.ChatCompletionRequest) (*http.Request, error)
+
+	// DecodeChatResponse converts a successful response body into a ChatCompletionResponse
+	DecodeChatResponse(body io.Reader) (*model.ChatCompletionResponse, error)
+
+	// NewStreamDecoder returns a decoder for a single streaming response
+	NewStreamDecoder() StreamDecoder
+}
+
+// StreamDecoder converts Server-Sent Events into stream chunks.
+// Decode returns a nil chunk for events that carry no content, and done=true
+// once the provider signals the end of the stream.
+type StreamDecoder interface {
+	Decode(event, data string) (chunk *model.ChatCompletionStreamResponse, done bool, err error)
+}
+
+// ModelLister is implemented by adapters that can enumerate the models an upstream offers
+type ModelLister interface {
+	ListModels(ctx context.Context, client *http.Client, target *Target) ([]model.DiscoveredModel, error)
+}
+
+// Embedder is implemented by adapters whose provider can embed text
+type Embedder interface {
+	// NewEmbeddingRequest builds the HTTP request that embeds request.Input with request.Model
+	NewEmbeddingRequest(ctx context.Context, target *Target, request *model.EmbeddingRequest) (*http.Request, error)
+
+	// DecodeEmbeddingResponse converts a successful response body into an EmbeddingResponse
+	DecodeEmbeddingResponse(body io.Reader) (*model.EmbeddingResponse, error)
+}
+
+// ForProvider returns the adapter for a provider type, defaulting to the OpenAI format
+func ForProvider(providerType string) Adapter {
+	switch providerType {
+	case model.ProviderTypeAnthropic:
+		return &anthropicAdapter{}
+	case model.ProviderTypeGemini:
+		return &geminiAdapter{}
+	case model.ProviderTypeAzure:
+		return &azureAdapter{}
+	case model.ProviderTypeLocal:
+		return &localAdapter{}
+	default:
+		return &openAIAdapter{}
+	}
+}
+
+// jsonObjectOrEmpty returns tool call arguments as a JSON object, substituting
+// {} for missing or malformed arguments, which native tool APIs reject
+func jsonObjectOrEmpty(arguments string) json.RawMessage {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(arguments), &obj); err != nil || obj == nil {
+		return json.RawMessage("{}")
+	}
+	return json.RawMessage(arguments)
+}
+
+// jsonSchemaOrEmpty returns a tool's parameter schema, defaulting to an
+// object without properties for tools that take no arguments
+func jsonSchemaOrEmpty(schema json.RawMessage) json.RawMessage {
+	if len(schema) == 0 {
+		return json.RawMessage(`{"type":"object","properties":{}}`)
+	}
+	return schema
+}
@@ -0,0 +1,135 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ai-chat/backend/internal/model"
+)
+
+// sampleRequest exercises every message kind the adapters translate: a system
+// prompt, a tool call and its result, and a user turn with an image
+func sampleRequest(stream bool) *model.ChatCompletionRequest {
+	temperature, maxTokens := 0.5, 256
+	return &model.ChatCompletionRequest{
+		Model:       "test-model",
+		Stream:      stream,
+		Temperature: &temperature,
+		MaxTokens:   &maxTokens,
+		Stop:        []string{"END"},
+		Messages: []model.ChatMessage{
+			{Role: "system", Content: "Be brief."},
+			{Role: "user", Content: "What is the weather in Paris?"},
+			{Role: "assistant", ToolCalls: []model.ToolCall{{
+				ID:       "call_1",
+				Type:     "function",
+				Function: model.FunctionCall{Name: "get_weather", Arguments: `{"city":"Paris"}`},
+			}}},
+			{Role: "tool", ToolCallID: "call_1", Content: "18°C"},
+			{Role: "user", Content: "And here?", Parts: []model.ContentPart{
+				{Type: model.ContentPartText, Text: "And here?"},
+				{Type: model.ContentPartImageURL, ImageURL: &model.ImageURL{URL: "data:image/png;base64,iVBORw0K"}},
+			}},
+		},
+		Tools: []model.Tool{{Type: "function", Function: model.FunctionDefinition{
+			Name:        "get_weather",
+			Description: "Current weather in a city",
+			Parameters:  json.RawMessage(`{"type":"object","properties":{"city":{"type":"string"}}}`),
+		}}},
+		ToolChoice: &model.ToolChoice{Mode: model.ToolChoiceRequired},
+	}
+}
+
+// newTestRequest builds a chat request with an adapter, failing the test on error
+func newTestRequest(t *testing.T, adapter Adapter, target *Target, request *model.ChatCompletionRequest) *http.Request {
+	t.Helper()
+	httpReq, err := adapter.NewChatRequest(context.Background(), target, request)
+	if err != nil {
+		t.Fatalf("NewChatRequest() error = %v", err)
+	}
+	return httpReq
+}
+
+// decodeBody unmarshals a request body into out
+func decodeBody(t *testing.T, httpReq *http.Request, out interface{}) {
+	t.Helper()
+	body, err := io.ReadAll(httpReq.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		t.Fatalf("request body %s: %v", body, err)
+	}
+}
+
+// readStream decodes a whole SSE body, returning its chunks, the usage
+// reported when the stream is closed and the error that ended it, if any
+func readStream(t *testing.T, adapter Adapter, sse string) ([]*model.ChatCompletionStreamResponse, *model.ChatCompletionUsage, error) {
+	t.Helper()
+	stream := NewStream(io.NopCloser(strings.NewReader(sse)), adapter.NewStreamDecoder())
+	var usage *model.ChatCompletionUsage
+	stream.OnUsage(func(u model.ChatCompletionUsage) { usage = &u })
+
+	var chunks []*model.ChatCompletionStreamResponse
+	var streamErr error
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			streamErr = err
+			break
+		}
+		chunks = append(chunks, chunk)
+	}
+	stream.Close()
+	return chunks, usage, streamErr
+}
+
+// streamText joins the content deltas of a stream's first choice
+func streamText(chunks []*model.ChatCompletionStreamResponse) string {
+	var text strings.Builder
+	for _, chunk := range chunks {
+		if len(chunk.Choices) > 0 {
+			text.WriteString(chunk.Choices[0].Delta.Content)
+		}
+	}
+	return text.String()
+}
+
+// finishReason returns the last finish reason a stream reported
+func finishReason(chunks []*model.ChatCompletionStreamResponse) string {
+	reason := ""
+	for _, chunk := range chunks {
+		if len(chunk.Choices) > 0 && chunk.Choices[0].FinishReason != nil {
+			reason = *chunk.Choices[0].FinishReason
+		}
+	}
+	return reason
+}
+
+func TestForProvider(t *testing.T) {
+	tests := []struct {
+		providerType string
+		want         Adapter
+	}{
+		{model.ProviderTypeAnthropic, &anthropicAdapter{}},
+		{model.ProviderTypeGemini, &geminiAdapter{}},
+		{model.ProviderTypeAzure, &azureAdapter{}},
+		{model.ProviderTypeLocal, &localAdapter{}},
+		{"", &openAIAdapter{}},
+		{"openrouter", &openAIAdapter{}},
+	}
+	for _, tt := range tests {
+		if got, want := fmt.Sprintf("%T", ForProvider(tt.providerType)), fmt.Sprintf("%T", tt.want); got != want {
+			t.Errorf("ForProvider(%q) = %s, want %s", tt.providerType, got, want)
+		}
+	}
+}
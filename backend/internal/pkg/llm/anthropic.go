@@ -0,0 +1,458 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ai-chat/backend/internal/model"
+)
+
+const (
+	anthropicVersion          = "2023-06-01"
+	anthropicDefaultMaxTokens = 4096
+)
+
+// anthropicAdapter speaks the Anthropic Messages API (/v1/messages)
+type anthropicAdapter struct{}
+
+type anthropicContentBlock struct {
+	Type string `json:"type"`
+	Text string `json:"text,omitempty"`
+
+	// tool_use
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+
+	// tool_result
+	ToolUseID string `json:"tool_use_id,omitempty"`
+	Content   string `json:"content,omitempty"`
+
+	// image
+	Source *anthropicImageSource `json:"source,omitempty"`
+}
+
+type anthropicImageSource struct {
+	Type      string `json:"type"` // base64 or url
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+type anthropicTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"input_schema"`
+}
+
+type anthropicToolChoice struct {
+	Type string `json:"type"` // auto, any, tool, none
+	Name string `json:"name,omitempty"`
+}
+
+type anthropicMessage struct {
+	Role    string                  `json:"role"`
+	Content []anthropicContentBlock `json:"content"`
+}
+
+type anthropicRequest struct {
+	Model         string               `json:"model"`
+	System        string               `json:"system,omitempty"`
+	Messages      []anthropicMessage   `json:"messages"`
+	MaxTokens     int                  `json:"max_tokens"`
+	Temperature   *float64             `json:"temperature,omitempty"`
+	TopP          *float64             `json:"top_p,omitempty"`
+	StopSequences []string             `json:"stop_sequences,omitempty"`
+	Stream        bool                 `json:"stream,omitempty"`
+	Tools         []anthropicTool      `json:"tools,omitempty"`
+	ToolChoice    *anthropicToolChoice `json:"tool_choice,omitempty"`
+}
+
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+type anthropicResponse struct {
+	ID         string                  `json:"id"`
+	Model      string                  `json:"model"`
+	Content    []anthropicContentBlock `json:"content"`
+	StopReason string                  `json:"stop_reason"`
+	Usage      anthropicUsage          `json:"usage"`
+}
+
+// anthropicMessagesURL accepts either the full messages URL or just the API base
+func anthropicMessagesURL(endpoint string) string {
+	endpoint = strings.TrimRight(endpoint, "/")
+	switch {
+	case strings.HasSuffix(endpoint, "/messages"):
+		return endpoint
+	case strings.HasSuffix(endpoint, "/v1"):
+		return endpoint + "/messages"
+	default:
+		return endpoint + "/v1/messages"
+	}
+}
+
+// toAnthropicRequest splits out system prompts and merges consecutive turns of
+// the same role, since the Messages API requires strictly alternating roles
+func toAnthropicRequest(request *model.ChatCompletionRequest) *anthropicRequest {
+	out := &anthropicRequest{
+		Model:         request.Model,
+		MaxTokens:     anthropicDefaultMaxTokens,
+		Temperature:   anthropicTemperature(request.Temperature),
+		TopP:          request.TopP,
+		StopSequences: request.Stop,
+		Stream:        request.Stream,
+	}
+	if request.MaxTokens != nil {
+		out.MaxTokens = *request.MaxTokens
+	}
+
+	for _, tool := range request.Tools {
+		out.Tools = append(out.Tools, anthropicTool{
+			Name:        tool.Function.Name,
+			Description: tool.Function.Description,
+			InputSchema: jsonSchemaOrEmpty(tool.Function.Parameters),
+		})
+	}
+	if choice := request.ToolChoice; choice != nil && len(out.Tools) > 0 {
+		switch {
+		case choice.Function != "":
+			out.ToolChoice = &anthropicToolChoice{Type: "tool", Name: choice.Function}
+		case choice.Mode == model.ToolChoiceRequired:
+			out.ToolChoice = &anthropicToolChoice{Type: "any"}
+		case choice.Mode == model.ToolChoiceNone:
+			out.ToolChoice = &anthropicToolChoice{Type: "none"}
+		default:
+			out.ToolChoice = &anthropicToolChoice{Type: "auto"}
+		}
+	}
+
+	var system []string
+	for _, msg := range request.Messages {
+		if msg.Role == "system" {
+			system = append(system, msg.Content)
+			continue
+		}
+
+		// Tool results go back as user turns; tool calls are tool_use blocks
+		// after the assistant's text
+		role := "user"
+		var blocks []anthropicContentBlock
+		switch msg.Role {
+		case "tool":
+			blocks = append(blocks, anthropicContentBlock{Type: "tool_result", ToolUseID: msg.ToolCallID, Content: msg.Content})
+		case "assistant":
+			role = "assistant"
+			if msg.Content != "" {
+				blocks = append(blocks, anthropicContentBlock{Type: "text", Text: msg.Content})
+			}
+			for _, call := range msg.ToolCalls {
+				blocks = append(blocks, anthropicContentBlock{
+					Type:  "tool_use",
+					ID:    call.ID,
+					Name:  call.Function.Name,
+					Input: jsonObjectOrEmpty(call.Function.Arguments),
+				})
+			}
+		default:
+			if len(msg.Parts) > 0 {
+				blocks = append(blocks, anthropicContentBlocks(msg.Parts)...)
+			} else if msg.Content != "" {
+				blocks = append(blocks, anthropicContentBlock{Type: "text", Text: msg.Content})
+			}
+		}
+		// The API rejects empty text blocks, so a turn with nothing to say
+		// (e.g. an assistant reply that failed before any output) is dropped
+		if len(blocks) == 0 {
+			continue
+		}
+
+		if n := len(out.Messages); n > 0 && out.Messages[n-1].Role == role {
+			out.Messages[n-1].Content = append(out.Messages[n-1].Content, blocks...)
+			continue
+		}
+		out.Messages = append(out.Messages, anthropicMessage{Role: role, Content: blocks})
+	}
+	out.System = strings.Join(system, "\n\n")
+
+	return out
+}
+
+// anthropicTemperature clamps the temperature to Anthropic's 0-1 range;
+// OpenAI-style clients may send values up to 2
+func anthropicTemperature(temperature *float64) *float64 {
+	if temperature == nil || *temperature <= 1 {
+		return temperature
+	}
+	clamped := 1.0
+	return &clamped
+}
+
+// anthropicContentBlocks converts multi-part content into text and image blocks
+func anthropicContentBlocks(parts []model.ContentPart) []anthropicContentBlock {
+	blocks := make([]anthropicContentBlock, 0, len(parts))
+	for _, part := range parts {
+		switch {
+		case part.Type == model.ContentPartText && part.Text != "":
+			blocks = append(blocks, anthropicContentBlock{Type: "text", Text: part.Text})
+		case part.Type == model.ContentPartImageURL && part.ImageURL != nil:
+			source := &anthropicImageSource{Type: "url", URL: part.ImageURL.URL}
+			if mediaType, data, ok := part.ImageURL.DataURL(); ok {
+				source = &anthropicImageSource{Type: "base64", MediaType: mediaType, Data: data}
+			}
+			blocks = append(blocks, anthropicContentBlock{Type: "image", Source: source})
+		}
+	}
+	return blocks
+}
+
+// anthropicFinishReason maps Anthropic stop reasons onto OpenAI finish reasons
+func anthropicFinishReason(stopReason string) string {
+	switch stopReason {
+	case "max_tokens":
+		return "length"
+	case "tool_use":
+		return "tool_calls"
+	case "":
+		return ""
+	default:
+		return "stop"
+	}
+}
+
+func (a *anthropicAdapter) NewChatRequest(ctx context.Context, target *Target, request *model.ChatCompletionRequest) (*http.Request, error) {
+	requestBody, err := json.Marshal(toAnthropicRequest(request))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", anthropicMessagesURL(target.Endpoint), bytes.NewBuffer(requestBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-api-key", target.APIKey)
+	httpReq.Header.Set("anthropic-version", anthropicVersion)
+	if request.Stream {
+		httpReq.Header.Set("Accept", "text/event-stream")
+	}
+
+	return httpReq, nil
+}
+
+func (a *anthropicAdapter) DecodeChatResponse(body io.Reader) (*model.ChatCompletionResponse, error) {
+	var resp anthropicResponse
+	if err := json.NewDecoder(body).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	var text strings.Builder
+	var toolCalls []model.ToolCall
+	for _, block := range resp.Content {
+		switch block.Type {
+		case "text":
+			text.WriteString(block.Text)
+		case "tool_use":
+			toolCalls = append(toolCalls, model.ToolCall{
+				ID:       block.ID,
+				Type:     "function",
+				Function: model.FunctionCall{Name: block.Name, Arguments: string(jsonObjectOrEmpty(string(block.Input)))},
+			})
+		}
+	}
+
+	return &model.ChatCompletionResponse{
+		ID:      resp.ID,
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   resp.Model,
+		Choices: []model.ChatCompletionChoice{
+			{
+				Index:        0,
+				Message:      model.ChatMessage{Role: "assistant", Content: text.String(), ToolCalls: toolCalls},
+				FinishReason: anthropicFinishReason(resp.StopReason),
+			},
+		},
+		Usage: model.ChatCompletionUsage{
+			PromptTokens:     resp.Usage.InputTokens,
+			CompletionTokens: resp.Usage.OutputTokens,
+			TotalTokens:      resp.Usage.InputTokens + resp.Usage.OutputTokens,
+		},
+	}, nil
+}
+
+func (a *anthropicAdapter) NewStreamDecoder() StreamDecoder {
+	return &anthropicStreamDecoder{created: time.Now().Unix()}
+}
+
+// anthropicStreamDecoder keeps the message metadata from message_start so
+// later deltas can be emitted as complete OpenAI-style chunks
+type anthropicStreamDecoder struct {
+	id          string
+	model       string
+	created     int64
+	inputTokens int
+	toolIndex   map[int]int // content block index -> tool call index
+}
+
+type anthropicStreamEvent struct {
+	Type         string                 `json:"type"`
+	Message      *anthropicResponse     `json:"message,omitempty"`
+	Index        int                    `json:"index"`
+	ContentBlock *anthropicContentBlock `json:"content_block,omitempty"`
+	Delta        *struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+		StopReason  string `json:"stop_reason"`
+	} `json:"delta,omitempty"`
+	Usage *anthropicUsage `json:"usage,omitempty"`
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+func (d *anthropicStreamDecoder) chunk(delta model.ChatMessageDelta, finishReason *string) *model.ChatCompletionStreamResponse {
+	return &model.ChatCompletionStreamResponse{
+		ID:      d.id,
+		Object:  "chat.completion.chunk",
+		Created: d.created,
+		Model:   d.model,
+		Choices: []model.ChatCompletionStreamChoice{
+			{Index: 0, Delta: delta, FinishReason: finishReason},
+		},
+	}
+}
+
+func (d *anthropicStreamDecoder) Decode(event, data string) (*model.ChatCompletionStreamResponse, bool, error) {
+	var ev anthropicStreamEvent
+	if err := json.Unmarshal([]byte(data), &ev); err != nil {
+		return nil, false, fmt.Errorf("failed to decode stream event: %w", err)
+	}
+
+	switch ev.Type {
+	case "message_start":
+		if ev.Message != nil {
+			d.id = ev.Message.ID
+			d.model = ev.Message.Model
+			d.inputTokens = ev.Message.Usage.InputTokens
+		}
+		return d.chunk(model.ChatMessageDelta{Role: "assistant"}, nil), false, nil
+
+	case "content_block_start":
+		if ev.ContentBlock == nil || ev.ContentBlock.Type != "tool_use" {
+			return nil, false, nil
+		}
+		if d.toolIndex == nil {
+			d.toolIndex = make(map[int]int)
+		}
+		index := len(d.toolIndex)
+		d.toolIndex[ev.Index] = index
+		return d.chunk(model.ChatMessageDelta{ToolCalls: []model.ToolCall{{
+			Index:    &index,
+			ID:       ev.ContentBlock.ID,
+			Type:     "function",
+			Function: model.FunctionCall{Name: ev.ContentBlock.Name},
+		}}}, nil), false, nil
+
+	case "content_block_delta":
+		if ev.Delta == nil {
+			return nil, false, nil
+		}
+		switch ev.Delta.Type {
+		case "text_delta":
+			return d.chunk(model.ChatMessageDelta{Content: ev.Delta.Text}, nil), false, nil
+		case "input_json_delta":
+			index, ok := d.toolIndex[ev.Index]
+			if !ok {
+				return nil, false, nil
+			}
+			return d.chunk(model.ChatMessageDelta{ToolCalls: []model.ToolCall{{
+				Index:    &index,
+				Function: model.FunctionCall{Arguments: ev.Delta.PartialJSON},
+			}}}, nil), false, nil
+		default:
+			return nil, false, nil
+		}
+
+	case "message_delta":
+		var finishReason *string
+		if ev.Delta != nil && ev.Delta.StopReason != "" {
+			reason := anthropicFinishReason(ev.Delta.StopReason)
+			finishReason = &reason
+		}
+		chunk := d.chunk(model.ChatMessageDelta{}, finishReason)
+		if ev.Usage != nil {
+			chunk.Usage = &model.ChatCompletionUsage{
+				PromptTokens:     d.inputTokens,
+				CompletionTokens: ev.Usage.OutputTokens,
+				TotalTokens:      d.inputTokens + ev.Usage.OutputTokens,
+			}
+		}
+		return chunk, false, nil
+
+	case "message_stop":
+		return nil, true, nil
+
+	case "error":
+		if ev.Error != nil {
+			return nil, true, fmt.Errorf("upstream stream error (%s): %s", ev.Error.Type, ev.Error.Message)
+		}
+		return nil, true, fmt.Errorf("upstream stream error")
+
+	default:
+		// ping, content_block_stop
+		return nil, false, nil
+	}
+}
+
+// ListModels pages through the Anthropic /v1/models listing
+func (a *anthropicAdapter) ListModels(ctx context.Context, client *http.Client, target *Target) ([]model.DiscoveredModel, error) {
+	listURL := strings.TrimSuffix(anthropicMessagesURL(target.Endpoint), "/messages") + "/models?limit=1000"
+
+	var models []model.DiscoveredModel
+	afterID := ""
+	for {
+		pageURL := listURL
+		if afterID != "" {
+			pageURL += "&after_id=" + afterID
+		}
+
+		httpReq, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		httpReq.Header.Set("x-api-key", target.APIKey)
+		httpReq.Header.Set("anthropic-version", anthropicVersion)
+
+		var page struct {
+			Data []struct {
+				ID          string `json:"id"`
+				DisplayName string `json:"display_name"`
+			} `json:"data"`
+			HasMore bool   `json:"has_more"`
+			LastID  string `json:"last_id"`
+		}
+		if err := getJSON(client, httpReq, &page); err != nil {
+			return nil, err
+		}
+
+		for _, m := range page.Data {
+			models = append(models, model.DiscoveredModel{ModelIdentifier: m.ID, DisplayName: m.DisplayName})
+		}
+		if !page.HasMore || page.LastID == "" {
+			return models, nil
+		}
+		afterID = page.LastID
+	}
+}
@@ -0,0 +1,239 @@
+package llm
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ai-chat/backend/internal/model"
+)
+
+func TestAnthropicMessagesURL(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		want     string
+	}{
+		{"https://api.anthropic.com", "https://api.anthropic.com/v1/messages"},
+		{"https://api.anthropic.com/", "https://api.anthropic.com/v1/messages"},
+		{"https://api.anthropic.com/v1", "https://api.anthropic.com/v1/messages"},
+		{"https://proxy.example.com/v1/messages", "https://proxy.example.com/v1/messages"},
+	}
+	for _, tt := range tests {
+		if got := anthropicMessagesURL(tt.endpoint); got != tt.want {
+			t.Errorf("anthropicMessagesURL(%q) = %q, want %q", tt.endpoint, got, tt.want)
+		}
+	}
+}
+
+func TestAnthropicRequest(t *testing.T) {
+	httpReq := newTestRequest(t, &anthropicAdapter{}, &Target{Endpoint: "https://api.anthropic.com", APIKey: "sk-ant"}, sampleRequest(true))
+
+	if got := httpReq.URL.String(); got != "https://api.anthropic.com/v1/messages" {
+		t.Errorf("URL = %s", got)
+	}
+	for header, want := range map[string]string{
+		"x-api-key":         "sk-ant",
+		"anthropic-version": anthropicVersion,
+		"Accept":            "text/event-stream",
+		"Authorization":     "",
+	} {
+		if got := httpReq.Header.Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+
+	var sent anthropicRequest
+	decodeBody(t, httpReq, &sent)
+	want := anthropicRequest{
+		Model:         "test-model",
+		System:        "Be brief.",
+		MaxTokens:     256,
+		Temperature:   sent.Temperature,
+		StopSequences: []string{"END"},
+		Stream:        true,
+		Messages: []anthropicMessage{
+			{Role: "user", Content: []anthropicContentBlock{{Type: "text", Text: "What is the weather in Paris?"}}},
+			{Role: "assistant", Content: []anthropicContentBlock{
+				{Type: "tool_use", ID: "call_1", Name: "get_weather", Input: json.RawMessage(`{"city":"Paris"}`)},
+			}},
+			// The tool result and the next user turn merge into one user message
+			{Role: "user", Content: []anthropicContentBlock{
+				{Type: "tool_result", ToolUseID: "call_1", Content: "18°C"},
+				{Type: "text", Text: "And here?"},
+				{Type: "image", Source: &anthropicImageSource{Type: "base64", MediaType: "image/png", Data: "iVBORw0K"}},
+			}},
+		},
+		Tools: []anthropicTool{{
+			Name:        "get_weather",
+			Description: "Current weather in a city",
+			InputSchema: json.RawMessage(`{"type":"object","properties":{"city":{"type":"string"}}}`),
+		}},
+		ToolChoice: &anthropicToolChoice{Type: "any"},
+	}
+	if sent.Temperature == nil || *sent.Temperature != 0.5 {
+		t.Errorf("temperature = %v, want 0.5", sent.Temperature)
+	}
+	if !reflect.DeepEqual(sent, want) {
+		got, _ := json.Marshal(sent)
+		wantJSON, _ := json.Marshal(want)
+		t.Errorf("request body =\n%s\nwant\n%s", got, wantJSON)
+	}
+}
+
+func TestAnthropicRequestDefaults(t *testing.T) {
+	temperature := 1.5
+	request := &model.ChatCompletionRequest{
+		Model:       "claude",
+		Temperature: &temperature,
+		Messages: []model.ChatMessage{
+			{Role: "system", Content: "One."},
+			{Role: "system", Content: "Two."},
+			{Role: "user", Content: "Hi"},
+			// An empty turn would be an empty text block, which the API rejects
+			{Role: "assistant", Content: ""},
+			{Role: "assistant", Content: "Calling", ToolCalls: []model.ToolCall{{ID: "t1", Function: model.FunctionCall{Name: "noop", Arguments: "not json"}}}},
+			{Role: "user", Parts: []model.ContentPart{{Type: model.ContentPartImageURL, ImageURL: &model.ImageURL{URL: "https://example.com/a.png"}}}},
+		},
+		Tools:      []model.Tool{{Type: "function", Function: model.FunctionDefinition{Name: "noop"}}},
+		ToolChoice: &model.ToolChoice{Function: "noop"},
+	}
+	out := toAnthropicRequest(request)
+
+	if out.MaxTokens != anthropicDefaultMaxTokens {
+		t.Errorf("max_tokens = %d, want %d", out.MaxTokens, anthropicDefaultMaxTokens)
+	}
+	if out.Temperature == nil || *out.Temperature != 1 {
+		t.Errorf("temperature = %v, want 1", out.Temperature)
+	}
+	if out.System != "One.\n\nTwo." {
+		t.Errorf("system = %q", out.System)
+	}
+	if got := string(out.Tools[0].InputSchema); got != `{"type":"object","properties":{}}` {
+		t.Errorf("input_schema = %s", got)
+	}
+	if *out.ToolChoice != (anthropicToolChoice{Type: "tool", Name: "noop"}) {
+		t.Errorf("tool_choice = %+v", out.ToolChoice)
+	}
+	if len(out.Messages) != 3 || out.Messages[0].Role != "user" || len(out.Messages[0].Content) != 1 {
+		t.Fatalf("messages = %+v", out.Messages)
+	}
+	assistant := out.Messages[1].Content
+	if len(assistant) != 2 || assistant[0].Text != "Calling" || string(assistant[1].Input) != "{}" {
+		t.Errorf("assistant blocks = %+v", assistant)
+	}
+	if source := out.Messages[2].Content[0].Source; source == nil || *source != (anthropicImageSource{Type: "url", URL: "https://example.com/a.png"}) {
+		t.Errorf("image source = %+v", source)
+	}
+}
+
+func TestAnthropicDecodeChatResponse(t *testing.T) {
+	body := `{
+		"id": "msg_1", "type": "message", "role": "assistant", "model": "claude-sonnet",
+		"content": [
+			{"type": "text", "text": "Let me check."},
+			{"type": "tool_use", "id": "toolu_1", "name": "get_weather", "input": {"city": "Paris"}}
+		],
+		"stop_reason": "tool_use",
+		"usage": {"input_tokens": 30, "output_tokens": 12}
+	}`
+
+	resp, err := (&anthropicAdapter{}).DecodeChatResponse(strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("DecodeChatResponse() error = %v", err)
+	}
+	if resp.ID != "msg_1" || resp.Model != "claude-sonnet" || resp.Object != "chat.completion" {
+		t.Errorf("response = %+v", resp)
+	}
+	choice := resp.Choices[0]
+	want := model.ChatMessage{Role: "assistant", Content: "Let me check.", ToolCalls: []model.ToolCall{{
+		ID: "toolu_1", Type: "function",
+		Function: model.FunctionCall{Name: "get_weather", Arguments: `{"city": "Paris"}`},
+	}}}
+	if !reflect.DeepEqual(choice.Message, want) || choice.FinishReason != "tool_calls" {
+		t.Errorf("choice = %+v, want message %+v and finish tool_calls", choice, want)
+	}
+	if resp.Usage != (model.ChatCompletionUsage{PromptTokens: 30, CompletionTokens: 12, TotalTokens: 42}) {
+		t.Errorf("usage = %+v", resp.Usage)
+	}
+}
+
+func TestAnthropicFinishReason(t *testing.T) {
+	for stop, want := range map[string]string{
+		"end_turn": "stop", "stop_sequence": "stop", "max_tokens": "length", "tool_use": "tool_calls", "": "",
+	} {
+		if got := anthropicFinishReason(stop); got != want {
+			t.Errorf("anthropicFinishReason(%q) = %q, want %q", stop, got, want)
+		}
+	}
+}
+
+func TestAnthropicStream(t *testing.T) {
+	sse := strings.Join([]string{
+		"event: message_start",
+		`data: {"type":"message_start","message":{"id":"msg_1","model":"claude-sonnet","content":[],"usage":{"input_tokens":25,"output_tokens":1}}}`,
+		"",
+		"event: ping",
+		`data: {"type":"ping"}`,
+		"",
+		`data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		"",
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Checking"}}`,
+		"",
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":" now"}}`,
+		"",
+		`data: {"type":"content_block_stop","index":0}`,
+		"",
+		`data: {"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"get_weather","input":{}}}`,
+		"",
+		`data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"city\":"}}`,
+		"",
+		`data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\"Paris\"}"}}`,
+		"",
+		`data: {"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":15}}`,
+		"",
+		`data: {"type":"message_stop"}`,
+		"",
+	}, "\n")
+
+	chunks, usage, err := readStream(t, &anthropicAdapter{}, sse)
+	if err != nil {
+		t.Fatalf("stream error = %v", err)
+	}
+	if got := streamText(chunks); got != "Checking now" {
+		t.Errorf("streamed text = %q", got)
+	}
+	if got := finishReason(chunks); got != "tool_calls" {
+		t.Errorf("finish reason = %q, want tool_calls", got)
+	}
+	if usage == nil || *usage != (model.ChatCompletionUsage{PromptTokens: 25, CompletionTokens: 15, TotalTokens: 40}) {
+		t.Errorf("usage = %+v", usage)
+	}
+
+	var name, arguments string
+	for _, chunk := range chunks {
+		if chunk.ID != "msg_1" || chunk.Model != "claude-sonnet" {
+			t.Errorf("chunk metadata = %s/%s", chunk.ID, chunk.Model)
+		}
+		for _, call := range chunk.Choices[0].Delta.ToolCalls {
+			if call.Index == nil || *call.Index != 0 {
+				t.Errorf("tool call index = %v, want 0", call.Index)
+			}
+			name += call.Function.Name
+			arguments += call.Function.Arguments
+		}
+	}
+	if name != "get_weather" || arguments != `{"city":"Paris"}` {
+		t.Errorf("tool call = %s(%s)", name, arguments)
+	}
+}
+
+func TestAnthropicStreamError(t *testing.T) {
+	sse := `data: {"type":"message_start","message":{"id":"msg_1"}}` + "\n\n" +
+		"event: error\n" + `data: {"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}` + "\n\n"
+
+	_, _, err := readStream(t, &anthropicAdapter{}, sse)
+	if err == nil || !strings.Contains(err.Error(), "overloaded_error") {
+		t.Errorf("stream error = %v, want overloaded_error", err)
+	}
+}
@@ -0,0 +1,166 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/ai-chat/backend/internal/model"
+)
+
+const azureDefaultAPIVersion = "2024-10-21"
+
+// azureAdapter speaks Azure OpenAI. The payloads are OpenAI-shaped, only the
+// URL (deployment based, with api-version) and the auth header differ.
+type azureAdapter struct {
+	openAIAdapter
+}
+
+// azureDeploymentURL builds the URL of an operation (chat/completions or
+// embeddings) on a model's deployment. An endpoint that already points at a
+// deployment is used as-is apart from the operation (api-version is filled in).
+func azureDeploymentURL(target *Target, modelIdentifier, operation string) (string, error) {
+	apiVersion := target.APIVersion
+	if apiVersion == "" {
+		apiVersion = azureDefaultAPIVersion
+	}
+
+	endpoint := strings.TrimRight(target.Endpoint, "/")
+	if strings.Contains(endpoint, "/deployments/") {
+		if operation != "chat/completions" {
+			endpoint = replaceAzureOperation(endpoint, operation)
+		}
+	} else {
+		if endpoint == "" {
+			if target.Resource == "" {
+				return "", fmt.Errorf("azure provider requires an endpoint or resource name")
+			}
+			endpoint = fmt.Sprintf("https://%s.openai.azure.com", target.Resource)
+		}
+
+		deployment := modelIdentifier
+		if name, ok := target.Deployments[modelIdentifier]; ok && name != "" {
+			deployment = name
+		}
+		endpoint = fmt.Sprintf("%s/openai/deployments/%s/%s", endpoint, url.PathEscape(deployment), operation)
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid azure endpoint: %w", err)
+	}
+	query := u.Query()
+	if query.Get("api-version") == "" {
+		query.Set("api-version", apiVersion)
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
+
+// replaceAzureOperation swaps the operation at the end of a deployment URL,
+// keeping any query string
+func replaceAzureOperation(endpoint, operation string) string {
+	query := ""
+	if i := strings.Index(endpoint, "?"); i >= 0 {
+		endpoint, query = endpoint[:i], endpoint[i:]
+	}
+	i := strings.Index(endpoint, "/deployments/")
+	rest := endpoint[i+len("/deployments/"):]
+	if j := strings.Index(rest, "/"); j >= 0 {
+		rest = rest[:j]
+	}
+	return endpoint[:i] + "/deployments/" + rest + "/" + operation + query
+}
+
+func (a *azureAdapter) NewChatRequest(ctx context.Context, target *Target, request *model.ChatCompletionRequest) (*http.Request, error) {
+	chatURL, err := azureDeploymentURL(target, request.Model, "chat/completions")
+	if err != nil {
+		return nil, err
+	}
+
+	requestBody, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", chatURL, bytes.NewBuffer(requestBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("api-key", target.APIKey)
+	if request.Stream {
+		httpReq.Header.Set("Accept", "text/event-stream")
+	}
+
+	return httpReq, nil
+}
+
+// ListModels returns the configured deployment mappings when present;
+// otherwise it asks the resource which base models support chat completions,
+// assuming deployments are named after their models
+func (a *azureAdapter) ListModels(ctx context.Context, client *http.Client, target *Target) ([]model.DiscoveredModel, error) {
+	if len(target.Deployments) > 0 {
+		models := make([]model.DiscoveredModel, 0, len(target.Deployments))
+		for identifier, deployment := range target.Deployments {
+			models = append(models, model.DiscoveredModel{
+				ModelIdentifier: identifier,
+				DisplayName:     fmt.Sprintf("%s (%s)", identifier, deployment),
+			})
+		}
+		sort.Slice(models, func(i, j int) bool { return models[i].ModelIdentifier < models[j].ModelIdentifier })
+		return models, nil
+	}
+
+	base := strings.TrimRight(target.Endpoint, "/")
+	if i := strings.Index(base, "/openai"); i >= 0 {
+		base = base[:i]
+	}
+	if base == "" {
+		if target.Resource == "" {
+			return nil, fmt.Errorf("azure provider requires an endpoint or resource name")
+		}
+		base = fmt.Sprintf("https://%s.openai.azure.com", target.Resource)
+	}
+
+	apiVersion := target.APIVersion
+	if apiVersion == "" {
+		apiVersion = azureDefaultAPIVersion
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/openai/models?api-version=%s", base, url.QueryEscape(apiVersion)), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("api-key", target.APIKey)
+
+	var listing struct {
+		Data []struct {
+			ID           string `json:"id"`
+			Capabilities struct {
+				ChatCompletion bool `json:"chat_completion"`
+			} `json:"capabilities"`
+		} `json:"data"`
+	}
+	if err := getJSON(client, httpReq, &listing); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	models := make([]model.DiscoveredModel, 0, len(listing.Data))
+	for _, m := range listing.Data {
+		if !m.Capabilities.ChatCompletion || seen[m.ID] {
+			continue
+		}
+		seen[m.ID] = true
+		models = append(models, model.DiscoveredModel{ModelIdentifier: m.ID})
+	}
+	return models, nil
+}
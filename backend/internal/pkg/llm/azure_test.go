@@ -0,0 +1,80 @@
+package llm
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ai-chat/backend/internal/model"
+)
+
+func TestAzureDeploymentURL(t *testing.T) {
+	tests := []struct {
+		name      string
+		target    Target
+		operation string
+		want      string
+	}{
+		{
+			name:      "resource and model name",
+			target:    Target{Resource: "acme"},
+			operation: "chat/completions",
+			want:      "https://acme.openai.azure.com/openai/deployments/gpt-4o/chat/completions?api-version=" + azureDefaultAPIVersion,
+		},
+		{
+			name:      "mapped deployment and API version",
+			target:    Target{Endpoint: "https://acme.openai.azure.com/", APIVersion: "2025-01-01", Deployments: map[string]string{"gpt-4o": "prod chat"}},
+			operation: "chat/completions",
+			want:      "https://acme.openai.azure.com/openai/deployments/prod%20chat/chat/completions?api-version=2025-01-01",
+		},
+		{
+			name:      "endpoint already naming a deployment",
+			target:    Target{Endpoint: "https://acme.openai.azure.com/openai/deployments/chat/chat/completions?api-version=2024-06-01"},
+			operation: "chat/completions",
+			want:      "https://acme.openai.azure.com/openai/deployments/chat/chat/completions?api-version=2024-06-01",
+		},
+		{
+			name:      "deployment endpoint reused for embeddings",
+			target:    Target{Endpoint: "https://acme.openai.azure.com/openai/deployments/chat/chat/completions"},
+			operation: "embeddings",
+			want:      "https://acme.openai.azure.com/openai/deployments/chat/embeddings?api-version=" + azureDefaultAPIVersion,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := azureDeploymentURL(&tt.target, "gpt-4o", tt.operation)
+			if err != nil {
+				t.Fatalf("azureDeploymentURL() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("azureDeploymentURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := azureDeploymentURL(&Target{}, "gpt-4o", "chat/completions"); err == nil {
+		t.Error("azureDeploymentURL() without endpoint or resource succeeded")
+	}
+}
+
+func TestAzureRequestRoundTrip(t *testing.T) {
+	request := sampleRequest(false)
+	target := &Target{Resource: "acme", APIKey: "azure-key", Deployments: map[string]string{"test-model": "chat"}}
+	httpReq := newTestRequest(t, &azureAdapter{}, target, request)
+
+	if want := "https://acme.openai.azure.com/openai/deployments/chat/chat/completions?api-version=" + azureDefaultAPIVersion; httpReq.URL.String() != want {
+		t.Errorf("URL = %s, want %s", httpReq.URL, want)
+	}
+	if got := httpReq.Header.Get("api-key"); got != "azure-key" {
+		t.Errorf("api-key = %q", got)
+	}
+	if got := httpReq.Header.Get("Authorization"); got != "" {
+		t.Errorf("Authorization = %q, Azure keys go in api-key", got)
+	}
+
+	var sent model.ChatCompletionRequest
+	decodeBody(t, httpReq, &sent)
+	if !reflect.DeepEqual(&sent, request) {
+		t.Errorf("request did not survive the round trip:\n got %+v\nwant %+v", sent, *request)
+	}
+}
@@ -0,0 +1,93 @@
+package llm
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ModelInfo holds well-known defaults for a model family. Every catalog
+// entry supports streaming chat completions.
+type ModelInfo struct {
+	ContextWindow    int
+	InputPricePer1k  float64 // USD
+	OutputPricePer1k float64 // USD
+	Functions        bool    // supports tool/function calling
+}
+
+// knownModels maps model names to their defaults. An identifier matches an
+// entry only with nothing after the name but a version or date stamp
+// ("gpt-4o-2024-08-06", "claude-3-5-sonnet-latest"), so newer models that
+// share a prefix ("gpt-4.5", "o3-pro") are not mistaken for older ones.
+var knownModels = map[string]ModelInfo{
+	// OpenAI
+	"gpt-4o":        {ContextWindow: 128000, InputPricePer1k: 0.0025, OutputPricePer1k: 0.01, Functions: true},
+	"gpt-4o-mini":   {ContextWindow: 128000, InputPricePer1k: 0.00015, OutputPricePer1k: 0.0006, Functions: true},
+	"gpt-4.1":       {ContextWindow: 1047576, InputPricePer1k: 0.002, OutputPricePer1k: 0.008, Functions: true},
+	"gpt-4.1-mini":  {ContextWindow: 1047576, InputPricePer1k: 0.0004, OutputPricePer1k: 0.0016, Functions: true},
+	"gpt-4.1-nano":  {ContextWindow: 1047576, InputPricePer1k: 0.0001, OutputPricePer1k: 0.0004, Functions: true},
+	"gpt-4-turbo":   {ContextWindow: 128000, InputPricePer1k: 0.01, OutputPricePer1k: 0.03, Functions: true},
+	"gpt-4":         {ContextWindow: 8192, InputPricePer1k: 0.03, OutputPricePer1k: 0.06, Functions: true},
+	"gpt-3.5-turbo": {ContextWindow: 16385, InputPricePer1k: 0.0005, OutputPricePer1k: 0.0015, Functions: true},
+	"o1":            {ContextWindow: 200000, InputPricePer1k: 0.015, OutputPricePer1k: 0.06, Functions: true},
+	"o1-mini":       {ContextWindow: 128000, InputPricePer1k: 0.0011, OutputPricePer1k: 0.0044},
+	"o3":            {ContextWindow: 200000, InputPricePer1k: 0.002, OutputPricePer1k: 0.008, Functions: true},
+	"o3-mini":       {ContextWindow: 200000, InputPricePer1k: 0.0011, OutputPricePer1k: 0.0044, Functions: true},
+	"o4-mini":       {ContextWindow: 200000, InputPricePer1k: 0.0011, OutputPricePer1k: 0.0044, Functions: true},
+
+	// Anthropic
+	"claude-3-haiku":    {ContextWindow: 200000, InputPricePer1k: 0.00025, OutputPricePer1k: 0.00125, Functions: true},
+	"claude-3-5-haiku":  {ContextWindow: 200000, InputPricePer1k: 0.0008, OutputPricePer1k: 0.004, Functions: true},
+	"claude-3-5-sonnet": {ContextWindow: 200000, InputPricePer1k: 0.003, OutputPricePer1k: 0.015, Functions: true},
+	"claude-3-7-sonnet": {ContextWindow: 200000, InputPricePer1k: 0.003, OutputPricePer1k: 0.015, Functions: true},
+	"claude-3-opus":     {ContextWindow: 200000, InputPricePer1k: 0.015, OutputPricePer1k: 0.075, Functions: true},
+	"claude-sonnet-4":   {ContextWindow: 200000, InputPricePer1k: 0.003, OutputPricePer1k: 0.015, Functions: true},
+	"claude-opus-4":     {ContextWindow: 200000, InputPricePer1k: 0.015, OutputPricePer1k: 0.075, Functions: true},
+
+	// Google
+	"gemini-1.5-flash": {ContextWindow: 1048576, InputPricePer1k: 0.000075, OutputPricePer1k: 0.0003, Functions: true},
+	"gemini-1.5-pro":   {ContextWindow: 2097152, InputPricePer1k: 0.00125, OutputPricePer1k: 0.005, Functions: true},
+	"gemini-2.0-flash": {ContextWindow: 1048576, InputPricePer1k: 0.0001, OutputPricePer1k: 0.0004, Functions: true},
+	"gemini-2.5-flash": {ContextWindow: 1048576, InputPricePer1k: 0.0003, OutputPricePer1k: 0.0025, Functions: true},
+	"gemini-2.5-pro":   {ContextWindow: 1048576, InputPricePer1k: 0.00125, OutputPricePer1k: 0.01, Functions: true},
+
+	// DeepSeek
+	"deepseek-chat":     {ContextWindow: 65536, InputPricePer1k: 0.00027, OutputPricePer1k: 0.0011, Functions: true},
+	"deepseek-reasoner": {ContextWindow: 65536, InputPricePer1k: 0.00055, OutputPricePer1k: 0.00219},
+}
+
+// LookupModel returns the known defaults for a model identifier, if any
+func LookupModel(identifier string) (ModelInfo, bool) {
+	id := strings.ToLower(identifier)
+	// Strip vendor prefixes such as "openai/" (OpenRouter) or "models/" (Gemini)
+	if i := strings.LastIndex(id, "/"); i >= 0 {
+		id = id[i+1:]
+	}
+
+	for name, info := range knownModels {
+		if strings.HasPrefix(id, name) && versionSuffix.MatchString(id[len(name):]) {
+			return info, true
+		}
+	}
+	return ModelInfo{}, false
+}
+
+// versionSuffix matches what may follow a catalog name in a model identifier
+var versionSuffix = regexp.MustCompile(`^(-latest|-\d{3,8}|-\d{4}-\d{2}-\d{2})?$`)
+
+// nonChatModelMarkers identify entries in an OpenAI-style model listing that
+// cannot serve chat completions
+var nonChatModelMarkers = []string{
+	"embedding", "whisper", "tts", "dall-e", "moderation", "transcribe",
+	"davinci", "babbage", "image", "realtime", "audio", "search",
+}
+
+// isChatModel reports whether a listed model looks usable for chat
+func isChatModel(identifier string) bool {
+	id := strings.ToLower(identifier)
+	for _, marker := range nonChatModelMarkers {
+		if strings.Contains(id, marker) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,40 @@
+package llm
+
+import "testing"
+
+func TestLookupModel(t *testing.T) {
+	tests := []struct {
+		identifier    string
+		wantKnown     bool
+		wantContext   int
+		wantFunctions bool
+	}{
+		{"gpt-4o", true, 128000, true},
+		{"gpt-4o-2024-08-06", true, 128000, true},
+		{"gpt-4o-mini-2024-07-18", true, 128000, true},
+		{"gpt-4-0613", true, 8192, true},
+		{"gpt-4-turbo-2024-04-09", true, 128000, true},
+		{"openai/gpt-4.1-mini", true, 1047576, true},
+		{"models/gemini-1.5-pro-002", true, 2097152, true},
+		{"claude-3-5-sonnet-20241022", true, 200000, true},
+		{"claude-3-5-sonnet-latest", true, 200000, true},
+		{"o1-mini", true, 128000, false},
+		{"DeepSeek-Reasoner", true, 65536, false},
+
+		// Newer models sharing a prefix with a catalog entry are unknown
+		{"gpt-4.5-preview", false, 0, false},
+		{"gpt-4-32k", false, 0, false},
+		{"o3-pro", false, 0, false},
+		{"gemini-2.5-flash-lite", false, 0, false},
+		{"gpt-4o-audio-preview", false, 0, false},
+		{"llama3.1:8b", false, 0, false},
+	}
+
+	for _, tt := range tests {
+		info, known := LookupModel(tt.identifier)
+		if known != tt.wantKnown || info.ContextWindow != tt.wantContext || info.Functions != tt.wantFunctions {
+			t.Errorf("LookupModel(%q) = %+v, %v; want context %d, functions %v, known %v",
+				tt.identifier, info, known, tt.wantContext, tt.wantFunctions, tt.wantKnown)
+		}
+	}
+}
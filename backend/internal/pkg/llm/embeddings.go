@@ -0,0 +1,128 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/ai-chat/backend/internal/model"
+)
+
+// NewEmbeddingRequest sends an OpenAI-format embeddings request to the
+// /embeddings endpoint next to the target's chat completions endpoint
+func (a *openAIAdapter) NewEmbeddingRequest(ctx context.Context, target *Target, request *model.EmbeddingRequest) (*http.Request, error) {
+	base := strings.TrimSuffix(strings.TrimRight(target.Endpoint, "/"), "/chat/completions")
+
+	httpReq, err := newEmbeddingHTTPRequest(ctx, base+"/embeddings", request)
+	if err != nil {
+		return nil, err
+	}
+	if target.APIKey != "" {
+		httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", target.APIKey))
+	}
+
+	return httpReq, nil
+}
+
+func (a *openAIAdapter) DecodeEmbeddingResponse(body io.Reader) (*model.EmbeddingResponse, error) {
+	var response model.EmbeddingResponse
+	if err := json.NewDecoder(body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &response, nil
+}
+
+// NewEmbeddingRequest sends the request to the model's deployment
+func (a *azureAdapter) NewEmbeddingRequest(ctx context.Context, target *Target, request *model.EmbeddingRequest) (*http.Request, error) {
+	embeddingURL, err := azureDeploymentURL(target, request.Model, "embeddings")
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := newEmbeddingHTTPRequest(ctx, embeddingURL, request)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("api-key", target.APIKey)
+
+	return httpReq, nil
+}
+
+func (a *localAdapter) NewEmbeddingRequest(ctx context.Context, target *Target, request *model.EmbeddingRequest) (*http.Request, error) {
+	resolved := *target
+	resolved.Endpoint = localBaseURL(target.Endpoint) + "/v1"
+	return a.openAIAdapter.NewEmbeddingRequest(ctx, &resolved, request)
+}
+
+// Gemini embeds a batch with batchEmbedContents, one request per input
+type geminiEmbedRequest struct {
+	Model   string        `json:"model"`
+	Content geminiContent `json:"content"`
+}
+
+type geminiEmbedResponse struct {
+	Embeddings []struct {
+		Values []float32 `json:"values"`
+	} `json:"embeddings"`
+}
+
+func (a *geminiAdapter) NewEmbeddingRequest(ctx context.Context, target *Target, request *model.EmbeddingRequest) (*http.Request, error) {
+	modelName := strings.TrimPrefix(request.Model, "models/")
+
+	body := struct {
+		Requests []geminiEmbedRequest `json:"requests"`
+	}{Requests: make([]geminiEmbedRequest, 0, len(request.Input))}
+	for _, input := range request.Input {
+		body.Requests = append(body.Requests, geminiEmbedRequest{
+			Model:   "models/" + modelName,
+			Content: geminiContent{Parts: []geminiPart{{Text: input}}},
+		})
+	}
+
+	requestBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", geminiMethodURL(target, modelName, "batchEmbedContents", nil), bytes.NewBuffer(requestBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	setGeminiHeaders(httpReq, target)
+
+	return httpReq, nil
+}
+
+// DecodeEmbeddingResponse numbers the embeddings in input order; Gemini
+// reports no token usage for embeddings
+func (a *geminiAdapter) DecodeEmbeddingResponse(body io.Reader) (*model.EmbeddingResponse, error) {
+	var resp geminiEmbedResponse
+	if err := json.NewDecoder(body).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	out := &model.EmbeddingResponse{Data: make([]model.EmbeddingData, 0, len(resp.Embeddings))}
+	for i, e := range resp.Embeddings {
+		out.Data = append(out.Data, model.EmbeddingData{Index: i, Embedding: e.Values})
+	}
+	return out, nil
+}
+
+func newEmbeddingHTTPRequest(ctx context.Context, embeddingURL string, request *model.EmbeddingRequest) (*http.Request, error) {
+	requestBody, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", embeddingURL, bytes.NewBuffer(requestBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	return httpReq, nil
+}
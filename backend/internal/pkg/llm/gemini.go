@@ -0,0 +1,473 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ai-chat/backend/internal/model"
+)
+
+// geminiAdapter speaks the Google Gemini generateContent API
+type geminiAdapter struct{}
+
+// ErrImageURLUnsupported is returned for images given by link to a provider
+// that only accepts the image data itself
+var ErrImageURLUnsupported = errors.New("this model cannot read images from links; upload the image instead")
+
+type geminiPart struct {
+	Text             string                  `json:"text,omitempty"`
+	InlineData       *geminiBlob             `json:"inlineData,omitempty"`
+	FunctionCall     *geminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *geminiFunctionResponse `json:"functionResponse,omitempty"`
+}
+
+type geminiBlob struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"` // base64
+}
+
+type geminiFunctionCall struct {
+	Name string          `json:"name"`
+	Args json.RawMessage `json:"args,omitempty"`
+}
+
+type geminiFunctionResponse struct {
+	Name     string          `json:"name"`
+	Response json.RawMessage `json:"response"`
+}
+
+type geminiFunctionDeclaration struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
+
+type geminiTool struct {
+	FunctionDeclarations []geminiFunctionDeclaration `json:"functionDeclarations"`
+}
+
+type geminiToolConfig struct {
+	FunctionCallingConfig struct {
+		Mode                 string   `json:"mode"` // AUTO, ANY, NONE
+		AllowedFunctionNames []string `json:"allowedFunctionNames,omitempty"`
+	} `json:"functionCallingConfig"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiGenerationConfig struct {
+	Temperature     *float64 `json:"temperature,omitempty"`
+	TopP            *float64 `json:"topP,omitempty"`
+	MaxOutputTokens *int     `json:"maxOutputTokens,omitempty"`
+	StopSequences   []string `json:"stopSequences,omitempty"`
+	CandidateCount  *int     `json:"candidateCount,omitempty"`
+}
+
+type geminiRequest struct {
+	Contents          []geminiContent         `json:"contents"`
+	SystemInstruction *geminiContent          `json:"systemInstruction,omitempty"`
+	GenerationConfig  *geminiGenerationConfig `json:"generationConfig,omitempty"`
+	Tools             []geminiTool            `json:"tools,omitempty"`
+	ToolConfig        *geminiToolConfig       `json:"toolConfig,omitempty"`
+}
+
+type geminiUsageMetadata struct {
+	PromptTokenCount     int `json:"promptTokenCount"`
+	CandidatesTokenCount int `json:"candidatesTokenCount"`
+	TotalTokenCount      int `json:"totalTokenCount"`
+}
+
+type geminiCandidate struct {
+	Content      geminiContent `json:"content"`
+	FinishReason string        `json:"finishReason"`
+	Index        int           `json:"index"`
+}
+
+type geminiResponse struct {
+	Candidates    []geminiCandidate    `json:"candidates"`
+	UsageMetadata *geminiUsageMetadata `json:"usageMetadata,omitempty"`
+	ModelVersion  string               `json:"modelVersion"`
+	ResponseID    string               `json:"responseId"`
+}
+
+// geminiURL builds the generateContent URL for a (streaming) chat request
+func geminiURL(target *Target, modelName string, stream bool) string {
+	if stream {
+		return geminiMethodURL(target, modelName, "streamGenerateContent", url.Values{"alt": {"sse"}})
+	}
+	return geminiMethodURL(target, modelName, "generateContent", nil)
+}
+
+// geminiMethodURL builds a model method URL; the endpoint may be the API base
+// with or without a version segment (defaults to v1beta)
+func geminiMethodURL(target *Target, modelName, method string, query url.Values) string {
+	base := strings.TrimSuffix(strings.TrimRight(target.Endpoint, "/"), "/models")
+	if !strings.Contains(base, "/v1") {
+		base += "/v1beta"
+	}
+
+	u := fmt.Sprintf("%s/models/%s:%s", base, url.PathEscape(modelName), method)
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	return u
+}
+
+// setGeminiHeaders authenticates a request. The key goes in a header rather
+// than the query string so it never shows up in URLs quoted by errors and logs.
+func setGeminiHeaders(httpReq *http.Request, target *Target) {
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-goog-api-key", target.APIKey)
+}
+
+// toGeminiRequest maps roles (assistant → model), lifts system prompts into the
+// system instruction and merges consecutive turns of the same role
+func toGeminiRequest(request *model.ChatCompletionRequest) (*geminiRequest, error) {
+	out := &geminiRequest{}
+
+	if len(request.Tools) > 0 {
+		tool := geminiTool{}
+		for _, t := range request.Tools {
+			tool.FunctionDeclarations = append(tool.FunctionDeclarations, geminiFunctionDeclaration{
+				Name:        t.Function.Name,
+				Description: t.Function.Description,
+				Parameters:  t.Function.Parameters,
+			})
+		}
+		out.Tools = []geminiTool{tool}
+
+		if choice := request.ToolChoice; choice != nil {
+			out.ToolConfig = &geminiToolConfig{}
+			switch {
+			case choice.Function != "":
+				out.ToolConfig.FunctionCallingConfig.Mode = "ANY"
+				out.ToolConfig.FunctionCallingConfig.AllowedFunctionNames = []string{choice.Function}
+			case choice.Mode == model.ToolChoiceRequired:
+				out.ToolConfig.FunctionCallingConfig.Mode = "ANY"
+			case choice.Mode == model.ToolChoiceNone:
+				out.ToolConfig.FunctionCallingConfig.Mode = "NONE"
+			default:
+				out.ToolConfig.FunctionCallingConfig.Mode = "AUTO"
+			}
+		}
+	}
+
+	// Gemini matches function responses by name rather than call ID
+	callNames := make(map[string]string)
+
+	var system []geminiPart
+	for _, msg := range request.Messages {
+		if msg.Role == "system" {
+			system = append(system, geminiPart{Text: msg.Content})
+			continue
+		}
+
+		role := "user"
+		var parts []geminiPart
+		switch msg.Role {
+		case "tool":
+			response, _ := json.Marshal(map[string]string{"result": msg.Content})
+			parts = append(parts, geminiPart{FunctionResponse: &geminiFunctionResponse{
+				Name:     callNames[msg.ToolCallID],
+				Response: response,
+			}})
+		case "assistant":
+			role = "model"
+			if msg.Content != "" || len(msg.ToolCalls) == 0 {
+				parts = append(parts, geminiPart{Text: msg.Content})
+			}
+			for _, call := range msg.ToolCalls {
+				callNames[call.ID] = call.Function.Name
+				parts = append(parts, geminiPart{FunctionCall: &geminiFunctionCall{
+					Name: call.Function.Name,
+					Args: jsonObjectOrEmpty(call.Function.Arguments),
+				}})
+			}
+		default:
+			if len(msg.Parts) > 0 {
+				converted, err := geminiParts(msg.Parts)
+				if err != nil {
+					return nil, err
+				}
+				parts = append(parts, converted...)
+			} else {
+				parts = append(parts, geminiPart{Text: msg.Content})
+			}
+		}
+
+		if n := len(out.Contents); n > 0 && out.Contents[n-1].Role == role {
+			out.Contents[n-1].Parts = append(out.Contents[n-1].Parts, parts...)
+			continue
+		}
+		out.Contents = append(out.Contents, geminiContent{Role: role, Parts: parts})
+	}
+	if len(system) > 0 {
+		out.SystemInstruction = &geminiContent{Parts: system}
+	}
+
+	if request.Temperature != nil || request.TopP != nil || request.MaxTokens != nil || len(request.Stop) > 0 || request.N != nil {
+		out.GenerationConfig = &geminiGenerationConfig{
+			Temperature:     request.Temperature,
+			TopP:            request.TopP,
+			MaxOutputTokens: request.MaxTokens,
+			StopSequences:   request.Stop,
+			CandidateCount:  request.N,
+		}
+	}
+
+	return out, nil
+}
+
+// geminiParts converts multi-part content into text and inline image parts.
+// Gemini's fileData only takes files uploaded to Google, so images must come
+// as data: URLs.
+func geminiParts(parts []model.ContentPart) ([]geminiPart, error) {
+	out := make([]geminiPart, 0, len(parts))
+	for _, part := range parts {
+		switch {
+		case part.Type == model.ContentPartText:
+			out = append(out, geminiPart{Text: part.Text})
+		case part.Type == model.ContentPartImageURL && part.ImageURL != nil:
+			mediaType, data, ok := part.ImageURL.DataURL()
+			if !ok {
+				return nil, ErrImageURLUnsupported
+			}
+			out = append(out, geminiPart{InlineData: &geminiBlob{MimeType: mediaType, Data: data}})
+		}
+	}
+	return out, nil
+}
+
+// geminiFinishReason maps Gemini finish reasons onto OpenAI finish reasons
+func geminiFinishReason(reason string) string {
+	switch reason {
+	case "", "FINISH_REASON_UNSPECIFIED":
+		return ""
+	case "STOP":
+		return "stop"
+	case "MAX_TOKENS":
+		return "length"
+	default:
+		// SAFETY, RECITATION, BLOCKLIST, PROHIBITED_CONTENT, ...
+		return "content_filter"
+	}
+}
+
+func geminiUsage(meta *geminiUsageMetadata) *model.ChatCompletionUsage {
+	if meta == nil {
+		return nil
+	}
+	total := meta.TotalTokenCount
+	if total == 0 {
+		total = meta.PromptTokenCount + meta.CandidatesTokenCount
+	}
+	return &model.ChatCompletionUsage{
+		PromptTokens:     meta.PromptTokenCount,
+		CompletionTokens: meta.CandidatesTokenCount,
+		TotalTokens:      total,
+	}
+}
+
+func geminiText(content geminiContent) string {
+	var text strings.Builder
+	for _, part := range content.Parts {
+		text.WriteString(part.Text)
+	}
+	return text.String()
+}
+
+// geminiToolCalls converts function call parts into tool calls. Gemini has no
+// call IDs, so they are numbered from `next`.
+func geminiToolCalls(content geminiContent, next int) []model.ToolCall {
+	var calls []model.ToolCall
+	for _, part := range content.Parts {
+		if part.FunctionCall == nil {
+			continue
+		}
+		index := next + len(calls)
+		calls = append(calls, model.ToolCall{
+			Index:    &index,
+			ID:       fmt.Sprintf("call_%d", index),
+			Type:     "function",
+			Function: model.FunctionCall{Name: part.FunctionCall.Name, Arguments: string(jsonObjectOrEmpty(string(part.FunctionCall.Args)))},
+		})
+	}
+	return calls
+}
+
+func (a *geminiAdapter) NewChatRequest(ctx context.Context, target *Target, request *model.ChatCompletionRequest) (*http.Request, error) {
+	geminiReq, err := toGeminiRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	requestBody, err := json.Marshal(geminiReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", geminiURL(target, request.Model, request.Stream), bytes.NewBuffer(requestBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	setGeminiHeaders(httpReq, target)
+	if request.Stream {
+		httpReq.Header.Set("Accept", "text/event-stream")
+	}
+
+	return httpReq, nil
+}
+
+func (a *geminiAdapter) DecodeChatResponse(body io.Reader) (*model.ChatCompletionResponse, error) {
+	var resp geminiResponse
+	if err := json.NewDecoder(body).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	out := &model.ChatCompletionResponse{
+		ID:      resp.ResponseID,
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   resp.ModelVersion,
+	}
+	for i, candidate := range resp.Candidates {
+		toolCalls := geminiToolCalls(candidate.Content, 0)
+		for j := range toolCalls {
+			toolCalls[j].Index = nil
+		}
+		finishReason := geminiFinishReason(candidate.FinishReason)
+		if len(toolCalls) > 0 {
+			finishReason = "tool_calls"
+		}
+		out.Choices = append(out.Choices, model.ChatCompletionChoice{
+			Index:        i,
+			Message:      model.ChatMessage{Role: "assistant", Content: geminiText(candidate.Content), ToolCalls: toolCalls},
+			FinishReason: finishReason,
+		})
+	}
+	if usage := geminiUsage(resp.UsageMetadata); usage != nil {
+		out.Usage = *usage
+	}
+
+	return out, nil
+}
+
+func (a *geminiAdapter) NewStreamDecoder() StreamDecoder {
+	return &geminiStreamDecoder{created: time.Now().Unix()}
+}
+
+// geminiStreamDecoder converts each streamed GenerateContentResponse into a chunk.
+// Gemini has no terminal event; the stream simply ends.
+type geminiStreamDecoder struct {
+	created   int64
+	toolCalls int // tool calls emitted so far, for numbering
+}
+
+func (d *geminiStreamDecoder) Decode(event, data string) (*model.ChatCompletionStreamResponse, bool, error) {
+	var resp geminiResponse
+	if err := json.Unmarshal([]byte(data), &resp); err != nil {
+		return nil, false, fmt.Errorf("failed to decode stream chunk: %w", err)
+	}
+
+	chunk := &model.ChatCompletionStreamResponse{
+		ID:      resp.ResponseID,
+		Object:  "chat.completion.chunk",
+		Created: d.created,
+		Model:   resp.ModelVersion,
+		Usage:   geminiUsage(resp.UsageMetadata),
+	}
+	for i, candidate := range resp.Candidates {
+		// Function calls arrive whole rather than in fragments
+		toolCalls := geminiToolCalls(candidate.Content, d.toolCalls)
+		d.toolCalls += len(toolCalls)
+
+		var finishReason *string
+		if reason := geminiFinishReason(candidate.FinishReason); reason != "" {
+			if d.toolCalls > 0 {
+				reason = "tool_calls"
+			}
+			finishReason = &reason
+		}
+		chunk.Choices = append(chunk.Choices, model.ChatCompletionStreamChoice{
+			Index:        i,
+			Delta:        model.ChatMessageDelta{Role: "assistant", Content: geminiText(candidate.Content), ToolCalls: toolCalls},
+			FinishReason: finishReason,
+		})
+	}
+
+	return chunk, false, nil
+}
+
+// ListModels pages through the Gemini models listing, keeping only models
+// that support generateContent
+func (a *geminiAdapter) ListModels(ctx context.Context, client *http.Client, target *Target) ([]model.DiscoveredModel, error) {
+	base := strings.TrimSuffix(strings.TrimRight(target.Endpoint, "/"), "/models")
+	if !strings.Contains(base, "/v1") {
+		base += "/v1beta"
+	}
+
+	var models []model.DiscoveredModel
+	pageToken := ""
+	for {
+		query := url.Values{}
+		query.Set("pageSize", "1000")
+		if pageToken != "" {
+			query.Set("pageToken", pageToken)
+		}
+
+		httpReq, err := http.NewRequestWithContext(ctx, "GET", base+"/models?"+query.Encode(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		httpReq.Header.Set("x-goog-api-key", target.APIKey)
+
+		var page struct {
+			Models []struct {
+				Name                       string   `json:"name"`
+				DisplayName                string   `json:"displayName"`
+				InputTokenLimit            int      `json:"inputTokenLimit"`
+				SupportedGenerationMethods []string `json:"supportedGenerationMethods"`
+			} `json:"models"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		if err := getJSON(client, httpReq, &page); err != nil {
+			return nil, err
+		}
+
+		for _, m := range page.Models {
+			supportsChat, supportsStreaming := false, false
+			for _, method := range m.SupportedGenerationMethods {
+				switch method {
+				case "generateContent":
+					supportsChat = true
+				case "streamGenerateContent":
+					supportsStreaming = true
+				}
+			}
+			if !supportsChat {
+				continue
+			}
+			models = append(models, model.DiscoveredModel{
+				ModelIdentifier:   strings.TrimPrefix(m.Name, "models/"),
+				DisplayName:       m.DisplayName,
+				MaxTokens:         m.InputTokenLimit,
+				SupportsStreaming: supportsStreaming,
+			})
+		}
+		if page.NextPageToken == "" {
+			return models, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
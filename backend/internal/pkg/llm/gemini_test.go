@@ -0,0 +1,163 @@
+package llm
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ai-chat/backend/internal/model"
+)
+
+func TestGeminiImageParts(t *testing.T) {
+	image := func(url string) model.ChatMessage {
+		return model.ChatMessage{Role: "user", Content: "what is this?", Parts: []model.ContentPart{
+			{Type: model.ContentPartText, Text: "what is this?"},
+			{Type: model.ContentPartImageURL, ImageURL: &model.ImageURL{URL: url}},
+		}}
+	}
+
+	req, err := toGeminiRequest(&model.ChatCompletionRequest{Messages: []model.ChatMessage{image("data:image/webp;base64,UklGRg==")}})
+	if err != nil {
+		t.Fatalf("toGeminiRequest() error = %v", err)
+	}
+	parts := req.Contents[0].Parts
+	if len(parts) != 2 || parts[1].InlineData == nil {
+		t.Fatalf("parts = %+v, want text and inline image", parts)
+	}
+	if blob := parts[1].InlineData; blob.MimeType != "image/webp" || blob.Data != "UklGRg==" {
+		t.Errorf("inline image = %+v", blob)
+	}
+
+	for _, url := range []string{"https://example.com/cat.png", "http://example.com/cat", "data:image/png,raw"} {
+		_, err := toGeminiRequest(&model.ChatCompletionRequest{Messages: []model.ChatMessage{image(url)}})
+		if !errors.Is(err, ErrImageURLUnsupported) {
+			t.Errorf("toGeminiRequest(%q) error = %v, want ErrImageURLUnsupported", url, err)
+		}
+	}
+}
+
+func TestGeminiRequest(t *testing.T) {
+	target := &Target{Endpoint: "https://generativelanguage.googleapis.com", APIKey: "AIza-secret"}
+
+	httpReq := newTestRequest(t, &geminiAdapter{}, target, sampleRequest(true))
+	if want := "https://generativelanguage.googleapis.com/v1beta/models/test-model:streamGenerateContent?alt=sse"; httpReq.URL.String() != want {
+		t.Errorf("URL = %s, want %s", httpReq.URL, want)
+	}
+	if got := httpReq.Header.Get("x-goog-api-key"); got != "AIza-secret" {
+		t.Errorf("x-goog-api-key = %q", got)
+	}
+	if strings.Contains(httpReq.URL.String(), "AIza-secret") {
+		t.Errorf("API key leaked into URL %s", httpReq.URL)
+	}
+
+	var sent geminiRequest
+	decodeBody(t, httpReq, &sent)
+	maxTokens := 256
+	want := geminiRequest{
+		Contents: []geminiContent{
+			{Role: "user", Parts: []geminiPart{{Text: "What is the weather in Paris?"}}},
+			{Role: "model", Parts: []geminiPart{{FunctionCall: &geminiFunctionCall{Name: "get_weather", Args: json.RawMessage(`{"city":"Paris"}`)}}}},
+			// Function responses are matched by name and merge with the next user turn
+			{Role: "user", Parts: []geminiPart{
+				{FunctionResponse: &geminiFunctionResponse{Name: "get_weather", Response: json.RawMessage(`{"result":"18°C"}`)}},
+				{Text: "And here?"},
+				{InlineData: &geminiBlob{MimeType: "image/png", Data: "iVBORw0K"}},
+			}},
+		},
+		SystemInstruction: &geminiContent{Parts: []geminiPart{{Text: "Be brief."}}},
+		GenerationConfig: &geminiGenerationConfig{
+			Temperature:     sent.GenerationConfig.Temperature,
+			MaxOutputTokens: &maxTokens,
+			StopSequences:   []string{"END"},
+		},
+		Tools: []geminiTool{{FunctionDeclarations: []geminiFunctionDeclaration{{
+			Name:        "get_weather",
+			Description: "Current weather in a city",
+			Parameters:  json.RawMessage(`{"type":"object","properties":{"city":{"type":"string"}}}`),
+		}}}},
+	}
+	want.ToolConfig = &geminiToolConfig{}
+	want.ToolConfig.FunctionCallingConfig.Mode = "ANY"
+	if !reflect.DeepEqual(sent, want) {
+		got, _ := json.Marshal(sent)
+		wantJSON, _ := json.Marshal(want)
+		t.Errorf("request body =\n%s\nwant\n%s", got, wantJSON)
+	}
+
+	httpReq = newTestRequest(t, &geminiAdapter{}, &Target{Endpoint: "https://proxy.example.com/v1/models", APIKey: "k"}, sampleRequest(false))
+	if want := "https://proxy.example.com/v1/models/test-model:generateContent"; httpReq.URL.String() != want {
+		t.Errorf("URL = %s, want %s", httpReq.URL, want)
+	}
+}
+
+func TestGeminiDecodeChatResponse(t *testing.T) {
+	body := `{
+		"candidates": [{
+			"content": {"role": "model", "parts": [
+				{"text": "Checking."},
+				{"functionCall": {"name": "get_weather", "args": {"city": "Paris"}}}
+			]},
+			"finishReason": "STOP", "index": 0
+		}],
+		"usageMetadata": {"promptTokenCount": 12, "candidatesTokenCount": 7, "totalTokenCount": 19},
+		"modelVersion": "gemini-2.0-flash", "responseId": "resp-1"
+	}`
+
+	resp, err := (&geminiAdapter{}).DecodeChatResponse(strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("DecodeChatResponse() error = %v", err)
+	}
+	if resp.ID != "resp-1" || resp.Model != "gemini-2.0-flash" {
+		t.Errorf("response = %+v", resp)
+	}
+	want := model.ChatMessage{Role: "assistant", Content: "Checking.", ToolCalls: []model.ToolCall{{
+		ID: "call_0", Type: "function",
+		Function: model.FunctionCall{Name: "get_weather", Arguments: `{"city": "Paris"}`},
+	}}}
+	if choice := resp.Choices[0]; !reflect.DeepEqual(choice.Message, want) || choice.FinishReason != "tool_calls" {
+		t.Errorf("choice = %+v, want message %+v and finish tool_calls", choice, want)
+	}
+	if resp.Usage != (model.ChatCompletionUsage{PromptTokens: 12, CompletionTokens: 7, TotalTokens: 19}) {
+		t.Errorf("usage = %+v", resp.Usage)
+	}
+}
+
+func TestGeminiFinishReason(t *testing.T) {
+	for reason, want := range map[string]string{
+		"": "", "FINISH_REASON_UNSPECIFIED": "", "STOP": "stop", "MAX_TOKENS": "length", "SAFETY": "content_filter",
+	} {
+		if got := geminiFinishReason(reason); got != want {
+			t.Errorf("geminiFinishReason(%q) = %q, want %q", reason, got, want)
+		}
+	}
+}
+
+func TestGeminiStream(t *testing.T) {
+	sse := `data: {"candidates":[{"content":{"role":"model","parts":[{"text":"Hel"}]}}],"responseId":"r1"}` + "\n\n" +
+		`data: {"candidates":[{"content":{"role":"model","parts":[{"text":"lo"}]}}],"responseId":"r1"}` + "\n\n" +
+		`data: {"candidates":[{"content":{"role":"model","parts":[{"functionCall":{"name":"a"}},{"functionCall":{"name":"b","args":{"x":1}}}]},"finishReason":"STOP"}],` +
+		`"usageMetadata":{"promptTokenCount":4,"candidatesTokenCount":3},"responseId":"r1"}` + "\n\n"
+
+	chunks, usage, err := readStream(t, &geminiAdapter{}, sse)
+	if err != nil {
+		t.Fatalf("stream error = %v", err)
+	}
+	if got := streamText(chunks); got != "Hello" {
+		t.Errorf("streamed text = %q", got)
+	}
+	if got := finishReason(chunks); got != "tool_calls" {
+		t.Errorf("finish reason = %q, want tool_calls", got)
+	}
+	// A missing total is derived from its parts
+	if usage == nil || *usage != (model.ChatCompletionUsage{PromptTokens: 4, CompletionTokens: 3, TotalTokens: 7}) {
+		t.Errorf("usage = %+v", usage)
+	}
+
+	calls := chunks[len(chunks)-1].Choices[0].Delta.ToolCalls
+	if len(calls) != 2 || *calls[0].Index != 0 || *calls[1].Index != 1 || calls[1].ID != "call_1" ||
+		calls[0].Function.Arguments != "{}" || calls[1].Function.Arguments != `{"x":1}` {
+		t.Errorf("tool calls = %+v", calls)
+	}
+}
@@ -0,0 +1,76 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ai-chat/backend/internal/model"
+)
+
+// localAdapter speaks to self-hosted OpenAI-compatible runtimes such as
+// Ollama, llama.cpp server and vLLM. The endpoint may be just the server
+// base URL (e.g. http://localhost:11434) and no API key is required.
+type localAdapter struct {
+	openAIAdapter
+}
+
+// localBaseURL strips any OpenAI path suffix from the endpoint
+func localBaseURL(endpoint string) string {
+	base := strings.TrimRight(endpoint, "/")
+	base = strings.TrimSuffix(base, "/chat/completions")
+	base = strings.TrimSuffix(base, "/v1")
+	return base
+}
+
+func (a *localAdapter) NewChatRequest(ctx context.Context, target *Target, request *model.ChatCompletionRequest) (*http.Request, error) {
+	resolved := *target
+	resolved.Endpoint = localBaseURL(target.Endpoint) + "/v1/chat/completions"
+	return a.openAIAdapter.NewChatRequest(ctx, &resolved, request)
+}
+
+// ListModels asks the runtime for its installed models, trying the OpenAI
+// compatible /v1/models first and Ollama's native /api/tags second
+func (a *localAdapter) ListModels(ctx context.Context, client *http.Client, target *Target) ([]model.DiscoveredModel, error) {
+	base := localBaseURL(target.Endpoint)
+
+	models, err := listOpenAIModels(ctx, client, base+"/v1/models", target.APIKey)
+	if err == nil {
+		return models, nil
+	}
+
+	ollamaModels, ollamaErr := listOllamaModels(ctx, client, base+"/api/tags")
+	if ollamaErr != nil {
+		return nil, fmt.Errorf("model discovery failed: %v; %v", err, ollamaErr)
+	}
+	return ollamaModels, nil
+}
+
+// listOllamaModels reads Ollama's {"models": [{"name": ...}]} listing
+func listOllamaModels(ctx context.Context, client *http.Client, listURL string) ([]model.DiscoveredModel, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", listURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	var listing struct {
+		Models []struct {
+			Name  string `json:"name"`
+			Model string `json:"model"`
+		} `json:"models"`
+	}
+	if err := getJSON(client, httpReq, &listing); err != nil {
+		return nil, err
+	}
+
+	models := make([]model.DiscoveredModel, 0, len(listing.Models))
+	for _, m := range listing.Models {
+		id := m.Model
+		if id == "" {
+			id = m.Name
+		}
+		models = append(models, model.DiscoveredModel{ModelIdentifier: id, DisplayName: m.Name})
+	}
+	return models, nil
+}
@@ -0,0 +1,36 @@
+package llm
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ai-chat/backend/internal/model"
+)
+
+func TestLocalRequest(t *testing.T) {
+	for _, endpoint := range []string{
+		"http://localhost:11434",
+		"http://localhost:11434/",
+		"http://localhost:11434/v1",
+		"http://localhost:11434/v1/chat/completions",
+	} {
+		httpReq := newTestRequest(t, &localAdapter{}, &Target{Endpoint: endpoint}, sampleRequest(false))
+		if got, want := httpReq.URL.String(), "http://localhost:11434/v1/chat/completions"; got != want {
+			t.Errorf("endpoint %q: URL = %s, want %s", endpoint, got, want)
+		}
+		if got := httpReq.Header.Get("Authorization"); got != "" {
+			t.Errorf("endpoint %q: Authorization = %q without a key", endpoint, got)
+		}
+	}
+
+	request := sampleRequest(true)
+	httpReq := newTestRequest(t, &localAdapter{}, &Target{Endpoint: "http://gpu:8000", APIKey: "vllm-key"}, request)
+	if got := httpReq.Header.Get("Authorization"); got != "Bearer vllm-key" {
+		t.Errorf("Authorization = %q", got)
+	}
+	var sent model.ChatCompletionRequest
+	decodeBody(t, httpReq, &sent)
+	if !reflect.DeepEqual(&sent, request) {
+		t.Errorf("request did not survive the round trip:\n got %+v\nwant %+v", sent, *request)
+	}
+}
@@ -0,0 +1,135 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/ai-chat/backend/internal/model"
+)
+
+// openAIAdapter speaks the OpenAI chat completions format, which most
+// third-party and self-hosted endpoints are compatible with
+type openAIAdapter struct{}
+
+func (a *openAIAdapter) NewChatRequest(ctx context.Context, target *Target, request *model.ChatCompletionRequest) (*http.Request, error) {
+	requestBody, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", target.Endpoint, bytes.NewBuffer(requestBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	if target.APIKey != "" {
+		httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", target.APIKey))
+	}
+	if request.Stream {
+		httpReq.Header.Set("Accept", "text/event-stream")
+	}
+
+	return httpReq, nil
+}
+
+func (a *openAIAdapter) DecodeChatResponse(body io.Reader) (*model.ChatCompletionResponse, error) {
+	var response model.ChatCompletionResponse
+	if err := json.NewDecoder(body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &response, nil
+}
+
+func (a *openAIAdapter) NewStreamDecoder() StreamDecoder {
+	return &openAIStreamDecoder{}
+}
+
+type openAIStreamDecoder struct{}
+
+func (d *openAIStreamDecoder) Decode(event, data string) (*model.ChatCompletionStreamResponse, bool, error) {
+	if data == "[DONE]" {
+		return nil, true, nil
+	}
+
+	var chunk model.ChatCompletionStreamResponse
+	if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+		return nil, false, fmt.Errorf("failed to decode stream chunk: %w", err)
+	}
+	return &chunk, false, nil
+}
+
+// ListModels reads the OpenAI-style /models listing next to the chat
+// completions endpoint, skipping entries that cannot serve chat
+func (a *openAIAdapter) ListModels(ctx context.Context, client *http.Client, target *Target) ([]model.DiscoveredModel, error) {
+	base := strings.TrimSuffix(strings.TrimRight(target.Endpoint, "/"), "/chat/completions")
+
+	listed, err := listOpenAIModels(ctx, client, base+"/models", target.APIKey)
+	if err != nil {
+		return nil, err
+	}
+
+	models := make([]model.DiscoveredModel, 0, len(listed))
+	for _, m := range listed {
+		if isChatModel(m.ModelIdentifier) {
+			models = append(models, m)
+		}
+	}
+	return models, nil
+}
+
+// listOpenAIModels reads an OpenAI-style {"data": [{"id": ...}]} listing
+func listOpenAIModels(ctx context.Context, client *http.Client, listURL, apiKey string) ([]model.DiscoveredModel, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", listURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if apiKey != "" {
+		httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiKey))
+	}
+
+	var listing struct {
+		Data []struct {
+			ID            string `json:"id"`
+			ContextLength int    `json:"context_length"` // OpenRouter
+			MaxModelLen   int    `json:"max_model_len"`  // vLLM
+		} `json:"data"`
+	}
+	if err := getJSON(client, httpReq, &listing); err != nil {
+		return nil, err
+	}
+
+	models := make([]model.DiscoveredModel, 0, len(listing.Data))
+	for _, m := range listing.Data {
+		maxTokens := m.ContextLength
+		if maxTokens == 0 {
+			maxTokens = m.MaxModelLen
+		}
+		models = append(models, model.DiscoveredModel{ModelIdentifier: m.ID, MaxTokens: maxTokens})
+	}
+	return models, nil
+}
+
+// getJSON performs a request and decodes a successful JSON response
+func getJSON(client *http.Client, httpReq *http.Request, out interface{}) error {
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
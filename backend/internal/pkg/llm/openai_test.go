@@ -0,0 +1,108 @@
+package llm
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ai-chat/backend/internal/model"
+)
+
+func TestOpenAIRequestRoundTrip(t *testing.T) {
+	request := sampleRequest(true)
+	target := &Target{Endpoint: "https://api.openai.com/v1/chat/completions", APIKey: "sk-test"}
+	httpReq := newTestRequest(t, &openAIAdapter{}, target, request)
+
+	if httpReq.Method != "POST" || httpReq.URL.String() != target.Endpoint {
+		t.Errorf("request = %s %s, want POST %s", httpReq.Method, httpReq.URL, target.Endpoint)
+	}
+	for header, want := range map[string]string{
+		"Authorization": "Bearer sk-test",
+		"Content-Type":  "application/json",
+		"Accept":        "text/event-stream",
+	} {
+		if got := httpReq.Header.Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+
+	var sent model.ChatCompletionRequest
+	decodeBody(t, httpReq, &sent)
+	if !reflect.DeepEqual(&sent, request) {
+		t.Errorf("request did not survive the round trip:\n got %+v\nwant %+v", sent, *request)
+	}
+
+	// Keyless endpoints get no Authorization header at all
+	httpReq = newTestRequest(t, &openAIAdapter{}, &Target{Endpoint: target.Endpoint}, sampleRequest(false))
+	if got := httpReq.Header.Get("Authorization"); got != "" {
+		t.Errorf("Authorization = %q without a key", got)
+	}
+	if got := httpReq.Header.Get("Accept"); got != "" {
+		t.Errorf("Accept = %q for a non-streaming request", got)
+	}
+}
+
+func TestOpenAIDecodeChatResponse(t *testing.T) {
+	body := `{
+		"id": "chatcmpl-1", "object": "chat.completion", "created": 1700000000, "model": "gpt-4o",
+		"choices": [{
+			"index": 0,
+			"message": {"role": "assistant", "content": null, "tool_calls": [
+				{"id": "call_1", "type": "function", "function": {"name": "get_weather", "arguments": "{\"city\":\"Paris\"}"}}
+			]},
+			"finish_reason": "tool_calls"
+		}],
+		"usage": {"prompt_tokens": 20, "completion_tokens": 8, "total_tokens": 28}
+	}`
+
+	resp, err := (&openAIAdapter{}).DecodeChatResponse(strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("DecodeChatResponse() error = %v", err)
+	}
+	want := &model.ChatCompletionResponse{
+		ID: "chatcmpl-1", Object: "chat.completion", Created: 1700000000, Model: "gpt-4o",
+		Choices: []model.ChatCompletionChoice{{
+			Message: model.ChatMessage{Role: "assistant", ToolCalls: []model.ToolCall{{
+				ID: "call_1", Type: "function",
+				Function: model.FunctionCall{Name: "get_weather", Arguments: `{"city":"Paris"}`},
+			}}},
+			FinishReason: "tool_calls",
+		}},
+		Usage: model.ChatCompletionUsage{PromptTokens: 20, CompletionTokens: 8, TotalTokens: 28},
+	}
+	if !reflect.DeepEqual(resp, want) {
+		t.Errorf("DecodeChatResponse() =\n%+v\nwant\n%+v", resp, want)
+	}
+
+	if _, err := (&openAIAdapter{}).DecodeChatResponse(strings.NewReader("<html>")); err == nil {
+		t.Error("DecodeChatResponse() of a non-JSON body succeeded")
+	}
+}
+
+func TestOpenAIStream(t *testing.T) {
+	sse := ": keep-alive\n\n" +
+		`data: {"id":"c1","object":"chat.completion.chunk","model":"gpt-4o","choices":[{"index":0,"delta":{"role":"assistant"},"finish_reason":null}]}` + "\n\n" +
+		`data: {"id":"c1","object":"chat.completion.chunk","model":"gpt-4o","choices":[{"index":0,"delta":{"content":"Hel"},"finish_reason":null}]}` + "\r\n\r\n" +
+		`data: {"id":"c1","object":"chat.completion.chunk","model":"gpt-4o","choices":[{"index":0,"delta":{"content":"lo"},"finish_reason":"stop"}]}` + "\n\n" +
+		`data: {"id":"c1","object":"chat.completion.chunk","model":"gpt-4o","choices":[],"usage":{"prompt_tokens":5,"completion_tokens":2,"total_tokens":7}}` + "\n\n" +
+		"data: [DONE]\n\n" +
+		`data: {"choices":[{"delta":{"content":"after done"}}]}` + "\n\n"
+
+	chunks, usage, err := readStream(t, &openAIAdapter{}, sse)
+	if err != nil {
+		t.Fatalf("stream error = %v", err)
+	}
+	if got := streamText(chunks); got != "Hello" {
+		t.Errorf("streamed text = %q, want %q", got, "Hello")
+	}
+	if got := finishReason(chunks); got != "stop" {
+		t.Errorf("finish reason = %q, want stop", got)
+	}
+	if usage == nil || *usage != (model.ChatCompletionUsage{PromptTokens: 5, CompletionTokens: 2, TotalTokens: 7}) {
+		t.Errorf("usage = %+v", usage)
+	}
+
+	if _, _, err := readStream(t, &openAIAdapter{}, "data: {broken\n\n"); err == nil {
+		t.Error("malformed chunk did not fail the stream")
+	}
+}
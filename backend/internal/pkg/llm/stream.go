@@ -0,0 +1,104 @@
+package llm
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ai-chat/backend/internal/model"
+)
+
+// Stream reads chunks from an upstream Server-Sent Events response
+type Stream struct {
+	body    io.ReadCloser
+	scanner *bufio.Scanner
+	decoder StreamDecoder
+	done    bool
+	onUsage func(model.ChatCompletionUsage)
+	usage   *model.ChatCompletionUsage // latest reported usage; some upstreams repeat it cumulatively
+}
+
+// NewStream wraps a streaming response body with the given decoder
+func NewStream(body io.ReadCloser, decoder StreamDecoder) *Stream {
+	scanner := bufio.NewScanner(body)
+	// Single SSE lines can be large (e.g. long deltas), allow up to 1MB
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	return &Stream{body: body, scanner: scanner, decoder: decoder}
+}
+
+// OnUsage registers a callback invoked once with the final token usage when
+// the stream is closed, if the upstream reported any
+func (s *Stream) OnUsage(fn func(model.ChatCompletionUsage)) {
+	s.onUsage = fn
+}
+
+// Recv returns the next chunk from the stream, or io.EOF once the stream is finished
+func (s *Stream) Recv() (*model.ChatCompletionStreamResponse, error) {
+	for !s.done {
+		event, data, ok := s.nextEvent()
+		if !ok {
+			if err := s.scanner.Err(); err != nil {
+				return nil, fmt.Errorf("failed to read stream: %w", err)
+			}
+			return nil, io.EOF
+		}
+
+		chunk, done, err := s.decoder.Decode(event, data)
+		if err != nil {
+			return nil, err
+		}
+		s.done = done
+		if chunk != nil {
+			if chunk.Usage != nil {
+				s.usage = chunk.Usage
+			}
+			return chunk, nil
+		}
+	}
+
+	return nil, io.EOF
+}
+
+// nextEvent reads lines until a complete event with data has been dispatched
+func (s *Stream) nextEvent() (event, data string, ok bool) {
+	var dataLines []string
+
+	for s.scanner.Scan() {
+		line := strings.TrimRight(s.scanner.Text(), "\r")
+
+		// A blank line dispatches the event
+		if line == "" {
+			if len(dataLines) > 0 {
+				return event, strings.Join(dataLines, "\n"), true
+			}
+			event = ""
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, ":"):
+			// Comment / keep-alive
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			dataLines = append(dataLines, strings.TrimSpace(strings.TrimPrefix(line, "data:")))
+		}
+	}
+
+	// Dispatch a trailing event that was not followed by a blank line
+	if len(dataLines) > 0 {
+		return event, strings.Join(dataLines, "\n"), true
+	}
+	return "", "", false
+}
+
+// Close closes the underlying response body and reports the final usage
+func (s *Stream) Close() error {
+	if s.onUsage != nil && s.usage != nil {
+		report := s.onUsage
+		s.onUsage = nil
+		report(*s.usage)
+	}
+	return s.body.Close()
+}
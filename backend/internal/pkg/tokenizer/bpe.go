@@ -0,0 +1,130 @@
+package tokenizer
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/dlclark/regexp2"
+)
+
+// Pre-tokenization patterns from OpenAI's tiktoken. They need lookahead, which
+// the standard library regexp does not support.
+const (
+	cl100kPattern = `(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+`
+
+	o200kPattern = `[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+(?i:'s|'t|'re|'ve|'m|'ll|'d)?` +
+		`|[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*(?i:'s|'t|'re|'ve|'m|'ll|'d)?` +
+		`|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n/]*|\s*[\r\n]+|\s+(?!\S)|\s+`
+)
+
+// bpeEncoding is a byte-level BPE encoding loaded from a .tiktoken rank file
+type bpeEncoding struct {
+	name    string
+	ranks   map[string]int
+	pattern *regexp2.Regexp
+}
+
+// loadBPE reads a .tiktoken file: one "<base64 token> <rank>" pair per line
+func loadBPE(name, path, pattern string) (*bpeEncoding, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	ranks := make(map[string]int, 200000)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid line in %s: %q", path, line)
+		}
+		token, err := base64.StdEncoding.DecodeString(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid token in %s: %w", path, err)
+		}
+		rank, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid rank in %s: %w", path, err)
+		}
+		ranks[string(token)] = rank
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	return &bpeEncoding{
+		name:    name,
+		ranks:   ranks,
+		pattern: regexp2.MustCompile(pattern, regexp2.None),
+	}, nil
+}
+
+func (e *bpeEncoding) Name() string {
+	return e.name
+}
+
+// Count returns the number of tokens the text encodes to
+func (e *bpeEncoding) Count(text string) int {
+	total := 0
+	m, _ := e.pattern.FindStringMatch(text)
+	for m != nil {
+		piece := m.String()
+		if _, ok := e.ranks[piece]; ok {
+			total++
+		} else {
+			total += e.mergeCount([]byte(piece))
+		}
+		m, _ = e.pattern.FindNextMatch(m)
+	}
+	return total
+}
+
+// mergeCount applies byte pair merges to a piece, always merging the
+// lowest-ranked adjacent pair first, and returns the resulting token count
+func (e *bpeEncoding) mergeCount(piece []byte) int {
+	if len(piece) <= 1 {
+		return len(piece)
+	}
+
+	// parts[i] is the start offset of the i-th current token; the last entry
+	// is the end of the piece
+	parts := make([]int, len(piece)+1)
+	for i := range parts {
+		parts[i] = i
+	}
+
+	rankOf := func(i int) int {
+		if i+2 >= len(parts) {
+			return math.MaxInt
+		}
+		if rank, ok := e.ranks[string(piece[parts[i]:parts[i+2]])]; ok {
+			return rank
+		}
+		return math.MaxInt
+	}
+
+	for len(parts) > 2 {
+		best, bestRank := -1, math.MaxInt
+		for i := 0; i < len(parts)-2; i++ {
+			if rank := rankOf(i); rank < bestRank {
+				best, bestRank = i, rank
+			}
+		}
+		if best < 0 {
+			break
+		}
+		parts = append(parts[:best+1], parts[best+2:]...)
+	}
+
+	return len(parts) - 1
+}
@@ -0,0 +1,113 @@
+// Package tokenizer counts tokens the way upstream models do. OpenAI's BPE
+// encodings (cl100k_base, o200k_base) are loaded from .tiktoken rank files in
+// a data directory, fetched by scripts/fetch-tokenizer.sh; models without a
+// known encoding fall back to a script-aware heuristic.
+package tokenizer
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Encoding names
+const (
+	Cl100kBase = "cl100k_base"
+	O200kBase  = "o200k_base"
+	Heuristic  = "heuristic"
+)
+
+// Tokenizer counts the tokens in a text
+type Tokenizer interface {
+	Name() string
+	Count(text string) int
+}
+
+// bpePatterns are the pre-tokenization patterns of the BPE encodings
+var bpePatterns = map[string]string{
+	Cl100kBase: cl100kPattern,
+	O200kBase:  o200kPattern,
+}
+
+var encodings sync.Map // name -> *bpeEncoding
+
+// Load reads the rank file of every BPE encoding from dir. Encodings that
+// load are used even if others fail; the error names each one that did not.
+func Load(dir string) error {
+	var errs []error
+	for name, pattern := range bpePatterns {
+		enc, err := loadBPE(name, filepath.Join(dir, name+".tiktoken"), pattern)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		encodings.Store(name, enc)
+	}
+	return errors.Join(errs...)
+}
+
+// Get returns the tokenizer for an encoding name, or the heuristic if the
+// encoding is unknown or has not been loaded
+func Get(name string) Tokenizer {
+	if enc, ok := encodings.Load(name); ok {
+		return enc.(*bpeEncoding)
+	}
+	return heuristic{}
+}
+
+// EncodingForModel picks the encoding a model identifier uses. An explicit
+// encoding configured on the model takes precedence over this guess.
+func EncodingForModel(modelIdentifier string) string {
+	id := strings.ToLower(modelIdentifier)
+	if i := strings.LastIndex(id, "/"); i >= 0 {
+		id = id[i+1:]
+	}
+
+	switch {
+	case strings.HasPrefix(id, "gpt-4o"), strings.HasPrefix(id, "gpt-4.1"), strings.HasPrefix(id, "gpt-4.5"),
+		strings.HasPrefix(id, "gpt-5"), strings.HasPrefix(id, "chatgpt-4o"),
+		strings.HasPrefix(id, "o1"), strings.HasPrefix(id, "o3"), strings.HasPrefix(id, "o4"):
+		return O200kBase
+	case strings.HasPrefix(id, "gpt-4"), strings.HasPrefix(id, "gpt-3.5"), strings.HasPrefix(id, "gpt-35"),
+		strings.HasPrefix(id, "text-embedding-"):
+		return Cl100kBase
+	default:
+		return Heuristic
+	}
+}
+
+// ForModel returns the tokenizer for a model, honouring an explicit encoding
+func ForModel(modelIdentifier, encoding string) Tokenizer {
+	if encoding == "" {
+		encoding = EncodingForModel(modelIdentifier)
+	}
+	return Get(encoding)
+}
+
+// heuristic approximates BPE counts without a vocabulary: runs of ASCII are
+// about four characters per token, while CJK and other scripts that BPE
+// vocabularies cover sparsely are close to one token per character
+type heuristic struct{}
+
+func (heuristic) Name() string {
+	return Heuristic
+}
+
+func (heuristic) Count(text string) int {
+	ascii, wide, other := 0, 0, 0
+	for _, r := range text {
+		switch {
+		case r <= unicode.MaxASCII:
+			ascii++
+		case unicode.Is(unicode.Han, r), unicode.Is(unicode.Hiragana, r),
+			unicode.Is(unicode.Katakana, r), unicode.Is(unicode.Hangul, r):
+			wide++
+		default:
+			other++
+		}
+	}
+	return (ascii+3)/4 + wide + (other+1)/2
+}
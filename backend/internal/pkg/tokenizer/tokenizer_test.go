@@ -0,0 +1,164 @@
+package tokenizer
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dlclark/regexp2"
+)
+
+// syntheticEncoding has every single byte as a token plus the given merges,
+// which is enough to exercise the merge order without a real vocabulary
+func syntheticEncoding(merges map[string]int) *bpeEncoding {
+	ranks := make(map[string]int, 256+len(merges))
+	for b := 0; b < 256; b++ {
+		ranks[string([]byte{byte(b)})] = b
+	}
+	for token, rank := range merges {
+		ranks[token] = rank
+	}
+	return &bpeEncoding{name: "synthetic", ranks: ranks, pattern: regexp2.MustCompile(cl100kPattern, regexp2.None)}
+}
+
+func TestMergeCount(t *testing.T) {
+	// "bc" has the lowest rank, so "abcd" becomes a|bc|d and stops there;
+	// merging left to right instead would give ab|cd
+	enc := syntheticEncoding(map[string]int{"bc": 256, "ab": 257, "cd": 258})
+
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"a", 1},
+		{"abcd", 3},
+		{"bcd", 2},
+		{"cd", 1},
+		{"abcd abcd", 3 + 4}, // the second piece starts with its space
+	}
+	for _, tt := range tests {
+		if got := enc.Count(tt.text); got != tt.want {
+			t.Errorf("Count(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestLoadBPE(t *testing.T) {
+	dir := t.TempDir()
+	var lines []string
+	for i, token := range []string{"a", "b", "ab"} {
+		lines = append(lines, fmt.Sprintf("%s %d", base64.StdEncoding.EncodeToString([]byte(token)), i))
+	}
+	path := filepath.Join(dir, "test.tiktoken")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	enc, err := loadBPE("test", path, cl100kPattern)
+	if err != nil {
+		t.Fatalf("loadBPE() error = %v", err)
+	}
+	if got := enc.Count("ab"); got != 1 {
+		t.Errorf("Count(%q) = %d, want 1", "ab", got)
+	}
+
+	bad := filepath.Join(dir, "bad.tiktoken")
+	if err := os.WriteFile(bad, []byte("not-base64! 1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadBPE("bad", bad, cl100kPattern); err == nil {
+		t.Error("loadBPE() of a corrupt file succeeded")
+	}
+}
+
+func TestLoadReportsMissingFiles(t *testing.T) {
+	err := Load(t.TempDir())
+	if err == nil {
+		t.Fatal("Load() of an empty directory succeeded")
+	}
+	for _, name := range []string{Cl100kBase, O200kBase} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("Load() error %q does not name %s", err, name)
+		}
+	}
+}
+
+func TestHeuristicCount(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"abcd", 1},
+		{"abcde", 2},
+		{"\x7f\x7f\x7f\x7f", 1}, // DEL is ASCII
+		{"你好世界", 4},
+		{"héllo", 1 + 1},
+	}
+	for _, tt := range tests {
+		if got := (heuristic{}).Count(tt.text); got != tt.want {
+			t.Errorf("Count(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestEncodingForModel(t *testing.T) {
+	tests := []struct {
+		model string
+		want  string
+	}{
+		{"gpt-4o-mini", O200kBase},
+		{"openai/gpt-4.1", O200kBase},
+		{"o3-mini", O200kBase},
+		{"gpt-4-turbo", Cl100kBase},
+		{"gpt-3.5-turbo", Cl100kBase},
+		{"text-embedding-3-small", Cl100kBase},
+		{"claude-3-5-sonnet", Heuristic},
+		{"gemini-1.5-pro", Heuristic},
+	}
+	for _, tt := range tests {
+		if got := EncodingForModel(tt.model); got != tt.want {
+			t.Errorf("EncodingForModel(%q) = %q, want %q", tt.model, got, tt.want)
+		}
+	}
+}
+
+// TestKnownCounts compares counts with tiktoken's output for the real rank
+// files. It needs them in TOKENIZER_DATA_DIR (make download-tokenizer) and is
+// skipped otherwise, so the test suite never needs the network.
+func TestKnownCounts(t *testing.T) {
+	dir := os.Getenv("TOKENIZER_DATA_DIR")
+	if dir == "" {
+		dir = filepath.Join("..", "..", "..", "data", "tokenizer")
+	}
+	if err := Load(dir); err != nil {
+		t.Skipf("rank files not available (run scripts/fetch-tokenizer.sh): %v", err)
+	}
+
+	tests := []struct {
+		encoding string
+		text     string
+		want     int
+	}{
+		{Cl100kBase, "hello world", 2},        // [15339, 1917]
+		{Cl100kBase, "Hello, world!", 4},      // [9906, 11, 1917, 0]
+		{Cl100kBase, "tiktoken is great!", 6}, // [83, 1609, 5963, 374, 2294, 0]
+		{Cl100kBase, "2 + 2 = 4", 7},          // [17, 489, 220, 17, 284, 220, 19]
+		{O200kBase, "hello world", 2},         // [24912, 2375]
+		{O200kBase, "Hello, world!", 4},       // [13225, 11, 2375, 0]
+		{O200kBase, "2 + 2 = 4", 7},
+	}
+	for _, tt := range tests {
+		tok := Get(tt.encoding)
+		if tok.Name() != tt.encoding {
+			t.Fatalf("Get(%q) returned %s", tt.encoding, tok.Name())
+		}
+		if got := tok.Count(tt.text); got != tt.want {
+			t.Errorf("%s Count(%q) = %d, want %d", tt.encoding, tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+)
+
+// approxEqual compares floats with a relative tolerance
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-9*math.Max(1, math.Max(math.Abs(a), math.Abs(b)))
+}
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		expr string
+		want float64
+	}{
+		// Precedence and associativity
+		{"1 + 2 * 3", 7},
+		{"(1 + 2) * 3", 9},
+		{"10 - 4 - 3", 3},
+		{"100 / 10 / 5", 2},
+		{"2 * (3 + 4) ^ 2", 98},
+		{"2 ^ 3 ^ 2", 512},
+		{"2 ** 3", 8},
+		{"-2 ^ 2", -4},
+		{"(-2) ^ 2", 4},
+		{"2 ^ -1", 0.5},
+		{"--3", 3},
+		{"+4 - -1", 5},
+		{"7 % 3", 1},
+		{"-7 % 3", -1},
+		{"1 + 6 % 4 * 2", 5},
+
+		// Numbers and names
+		{"1.5e3", 1500},
+		{"2.5E-1", 0.25},
+		{".5 + 1.", 1.5},
+		{"2*e", 2 * math.E},
+		{"2 * PI", 2 * math.Pi},
+		{" 3\t*\n4 ", 12},
+
+		// Functions
+		{"sqrt(16) + abs(-3)", 7},
+		{"max(1, 5, 3) - min(4, 2)", 3},
+		{"pow(2, 10)", 1024},
+		{"log(1000)", 3},
+		{"log2(8) + ln(e)", 4},
+		{"ROUND(2.5)", 3},
+		{"floor(-1.5) + ceil(1.2)", 0},
+		{"sqrt(pow(3, 2) + 4 ^ 2)", 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := Evaluate(tt.expr)
+			if err != nil {
+				t.Fatalf("Evaluate() error = %v", err)
+			}
+			if !approxEqual(got, tt.want) {
+				t.Errorf("Evaluate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateErrors(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr string
+	}{
+		{"", "expression is required"},
+		{"   ", "expression is required"},
+		{"1 +", "unexpected end of expression"},
+		{"(1 + 2", "missing closing parenthesis"},
+		{"1 + 2)", "unexpected ')'"},
+		{"2 3", "unexpected '3'"},
+		{"2e", "unexpected 'e'"},
+		{"1 $ 2", "unexpected '$'"},
+		{"1..2", "invalid number"},
+		{"1 / 0", "division by zero"},
+		{"5 % (2 - 2)", "division by zero"},
+		{"foo", "unknown name"},
+		{"foo(1)", "unknown function"},
+		{"sqrt(1, 2)", "sqrt takes 1 argument"},
+		{"pow(2)", "pow takes 2 arguments"},
+		{"max()", "max takes at least 1 argument"},
+		{"min(1, 2", "missing closing parenthesis after min arguments"},
+		{"sqrt(-1)", "not a finite number"},
+		{"10 ^ 400", "not a finite number"},
+		{strings.Repeat("(", 100) + "1" + strings.Repeat(")", 100), "nested too deeply"},
+		{strings.Repeat("-", 100) + "1", "nested too deeply"},
+		{strings.Repeat("1+", 600) + "1", "too long"},
+	}
+
+	for _, tt := range tests {
+		name := tt.expr
+		if len(name) > 20 {
+			name = name[:20] + "..."
+		}
+		t.Run(name, func(t *testing.T) {
+			_, err := Evaluate(tt.expr)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Evaluate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCalculatorExecute(t *testing.T) {
+	tests := []struct {
+		args string
+		want string
+	}{
+		{`{"expression": "0.1 + 0.2"}`, "0.3"},
+		{`{"expression": "1250 * 0.15 / 12"}`, "15.625"},
+		{`{"expression": "2 ^ 64"}`, "1.84467440737e+19"},
+		{`{"expression": "10 / 3"}`, "3.33333333333"},
+		{`{"expression": "-(4 - 6)"}`, "2"},
+	}
+
+	tool := NewCalculatorTool()
+	for _, tt := range tests {
+		got, err := tool.Execute(context.Background(), &Call{Arguments: json.RawMessage(tt.args)})
+		if err != nil {
+			t.Errorf("Execute(%s) error = %v", tt.args, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Execute(%s) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+
+	if _, err := tool.Execute(context.Background(), &Call{Arguments: json.RawMessage(`{"expression": 5}`)}); err == nil {
+		t.Error("Execute() with a non-string expression succeeded")
+	}
+}
@@ -0,0 +1,76 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ai-chat/backend/internal/model"
+)
+
+// TimezoneFunc returns a user's IANA timezone name, or "" when unknown
+type TimezoneFunc func(ctx context.Context, userID uuid.UUID) string
+
+// DateTimeTool reports the current date and time in the user's timezone
+type DateTimeTool struct {
+	timezone TimezoneFunc
+	now      func() time.Time
+}
+
+// NewDateTimeTool creates the datetime tool; users without a timezone get UTC
+func NewDateTimeTool(timezone TimezoneFunc) *DateTimeTool {
+	return &DateTimeTool{timezone: timezone, now: time.Now}
+}
+
+// Definition describes the tool to the model
+func (t *DateTimeTool) Definition() model.FunctionDefinition {
+	return model.FunctionDefinition{
+		Name:        "get_current_datetime",
+		Description: "Get the current date, time and weekday. Uses the user's timezone unless another IANA timezone is given.",
+		Parameters: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"timezone": {"type": "string", "description": "IANA timezone, e.g. Asia/Shanghai or America/New_York"}
+			}
+		}`),
+	}
+}
+
+// Execute returns the current time as a JSON object
+func (t *DateTimeTool) Execute(ctx context.Context, call *Call) (string, error) {
+	var args struct {
+		Timezone string `json:"timezone"`
+	}
+	if err := json.Unmarshal(call.Arguments, &args); err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	name := args.Timezone
+	if name == "" && t.timezone != nil {
+		name = t.timezone(ctx, call.UserID)
+	}
+	if name == "" {
+		name = "UTC"
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return "", fmt.Errorf("unknown timezone: %s", name)
+	}
+
+	now := t.now().In(loc)
+	result, err := json.Marshal(map[string]string{
+		"datetime":   now.Format(time.RFC3339),
+		"date":       now.Format("2006-01-02"),
+		"time":       now.Format("15:04:05"),
+		"weekday":    now.Weekday().String(),
+		"timezone":   loc.String(),
+		"utc_offset": now.Format("-07:00"),
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(result), nil
+}
@@ -0,0 +1,95 @@
+// Package tools holds the server-side functions that models can call during a
+// chat. Each tool describes itself with a JSON Schema and is executed by name.
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/ai-chat/backend/internal/model"
+)
+
+// Call is a single invocation of a tool on behalf of a user
+type Call struct {
+	UserID    uuid.UUID
+	Arguments json.RawMessage // JSON object matching the tool's parameter schema
+}
+
+// Tool is a function the server runs when a model asks for it. The returned
+// string is passed back to the model verbatim.
+type Tool interface {
+	Definition() model.FunctionDefinition
+	Execute(ctx context.Context, call *Call) (string, error)
+}
+
+// Registry holds the tools available to models, keyed by function name
+type Registry struct {
+	mu    sync.RWMutex
+	tools map[string]Tool
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{tools: make(map[string]Tool)}
+}
+
+// Register adds a tool; names must be unique
+func (r *Registry) Register(tool Tool) error {
+	name := tool.Definition().Name
+	if name == "" {
+		return fmt.Errorf("tool name is required")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.tools[name]; exists {
+		return fmt.Errorf("tool %s is already registered", name)
+	}
+	r.tools[name] = tool
+	return nil
+}
+
+// Get returns a tool by name
+func (r *Registry) Get(name string) (Tool, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tool, ok := r.tools[name]
+	return tool, ok
+}
+
+// Definitions returns the tool definitions to offer a model, sorted by name,
+// leaving out the disabled ones
+func (r *Registry) Definitions(disabled []string) []model.Tool {
+	skip := make(map[string]bool, len(disabled))
+	for _, name := range disabled {
+		skip[name] = true
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	defs := make([]model.Tool, 0, len(r.tools))
+	for name, tool := range r.tools {
+		if skip[name] {
+			continue
+		}
+		defs = append(defs, model.Tool{Type: "function", Function: tool.Definition()})
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Function.Name < defs[j].Function.Name })
+	return defs
+}
+
+// Execute runs the named tool
+func (r *Registry) Execute(ctx context.Context, name string, call *Call) (string, error) {
+	tool, ok := r.Get(name)
+	if !ok {
+		return "", fmt.Errorf("unknown tool: %s", name)
+	}
+	return tool.Execute(ctx, call)
+}
@@ -0,0 +1,139 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/ai-chat/backend/internal/model"
+)
+
+const (
+	// maxSearchTerms caps how many keywords a search matches on
+	maxSearchTerms = 5
+	// defaultSearchLimit and maxSearchLimit bound results per source
+	defaultSearchLimit = 5
+	maxSearchLimit     = 20
+	// searchSnippetRunes truncates long matched messages
+	searchSnippetRunes = 300
+)
+
+// SearchToolName is the name the search tool is registered under
+const SearchToolName = "search_history"
+
+// MemorySearcher finds a user's memories containing every term
+type MemorySearcher interface {
+	SearchByUser(ctx context.Context, userID uuid.UUID, terms []string, limit int) ([]*model.Memory, error)
+}
+
+// MessageSearcher finds messages in a user's conversations containing every term
+type MessageSearcher interface {
+	SearchByUser(ctx context.Context, userID uuid.UUID, terms []string, limit int) ([]*model.MessageSearchResult, error)
+}
+
+// SearchTool searches the calling user's own memories and past conversations.
+// Results never cross users: every lookup is scoped to Call.UserID.
+type SearchTool struct {
+	memories MemorySearcher
+	messages MessageSearcher
+}
+
+// NewSearchTool creates the memory and conversation search tool
+func NewSearchTool(memories MemorySearcher, messages MessageSearcher) *SearchTool {
+	return &SearchTool{memories: memories, messages: messages}
+}
+
+// Definition describes the tool to the model
+func (t *SearchTool) Definition() model.FunctionDefinition {
+	return model.FunctionDefinition{
+		Name:        SearchToolName,
+		Description: "Search the user's saved memories and their past conversations by keywords, e.g. when they refer to something discussed before.",
+		Parameters: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"query": {"type": "string", "description": "Keywords separated by spaces; results contain all of them"},
+				"scope": {"type": "string", "enum": ["all", "memories", "conversations"], "description": "Where to search, defaults to all"},
+				"limit": {"type": "integer", "minimum": 1, "maximum": 20, "description": "Maximum results per source, defaults to 5"}
+			},
+			"required": ["query"]
+		}`),
+	}
+}
+
+// Execute runs the search and lists the matches
+func (t *SearchTool) Execute(ctx context.Context, call *Call) (string, error) {
+	var args struct {
+		Query string `json:"query"`
+		Scope string `json:"scope"`
+		Limit int    `json:"limit"`
+	}
+	if err := json.Unmarshal(call.Arguments, &args); err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	terms := strings.Fields(args.Query)
+	if len(terms) == 0 {
+		return "", fmt.Errorf("query is required")
+	}
+	if len(terms) > maxSearchTerms {
+		terms = terms[:maxSearchTerms]
+	}
+
+	limit := args.Limit
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+
+	scope := args.Scope
+	if scope == "" {
+		scope = "all"
+	}
+
+	var out strings.Builder
+
+	if scope == "all" || scope == "memories" {
+		memories, err := t.memories.SearchByUser(ctx, call.UserID, terms, limit)
+		if err != nil {
+			return "", err
+		}
+		out.WriteString(fmt.Sprintf("Memories (%d):\n", len(memories)))
+		for _, memory := range memories {
+			out.WriteString(fmt.Sprintf("- [%s] %s\n", memory.Category, memory.Content))
+		}
+	}
+
+	if scope == "all" || scope == "conversations" {
+		messages, err := t.messages.SearchByUser(ctx, call.UserID, terms, limit)
+		if err != nil {
+			return "", err
+		}
+		if out.Len() > 0 {
+			out.WriteString("\n")
+		}
+		out.WriteString(fmt.Sprintf("Conversation messages (%d):\n", len(messages)))
+		for _, msg := range messages {
+			out.WriteString(fmt.Sprintf("- %s, \"%s\", %s: %s\n",
+				msg.CreatedAt.Format("2006-01-02"), msg.ConversationTitle, msg.Role, snippet(msg.Content)))
+		}
+	}
+
+	if out.Len() == 0 {
+		return "", fmt.Errorf("unknown scope: %s", scope)
+	}
+	return out.String(), nil
+}
+
+// snippet flattens and shortens a message for listing
+func snippet(content string) string {
+	content = strings.Join(strings.Fields(content), " ")
+	runes := []rune(content)
+	if len(runes) <= searchSnippetRunes {
+		return content
+	}
+	return string(runes[:searchSnippetRunes]) + "…"
+}
@@ -0,0 +1,188 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/ai-chat/backend/internal/model"
+)
+
+// unit is a linear conversion to its dimension's base unit: base = value*factor + offset
+type unit struct {
+	dimension string
+	factor    float64
+	offset    float64
+}
+
+// units maps lower-cased names and symbols to their conversions. Base units
+// are metre, kilogram, litre, second, square metre, metre per second, byte
+// and kelvin.
+var units = func() map[string]unit {
+	table := []struct {
+		names     []string
+		dimension string
+		factor    float64
+		offset    float64
+	}{
+		// Length
+		{[]string{"mm", "millimeter", "millimeters", "millimetre", "millimetres"}, "length", 0.001, 0},
+		{[]string{"cm", "centimeter", "centimeters", "centimetre", "centimetres"}, "length", 0.01, 0},
+		{[]string{"m", "meter", "meters", "metre", "metres"}, "length", 1, 0},
+		{[]string{"km", "kilometer", "kilometers", "kilometre", "kilometres"}, "length", 1000, 0},
+		{[]string{"in", "inch", "inches"}, "length", 0.0254, 0},
+		{[]string{"ft", "foot", "feet"}, "length", 0.3048, 0},
+		{[]string{"yd", "yard", "yards"}, "length", 0.9144, 0},
+		{[]string{"mi", "mile", "miles"}, "length", 1609.344, 0},
+		{[]string{"nmi", "nautical mile", "nautical miles"}, "length", 1852, 0},
+		{[]string{"li", "市里"}, "length", 500, 0},
+		{[]string{"chi", "市尺"}, "length", 1.0 / 3, 0},
+
+		// Mass
+		{[]string{"mg", "milligram", "milligrams"}, "mass", 1e-6, 0},
+		{[]string{"g", "gram", "grams"}, "mass", 0.001, 0},
+		{[]string{"kg", "kilogram", "kilograms"}, "mass", 1, 0},
+		{[]string{"t", "tonne", "tonnes", "metric ton", "metric tons"}, "mass", 1000, 0},
+		{[]string{"oz", "ounce", "ounces"}, "mass", 0.028349523125, 0},
+		{[]string{"lb", "lbs", "pound", "pounds"}, "mass", 0.45359237, 0},
+		{[]string{"st", "stone", "stones"}, "mass", 6.35029318, 0},
+		{[]string{"jin", "斤"}, "mass", 0.5, 0},
+		{[]string{"liang", "两"}, "mass", 0.05, 0},
+
+		// Volume
+		{[]string{"ml", "milliliter", "milliliters", "millilitre", "millilitres"}, "volume", 0.001, 0},
+		{[]string{"l", "liter", "liters", "litre", "litres"}, "volume", 1, 0},
+		{[]string{"m3", "cubic meter", "cubic meters", "cubic metre", "cubic metres"}, "volume", 1000, 0},
+		{[]string{"tsp", "teaspoon", "teaspoons"}, "volume", 0.00492892159375, 0},
+		{[]string{"tbsp", "tablespoon", "tablespoons"}, "volume", 0.01478676478125, 0},
+		{[]string{"floz", "fl oz", "fluid ounce", "fluid ounces"}, "volume", 0.0295735295625, 0},
+		{[]string{"cup", "cups"}, "volume", 0.2365882365, 0},
+		{[]string{"pt", "pint", "pints"}, "volume", 0.473176473, 0},
+		{[]string{"qt", "quart", "quarts"}, "volume", 0.946352946, 0},
+		{[]string{"gal", "gallon", "gallons"}, "volume", 3.785411784, 0},
+
+		// Time
+		{[]string{"ms", "millisecond", "milliseconds"}, "time", 0.001, 0},
+		{[]string{"s", "sec", "second", "seconds"}, "time", 1, 0},
+		{[]string{"min", "minute", "minutes"}, "time", 60, 0},
+		{[]string{"h", "hr", "hour", "hours"}, "time", 3600, 0},
+		{[]string{"d", "day", "days"}, "time", 86400, 0},
+		{[]string{"wk", "week", "weeks"}, "time", 604800, 0},
+
+		// Area
+		{[]string{"cm2", "square centimeter", "square centimeters"}, "area", 1e-4, 0},
+		{[]string{"m2", "square meter", "square meters", "square metre", "square metres"}, "area", 1, 0},
+		{[]string{"km2", "square kilometer", "square kilometers"}, "area", 1e6, 0},
+		{[]string{"ft2", "sqft", "square foot", "square feet"}, "area", 0.09290304, 0},
+		{[]string{"acre", "acres"}, "area", 4046.8564224, 0},
+		{[]string{"ha", "hectare", "hectares"}, "area", 10000, 0},
+		{[]string{"mu", "亩"}, "area", 2000.0 / 3, 0},
+
+		// Speed
+		{[]string{"m/s", "mps", "meters per second"}, "speed", 1, 0},
+		{[]string{"km/h", "kmh", "kph", "kilometers per hour"}, "speed", 1000.0 / 3600, 0},
+		{[]string{"mph", "miles per hour"}, "speed", 0.44704, 0},
+		{[]string{"kn", "knot", "knots"}, "speed", 1852.0 / 3600, 0},
+
+		// Data
+		{[]string{"b", "byte", "bytes"}, "data", 1, 0},
+		{[]string{"kb", "kilobyte", "kilobytes"}, "data", 1e3, 0},
+		{[]string{"mb", "megabyte", "megabytes"}, "data", 1e6, 0},
+		{[]string{"gb", "gigabyte", "gigabytes"}, "data", 1e9, 0},
+		{[]string{"tb", "terabyte", "terabytes"}, "data", 1e12, 0},
+		{[]string{"kib", "kibibyte", "kibibytes"}, "data", 1024, 0},
+		{[]string{"mib", "mebibyte", "mebibytes"}, "data", 1 << 20, 0},
+		{[]string{"gib", "gibibyte", "gibibytes"}, "data", 1 << 30, 0},
+		{[]string{"tib", "tebibyte", "tebibytes"}, "data", 1 << 40, 0},
+
+		// Temperature
+		{[]string{"k", "kelvin"}, "temperature", 1, 0},
+		{[]string{"c", "°c", "celsius"}, "temperature", 1, 273.15},
+		{[]string{"f", "°f", "fahrenheit"}, "temperature", 5.0 / 9, 273.15 - 32*5.0/9},
+	}
+
+	m := make(map[string]unit)
+	for _, row := range table {
+		for _, name := range row.names {
+			m[name] = unit{dimension: row.dimension, factor: row.factor, offset: row.offset}
+		}
+	}
+	return m
+}()
+
+// UnitConvertTool converts values between units of the same dimension
+type UnitConvertTool struct{}
+
+// NewUnitConvertTool creates the unit conversion tool
+func NewUnitConvertTool() *UnitConvertTool {
+	return &UnitConvertTool{}
+}
+
+// Definition describes the tool to the model
+func (t *UnitConvertTool) Definition() model.FunctionDefinition {
+	return model.FunctionDefinition{
+		Name: "convert_units",
+		Description: "Convert a value between units of length, mass, volume, time, area, speed, data size or temperature, " +
+			"e.g. miles to km, lb to kg, °F to °C, GiB to GB. Accepts common names and symbols.",
+		Parameters: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"value": {"type": "number", "description": "Value to convert"},
+				"from": {"type": "string", "description": "Unit of the value, e.g. mi, pound, fahrenheit"},
+				"to": {"type": "string", "description": "Unit to convert to, e.g. km, kg, celsius"}
+			},
+			"required": ["value", "from", "to"]
+		}`),
+	}
+}
+
+// Execute performs the conversion
+func (t *UnitConvertTool) Execute(ctx context.Context, call *Call) (string, error) {
+	var args struct {
+		Value float64 `json:"value"`
+		From  string  `json:"from"`
+		To    string  `json:"to"`
+	}
+	if err := json.Unmarshal(call.Arguments, &args); err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	result, err := ConvertUnits(args.Value, args.From, args.To)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s %s = %s %s", formatNumber(args.Value), args.From, formatNumber(result), args.To), nil
+}
+
+// ConvertUnits converts value from one unit to another
+func ConvertUnits(value float64, from, to string) (float64, error) {
+	fromUnit, ok := lookupUnit(from)
+	if !ok {
+		return 0, fmt.Errorf("unknown unit: %s", from)
+	}
+	toUnit, ok := lookupUnit(to)
+	if !ok {
+		return 0, fmt.Errorf("unknown unit: %s", to)
+	}
+	if fromUnit.dimension != toUnit.dimension {
+		return 0, fmt.Errorf("cannot convert %s (%s) to %s (%s)", from, fromUnit.dimension, to, toUnit.dimension)
+	}
+
+	base := value*fromUnit.factor + fromUnit.offset
+	result := (base - toUnit.offset) / toUnit.factor
+	if math.IsNaN(result) || math.IsInf(result, 0) {
+		return 0, fmt.Errorf("result is not a finite number")
+	}
+	return result, nil
+}
+
+// lookupUnit finds a unit by name or symbol. Symbols are matched
+// case-insensitively, so "MB" and "mb" are both megabytes.
+func lookupUnit(name string) (unit, bool) {
+	key := strings.ToLower(strings.TrimSpace(name))
+	key = strings.NewReplacer("²", "2", "³", "3", "degrees ", "", "degree ", "").Replace(key)
+	u, ok := units[key]
+	return u, ok
+}
@@ -0,0 +1,75 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestConvertUnits(t *testing.T) {
+	tests := []struct {
+		value    float64
+		from, to string
+		want     float64
+	}{
+		{1, "mi", "km", 1.609344},
+		{1, "ft", "in", 12},
+		{3, "市尺", "m", 1},
+		{2, "斤", "kg", 1},
+		{1, "lb", "g", 453.59237},
+		{1, "gal", "L", 3.785411784},
+		{1, "m²", "ft2", 10.763910416709722},
+		{1, "亩", "m2", 2000.0 / 3},
+		{90, "min", "h", 1.5},
+		{100, "km/h", "m/s", 27.77777777777778},
+		{1, "GiB", "MB", 1073.741824},
+		{1, "MB", "KB", 1000},
+		{212, "F", "C", 100},
+		{-40, "celsius", "fahrenheit", -40},
+		{32, "degrees Fahrenheit", "°C", 0},
+		{0, "k", "c", -273.15},
+		{0, " Kelvin ", "f", -459.67},
+	}
+
+	for _, tt := range tests {
+		got, err := ConvertUnits(tt.value, tt.from, tt.to)
+		if err != nil {
+			t.Errorf("ConvertUnits(%v, %q, %q) error = %v", tt.value, tt.from, tt.to, err)
+			continue
+		}
+		if !approxEqual(got, tt.want) {
+			t.Errorf("ConvertUnits(%v, %q, %q) = %v, want %v", tt.value, tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestConvertUnitsErrors(t *testing.T) {
+	tests := []struct {
+		from, to string
+		wantErr  string
+	}{
+		{"furlong", "m", "unknown unit: furlong"},
+		{"m", "", "unknown unit: "},
+		{"kg", "m", "cannot convert kg (mass) to m (length)"},
+		{"c", "s", "cannot convert c (temperature) to s (time)"},
+	}
+
+	for _, tt := range tests {
+		_, err := ConvertUnits(1, tt.from, tt.to)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("ConvertUnits(1, %q, %q) error = %v, want %q", tt.from, tt.to, err, tt.wantErr)
+		}
+	}
+}
+
+func TestUnitConvertExecute(t *testing.T) {
+	tool := NewUnitConvertTool()
+	got, err := tool.Execute(context.Background(), &Call{Arguments: json.RawMessage(`{"value": 5, "from": "km", "to": "m"}`)})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if want := "5 km = 5000 m"; got != want {
+		t.Errorf("Execute() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,19 @@
+// Package vector provides helpers for comparing embeddings
+package vector
+
+import "math"
+
+// Cosine returns the cosine similarity of two vectors of equal length, or 0
+// when either is a zero vector
+func Cosine(a, b []float32) float64 {
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
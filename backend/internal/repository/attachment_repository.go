@@ -0,0 +1,250 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/ai-chat/backend/internal/model"
+)
+
+// ErrQuotaExceeded is returned by Create when an attachment does not fit the user's quota
+var ErrQuotaExceeded = errors.New("attachment quota exceeded")
+
+// AttachmentRepository handles attachment metadata access
+type AttachmentRepository struct {
+	db *sql.DB
+}
+
+// NewAttachmentRepository creates a new attachment repository
+func NewAttachmentRepository(db *sql.DB) *AttachmentRepository {
+	return &AttachmentRepository{db: db}
+}
+
+const attachmentColumns = `id, user_id, conversation_id, message_id, storage_key,
+	filename, content_type, size_bytes, checksum, created_at`
+
+// Create records an uploaded attachment, or returns ErrQuotaExceeded if it
+// would take the user's attachments past quota bytes. The user's row is locked
+// while the usage is summed, so concurrent uploads cannot both pass the check.
+func (r *AttachmentRepository) Create(ctx context.Context, a *model.Attachment, quota int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var locked int
+	if err := tx.QueryRowContext(ctx, `SELECT 1 FROM users WHERE id = $1 FOR UPDATE`, a.UserID).Scan(&locked); err != nil {
+		return fmt.Errorf("failed to lock user: %w", err)
+	}
+
+	var used int64
+	usageQuery := `SELECT COALESCE(SUM(size_bytes), 0) FROM attachments WHERE user_id = $1`
+	if err := tx.QueryRowContext(ctx, usageQuery, a.UserID).Scan(&used); err != nil {
+		return fmt.Errorf("failed to get attachment usage: %w", err)
+	}
+	if used+a.SizeBytes > quota {
+		return ErrQuotaExceeded
+	}
+
+	query := `
+		INSERT INTO attachments (id, user_id, conversation_id, storage_key, filename, content_type, size_bytes, checksum)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING created_at
+	`
+
+	err = tx.QueryRowContext(
+		ctx, query,
+		a.ID, a.UserID, a.ConversationID, a.StorageKey, a.Filename, a.ContentType, a.SizeBytes, a.Checksum,
+	).Scan(&a.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create attachment: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// GetByID retrieves an attachment by ID
+func (r *AttachmentRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.Attachment, error) {
+	query := `SELECT ` + attachmentColumns + ` FROM attachments WHERE id = $1`
+
+	a, err := scanAttachment(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("attachment not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attachment: %w", err)
+	}
+
+	return a, nil
+}
+
+// ListByUser retrieves a user's attachments, newest first
+func (r *AttachmentRepository) ListByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*model.Attachment, error) {
+	query := `
+		SELECT ` + attachmentColumns + `
+		FROM attachments
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	return r.query(ctx, query, userID, limit, offset)
+}
+
+// ListByMessages retrieves the attachments linked to any of the given messages
+func (r *AttachmentRepository) ListByMessages(ctx context.Context, messageIDs []uuid.UUID) ([]*model.Attachment, error) {
+	if len(messageIDs) == 0 {
+		return nil, nil
+	}
+
+	query := `
+		SELECT ` + attachmentColumns + `
+		FROM attachments
+		WHERE message_id = ANY($1)
+		ORDER BY created_at ASC
+	`
+
+	return r.query(ctx, query, pq.Array(messageIDs))
+}
+
+// ListUnlinkedBefore retrieves uploads never linked to a message or indexed
+// as a document that are older than the given time
+func (r *AttachmentRepository) ListUnlinkedBefore(ctx context.Context, before time.Time, limit int) ([]*model.Attachment, error) {
+	query := `
+		SELECT ` + attachmentColumns + `
+		FROM attachments
+		WHERE message_id IS NULL AND created_at < $1
+		  AND NOT EXISTS (SELECT 1 FROM documents d WHERE d.attachment_id = attachments.id)
+		ORDER BY created_at ASC
+		LIMIT $2
+	`
+
+	return r.query(ctx, query, before, limit)
+}
+
+// UsageByUser counts a user's attachments and their total size
+func (r *AttachmentRepository) UsageByUser(ctx context.Context, userID uuid.UUID) (int, int64, error) {
+	var count int
+	var used int64
+	query := `SELECT COUNT(*), COALESCE(SUM(size_bytes), 0) FROM attachments WHERE user_id = $1`
+	if err := r.db.QueryRowContext(ctx, query, userID).Scan(&count, &used); err != nil {
+		return 0, 0, fmt.Errorf("failed to get attachment usage: %w", err)
+	}
+	return count, used, nil
+}
+
+// LinkToMessage attaches the user's unlinked attachments to a message. It
+// links all of them or none: if any ID is missing, owned by someone else,
+// already linked or in the knowledge base, nothing is changed and an error is
+// returned. Knowledge base files are excluded because linking would tie them
+// to the conversation and delete them with it.
+func (r *AttachmentRepository) LinkToMessage(ctx context.Context, userID, conversationID, messageID uuid.UUID, ids []uuid.UUID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE attachments
+		SET message_id = $1, conversation_id = $2
+		WHERE id = ANY($3) AND user_id = $4 AND message_id IS NULL
+		  AND (conversation_id IS NULL OR conversation_id = $2)
+		  AND NOT EXISTS (
+			SELECT 1 FROM documents d WHERE d.attachment_id = attachments.id AND d.conversation_id IS NULL
+		  )
+	`
+	result, err := tx.ExecContext(ctx, query, messageID, conversationID, pq.Array(ids), userID)
+	if err != nil {
+		return fmt.Errorf("failed to link attachments: %w", err)
+	}
+	linked, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to link attachments: %w", err)
+	}
+	if linked != int64(len(ids)) {
+		return fmt.Errorf("attachment not found or already used")
+	}
+
+	return tx.Commit()
+}
+
+// Delete deletes an attachment record; the trigger queues its file for removal
+func (r *AttachmentRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	query := `DELETE FROM attachments WHERE id = $1`
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
+
+// ListPendingDeletions retrieves storage keys whose records have been deleted
+// but whose files have not been removed yet
+func (r *AttachmentRepository) ListPendingDeletions(ctx context.Context, limit int) ([]string, error) {
+	query := `SELECT storage_key FROM attachment_deletions ORDER BY queued_at ASC LIMIT $1`
+
+	rows, err := r.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pending deletions: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("failed to scan pending deletion: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// CompleteDeletion removes a storage key from the deletion queue once its
+// file is gone
+func (r *AttachmentRepository) CompleteDeletion(ctx context.Context, storageKey string) error {
+	query := `DELETE FROM attachment_deletions WHERE storage_key = $1`
+	_, err := r.db.ExecContext(ctx, query, storageKey)
+	return err
+}
+
+func (r *AttachmentRepository) query(ctx context.Context, query string, args ...interface{}) ([]*model.Attachment, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list attachments: %w", err)
+	}
+	defer rows.Close()
+
+	var attachments []*model.Attachment
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan attachment: %w", err)
+		}
+		attachments = append(attachments, a)
+	}
+
+	return attachments, rows.Err()
+}
+
+// scanAttachment scans a row selected with attachmentColumns
+func scanAttachment(row interface{ Scan(...interface{}) error }) (*model.Attachment, error) {
+	a := &model.Attachment{}
+	err := row.Scan(
+		&a.ID, &a.UserID, &a.ConversationID, &a.MessageID, &a.StorageKey,
+		&a.Filename, &a.ContentType, &a.SizeBytes, &a.Checksum, &a.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
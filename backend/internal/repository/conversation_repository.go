@@ -3,7 +3,10 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/ai-chat/backend/internal/model"
@@ -22,14 +25,14 @@ func NewConversationRepository(db *sql.DB) *ConversationRepository {
 // Create creates a new conversation
 func (r *ConversationRepository) Create(ctx context.Context, conv *model.Conversation) error {
 	query := `
-		INSERT INTO conversations (id, user_id, title, model_id)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO conversations (id, user_id, title, model_id, memory_mode)
+		VALUES ($1, $2, $3, $4, $5)
 		RETURNING created_at, updated_at
 	`
 
 	err := r.db.QueryRowContext(
 		ctx, query,
-		conv.ID, conv.UserID, conv.Title, conv.ModelID,
+		conv.ID, conv.UserID, conv.Title, conv.ModelID, conv.MemoryMode,
 	).Scan(&conv.CreatedAt, &conv.UpdatedAt)
 
 	if err != nil {
@@ -43,14 +46,14 @@ func (r *ConversationRepository) Create(ctx context.Context, conv *model.Convers
 func (r *ConversationRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.Conversation, error) {
 	query := `
 		SELECT id, user_id, title, model_id, message_count, total_tokens,
-			last_message_at, created_at, updated_at
+			last_message_at, memory_mode, summary, summary_up_to, summary_up_to_id, summary_updated_at, created_at, updated_at
 		FROM conversations WHERE id = $1
 	`
 
 	conv := &model.Conversation{}
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
 		&conv.ID, &conv.UserID, &conv.Title, &conv.ModelID, &conv.MessageCount, &conv.TotalTokens,
-		&conv.LastMessageAt, &conv.CreatedAt, &conv.UpdatedAt,
+		&conv.LastMessageAt, &conv.MemoryMode, &conv.Summary, &conv.SummaryUpTo, &conv.SummaryUpToID, &conv.SummaryUpdatedAt, &conv.CreatedAt, &conv.UpdatedAt,
 	)
 
 	if err == sql.ErrNoRows {
@@ -67,7 +70,7 @@ func (r *ConversationRepository) GetByID(ctx context.Context, id uuid.UUID) (*mo
 func (r *ConversationRepository) ListByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*model.Conversation, error) {
 	query := `
 		SELECT id, user_id, title, model_id, message_count, total_tokens,
-			last_message_at, created_at, updated_at
+			last_message_at, memory_mode, summary, summary_up_to, summary_up_to_id, summary_updated_at, created_at, updated_at
 		FROM conversations
 		WHERE user_id = $1
 		ORDER BY updated_at DESC
@@ -85,7 +88,7 @@ func (r *ConversationRepository) ListByUser(ctx context.Context, userID uuid.UUI
 		conv := &model.Conversation{}
 		err := rows.Scan(
 			&conv.ID, &conv.UserID, &conv.Title, &conv.ModelID, &conv.MessageCount, &conv.TotalTokens,
-			&conv.LastMessageAt, &conv.CreatedAt, &conv.UpdatedAt,
+			&conv.LastMessageAt, &conv.MemoryMode, &conv.Summary, &conv.SummaryUpTo, &conv.SummaryUpToID, &conv.SummaryUpdatedAt, &conv.CreatedAt, &conv.UpdatedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan conversation: %w", err)
@@ -101,11 +104,12 @@ func (r *ConversationRepository) Update(ctx context.Context, conv *model.Convers
 	query := `
 		UPDATE conversations SET
 			title = $2,
-			model_id = $3
+			model_id = $3,
+			memory_mode = $4
 		WHERE id = $1
 	`
 
-	_, err := r.db.ExecContext(ctx, query, conv.ID, conv.Title, conv.ModelID)
+	_, err := r.db.ExecContext(ctx, query, conv.ID, conv.Title, conv.ModelID, conv.MemoryMode)
 	if err != nil {
 		return fmt.Errorf("failed to update conversation: %w", err)
 	}
@@ -113,6 +117,25 @@ func (r *ConversationRepository) Update(ctx context.Context, conv *model.Convers
 	return nil
 }
 
+// UpdateSummary stores a conversation's rolling summary and the last message it covers
+func (r *ConversationRepository) UpdateSummary(ctx context.Context, id uuid.UUID, summary string, upTo model.MessageCursor) error {
+	query := `
+		UPDATE conversations SET
+			summary = $2,
+			summary_up_to = $3,
+			summary_up_to_id = $4,
+			summary_updated_at = NOW()
+		WHERE id = $1
+	`
+
+	_, err := r.db.ExecContext(ctx, query, id, summary, upTo.CreatedAt, upTo.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update conversation summary: %w", err)
+	}
+
+	return nil
+}
+
 // Delete deletes a conversation
 func (r *ConversationRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	query := `DELETE FROM conversations WHERE id = $1`
@@ -120,6 +143,22 @@ func (r *ConversationRepository) Delete(ctx context.Context, id uuid.UUID) error
 	return err
 }
 
+// DeleteIdleEphemeral deletes ephemeral conversations without a message for
+// longer than idle and returns how many were deleted
+func (r *ConversationRepository) DeleteIdleEphemeral(ctx context.Context, idle time.Duration) (int64, error) {
+	query := `
+		DELETE FROM conversations
+		WHERE memory_mode = 'ephemeral'
+			AND COALESCE(last_message_at, created_at) < NOW() - $1 * INTERVAL '1 second'
+	`
+
+	result, err := r.db.ExecContext(ctx, query, idle.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete ephemeral conversations: %w", err)
+	}
+	return result.RowsAffected()
+}
+
 // Count counts total conversations
 func (r *ConversationRepository) Count(ctx context.Context) (int, error) {
 	var count int
@@ -144,15 +183,25 @@ func NewMessageRepository(db *sql.DB) *MessageRepository {
 // Create creates a new message
 func (r *MessageRepository) Create(ctx context.Context, msg *model.Message) error {
 	query := `
-		INSERT INTO messages (id, conversation_id, role, content, input_tokens, output_tokens, total_tokens, model_id)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO messages (id, conversation_id, role, content, input_tokens, output_tokens, total_tokens, model_id, tool_calls, tool_call_id, content_parts)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 		RETURNING created_at
 	`
 
-	err := r.db.QueryRowContext(
+	toolCallsJSON, err := marshalToolCalls(msg.ToolCalls)
+	if err != nil {
+		return err
+	}
+	partsJSON, err := marshalContentParts(msg.Parts)
+	if err != nil {
+		return err
+	}
+
+	err = r.db.QueryRowContext(
 		ctx, query,
 		msg.ID, msg.ConversationID, msg.Role, msg.Content,
 		msg.InputTokens, msg.OutputTokens, msg.TotalTokens, msg.ModelID,
+		toolCallsJSON, msg.ToolCallID, partsJSON,
 	).Scan(&msg.CreatedAt)
 
 	if err != nil {
@@ -165,14 +214,17 @@ func (r *MessageRepository) Create(ctx context.Context, msg *model.Message) erro
 // GetByID retrieves a message by ID
 func (r *MessageRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.Message, error) {
 	query := `
-		SELECT id, conversation_id, role, content, input_tokens, output_tokens, total_tokens, model_id, created_at
+		SELECT id, conversation_id, role, content, input_tokens, output_tokens, total_tokens, model_id,
+			tool_calls, tool_call_id, content_parts, created_at
 		FROM messages WHERE id = $1
 	`
 
 	msg := &model.Message{}
+	var toolCallsJSON, partsJSON []byte
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
 		&msg.ID, &msg.ConversationID, &msg.Role, &msg.Content,
-		&msg.InputTokens, &msg.OutputTokens, &msg.TotalTokens, &msg.ModelID, &msg.CreatedAt,
+		&msg.InputTokens, &msg.OutputTokens, &msg.TotalTokens, &msg.ModelID,
+		&toolCallsJSON, &msg.ToolCallID, &partsJSON, &msg.CreatedAt,
 	)
 
 	if err == sql.ErrNoRows {
@@ -181,6 +233,12 @@ func (r *MessageRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.M
 	if err != nil {
 		return nil, fmt.Errorf("failed to get message: %w", err)
 	}
+	if err := unmarshalToolCalls(toolCallsJSON, msg); err != nil {
+		return nil, err
+	}
+	if err := unmarshalContentParts(partsJSON, msg); err != nil {
+		return nil, err
+	}
 
 	return msg, nil
 }
@@ -188,7 +246,8 @@ func (r *MessageRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.M
 // ListByConversation retrieves messages for a conversation
 func (r *MessageRepository) ListByConversation(ctx context.Context, conversationID uuid.UUID, limit, offset int) ([]*model.Message, error) {
 	query := `
-		SELECT id, conversation_id, role, content, input_tokens, output_tokens, total_tokens, model_id, created_at
+		SELECT id, conversation_id, role, content, input_tokens, output_tokens, total_tokens, model_id,
+			tool_calls, tool_call_id, content_parts, created_at
 		FROM messages
 		WHERE conversation_id = $1
 		ORDER BY created_at ASC
@@ -204,23 +263,130 @@ func (r *MessageRepository) ListByConversation(ctx context.Context, conversation
 	var messages []*model.Message
 	for rows.Next() {
 		msg := &model.Message{}
+		var toolCallsJSON, partsJSON []byte
+		err := rows.Scan(
+			&msg.ID, &msg.ConversationID, &msg.Role, &msg.Content,
+			&msg.InputTokens, &msg.OutputTokens, &msg.TotalTokens, &msg.ModelID,
+			&toolCallsJSON, &msg.ToolCallID, &partsJSON, &msg.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan message: %w", err)
+		}
+		if err := unmarshalToolCalls(toolCallsJSON, msg); err != nil {
+			return nil, err
+		}
+		if err := unmarshalContentParts(partsJSON, msg); err != nil {
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+
+	return messages, nil
+}
+
+// ListRange retrieves the messages after the cursor (all, if nil) created up to
+// and including `until`, in cursor order
+func (r *MessageRepository) ListRange(ctx context.Context, conversationID uuid.UUID, after *model.MessageCursor, until time.Time, limit int) ([]*model.Message, error) {
+	query := `
+		SELECT id, conversation_id, role, content, input_tokens, output_tokens, total_tokens, model_id,
+			tool_calls, tool_call_id, content_parts, created_at
+		FROM messages
+		WHERE conversation_id = $1
+			AND ($2::timestamp IS NULL OR (created_at, id) > ($2, $3))
+			AND created_at <= $4
+		ORDER BY created_at ASC, id ASC
+		LIMIT $5
+	`
+
+	var afterAt *time.Time
+	afterID := uuid.Nil
+	if after != nil {
+		afterAt, afterID = &after.CreatedAt, after.ID
+	}
+	rows, err := r.db.QueryContext(ctx, query, conversationID, afterAt, afterID, until, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list messages: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []*model.Message
+	for rows.Next() {
+		msg := &model.Message{}
+		var toolCallsJSON, partsJSON []byte
 		err := rows.Scan(
 			&msg.ID, &msg.ConversationID, &msg.Role, &msg.Content,
-			&msg.InputTokens, &msg.OutputTokens, &msg.TotalTokens, &msg.ModelID, &msg.CreatedAt,
+			&msg.InputTokens, &msg.OutputTokens, &msg.TotalTokens, &msg.ModelID,
+			&toolCallsJSON, &msg.ToolCallID, &partsJSON, &msg.CreatedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan message: %w", err)
 		}
+		if err := unmarshalToolCalls(toolCallsJSON, msg); err != nil {
+			return nil, err
+		}
+		if err := unmarshalContentParts(partsJSON, msg); err != nil {
+			return nil, err
+		}
 		messages = append(messages, msg)
 	}
 
 	return messages, nil
 }
 
+// SearchByUser finds user and assistant messages in a user's conversations
+// containing every term (case-insensitive), newest first
+func (r *MessageRepository) SearchByUser(ctx context.Context, userID uuid.UUID, terms []string, limit int) ([]*model.MessageSearchResult, error) {
+	query := `
+		SELECT m.id, m.conversation_id, c.title, m.role, m.content, m.created_at
+		FROM messages m
+		JOIN conversations c ON c.id = m.conversation_id
+		WHERE c.user_id = $1
+			AND c.memory_mode = 'full'
+			AND m.role IN ('user', 'assistant')
+			AND m.content <> ''
+	`
+	args := []interface{}{userID}
+	for _, term := range terms {
+		args = append(args, likePattern(term))
+		query += fmt.Sprintf(" AND m.content ILIKE $%d ESCAPE '\\'", len(args))
+	}
+	args = append(args, limit)
+	query += fmt.Sprintf(" ORDER BY m.created_at DESC LIMIT $%d", len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search messages: %w", err)
+	}
+	defer rows.Close()
+
+	var results []*model.MessageSearchResult
+	for rows.Next() {
+		result := &model.MessageSearchResult{}
+		err := rows.Scan(
+			&result.MessageID, &result.ConversationID, &result.ConversationTitle,
+			&result.Role, &result.Content, &result.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan message: %w", err)
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// likePattern turns a search term into an ILIKE substring pattern, escaping
+// the wildcard characters it contains
+func likePattern(term string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(term)
+	return "%" + escaped + "%"
+}
+
 // GetRecentMessages retrieves recent messages for memory context
 func (r *MessageRepository) GetRecentMessages(ctx context.Context, conversationID uuid.UUID, limit int) ([]*model.Message, error) {
 	query := `
-		SELECT id, conversation_id, role, content, input_tokens, output_tokens, total_tokens, model_id, created_at
+		SELECT id, conversation_id, role, content, input_tokens, output_tokens, total_tokens, model_id,
+			tool_calls, tool_call_id, content_parts, created_at
 		FROM messages
 		WHERE conversation_id = $1
 		ORDER BY created_at DESC
@@ -236,13 +402,21 @@ func (r *MessageRepository) GetRecentMessages(ctx context.Context, conversationI
 	var messages []*model.Message
 	for rows.Next() {
 		msg := &model.Message{}
+		var toolCallsJSON, partsJSON []byte
 		err := rows.Scan(
 			&msg.ID, &msg.ConversationID, &msg.Role, &msg.Content,
-			&msg.InputTokens, &msg.OutputTokens, &msg.TotalTokens, &msg.ModelID, &msg.CreatedAt,
+			&msg.InputTokens, &msg.OutputTokens, &msg.TotalTokens, &msg.ModelID,
+			&toolCallsJSON, &msg.ToolCallID, &partsJSON, &msg.CreatedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan message: %w", err)
 		}
+		if err := unmarshalToolCalls(toolCallsJSON, msg); err != nil {
+			return nil, err
+		}
+		if err := unmarshalContentParts(partsJSON, msg); err != nil {
+			return nil, err
+		}
 		messages = append(messages, msg)
 	}
 
@@ -299,3 +473,51 @@ func (r *MessageRepository) CountLastDays(ctx context.Context, days int) (int, e
 	}
 	return count, nil
 }
+
+// marshalToolCalls encodes an assistant turn's tool calls for storage; turns
+// without calls are stored as NULL
+func marshalToolCalls(calls []model.ToolCall) ([]byte, error) {
+	if len(calls) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(calls)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal tool calls: %w", err)
+	}
+	return data, nil
+}
+
+// unmarshalToolCalls decodes stored tool calls into the message
+func unmarshalToolCalls(data []byte, msg *model.Message) error {
+	if len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, &msg.ToolCalls); err != nil {
+		return fmt.Errorf("failed to unmarshal tool calls: %w", err)
+	}
+	return nil
+}
+
+// marshalContentParts encodes a multi-part message's parts for storage;
+// plain text messages are stored as NULL
+func marshalContentParts(parts []model.ContentPart) ([]byte, error) {
+	if len(parts) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(parts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal content parts: %w", err)
+	}
+	return data, nil
+}
+
+// unmarshalContentParts decodes stored content parts into the message
+func unmarshalContentParts(data []byte, msg *model.Message) error {
+	if len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, &msg.Parts); err != nil {
+		return fmt.Errorf("failed to unmarshal content parts: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,330 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/ai-chat/backend/internal/model"
+	"github.com/ai-chat/backend/internal/pkg/vector"
+)
+
+// maxScannedChunks bounds how many chunks a search without pgvector ranks in
+// the application
+const maxScannedChunks = 20000
+
+// indexedVectorDims are the embedding sizes with an HNSW index (migration 017)
+var indexedVectorDims = map[int]bool{768: true, 1024: true, 1536: true}
+
+// vectorSearchCandidates widens the HNSW search. The index is searched before
+// the user and document filters apply, so the default of 40 candidates could
+// leave a user with few matches among them.
+const vectorSearchCandidates = 400
+
+// DocumentRepository handles document and chunk data access
+type DocumentRepository struct {
+	db *sql.DB
+
+	vectorOnce sync.Once
+	hasVector  bool // chunks have a pgvector copy of their embedding
+}
+
+// NewDocumentRepository creates a new document repository
+func NewDocumentRepository(db *sql.DB) *DocumentRepository {
+	return &DocumentRepository{db: db}
+}
+
+const documentColumns = `id, user_id, attachment_id, conversation_id, filename, status,
+	error, chunk_count, embedding_model, created_at, updated_at`
+
+// Create creates a new document
+func (r *DocumentRepository) Create(ctx context.Context, d *model.Document) error {
+	query := `
+		INSERT INTO documents (id, user_id, attachment_id, conversation_id, filename, status)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING created_at, updated_at
+	`
+
+	err := r.db.QueryRowContext(
+		ctx, query,
+		d.ID, d.UserID, d.AttachmentID, d.ConversationID, d.Filename, d.Status,
+	).Scan(&d.CreatedAt, &d.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create document: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID retrieves a document by ID
+func (r *DocumentRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.Document, error) {
+	query := `SELECT ` + documentColumns + ` FROM documents WHERE id = $1`
+
+	d, err := scanDocument(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("document not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document: %w", err)
+	}
+
+	return d, nil
+}
+
+// ListByUser retrieves a user's documents, newest first. A conversation ID
+// restricts the list to that conversation's documents.
+func (r *DocumentRepository) ListByUser(ctx context.Context, userID uuid.UUID, conversationID *uuid.UUID) ([]*model.Document, error) {
+	query := `
+		SELECT ` + documentColumns + `
+		FROM documents
+		WHERE user_id = $1 AND ($2::uuid IS NULL OR conversation_id = $2)
+		ORDER BY created_at DESC
+	`
+
+	return r.query(ctx, query, userID, conversationID)
+}
+
+// ListUnfinished retrieves documents still waiting to be processed, including
+// ones interrupted by a restart
+func (r *DocumentRepository) ListUnfinished(ctx context.Context) ([]*model.Document, error) {
+	query := `
+		SELECT ` + documentColumns + `
+		FROM documents
+		WHERE status IN ('pending', 'processing')
+		ORDER BY created_at ASC
+	`
+
+	return r.query(ctx, query)
+}
+
+// UpdateStatus sets a document's processing status and error message
+func (r *DocumentRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status model.DocumentStatus, errMsg string) error {
+	query := `UPDATE documents SET status = $2, error = $3 WHERE id = $1`
+	_, err := r.db.ExecContext(ctx, query, id, status, errMsg)
+	return err
+}
+
+// SaveChunks replaces a document's chunks and marks it ready, in one transaction
+func (r *DocumentRepository) SaveChunks(ctx context.Context, d *model.Document, embeddingModel string, chunks []*model.DocumentChunk) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM document_chunks WHERE document_id = $1`, d.ID); err != nil {
+		return fmt.Errorf("failed to clear chunks: %w", err)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO document_chunks (document_id, user_id, chunk_index, content, embedding)
+		VALUES ($1, $2, $3, $4, $5)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare chunk insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, c := range chunks {
+		if _, err := stmt.ExecContext(ctx, d.ID, d.UserID, c.ChunkIndex, c.Content, pq.Array(c.Embedding)); err != nil {
+			return fmt.Errorf("failed to save chunk: %w", err)
+		}
+	}
+
+	query := `
+		UPDATE documents
+		SET status = 'ready', error = '', chunk_count = $2, embedding_model = $3
+		WHERE id = $1
+	`
+	if _, err := tx.ExecContext(ctx, query, d.ID, len(chunks), embeddingModel); err != nil {
+		return fmt.Errorf("failed to update document: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// Delete deletes a document; its chunks are removed by cascade
+func (r *DocumentRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	query := `DELETE FROM documents WHERE id = $1`
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
+
+// HasSearchable reports whether the user has ready documents embedded with
+// the model that a search in the conversation would cover
+func (r *DocumentRepository) HasSearchable(ctx context.Context, userID, conversationID uuid.UUID, embeddingModel string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM documents
+			WHERE user_id = $1 AND status = 'ready' AND embedding_model = $3
+			  AND (conversation_id IS NULL OR conversation_id = $2)
+		)
+	`
+
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, query, userID, conversationID, embeddingModel).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check documents: %w", err)
+	}
+	return exists, nil
+}
+
+// Search returns the chunks most similar to the query embedding among the
+// user's knowledge base and the conversation's documents, best first
+func (r *DocumentRepository) Search(ctx context.Context, userID, conversationID uuid.UUID, embeddingModel string, embedding []float32, limit int) ([]*model.DocumentExcerpt, error) {
+	if r.vectorAvailable(ctx) {
+		return r.searchVector(ctx, userID, conversationID, embeddingModel, embedding, limit)
+	}
+	return r.searchScan(ctx, userID, conversationID, embeddingModel, embedding, limit)
+}
+
+// vectorAvailable reports, once per process, whether migration 017 found
+// pgvector and added the embedding_vector column
+func (r *DocumentRepository) vectorAvailable(ctx context.Context) bool {
+	r.vectorOnce.Do(func() {
+		query := `
+			SELECT EXISTS (
+				SELECT 1 FROM information_schema.columns
+				WHERE table_schema = current_schema() AND table_name = 'document_chunks' AND column_name = 'embedding_vector'
+			)
+		`
+		if err := r.db.QueryRowContext(ctx, query).Scan(&r.hasVector); err != nil {
+			log.Printf("Failed to detect pgvector, ranking documents in the application: %v", err)
+		}
+	})
+	return r.hasVector
+}
+
+// searchVector lets pgvector rank by cosine distance, through the HNSW index
+// when the embedding size has one
+func (r *DocumentRepository) searchVector(ctx context.Context, userID, conversationID uuid.UUID, embeddingModel string, embedding []float32, limit int) ([]*model.DocumentExcerpt, error) {
+	// The index is on a cast to the fixed size, with the size as its predicate;
+	// the query has to spell both out for the planner to use it
+	dims := len(embedding)
+	column, target := "c.embedding_vector", "$4::real[]::vector"
+	if indexedVectorDims[dims] {
+		column = fmt.Sprintf("(c.embedding_vector::vector(%d))", dims)
+		target = fmt.Sprintf("$4::real[]::vector(%d)", dims)
+	}
+	query := fmt.Sprintf(`
+		SELECT c.document_id, d.filename, c.chunk_index, c.content,
+			1 - (%[1]s <=> %[2]s) AS score
+		FROM document_chunks c
+		JOIN documents d ON d.id = c.document_id
+		WHERE c.user_id = $1 AND d.status = 'ready' AND d.embedding_model = $3
+		  AND (d.conversation_id IS NULL OR d.conversation_id = $2)
+		  AND vector_dims(c.embedding_vector) = %[3]d
+		ORDER BY %[1]s <=> %[2]s
+		LIMIT $5
+	`, column, target, dims)
+
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("SET LOCAL hnsw.ef_search = %d", vectorSearchCandidates)); err != nil {
+		return nil, fmt.Errorf("failed to configure document search: %w", err)
+	}
+
+	rows, err := tx.QueryContext(ctx, query, userID, conversationID, embeddingModel, pq.Array(embedding), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search documents: %w", err)
+	}
+	defer rows.Close()
+
+	var excerpts []*model.DocumentExcerpt
+	for rows.Next() {
+		e := &model.DocumentExcerpt{}
+		if err := rows.Scan(&e.DocumentID, &e.Filename, &e.ChunkIndex, &e.Content, &e.Score); err != nil {
+			return nil, fmt.Errorf("failed to scan excerpt: %w", err)
+		}
+		excerpts = append(excerpts, e)
+	}
+
+	return excerpts, rows.Err()
+}
+
+// searchScan ranks chunks in the application, for databases without pgvector
+func (r *DocumentRepository) searchScan(ctx context.Context, userID, conversationID uuid.UUID, embeddingModel string, embedding []float32, limit int) ([]*model.DocumentExcerpt, error) {
+	query := `
+		SELECT c.document_id, d.filename, c.chunk_index, c.content, c.embedding
+		FROM document_chunks c
+		JOIN documents d ON d.id = c.document_id
+		WHERE c.user_id = $1 AND d.status = 'ready' AND d.embedding_model = $3
+		  AND (d.conversation_id IS NULL OR d.conversation_id = $2)
+		ORDER BY d.created_at DESC
+		LIMIT $4
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, conversationID, embeddingModel, maxScannedChunks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search documents: %w", err)
+	}
+	defer rows.Close()
+
+	var excerpts []*model.DocumentExcerpt
+	scanned := 0
+	for rows.Next() {
+		scanned++
+		e := &model.DocumentExcerpt{}
+		var vec pq.Float32Array
+		if err := rows.Scan(&e.DocumentID, &e.Filename, &e.ChunkIndex, &e.Content, &vec); err != nil {
+			return nil, fmt.Errorf("failed to scan excerpt: %w", err)
+		}
+		if len(vec) != len(embedding) {
+			continue
+		}
+		e.Score = vector.Cosine(embedding, vec)
+		excerpts = append(excerpts, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if scanned == maxScannedChunks {
+		log.Printf("Document search for user %s ranked only the newest %d chunks; install pgvector to search them all", userID, maxScannedChunks)
+	}
+
+	sort.Slice(excerpts, func(i, j int) bool { return excerpts[i].Score > excerpts[j].Score })
+	if len(excerpts) > limit {
+		excerpts = excerpts[:limit]
+	}
+	return excerpts, nil
+}
+
+func (r *DocumentRepository) query(ctx context.Context, query string, args ...interface{}) ([]*model.Document, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list documents: %w", err)
+	}
+	defer rows.Close()
+
+	var documents []*model.Document
+	for rows.Next() {
+		d, err := scanDocument(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan document: %w", err)
+		}
+		documents = append(documents, d)
+	}
+
+	return documents, rows.Err()
+}
+
+// scanDocument scans a row selected with documentColumns
+func scanDocument(row interface{ Scan(...interface{}) error }) (*model.Document, error) {
+	d := &model.Document{}
+	err := row.Scan(
+		&d.ID, &d.UserID, &d.AttachmentID, &d.ConversationID, &d.Filename, &d.Status,
+		&d.Error, &d.ChunkCount, &d.EmbeddingModel, &d.CreatedAt, &d.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
@@ -0,0 +1,251 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ai-chat/backend/internal/model"
+)
+
+// MemoryJobRepository handles the memory extraction job queue
+type MemoryJobRepository struct {
+	db *sql.DB
+}
+
+// NewMemoryJobRepository creates a new memory job repository
+func NewMemoryJobRepository(db *sql.DB) *MemoryJobRepository {
+	return &MemoryJobRepository{db: db}
+}
+
+const memoryJobColumns = `id, user_id, conversation_id, status, pending_turns, run_after, attempts,
+	last_error, watermark_at, watermark_id, locked_at, finished_at, created_at, updated_at`
+
+// Enqueue records a new turn in a conversation. The job becomes due once it
+// has collected `turns` turns, or after `idle` without another turn. A running
+// job keeps running and picks the turn up afterwards; a job waiting to retry
+// keeps its backoff; a failed job starts over.
+func (r *MemoryJobRepository) Enqueue(ctx context.Context, userID, conversationID uuid.UUID, turns int, idle time.Duration) error {
+	query := `
+		INSERT INTO memory_extraction_jobs (user_id, conversation_id, status, pending_turns, run_after)
+		VALUES ($1, $2, 'pending', 1, CASE WHEN 1 >= $3 THEN NOW() ELSE NOW() + $4 * INTERVAL '1 second' END)
+		ON CONFLICT (conversation_id) DO UPDATE SET
+			pending_turns = memory_extraction_jobs.pending_turns + 1,
+			status = CASE WHEN memory_extraction_jobs.status = 'running' THEN 'running' ELSE 'pending' END,
+			attempts = CASE WHEN memory_extraction_jobs.status = 'failed' THEN 0 ELSE memory_extraction_jobs.attempts END,
+			run_after = CASE
+				WHEN memory_extraction_jobs.status = 'running' THEN memory_extraction_jobs.run_after
+				WHEN memory_extraction_jobs.status = 'pending' AND memory_extraction_jobs.attempts > 0 THEN memory_extraction_jobs.run_after
+				WHEN memory_extraction_jobs.pending_turns + 1 >= $3 THEN NOW()
+				ELSE NOW() + $4 * INTERVAL '1 second'
+			END
+	`
+
+	_, err := r.db.ExecContext(ctx, query, userID, conversationID, turns, idle.Seconds())
+	if err != nil {
+		return fmt.Errorf("failed to enqueue memory extraction: %w", err)
+	}
+	return nil
+}
+
+// Claim marks the next due job running and returns it, or nil when no job is
+// due. Jobs left running longer than `stale`, e.g. by a restart, are due again.
+func (r *MemoryJobRepository) Claim(ctx context.Context, stale time.Duration) (*model.MemoryExtractionJob, error) {
+	query := `
+		UPDATE memory_extraction_jobs SET status = 'running', locked_at = NOW()
+		WHERE id = (
+			SELECT id FROM memory_extraction_jobs
+			WHERE (status = 'pending' AND run_after <= NOW())
+			   OR (status = 'running' AND locked_at < NOW() - $1 * INTERVAL '1 second')
+			ORDER BY run_after
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + memoryJobColumns
+
+	job, err := scanMemoryJob(r.db.QueryRowContext(ctx, query, stale.Seconds()))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim memory extraction job: %w", err)
+	}
+	return job, nil
+}
+
+// Complete records a successful run that processed `turns` turns up to the
+// watermark (nil when there were no messages). The job stays pending while
+// turns arrived during the run or, with `more`, while messages remain past the
+// watermark. The watermark only ever moves forward.
+func (r *MemoryJobRepository) Complete(ctx context.Context, id uuid.UUID, turns int, watermark *model.MessageCursor, more bool, nextTurns int, idle time.Duration) error {
+	query := `
+		UPDATE memory_extraction_jobs SET
+			status = CASE WHEN $4 OR pending_turns - $2 > 0 THEN 'pending' ELSE 'done' END,
+			run_after = CASE
+				WHEN $4 OR pending_turns - $2 >= $5 THEN NOW()
+				ELSE NOW() + $6 * INTERVAL '1 second'
+			END,
+			pending_turns = GREATEST(pending_turns - $2, 0),
+			watermark_at = CASE WHEN $3::timestamp IS NOT NULL AND (watermark_at IS NULL OR ($3, $7::uuid) > (watermark_at, watermark_id))
+				THEN $3 ELSE watermark_at END,
+			watermark_id = CASE WHEN $3::timestamp IS NOT NULL AND (watermark_at IS NULL OR ($3, $7::uuid) > (watermark_at, watermark_id))
+				THEN $7 ELSE watermark_id END,
+			attempts = 0,
+			last_error = '',
+			locked_at = NULL,
+			finished_at = NOW()
+		WHERE id = $1
+	`
+
+	var watermarkAt *time.Time
+	watermarkID := uuid.Nil
+	if watermark != nil {
+		watermarkAt, watermarkID = &watermark.CreatedAt, watermark.ID
+	}
+	_, err := r.db.ExecContext(ctx, query, id, turns, watermarkAt, more, nextTurns, idle.Seconds(), watermarkID)
+	if err != nil {
+		return fmt.Errorf("failed to complete memory extraction job: %w", err)
+	}
+	return nil
+}
+
+// Skip moves the conversation's watermark past its current messages, so they
+// are never read for memories, and drops the turns counted so far
+func (r *MemoryJobRepository) Skip(ctx context.Context, userID, conversationID uuid.UUID) error {
+	query := `
+		INSERT INTO memory_extraction_jobs (user_id, conversation_id, status, watermark_at, watermark_id)
+		SELECT $1, $2, 'done', last.created_at, last.id
+		FROM (SELECT 1) AS one
+		LEFT JOIN LATERAL (
+			SELECT created_at, id FROM messages
+			WHERE conversation_id = $2
+			ORDER BY created_at DESC, id DESC
+			LIMIT 1
+		) AS last ON TRUE
+		ON CONFLICT (conversation_id) DO UPDATE SET
+			status = CASE WHEN memory_extraction_jobs.status = 'running' THEN 'running' ELSE 'done' END,
+			pending_turns = 0,
+			attempts = 0,
+			watermark_at = CASE WHEN EXCLUDED.watermark_at IS NOT NULL AND (memory_extraction_jobs.watermark_at IS NULL OR
+					(EXCLUDED.watermark_at, EXCLUDED.watermark_id) > (memory_extraction_jobs.watermark_at, memory_extraction_jobs.watermark_id))
+				THEN EXCLUDED.watermark_at ELSE memory_extraction_jobs.watermark_at END,
+			watermark_id = CASE WHEN EXCLUDED.watermark_at IS NOT NULL AND (memory_extraction_jobs.watermark_at IS NULL OR
+					(EXCLUDED.watermark_at, EXCLUDED.watermark_id) > (memory_extraction_jobs.watermark_at, memory_extraction_jobs.watermark_id))
+				THEN EXCLUDED.watermark_id ELSE memory_extraction_jobs.watermark_id END
+	`
+
+	_, err := r.db.ExecContext(ctx, query, userID, conversationID)
+	if err != nil {
+		return fmt.Errorf("failed to skip memory extraction: %w", err)
+	}
+	return nil
+}
+
+// Fail records a failed run. The job is retried after backoff, doubled with
+// every attempt, until maxAttempts attempts have failed.
+func (r *MemoryJobRepository) Fail(ctx context.Context, id uuid.UUID, errMsg string, maxAttempts int, backoff time.Duration) error {
+	query := `
+		UPDATE memory_extraction_jobs SET
+			status = CASE WHEN attempts + 1 >= $3 THEN 'failed' ELSE 'pending' END,
+			run_after = NOW() + $4 * POWER(2, attempts) * INTERVAL '1 second',
+			attempts = attempts + 1,
+			last_error = $2,
+			locked_at = NULL
+		WHERE id = $1
+	`
+
+	_, err := r.db.ExecContext(ctx, query, id, errMsg, maxAttempts, backoff.Seconds())
+	if err != nil {
+		return fmt.Errorf("failed to record memory extraction failure: %w", err)
+	}
+	return nil
+}
+
+// Retry makes a failed job due immediately. It reports whether the job was failed.
+func (r *MemoryJobRepository) Retry(ctx context.Context, id uuid.UUID) (bool, error) {
+	query := `
+		UPDATE memory_extraction_jobs SET status = 'pending', attempts = 0, run_after = NOW()
+		WHERE id = $1 AND status = 'failed'
+	`
+
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to retry memory extraction job: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// List retrieves jobs, most recently changed first, optionally only those with a status
+func (r *MemoryJobRepository) List(ctx context.Context, status model.MemoryJobStatus, limit, offset int) ([]*model.MemoryExtractionJob, error) {
+	query := `
+		SELECT ` + memoryJobColumns + `
+		FROM memory_extraction_jobs
+		WHERE $1::text = '' OR status = $1
+		ORDER BY updated_at DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, status, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list memory extraction jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []*model.MemoryExtractionJob
+	for rows.Next() {
+		job, err := scanMemoryJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan memory extraction job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, rows.Err()
+}
+
+// CountByStatus counts jobs per status
+func (r *MemoryJobRepository) CountByStatus(ctx context.Context) (map[model.MemoryJobStatus]int, error) {
+	query := `SELECT status, COUNT(*) FROM memory_extraction_jobs GROUP BY status`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count memory extraction jobs: %w", err)
+	}
+	defer rows.Close()
+
+	counts := map[model.MemoryJobStatus]int{
+		model.MemoryJobStatusPending: 0,
+		model.MemoryJobStatusRunning: 0,
+		model.MemoryJobStatusDone:    0,
+		model.MemoryJobStatusFailed:  0,
+	}
+	for rows.Next() {
+		var status model.MemoryJobStatus
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan job count: %w", err)
+		}
+		counts[status] = count
+	}
+
+	return counts, rows.Err()
+}
+
+// scanMemoryJob scans a row selected with memoryJobColumns
+func scanMemoryJob(row interface{ Scan(...interface{}) error }) (*model.MemoryExtractionJob, error) {
+	job := &model.MemoryExtractionJob{}
+	err := row.Scan(
+		&job.ID, &job.UserID, &job.ConversationID, &job.Status, &job.PendingTurns, &job.RunAfter, &job.Attempts,
+		&job.LastError, &job.WatermarkAt, &job.WatermarkID, &job.LockedAt, &job.FinishedAt, &job.CreatedAt, &job.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
@@ -0,0 +1,189 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ai-chat/backend/internal/database/dbtest"
+	"github.com/ai-chat/backend/internal/model"
+)
+
+func TestMemoryJobQueue(t *testing.T) {
+	db := dbtest.Open(t)
+	repo := NewMemoryJobRepository(db)
+	ctx := context.Background()
+	userID := dbtest.CreateUser(t, db)
+	convID := dbtest.CreateConversation(t, db, userID)
+
+	get := func() *model.MemoryExtractionJob {
+		t.Helper()
+		job, err := scanMemoryJob(db.QueryRow(`SELECT `+memoryJobColumns+` FROM memory_extraction_jobs WHERE conversation_id = $1`, convID))
+		if err != nil {
+			t.Fatalf("failed to load job: %v", err)
+		}
+		return job
+	}
+	claim := func() *model.MemoryExtractionJob {
+		t.Helper()
+		job, err := repo.Claim(ctx, time.Hour)
+		if err != nil {
+			t.Fatalf("Claim() error = %v", err)
+		}
+		return job
+	}
+	enqueue := func() {
+		t.Helper()
+		if err := repo.Enqueue(ctx, userID, convID, 3, time.Hour); err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+	}
+
+	// The job waits for three turns
+	enqueue()
+	enqueue()
+	if job := claim(); job != nil {
+		t.Fatalf("Claim() after 2 turns = %+v, want nil", job)
+	}
+	enqueue()
+	job := claim()
+	if job == nil || job.PendingTurns != 3 || job.Status != model.MemoryJobStatusRunning {
+		t.Fatalf("Claim() after 3 turns = %+v, want the running job", job)
+	}
+	if other := claim(); other != nil {
+		t.Fatalf("Claim() of a running job = %+v, want nil", other)
+	}
+
+	// A turn arriving during the run keeps the job running and is counted
+	enqueue()
+	if got := get(); got.Status != model.MemoryJobStatusRunning || got.PendingTurns != 4 {
+		t.Fatalf("job after a turn during the run = %s with %d turns, want running with 4", got.Status, got.PendingTurns)
+	}
+
+	watermark := &model.MessageCursor{CreatedAt: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC), ID: uuid.New()}
+	if err := repo.Complete(ctx, job.ID, 3, watermark, false, 3, time.Hour); err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	got := get()
+	if got.Status != model.MemoryJobStatusPending || got.PendingTurns != 1 || got.LockedAt != nil {
+		t.Fatalf("job after completing = %+v, want pending with the 1 new turn", got)
+	}
+	if w := got.Watermark(); w == nil || !w.CreatedAt.Equal(watermark.CreatedAt) || w.ID != watermark.ID {
+		t.Fatalf("watermark = %+v, want %+v", w, watermark)
+	}
+	if job := claim(); job != nil {
+		t.Fatalf("Claim() with 1 turn pending = %+v, want nil", job)
+	}
+
+	// With more messages left past the watermark the job is due at once; the
+	// watermark never moves back
+	older := &model.MessageCursor{CreatedAt: watermark.CreatedAt.Add(-time.Minute), ID: uuid.New()}
+	if err := repo.Complete(ctx, job.ID, 0, older, true, 3, time.Hour); err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	if w := get().Watermark(); w == nil || w.ID != watermark.ID {
+		t.Fatalf("watermark moved back to %+v", w)
+	}
+	job = claim()
+	if job == nil {
+		t.Fatal("Claim() with more messages left = nil, want the job")
+	}
+
+	// A job left running past the stale timeout is claimed again
+	dbtest.Exec(t, db, `UPDATE memory_extraction_jobs SET locked_at = NOW() - INTERVAL '2 hours' WHERE id = $1`, job.ID)
+	if again := claim(); again == nil || again.ID != job.ID {
+		t.Fatalf("Claim() of a stale job = %+v, want the job", again)
+	}
+
+	// Failures back off and, after maxAttempts, stay failed until retried
+	if err := repo.Fail(ctx, job.ID, "boom", 2, time.Hour); err != nil {
+		t.Fatalf("Fail() error = %v", err)
+	}
+	if got := get(); got.Status != model.MemoryJobStatusPending || got.Attempts != 1 || got.LastError != "boom" {
+		t.Fatalf("job after a failure = %+v, want pending with 1 attempt", got)
+	}
+	if job := claim(); job != nil {
+		t.Fatalf("Claim() during the backoff = %+v, want nil", job)
+	}
+	// A new turn does not cut the backoff short
+	enqueue()
+	if job := claim(); job != nil {
+		t.Fatalf("Claim() after a turn during the backoff = %+v, want nil", job)
+	}
+	if err := repo.Fail(ctx, job.ID, "boom again", 2, time.Hour); err != nil {
+		t.Fatalf("Fail() error = %v", err)
+	}
+	if got := get(); got.Status != model.MemoryJobStatusFailed || got.Attempts != 2 {
+		t.Fatalf("job after the last attempt = %+v, want failed", got)
+	}
+
+	counts, err := repo.CountByStatus(ctx)
+	if err != nil {
+		t.Fatalf("CountByStatus() error = %v", err)
+	}
+	if counts[model.MemoryJobStatusFailed] != 1 || counts[model.MemoryJobStatusPending] != 0 {
+		t.Errorf("CountByStatus() = %v, want 1 failed", counts)
+	}
+
+	if ok, err := repo.Retry(ctx, job.ID); err != nil || !ok {
+		t.Fatalf("Retry() = %v, %v, want true", ok, err)
+	}
+	if ok, _ := repo.Retry(ctx, job.ID); ok {
+		t.Error("Retry() of a pending job = true, want false")
+	}
+	if job := claim(); job == nil || job.Attempts != 0 {
+		t.Fatalf("Claim() after a retry = %+v, want the job with no attempts", job)
+	}
+}
+
+func TestMemoryJobSkip(t *testing.T) {
+	db := dbtest.Open(t)
+	repo := NewMemoryJobRepository(db)
+	ctx := context.Background()
+	userID := dbtest.CreateUser(t, db)
+	convID := dbtest.CreateConversation(t, db, userID)
+
+	// Two messages created in the same instant are ordered by id
+	at := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	first, last := uuid.New(), uuid.New()
+	if last.String() < first.String() {
+		first, last = last, first
+	}
+	for _, id := range []uuid.UUID{first, last} {
+		dbtest.Exec(t, db, `INSERT INTO messages (id, conversation_id, role, content, created_at) VALUES ($1, $2, 'user', 'hi', $3)`, id, convID, at)
+	}
+
+	if err := repo.Enqueue(ctx, userID, convID, 1, time.Hour); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	if err := repo.Skip(ctx, userID, convID); err != nil {
+		t.Fatalf("Skip() error = %v", err)
+	}
+
+	var status string
+	var pending int
+	var watermarkID uuid.NullUUID
+	err := db.QueryRow(`SELECT status, pending_turns, watermark_id FROM memory_extraction_jobs WHERE conversation_id = $1`, convID).
+		Scan(&status, &pending, &watermarkID)
+	if err != nil {
+		t.Fatalf("failed to load job: %v", err)
+	}
+	if status != string(model.MemoryJobStatusDone) || pending != 0 || watermarkID.UUID != last {
+		t.Errorf("job after Skip() = %s, %d turns, watermark %v; want done, 0 turns, watermark %s", status, pending, watermarkID.UUID, last)
+	}
+
+	// A conversation without messages gets a job with no watermark
+	empty := dbtest.CreateConversation(t, db, userID)
+	if err := repo.Skip(ctx, userID, empty); err != nil {
+		t.Fatalf("Skip() of an empty conversation error = %v", err)
+	}
+	var emptyWatermark uuid.NullUUID
+	err = db.QueryRow(`SELECT watermark_id FROM memory_extraction_jobs WHERE conversation_id = $1`, empty).Scan(&emptyWatermark)
+	if err != nil {
+		t.Fatalf("failed to load job: %v", err)
+	}
+	if emptyWatermark.Valid {
+		t.Errorf("watermark of an empty conversation = %s, want none", emptyWatermark.UUID)
+	}
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"github.com/ai-chat/backend/internal/model"
 )
 
@@ -101,21 +103,26 @@ func (r *MemoryRepository) ListByUser(ctx context.Context, userID uuid.UUID, lim
 	return memories, nil
 }
 
-// GetRelevantMemories retrieves most relevant memories for a user
-func (r *MemoryRepository) GetRelevantMemories(ctx context.Context, userID uuid.UUID, limit int) ([]*model.Memory, error) {
+// SearchByUser finds a user's memories containing every term (case-insensitive)
+func (r *MemoryRepository) SearchByUser(ctx context.Context, userID uuid.UUID, terms []string, limit int) ([]*model.Memory, error) {
 	query := `
 		SELECT id, user_id, content, category, importance,
 			source_conversation_id, source_message_id,
 			times_used, last_used_at, created_at, updated_at
 		FROM memories
 		WHERE user_id = $1
-		ORDER BY importance DESC, times_used DESC, last_used_at DESC NULLS LAST
-		LIMIT $2
 	`
+	args := []interface{}{userID}
+	for _, term := range terms {
+		args = append(args, likePattern(term))
+		query += fmt.Sprintf(" AND content ILIKE $%d ESCAPE '\\'", len(args))
+	}
+	args = append(args, limit)
+	query += fmt.Sprintf(" ORDER BY importance DESC, updated_at DESC LIMIT $%d", len(args))
 
-	rows, err := r.db.QueryContext(ctx, query, userID, limit)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get relevant memories: %w", err)
+		return nil, fmt.Errorf("failed to search memories: %w", err)
 	}
 	defer rows.Close()
 
@@ -136,17 +143,119 @@ func (r *MemoryRepository) GetRelevantMemories(ctx context.Context, userID uuid.
 	return memories, nil
 }
 
-// Update updates a memory
+// ListForRanking retrieves up to limit of a user's memories with their
+// embeddings from the given model, most important first
+func (r *MemoryRepository) ListForRanking(ctx context.Context, userID uuid.UUID, embeddingModel string, limit int) ([]*model.Memory, error) {
+	query := `
+		SELECT id, user_id, content, category, importance,
+			source_conversation_id, source_message_id,
+			times_used, last_used_at, created_at, updated_at,
+			CASE WHEN embedding_model = $2 THEN embedding END
+		FROM memories
+		WHERE user_id = $1
+		ORDER BY importance DESC, created_at DESC
+		LIMIT $3
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, embeddingModel, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list memories: %w", err)
+	}
+	defer rows.Close()
+
+	var memories []*model.Memory
+	for rows.Next() {
+		memory := &model.Memory{}
+		var embedding pq.Float32Array
+		err := rows.Scan(
+			&memory.ID, &memory.UserID, &memory.Content, &memory.Category, &memory.Importance,
+			&memory.SourceConversationID, &memory.SourceMessageID,
+			&memory.TimesUsed, &memory.LastUsedAt, &memory.CreatedAt, &memory.UpdatedAt,
+			&embedding,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan memory: %w", err)
+		}
+		memory.Embedding = embedding
+		memories = append(memories, memory)
+	}
+
+	return memories, rows.Err()
+}
+
+// ListPage retrieves up to limit of a user's memories in creation order,
+// starting after the memory `after` (nil for the first page), with their
+// embeddings from the given model. Unlike an offset, the position stays valid
+// while memories are added or used.
+func (r *MemoryRepository) ListPage(ctx context.Context, userID uuid.UUID, embeddingModel string, after *model.Memory, limit int) ([]*model.Memory, error) {
+	query := `
+		SELECT id, user_id, content, category, importance,
+			source_conversation_id, source_message_id,
+			times_used, last_used_at, created_at, updated_at,
+			CASE WHEN embedding_model = $2 THEN embedding END
+		FROM memories
+		WHERE user_id = $1 AND ($3::timestamp IS NULL OR (created_at, id) > ($3, $4))
+		ORDER BY created_at ASC, id ASC
+		LIMIT $5
+	`
+
+	var afterAt *time.Time
+	afterID := uuid.Nil
+	if after != nil {
+		afterAt, afterID = &after.CreatedAt, after.ID
+	}
+	rows, err := r.db.QueryContext(ctx, query, userID, embeddingModel, afterAt, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list memories: %w", err)
+	}
+	defer rows.Close()
+
+	var memories []*model.Memory
+	for rows.Next() {
+		memory := &model.Memory{}
+		var embedding pq.Float32Array
+		err := rows.Scan(
+			&memory.ID, &memory.UserID, &memory.Content, &memory.Category, &memory.Importance,
+			&memory.SourceConversationID, &memory.SourceMessageID,
+			&memory.TimesUsed, &memory.LastUsedAt, &memory.CreatedAt, &memory.UpdatedAt,
+			&embedding,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan memory: %w", err)
+		}
+		memory.Embedding = embedding
+		memories = append(memories, memory)
+	}
+
+	return memories, rows.Err()
+}
+
+// SetEmbedding stores a memory's embedding and the model that computed it
+func (r *MemoryRepository) SetEmbedding(ctx context.Context, id uuid.UUID, embeddingModel string, embedding []float32) error {
+	query := `UPDATE memories SET embedding = $2, embedding_model = $3 WHERE id = $1`
+	_, err := r.db.ExecContext(ctx, query, id, pq.Array(embedding), embeddingModel)
+	return err
+}
+
+// Update updates a memory. A changed content clears the embedding.
 func (r *MemoryRepository) Update(ctx context.Context, memory *model.Memory) error {
 	query := `
 		UPDATE memories SET
+			embedding = CASE WHEN content = $2 THEN embedding END,
+			embedding_model = CASE WHEN content = $2 THEN embedding_model ELSE '' END,
 			content = $2,
 			category = $3,
-			importance = $4
+			importance = $4,
+			source_conversation_id = $5,
+			source_message_id = $6
 		WHERE id = $1
 	`
 
-	_, err := r.db.ExecContext(ctx, query, memory.ID, memory.Content, memory.Category, memory.Importance)
+	_, err := r.db.ExecContext(
+		ctx, query,
+		memory.ID, memory.Content, memory.Category, memory.Importance,
+		memory.SourceConversationID, memory.SourceMessageID,
+	)
 	if err != nil {
 		return fmt.Errorf("failed to update memory: %w", err)
 	}
@@ -154,16 +263,20 @@ func (r *MemoryRepository) Update(ctx context.Context, memory *model.Memory) err
 	return nil
 }
 
-// IncrementUsage increments the usage count and updates last used time
-func (r *MemoryRepository) IncrementUsage(ctx context.Context, id uuid.UUID) error {
+// IncrementUsage increments the usage count and updates last used time of memories
+func (r *MemoryRepository) IncrementUsage(ctx context.Context, ids ...uuid.UUID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
 	query := `
 		UPDATE memories SET
 			times_used = times_used + 1,
 			last_used_at = NOW()
-		WHERE id = $1
+		WHERE id = ANY($1)
 	`
 
-	_, err := r.db.ExecContext(ctx, query, id)
+	_, err := r.db.ExecContext(ctx, query, pq.Array(ids))
 	return err
 }
 
@@ -174,16 +287,60 @@ func (r *MemoryRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
-// DeleteLowImportance deletes old low-importance memories for cleanup
-func (r *MemoryRepository) DeleteLowImportance(ctx context.Context, userID uuid.UUID, maxAge int, maxImportance int) error {
+// DeleteByUser deletes a user's memories of a category and/or from a source
+// conversation and returns them. Empty filters match all.
+func (r *MemoryRepository) DeleteByUser(ctx context.Context, userID uuid.UUID, category model.MemoryCategory, conversationID *uuid.UUID) ([]*model.Memory, error) {
+	query := `
+		DELETE FROM memories
+		WHERE user_id = $1
+			AND ($2::text = '' OR category::text = $2)
+			AND ($3::uuid IS NULL OR source_conversation_id = $3)
+		RETURNING ` + deletedMemoryColumns
+
+	rows, err := r.db.QueryContext(ctx, query, userID, category, conversationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete memories: %w", err)
+	}
+	return scanDeletedMemories(rows)
+}
+
+// DeleteLowImportance deletes old low-importance memories for cleanup and
+// returns them
+func (r *MemoryRepository) DeleteLowImportance(ctx context.Context, userID uuid.UUID, maxAge int, maxImportance int) ([]*model.Memory, error) {
 	query := `
 		DELETE FROM memories
 		WHERE user_id = $1
 			AND importance <= $2
 			AND created_at < NOW() - INTERVAL '1 day' * $3
 			AND times_used = 0
-	`
+		RETURNING ` + deletedMemoryColumns
 
-	_, err := r.db.ExecContext(ctx, query, userID, maxImportance, maxAge)
-	return err
+	rows, err := r.db.QueryContext(ctx, query, userID, maxImportance, maxAge)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete memories: %w", err)
+	}
+	return scanDeletedMemories(rows)
+}
+
+// deletedMemoryColumns are the columns a delete returns, what a version records
+const deletedMemoryColumns = `id, user_id, content, category, importance, source_conversation_id, source_message_id`
+
+// scanDeletedMemories scans and closes rows returned with deletedMemoryColumns
+func scanDeletedMemories(rows *sql.Rows) ([]*model.Memory, error) {
+	defer rows.Close()
+
+	var memories []*model.Memory
+	for rows.Next() {
+		memory := &model.Memory{}
+		err := rows.Scan(
+			&memory.ID, &memory.UserID, &memory.Content, &memory.Category, &memory.Importance,
+			&memory.SourceConversationID, &memory.SourceMessageID,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan deleted memory: %w", err)
+		}
+		memories = append(memories, memory)
+	}
+
+	return memories, rows.Err()
 }
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ai-chat/backend/internal/model"
+)
+
+// MemoryVersionRepository handles memory version history
+type MemoryVersionRepository struct {
+	db *sql.DB
+}
+
+// NewMemoryVersionRepository creates a new memory version repository
+func NewMemoryVersionRepository(db *sql.DB) *MemoryVersionRepository {
+	return &MemoryVersionRepository{db: db}
+}
+
+const memoryVersionColumns = `id, memory_id, user_id, version, change_type, changed_by, content, category, importance,
+	source_conversation_id, source_message_id, created_at`
+
+// Record stores the memory's current state as its next version
+func (r *MemoryVersionRepository) Record(ctx context.Context, memory *model.Memory, changeType model.MemoryChangeType, changedBy model.MemoryChangeSource) error {
+	query := `
+		INSERT INTO memory_versions (memory_id, user_id, version, change_type, changed_by, content, category, importance,
+			source_conversation_id, source_message_id)
+		VALUES ($1, $2, (SELECT COALESCE(MAX(version), 0) + 1 FROM memory_versions WHERE memory_id = $1),
+			$3, $4, $5, $6, $7, $8, $9)
+	`
+
+	_, err := r.db.ExecContext(
+		ctx, query,
+		memory.ID, memory.UserID, changeType, changedBy, memory.Content, memory.Category, memory.Importance,
+		memory.SourceConversationID, memory.SourceMessageID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record memory version: %w", err)
+	}
+	return nil
+}
+
+// ListByMemory retrieves a user's memory's versions, newest first
+func (r *MemoryVersionRepository) ListByMemory(ctx context.Context, userID, memoryID uuid.UUID) ([]*model.MemoryVersion, error) {
+	query := `
+		SELECT ` + memoryVersionColumns + `
+		FROM memory_versions
+		WHERE memory_id = $1 AND user_id = $2
+		ORDER BY version DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, memoryID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list memory versions: %w", err)
+	}
+	defer rows.Close()
+
+	var versions []*model.MemoryVersion
+	for rows.Next() {
+		version, err := scanMemoryVersion(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan memory version: %w", err)
+		}
+		versions = append(versions, version)
+	}
+
+	return versions, rows.Err()
+}
+
+// Get retrieves one version of a user's memory
+func (r *MemoryVersionRepository) Get(ctx context.Context, userID, memoryID uuid.UUID, version int) (*model.MemoryVersion, error) {
+	query := `
+		SELECT ` + memoryVersionColumns + `
+		FROM memory_versions
+		WHERE memory_id = $1 AND user_id = $2 AND version = $3
+	`
+
+	v, err := scanMemoryVersion(r.db.QueryRowContext(ctx, query, memoryID, userID, version))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("memory version not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get memory version: %w", err)
+	}
+	return v, nil
+}
+
+// DeleteBefore deletes history recorded before the given time that is no
+// longer needed: versions superseded by a later one, and every version of a
+// memory deleted before then, which can then no longer be restored. The
+// current state of existing memories is always kept.
+func (r *MemoryVersionRepository) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	query := `
+		DELETE FROM memory_versions v
+		WHERE v.created_at < $1
+			AND (v.change_type = 'delete'
+				OR v.version < (SELECT MAX(w.version) FROM memory_versions w WHERE w.memory_id = v.memory_id))
+	`
+
+	result, err := r.db.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete memory versions: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// scanMemoryVersion scans a row selected with memoryVersionColumns
+func scanMemoryVersion(row interface{ Scan(...interface{}) error }) (*model.MemoryVersion, error) {
+	v := &model.MemoryVersion{}
+	err := row.Scan(
+		&v.ID, &v.MemoryID, &v.UserID, &v.Version, &v.ChangeType, &v.ChangedBy, &v.Content, &v.Category, &v.Importance,
+		&v.SourceConversationID, &v.SourceMessageID, &v.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
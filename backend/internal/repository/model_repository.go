@@ -21,25 +21,36 @@ func NewAIModelRepository(db *sql.DB) *AIModelRepository {
 
 // Create creates a new AI model
 func (r *AIModelRepository) Create(ctx context.Context, aiModel *model.AIModel) error {
+	fallbacksJSON, err := marshalFallbacks(aiModel.FallbackModelIDs)
+	if err != nil {
+		return err
+	}
+	disabledToolsJSON, err := marshalDisabledTools(aiModel.DisabledTools)
+	if err != nil {
+		return err
+	}
+
 	query := `
 		INSERT INTO ai_models (
 			id, name, display_name, provider,
 			api_endpoint, api_key_encrypted, model_identifier,
-			provider_id, supports_streaming, supports_functions, max_tokens,
+			provider_id, supports_streaming, supports_functions, supports_vision, max_tokens,
 			input_price_per_1k, output_price_per_1k,
-			is_active, is_default, description, created_by
-		) VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+			is_active, is_default, description, created_by, fallback_model_ids, tokenizer,
+			disabled_tools, supports_embeddings
+		) VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22)
 		RETURNING created_at, updated_at
 	`
 
-	err := r.db.QueryRowContext(
+	err = r.db.QueryRowContext(
 		ctx, query,
 		aiModel.ID, aiModel.Name, aiModel.DisplayName, aiModel.Provider,
 		aiModel.APIEndpoint, aiModel.APIKeyEncrypted, aiModel.ModelIdentifier,
 		aiModel.ProviderID,
-		aiModel.SupportsStreaming, aiModel.SupportsFunctions, aiModel.MaxTokens,
+		aiModel.SupportsStreaming, aiModel.SupportsFunctions, aiModel.SupportsVision, aiModel.MaxTokens,
 		aiModel.InputPricePer1k, aiModel.OutputPricePer1k,
 		aiModel.IsActive, aiModel.IsDefault, aiModel.Description, aiModel.CreatedBy,
+		fallbacksJSON, aiModel.Tokenizer, disabledToolsJSON, aiModel.SupportsEmbeddings,
 	).Scan(&aiModel.CreatedAt, &aiModel.UpdatedAt)
 
 	if err != nil {
@@ -55,20 +66,23 @@ func (r *AIModelRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.A
 		SELECT id, name, display_name, provider,
 			COALESCE(api_endpoint, ''), COALESCE(api_key_encrypted, ''),
 			model_identifier, provider_id,
-			supports_streaming, supports_functions, max_tokens,
+			supports_streaming, supports_functions, supports_vision, max_tokens,
 			input_price_per_1k, output_price_per_1k,
-			is_active, is_default, description, created_by, created_at, updated_at
+			is_active, is_default, description, created_by, fallback_model_ids, tokenizer,
+			disabled_tools, supports_embeddings, created_at, updated_at
 		FROM ai_models WHERE id = $1
 	`
 
 	aiModel := &model.AIModel{}
+	var fallbacksJSON, disabledToolsJSON []byte
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
 		&aiModel.ID, &aiModel.Name, &aiModel.DisplayName, &aiModel.Provider,
 		&aiModel.APIEndpoint, &aiModel.APIKeyEncrypted,
 		&aiModel.ModelIdentifier, &aiModel.ProviderID,
-		&aiModel.SupportsStreaming, &aiModel.SupportsFunctions, &aiModel.MaxTokens,
+		&aiModel.SupportsStreaming, &aiModel.SupportsFunctions, &aiModel.SupportsVision, &aiModel.MaxTokens,
 		&aiModel.InputPricePer1k, &aiModel.OutputPricePer1k,
 		&aiModel.IsActive, &aiModel.IsDefault, &aiModel.Description, &aiModel.CreatedBy,
+		&fallbacksJSON, &aiModel.Tokenizer, &disabledToolsJSON, &aiModel.SupportsEmbeddings,
 		&aiModel.CreatedAt, &aiModel.UpdatedAt,
 	)
 
@@ -78,6 +92,12 @@ func (r *AIModelRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.A
 	if err != nil {
 		return nil, fmt.Errorf("failed to get AI model: %w", err)
 	}
+	if err := unmarshalFallbacks(fallbacksJSON, aiModel); err != nil {
+		return nil, err
+	}
+	if err := unmarshalDisabledTools(disabledToolsJSON, aiModel); err != nil {
+		return nil, err
+	}
 
 	return aiModel, nil
 }
@@ -88,20 +108,23 @@ func (r *AIModelRepository) GetDefault(ctx context.Context) (*model.AIModel, err
 		SELECT id, name, display_name, provider,
 			COALESCE(api_endpoint, ''), COALESCE(api_key_encrypted, ''),
 			model_identifier, provider_id,
-			supports_streaming, supports_functions, max_tokens,
+			supports_streaming, supports_functions, supports_vision, max_tokens,
 			input_price_per_1k, output_price_per_1k,
-			is_active, is_default, description, created_by, created_at, updated_at
+			is_active, is_default, description, created_by, fallback_model_ids, tokenizer,
+			disabled_tools, supports_embeddings, created_at, updated_at
 		FROM ai_models WHERE is_default = true AND is_active = true LIMIT 1
 	`
 
 	aiModel := &model.AIModel{}
+	var fallbacksJSON, disabledToolsJSON []byte
 	err := r.db.QueryRowContext(ctx, query).Scan(
 		&aiModel.ID, &aiModel.Name, &aiModel.DisplayName, &aiModel.Provider,
 		&aiModel.APIEndpoint, &aiModel.APIKeyEncrypted,
 		&aiModel.ModelIdentifier, &aiModel.ProviderID,
-		&aiModel.SupportsStreaming, &aiModel.SupportsFunctions, &aiModel.MaxTokens,
+		&aiModel.SupportsStreaming, &aiModel.SupportsFunctions, &aiModel.SupportsVision, &aiModel.MaxTokens,
 		&aiModel.InputPricePer1k, &aiModel.OutputPricePer1k,
 		&aiModel.IsActive, &aiModel.IsDefault, &aiModel.Description, &aiModel.CreatedBy,
+		&fallbacksJSON, &aiModel.Tokenizer, &disabledToolsJSON, &aiModel.SupportsEmbeddings,
 		&aiModel.CreatedAt, &aiModel.UpdatedAt,
 	)
 
@@ -111,6 +134,12 @@ func (r *AIModelRepository) GetDefault(ctx context.Context) (*model.AIModel, err
 	if err != nil {
 		return nil, fmt.Errorf("failed to get default AI model: %w", err)
 	}
+	if err := unmarshalFallbacks(fallbacksJSON, aiModel); err != nil {
+		return nil, err
+	}
+	if err := unmarshalDisabledTools(disabledToolsJSON, aiModel); err != nil {
+		return nil, err
+	}
 
 	return aiModel, nil
 }
@@ -121,9 +150,10 @@ func (r *AIModelRepository) List(ctx context.Context, activeOnly bool) ([]*model
 		SELECT id, name, display_name, provider,
 			COALESCE(api_endpoint, ''), COALESCE(api_key_encrypted, ''),
 			model_identifier, provider_id,
-			supports_streaming, supports_functions, max_tokens,
+			supports_streaming, supports_functions, supports_vision, max_tokens,
 			input_price_per_1k, output_price_per_1k,
-			is_active, is_default, description, created_by, created_at, updated_at
+			is_active, is_default, description, created_by, fallback_model_ids, tokenizer,
+			disabled_tools, supports_embeddings, created_at, updated_at
 		FROM ai_models
 	`
 
@@ -142,18 +172,26 @@ func (r *AIModelRepository) List(ctx context.Context, activeOnly bool) ([]*model
 	var models []*model.AIModel
 	for rows.Next() {
 		aiModel := &model.AIModel{}
+		var fallbacksJSON, disabledToolsJSON []byte
 		err := rows.Scan(
 			&aiModel.ID, &aiModel.Name, &aiModel.DisplayName, &aiModel.Provider,
 			&aiModel.APIEndpoint, &aiModel.APIKeyEncrypted,
 			&aiModel.ModelIdentifier, &aiModel.ProviderID,
-			&aiModel.SupportsStreaming, &aiModel.SupportsFunctions, &aiModel.MaxTokens,
+			&aiModel.SupportsStreaming, &aiModel.SupportsFunctions, &aiModel.SupportsVision, &aiModel.MaxTokens,
 			&aiModel.InputPricePer1k, &aiModel.OutputPricePer1k,
 			&aiModel.IsActive, &aiModel.IsDefault, &aiModel.Description, &aiModel.CreatedBy,
+			&fallbacksJSON, &aiModel.Tokenizer, &disabledToolsJSON, &aiModel.SupportsEmbeddings,
 			&aiModel.CreatedAt, &aiModel.UpdatedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan AI model: %w", err)
 		}
+		if err := unmarshalFallbacks(fallbacksJSON, aiModel); err != nil {
+			return nil, err
+		}
+		if err := unmarshalDisabledTools(disabledToolsJSON, aiModel); err != nil {
+			return nil, err
+		}
 		models = append(models, aiModel)
 	}
 
@@ -166,9 +204,10 @@ func (r *AIModelRepository) ListByProvider(ctx context.Context, providerID uuid.
 		SELECT id, name, display_name, provider,
 			COALESCE(api_endpoint, ''), COALESCE(api_key_encrypted, ''),
 			model_identifier, provider_id,
-			supports_streaming, supports_functions, max_tokens,
+			supports_streaming, supports_functions, supports_vision, max_tokens,
 			input_price_per_1k, output_price_per_1k,
-			is_active, is_default, description, created_by, created_at, updated_at
+			is_active, is_default, description, created_by, fallback_model_ids, tokenizer,
+			disabled_tools, supports_embeddings, created_at, updated_at
 		FROM ai_models WHERE provider_id = $1
 		ORDER BY display_name ASC
 	`
@@ -182,18 +221,26 @@ func (r *AIModelRepository) ListByProvider(ctx context.Context, providerID uuid.
 	var models []*model.AIModel
 	for rows.Next() {
 		aiModel := &model.AIModel{}
+		var fallbacksJSON, disabledToolsJSON []byte
 		err := rows.Scan(
 			&aiModel.ID, &aiModel.Name, &aiModel.DisplayName, &aiModel.Provider,
 			&aiModel.APIEndpoint, &aiModel.APIKeyEncrypted,
 			&aiModel.ModelIdentifier, &aiModel.ProviderID,
-			&aiModel.SupportsStreaming, &aiModel.SupportsFunctions, &aiModel.MaxTokens,
+			&aiModel.SupportsStreaming, &aiModel.SupportsFunctions, &aiModel.SupportsVision, &aiModel.MaxTokens,
 			&aiModel.InputPricePer1k, &aiModel.OutputPricePer1k,
 			&aiModel.IsActive, &aiModel.IsDefault, &aiModel.Description, &aiModel.CreatedBy,
+			&fallbacksJSON, &aiModel.Tokenizer, &disabledToolsJSON, &aiModel.SupportsEmbeddings,
 			&aiModel.CreatedAt, &aiModel.UpdatedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan AI model: %w", err)
 		}
+		if err := unmarshalFallbacks(fallbacksJSON, aiModel); err != nil {
+			return nil, err
+		}
+		if err := unmarshalDisabledTools(disabledToolsJSON, aiModel); err != nil {
+			return nil, err
+		}
 		models = append(models, aiModel)
 	}
 
@@ -202,6 +249,15 @@ func (r *AIModelRepository) ListByProvider(ctx context.Context, providerID uuid.
 
 // Update updates an AI model
 func (r *AIModelRepository) Update(ctx context.Context, aiModel *model.AIModel) error {
+	fallbacksJSON, err := marshalFallbacks(aiModel.FallbackModelIDs)
+	if err != nil {
+		return err
+	}
+	disabledToolsJSON, err := marshalDisabledTools(aiModel.DisabledTools)
+	if err != nil {
+		return err
+	}
+
 	query := `
 		UPDATE ai_models SET
 			display_name = $2,
@@ -214,17 +270,23 @@ func (r *AIModelRepository) Update(ctx context.Context, aiModel *model.AIModel)
 			input_price_per_1k = $9,
 			output_price_per_1k = $10,
 			is_active = $11,
-			description = $12
+			description = $12,
+			fallback_model_ids = $13,
+			tokenizer = $14,
+			disabled_tools = $15,
+			supports_vision = $16,
+			supports_embeddings = $17
 		WHERE id = $1
 	`
 
-	_, err := r.db.ExecContext(
+	_, err = r.db.ExecContext(
 		ctx, query,
 		aiModel.ID, aiModel.DisplayName, aiModel.APIEndpoint, aiModel.APIKeyEncrypted,
 		aiModel.ProviderID,
 		aiModel.SupportsStreaming, aiModel.SupportsFunctions, aiModel.MaxTokens,
 		aiModel.InputPricePer1k, aiModel.OutputPricePer1k,
-		aiModel.IsActive, aiModel.Description,
+		aiModel.IsActive, aiModel.Description, fallbacksJSON, aiModel.Tokenizer,
+		disabledToolsJSON, aiModel.SupportsVision, aiModel.SupportsEmbeddings,
 	)
 
 	if err != nil {
@@ -264,6 +326,52 @@ func (r *AIModelRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
+// marshalFallbacks encodes the ordered fallback model IDs for storage
+func marshalFallbacks(ids []uuid.UUID) ([]byte, error) {
+	if ids == nil {
+		ids = []uuid.UUID{}
+	}
+	data, err := json.Marshal(ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal fallback models: %w", err)
+	}
+	return data, nil
+}
+
+// unmarshalFallbacks decodes the ordered fallback model IDs into the model
+func unmarshalFallbacks(data []byte, aiModel *model.AIModel) error {
+	if len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, &aiModel.FallbackModelIDs); err != nil {
+		return fmt.Errorf("failed to unmarshal fallback models: %w", err)
+	}
+	return nil
+}
+
+// marshalDisabledTools encodes the names of tools turned off for a model
+func marshalDisabledTools(names []string) ([]byte, error) {
+	if names == nil {
+		names = []string{}
+	}
+	data, err := json.Marshal(names)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal disabled tools: %w", err)
+	}
+	return data, nil
+}
+
+// unmarshalDisabledTools decodes the names of tools turned off for a model
+func unmarshalDisabledTools(data []byte, aiModel *model.AIModel) error {
+	if len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, &aiModel.DisabledTools); err != nil {
+		return fmt.Errorf("failed to unmarshal disabled tools: %w", err)
+	}
+	return nil
+}
+
 // UserSettingsRepository handles user settings data access
 type UserSettingsRepository struct {
 	db *sql.DB
@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/ai-chat/backend/internal/model"
+)
+
+// ProviderAPIKeyRepository handles provider key pool data access
+type ProviderAPIKeyRepository struct {
+	db *sql.DB
+}
+
+// NewProviderAPIKeyRepository creates a new provider API key repository
+func NewProviderAPIKeyRepository(db *sql.DB) *ProviderAPIKeyRepository {
+	return &ProviderAPIKeyRepository{db: db}
+}
+
+// Create adds a key to a provider's pool
+func (r *ProviderAPIKeyRepository) Create(ctx context.Context, key *model.ProviderAPIKey) error {
+	query := `
+		INSERT INTO provider_api_keys (
+			id, provider_id, name, api_key_encrypted, key_hint, is_active, created_by
+		) VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING created_at, updated_at
+	`
+
+	err := r.db.QueryRowContext(
+		ctx, query,
+		key.ID, key.ProviderID, key.Name, key.APIKeyEncrypted, key.KeyHint, key.IsActive, key.CreatedBy,
+	).Scan(&key.CreatedAt, &key.UpdatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to create provider API key: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID retrieves a pooled key by ID
+func (r *ProviderAPIKeyRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.ProviderAPIKey, error) {
+	query := `
+		SELECT id, provider_id, name, api_key_encrypted, key_hint, is_active,
+			disabled_reason, disabled_at, request_count, input_tokens, output_tokens,
+			last_used_at, created_by, created_at, updated_at
+		FROM provider_api_keys WHERE id = $1
+	`
+
+	k := &model.ProviderAPIKey{}
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&k.ID, &k.ProviderID, &k.Name, &k.APIKeyEncrypted, &k.KeyHint, &k.IsActive,
+		&k.DisabledReason, &k.DisabledAt, &k.RequestCount, &k.InputTokens, &k.OutputTokens,
+		&k.LastUsedAt, &k.CreatedBy, &k.CreatedAt, &k.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("provider API key not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get provider API key: %w", err)
+	}
+
+	return k, nil
+}
+
+// ListByProvider retrieves the keys of a provider, oldest first
+func (r *ProviderAPIKeyRepository) ListByProvider(ctx context.Context, providerID uuid.UUID, activeOnly bool) ([]*model.ProviderAPIKey, error) {
+	query := `
+		SELECT id, provider_id, name, api_key_encrypted, key_hint, is_active,
+			disabled_reason, disabled_at, request_count, input_tokens, output_tokens,
+			last_used_at, created_by, created_at, updated_at
+		FROM provider_api_keys WHERE provider_id = $1
+	`
+
+	if activeOnly {
+		query += " AND is_active = true"
+	}
+
+	query += " ORDER BY created_at ASC, id ASC"
+
+	rows, err := r.db.QueryContext(ctx, query, providerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list provider API keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []*model.ProviderAPIKey
+	for rows.Next() {
+		k := &model.ProviderAPIKey{}
+		err := rows.Scan(
+			&k.ID, &k.ProviderID, &k.Name, &k.APIKeyEncrypted, &k.KeyHint, &k.IsActive,
+			&k.DisabledReason, &k.DisabledAt, &k.RequestCount, &k.InputTokens, &k.OutputTokens,
+			&k.LastUsedAt, &k.CreatedBy, &k.CreatedAt, &k.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan provider API key: %w", err)
+		}
+		keys = append(keys, k)
+	}
+
+	return keys, nil
+}
+
+// Update updates a pooled key's name, secret and status
+func (r *ProviderAPIKeyRepository) Update(ctx context.Context, key *model.ProviderAPIKey) error {
+	query := `
+		UPDATE provider_api_keys SET
+			name = $2,
+			api_key_encrypted = $3,
+			key_hint = $4,
+			is_active = $5,
+			disabled_reason = $6,
+			disabled_at = $7,
+			updated_at = NOW()
+		WHERE id = $1
+	`
+
+	_, err := r.db.ExecContext(
+		ctx, query,
+		key.ID, key.Name, key.APIKeyEncrypted, key.KeyHint, key.IsActive,
+		key.DisabledReason, key.DisabledAt,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to update provider API key: %w", err)
+	}
+
+	return nil
+}
+
+// Disable takes a key out of rotation, recording why
+func (r *ProviderAPIKeyRepository) Disable(ctx context.Context, id uuid.UUID, reason string) error {
+	query := `
+		UPDATE provider_api_keys SET
+			is_active = false,
+			disabled_reason = $2,
+			disabled_at = NOW(),
+			updated_at = NOW()
+		WHERE id = $1 AND is_active = true
+	`
+
+	_, err := r.db.ExecContext(ctx, query, id, reason)
+	if err != nil {
+		return fmt.Errorf("failed to disable provider API key: %w", err)
+	}
+
+	return nil
+}
+
+// RecordUsage adds requests and tokens to a key's usage counters
+func (r *ProviderAPIKeyRepository) RecordUsage(ctx context.Context, id uuid.UUID, requests, inputTokens, outputTokens int) error {
+	query := `
+		UPDATE provider_api_keys SET
+			request_count = request_count + $2,
+			input_tokens = input_tokens + $3,
+			output_tokens = output_tokens + $4,
+			last_used_at = CASE WHEN $2 > 0 THEN NOW() ELSE last_used_at END
+		WHERE id = $1
+	`
+
+	_, err := r.db.ExecContext(ctx, query, id, requests, inputTokens, outputTokens)
+	return err
+}
+
+// Delete removes a key from a provider's pool
+func (r *ProviderAPIKeyRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	query := `DELETE FROM provider_api_keys WHERE id = $1`
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
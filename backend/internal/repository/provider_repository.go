@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 
 	"github.com/google/uuid"
@@ -21,19 +22,31 @@ func NewAIProviderRepository(db *sql.DB) *AIProviderRepository {
 
 // Create creates a new AI provider
 func (r *AIProviderRepository) Create(ctx context.Context, provider *model.AIProvider) error {
+	deploymentsJSON, err := marshalDeployments(provider.AzureDeployments)
+	if err != nil {
+		return err
+	}
+	retryJSON, err := json.Marshal(provider.RetryPolicy)
+	if err != nil {
+		return fmt.Errorf("failed to marshal retry policy: %w", err)
+	}
+
 	query := `
 		INSERT INTO ai_providers (
 			id, name, display_name, provider_type, api_endpoint, api_key_encrypted,
-			is_active, description, created_by
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			is_active, description, azure_resource, azure_api_version, azure_deployments,
+			retry_policy, key_selection, created_by
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
 		RETURNING created_at, updated_at
 	`
 
-	err := r.db.QueryRowContext(
+	err = r.db.QueryRowContext(
 		ctx, query,
 		provider.ID, provider.Name, provider.DisplayName, provider.ProviderType,
 		provider.APIEndpoint, provider.APIKeyEncrypted,
-		provider.IsActive, provider.Description, provider.CreatedBy,
+		provider.IsActive, provider.Description,
+		provider.AzureResource, provider.AzureAPIVersion, deploymentsJSON,
+		retryJSON, provider.KeySelection, provider.CreatedBy,
 	).Scan(&provider.CreatedAt, &provider.UpdatedAt)
 
 	if err != nil {
@@ -47,14 +60,17 @@ func (r *AIProviderRepository) Create(ctx context.Context, provider *model.AIPro
 func (r *AIProviderRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.AIProvider, error) {
 	query := `
 		SELECT id, name, display_name, provider_type, api_endpoint, api_key_encrypted,
-			is_active, description, created_by, created_at, updated_at
+			is_active, description, azure_resource, azure_api_version, azure_deployments,
+			retry_policy, key_selection, created_by, created_at, updated_at
 		FROM ai_providers WHERE id = $1
 	`
 
 	p := &model.AIProvider{}
+	var deploymentsJSON, retryJSON []byte
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
 		&p.ID, &p.Name, &p.DisplayName, &p.ProviderType, &p.APIEndpoint, &p.APIKeyEncrypted,
-		&p.IsActive, &p.Description, &p.CreatedBy, &p.CreatedAt, &p.UpdatedAt,
+		&p.IsActive, &p.Description, &p.AzureResource, &p.AzureAPIVersion, &deploymentsJSON,
+		&retryJSON, &p.KeySelection, &p.CreatedBy, &p.CreatedAt, &p.UpdatedAt,
 	)
 
 	if err == sql.ErrNoRows {
@@ -64,6 +80,13 @@ func (r *AIProviderRepository) GetByID(ctx context.Context, id uuid.UUID) (*mode
 		return nil, fmt.Errorf("failed to get AI provider: %w", err)
 	}
 
+	if err := unmarshalDeployments(deploymentsJSON, p); err != nil {
+		return nil, err
+	}
+	if err := unmarshalRetryPolicy(retryJSON, p); err != nil {
+		return nil, err
+	}
+
 	return p, nil
 }
 
@@ -71,7 +94,8 @@ func (r *AIProviderRepository) GetByID(ctx context.Context, id uuid.UUID) (*mode
 func (r *AIProviderRepository) List(ctx context.Context, activeOnly bool) ([]*model.AIProvider, error) {
 	query := `
 		SELECT p.id, p.name, p.display_name, p.provider_type, p.api_endpoint, p.api_key_encrypted,
-			p.is_active, p.description, p.created_by, p.created_at, p.updated_at,
+			p.is_active, p.description, p.azure_resource, p.azure_api_version, p.azure_deployments,
+			p.retry_policy, p.key_selection, p.created_by, p.created_at, p.updated_at,
 			COUNT(m.id) AS model_count
 		FROM ai_providers p
 		LEFT JOIN ai_models m ON m.provider_id = p.id
@@ -92,14 +116,22 @@ func (r *AIProviderRepository) List(ctx context.Context, activeOnly bool) ([]*mo
 	var providers []*model.AIProvider
 	for rows.Next() {
 		p := &model.AIProvider{}
+		var deploymentsJSON, retryJSON []byte
 		err := rows.Scan(
 			&p.ID, &p.Name, &p.DisplayName, &p.ProviderType, &p.APIEndpoint, &p.APIKeyEncrypted,
-			&p.IsActive, &p.Description, &p.CreatedBy, &p.CreatedAt, &p.UpdatedAt,
+			&p.IsActive, &p.Description, &p.AzureResource, &p.AzureAPIVersion, &deploymentsJSON,
+			&retryJSON, &p.KeySelection, &p.CreatedBy, &p.CreatedAt, &p.UpdatedAt,
 			&p.ModelCount,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan AI provider: %w", err)
 		}
+		if err := unmarshalDeployments(deploymentsJSON, p); err != nil {
+			return nil, err
+		}
+		if err := unmarshalRetryPolicy(retryJSON, p); err != nil {
+			return nil, err
+		}
 		providers = append(providers, p)
 	}
 
@@ -108,6 +140,15 @@ func (r *AIProviderRepository) List(ctx context.Context, activeOnly bool) ([]*mo
 
 // Update updates an AI provider
 func (r *AIProviderRepository) Update(ctx context.Context, provider *model.AIProvider) error {
+	deploymentsJSON, err := marshalDeployments(provider.AzureDeployments)
+	if err != nil {
+		return err
+	}
+	retryJSON, err := json.Marshal(provider.RetryPolicy)
+	if err != nil {
+		return fmt.Errorf("failed to marshal retry policy: %w", err)
+	}
+
 	query := `
 		UPDATE ai_providers SET
 			display_name = $2,
@@ -116,14 +157,21 @@ func (r *AIProviderRepository) Update(ctx context.Context, provider *model.AIPro
 			api_key_encrypted = $5,
 			is_active = $6,
 			description = $7,
+			azure_resource = $8,
+			azure_api_version = $9,
+			azure_deployments = $10,
+			retry_policy = $11,
+			key_selection = $12,
 			updated_at = NOW()
 		WHERE id = $1
 	`
 
-	_, err := r.db.ExecContext(
+	_, err = r.db.ExecContext(
 		ctx, query,
 		provider.ID, provider.DisplayName, provider.ProviderType, provider.APIEndpoint,
 		provider.APIKeyEncrypted, provider.IsActive, provider.Description,
+		provider.AzureResource, provider.AzureAPIVersion, deploymentsJSON,
+		retryJSON, provider.KeySelection,
 	)
 
 	if err != nil {
@@ -148,3 +196,37 @@ func (r *AIProviderRepository) CountModels(ctx context.Context, providerID uuid.
 	).Scan(&count)
 	return count, err
 }
+
+// marshalDeployments encodes the Azure deployment mapping for the JSONB column
+func marshalDeployments(deployments map[string]string) ([]byte, error) {
+	if deployments == nil {
+		deployments = map[string]string{}
+	}
+	data, err := json.Marshal(deployments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal azure deployments: %w", err)
+	}
+	return data, nil
+}
+
+// unmarshalDeployments decodes the Azure deployment mapping into the provider
+func unmarshalDeployments(data []byte, provider *model.AIProvider) error {
+	if len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, &provider.AzureDeployments); err != nil {
+		return fmt.Errorf("failed to unmarshal azure deployments: %w", err)
+	}
+	return nil
+}
+
+// unmarshalRetryPolicy decodes the retry policy into the provider
+func unmarshalRetryPolicy(data []byte, provider *model.AIProvider) error {
+	if len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, &provider.RetryPolicy); err != nil {
+		return fmt.Errorf("failed to unmarshal retry policy: %w", err)
+	}
+	return nil
+}
@@ -8,6 +8,8 @@ import (
 	"github.com/google/uuid"
 	"github.com/ai-chat/backend/internal/model"
 	"github.com/ai-chat/backend/internal/pkg/crypto"
+	"github.com/ai-chat/backend/internal/pkg/tokenizer"
+	"github.com/ai-chat/backend/internal/pkg/tools"
 	"github.com/ai-chat/backend/internal/repository"
 )
 
@@ -16,10 +18,13 @@ type AdminService struct {
 	userRepo         *repository.UserRepository
 	modelRepo        *repository.AIModelRepository
 	providerRepo     *repository.AIProviderRepository
+	providerKeyRepo  *repository.ProviderAPIKeyRepository
 	auditRepo        *repository.AuditLogRepository
 	tokenUsageRepo   *repository.TokenUsageRepository
 	conversationRepo *repository.ConversationRepository
 	messageRepo      *repository.MessageRepository
+	aiProxyService   *AIProxyService
+	toolRegistry     *tools.Registry
 	encryptionKey    string
 	startTime        time.Time // Track server start time
 }
@@ -29,20 +34,26 @@ func NewAdminService(
 	userRepo *repository.UserRepository,
 	modelRepo *repository.AIModelRepository,
 	providerRepo *repository.AIProviderRepository,
+	providerKeyRepo *repository.ProviderAPIKeyRepository,
 	auditRepo *repository.AuditLogRepository,
 	tokenUsageRepo *repository.TokenUsageRepository,
 	conversationRepo *repository.ConversationRepository,
 	messageRepo *repository.MessageRepository,
+	aiProxyService *AIProxyService,
+	toolRegistry *tools.Registry,
 	encryptionKey string,
 ) *AdminService {
 	return &AdminService{
 		userRepo:         userRepo,
 		modelRepo:        modelRepo,
 		providerRepo:     providerRepo,
+		providerKeyRepo:  providerKeyRepo,
 		auditRepo:        auditRepo,
 		tokenUsageRepo:   tokenUsageRepo,
 		conversationRepo: conversationRepo,
 		messageRepo:      messageRepo,
+		aiProxyService:   aiProxyService,
+		toolRegistry:     toolRegistry,
 		encryptionKey:    encryptionKey,
 		startTime:        time.Now(),
 	}
@@ -204,24 +215,37 @@ func (s *AdminService) CreateAIModel(ctx context.Context, adminUserID uuid.UUID,
 		}
 	}
 
+	modelID := uuid.New()
+	if err := s.validateFallbackModels(ctx, modelID, req.FallbackModelIDs); err != nil {
+		return nil, err
+	}
+	if err := s.validateToolNames(req.DisabledTools); err != nil {
+		return nil, err
+	}
+
 	aiModel := &model.AIModel{
-		ID:                uuid.New(),
-		Name:              req.Name,
-		DisplayName:       req.DisplayName,
-		Provider:          req.Provider,
-		APIEndpoint:       req.APIEndpoint,
-		APIKeyEncrypted:   encryptedKey,
-		ModelIdentifier:   req.ModelIdentifier,
-		ProviderID:        req.ProviderID,
-		SupportsStreaming: req.SupportsStreaming,
-		SupportsFunctions: req.SupportsFunctions,
-		MaxTokens:         req.MaxTokens,
-		InputPricePer1k:   req.InputPricePer1k,
-		OutputPricePer1k:  req.OutputPricePer1k,
-		IsActive:          true,
-		IsDefault:         false,
-		Description:       req.Description,
-		CreatedBy:         &adminUserID,
+		ID:                 modelID,
+		Name:               req.Name,
+		DisplayName:        req.DisplayName,
+		Provider:           req.Provider,
+		APIEndpoint:        req.APIEndpoint,
+		APIKeyEncrypted:    encryptedKey,
+		ModelIdentifier:    req.ModelIdentifier,
+		ProviderID:         req.ProviderID,
+		SupportsStreaming:  req.SupportsStreaming,
+		SupportsFunctions:  req.SupportsFunctions,
+		SupportsVision:     req.SupportsVision,
+		SupportsEmbeddings: req.SupportsEmbeddings,
+		MaxTokens:          req.MaxTokens,
+		InputPricePer1k:    req.InputPricePer1k,
+		OutputPricePer1k:   req.OutputPricePer1k,
+		IsActive:           true,
+		IsDefault:          false,
+		Description:        req.Description,
+		FallbackModelIDs:   req.FallbackModelIDs,
+		Tokenizer:          req.Tokenizer,
+		DisabledTools:      req.DisabledTools,
+		CreatedBy:          &adminUserID,
 	}
 
 	if err := s.modelRepo.Create(ctx, aiModel); err != nil {
@@ -258,6 +282,12 @@ func (s *AdminService) UpdateAIModel(ctx context.Context, modelID uuid.UUID, req
 	if req.SupportsFunctions != nil {
 		aiModel.SupportsFunctions = *req.SupportsFunctions
 	}
+	if req.SupportsVision != nil {
+		aiModel.SupportsVision = *req.SupportsVision
+	}
+	if req.SupportsEmbeddings != nil {
+		aiModel.SupportsEmbeddings = *req.SupportsEmbeddings
+	}
 	if req.MaxTokens != nil {
 		aiModel.MaxTokens = *req.MaxTokens
 	}
@@ -273,6 +303,26 @@ func (s *AdminService) UpdateAIModel(ctx context.Context, modelID uuid.UUID, req
 	if req.IsActive != nil {
 		aiModel.IsActive = *req.IsActive
 	}
+	if req.FallbackModelIDs != nil {
+		if err := s.validateFallbackModels(ctx, modelID, *req.FallbackModelIDs); err != nil {
+			return nil, err
+		}
+		aiModel.FallbackModelIDs = *req.FallbackModelIDs
+	}
+	if req.Tokenizer != nil {
+		switch *req.Tokenizer {
+		case "", tokenizer.Cl100kBase, tokenizer.O200kBase, tokenizer.Heuristic:
+			aiModel.Tokenizer = *req.Tokenizer
+		default:
+			return nil, fmt.Errorf("unsupported tokenizer: %s", *req.Tokenizer)
+		}
+	}
+	if req.DisabledTools != nil {
+		if err := s.validateToolNames(*req.DisabledTools); err != nil {
+			return nil, err
+		}
+		aiModel.DisabledTools = *req.DisabledTools
+	}
 
 	if err := s.modelRepo.Update(ctx, aiModel); err != nil {
 		return nil, fmt.Errorf("failed to update AI model: %w", err)
@@ -281,6 +331,41 @@ func (s *AdminService) UpdateAIModel(ctx context.Context, modelID uuid.UUID, req
 	return aiModel, nil
 }
 
+// ListTools returns the server-side tools that can be enabled per model
+func (s *AdminService) ListTools() []model.Tool {
+	return s.toolRegistry.Definitions(nil)
+}
+
+// validateToolNames checks that every name refers to a registered tool
+func (s *AdminService) validateToolNames(names []string) error {
+	for _, name := range names {
+		if _, ok := s.toolRegistry.Get(name); !ok {
+			return fmt.Errorf("unknown tool: %s", name)
+		}
+	}
+	return nil
+}
+
+// validateFallbackModels checks that a failover chain only references other,
+// existing models and lists each at most once
+func (s *AdminService) validateFallbackModels(ctx context.Context, modelID uuid.UUID, fallbackIDs []uuid.UUID) error {
+	seen := make(map[uuid.UUID]bool, len(fallbackIDs))
+	for _, id := range fallbackIDs {
+		if id == modelID {
+			return fmt.Errorf("a model cannot fall back to itself")
+		}
+		if seen[id] {
+			return fmt.Errorf("fallback model %s is listed more than once", id)
+		}
+		seen[id] = true
+
+		if _, err := s.modelRepo.GetByID(ctx, id); err != nil {
+			return fmt.Errorf("fallback model %s not found", id)
+		}
+	}
+	return nil
+}
+
 // DeleteAIModel deletes an AI model
 func (s *AdminService) DeleteAIModel(ctx context.Context, modelID uuid.UUID) error {
 	return s.modelRepo.Delete(ctx, modelID)
@@ -295,14 +380,36 @@ func (s *AdminService) SetDefaultModel(ctx context.Context, modelID uuid.UUID) e
 
 // ListProviders lists all AI providers
 func (s *AdminService) ListProviders(ctx context.Context, activeOnly bool) ([]*model.AIProvider, error) {
-	return s.providerRepo.List(ctx, activeOnly)
+	providers, err := s.providerRepo.List(ctx, activeOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range providers {
+		p.Health = s.aiProxyService.ProviderHealth(p.ID)
+	}
+
+	return providers, nil
 }
 
 // CreateProvider creates a new AI provider
 func (s *AdminService) CreateProvider(ctx context.Context, adminUserID uuid.UUID, req *model.AIProviderCreateRequest) (*model.AIProvider, error) {
-	encryptedKey, err := crypto.Encrypt(req.APIKey, s.encryptionKey)
-	if err != nil {
-		return nil, fmt.Errorf("failed to encrypt API key: %w", err)
+	if req.APIEndpoint == "" && !(req.ProviderType == model.ProviderTypeAzure && req.AzureResource != "") {
+		return nil, fmt.Errorf("api_endpoint is required unless an Azure resource is specified")
+	}
+
+	// Local runtimes need no key; every other provider type does
+	var encryptedKey string
+	if req.APIKey == "" {
+		if req.ProviderType != model.ProviderTypeLocal {
+			return nil, fmt.Errorf("api_key is required for provider type %s", req.ProviderType)
+		}
+	} else {
+		var err error
+		encryptedKey, err = crypto.Encrypt(req.APIKey, s.encryptionKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt API key: %w", err)
+		}
 	}
 
 	provider := &model.AIProvider{
@@ -315,6 +422,17 @@ func (s *AdminService) CreateProvider(ctx context.Context, adminUserID uuid.UUID
 		IsActive:        true,
 		Description:     req.Description,
 		CreatedBy:       &adminUserID,
+
+		AzureResource:    req.AzureResource,
+		AzureAPIVersion:  req.AzureAPIVersion,
+		AzureDeployments: req.AzureDeployments,
+	}
+	if req.RetryPolicy != nil {
+		provider.RetryPolicy = *req.RetryPolicy
+	}
+	provider.KeySelection = req.KeySelection
+	if provider.KeySelection == "" {
+		provider.KeySelection = model.KeySelectionRoundRobin
 	}
 
 	if err := s.providerRepo.Create(ctx, provider); err != nil {
@@ -347,12 +465,31 @@ func (s *AdminService) UpdateProvider(ctx context.Context, providerID uuid.UUID,
 		}
 		provider.APIKeyEncrypted = encryptedKey
 	}
+	// Changing a keyless local runtime into a hosted type needs a key, as on create
+	if provider.APIKeyEncrypted == "" && provider.ProviderType != model.ProviderTypeLocal {
+		return nil, fmt.Errorf("api_key is required for provider type %s", provider.ProviderType)
+	}
 	if req.IsActive != nil {
 		provider.IsActive = *req.IsActive
 	}
 	if req.Description != nil {
 		provider.Description = *req.Description
 	}
+	if req.AzureResource != nil {
+		provider.AzureResource = *req.AzureResource
+	}
+	if req.AzureAPIVersion != nil {
+		provider.AzureAPIVersion = *req.AzureAPIVersion
+	}
+	if req.AzureDeployments != nil {
+		provider.AzureDeployments = req.AzureDeployments
+	}
+	if req.RetryPolicy != nil {
+		provider.RetryPolicy = *req.RetryPolicy
+	}
+	if req.KeySelection != nil {
+		provider.KeySelection = *req.KeySelection
+	}
 
 	if err := s.providerRepo.Update(ctx, provider); err != nil {
 		return nil, fmt.Errorf("failed to update provider: %w", err)
@@ -374,6 +511,193 @@ func (s *AdminService) DeleteProvider(ctx context.Context, providerID uuid.UUID)
 	return s.providerRepo.Delete(ctx, providerID)
 }
 
+// ListProviderKeys lists the keys in a provider's pool
+func (s *AdminService) ListProviderKeys(ctx context.Context, providerID uuid.UUID) ([]*model.ProviderAPIKey, error) {
+	if _, err := s.providerRepo.GetByID(ctx, providerID); err != nil {
+		return nil, fmt.Errorf("provider not found")
+	}
+	return s.providerKeyRepo.ListByProvider(ctx, providerID, false)
+}
+
+// CreateProviderKey adds an encrypted key to a provider's pool
+func (s *AdminService) CreateProviderKey(ctx context.Context, adminUserID, providerID uuid.UUID, req *model.ProviderAPIKeyCreateRequest) (*model.ProviderAPIKey, error) {
+	if _, err := s.providerRepo.GetByID(ctx, providerID); err != nil {
+		return nil, fmt.Errorf("provider not found")
+	}
+
+	encryptedKey, err := crypto.Encrypt(req.APIKey, s.encryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt API key: %w", err)
+	}
+
+	key := &model.ProviderAPIKey{
+		ID:              uuid.New(),
+		ProviderID:      providerID,
+		Name:            req.Name,
+		APIKeyEncrypted: encryptedKey,
+		KeyHint:         apiKeyHint(req.APIKey),
+		IsActive:        true,
+		CreatedBy:       &adminUserID,
+	}
+
+	if err := s.providerKeyRepo.Create(ctx, key); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// UpdateProviderKey renames, replaces, disables or re-enables a pooled key
+func (s *AdminService) UpdateProviderKey(ctx context.Context, providerID, keyID uuid.UUID, req *model.ProviderAPIKeyUpdateRequest) (*model.ProviderAPIKey, error) {
+	key, err := s.providerKeyRepo.GetByID(ctx, keyID)
+	if err != nil || key.ProviderID != providerID {
+		return nil, fmt.Errorf("provider API key not found")
+	}
+
+	if req.Name != nil {
+		key.Name = *req.Name
+	}
+	if req.APIKey != nil && *req.APIKey != "" {
+		encryptedKey, err := crypto.Encrypt(*req.APIKey, s.encryptionKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt API key: %w", err)
+		}
+		key.APIKeyEncrypted = encryptedKey
+		key.KeyHint = apiKeyHint(*req.APIKey)
+	}
+	if req.IsActive != nil && *req.IsActive != key.IsActive {
+		key.IsActive = *req.IsActive
+		if key.IsActive {
+			key.DisabledReason = ""
+			key.DisabledAt = nil
+		} else {
+			now := time.Now()
+			key.DisabledReason = "disabled by admin"
+			key.DisabledAt = &now
+		}
+	}
+
+	if err := s.providerKeyRepo.Update(ctx, key); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// DeleteProviderKey removes a key from a provider's pool
+func (s *AdminService) DeleteProviderKey(ctx context.Context, providerID, keyID uuid.UUID) error {
+	key, err := s.providerKeyRepo.GetByID(ctx, keyID)
+	if err != nil || key.ProviderID != providerID {
+		return fmt.Errorf("provider API key not found")
+	}
+	return s.providerKeyRepo.Delete(ctx, keyID)
+}
+
+// apiKeyHint returns the last characters of a key for display
+func apiKeyHint(apiKey string) string {
+	if len(apiKey) <= 8 {
+		return "****"
+	}
+	return "…" + apiKey[len(apiKey)-4:]
+}
+
+// DiscoverProviderModels lists the models a provider offers, flagging those already imported
+func (s *AdminService) DiscoverProviderModels(ctx context.Context, providerID uuid.UUID) ([]model.DiscoveredModel, error) {
+	provider, err := s.providerRepo.GetByID(ctx, providerID)
+	if err != nil {
+		return nil, fmt.Errorf("provider not found")
+	}
+
+	discovered, err := s.aiProxyService.ListProviderModels(ctx, provider)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.modelRepo.ListByProvider(ctx, providerID)
+	if err != nil {
+		return nil, err
+	}
+	imported := make(map[string]bool, len(existing))
+	for _, m := range existing {
+		imported[m.ModelIdentifier] = true
+	}
+
+	for i := range discovered {
+		discovered[i].AlreadyImported = imported[discovered[i].ModelIdentifier]
+	}
+
+	return discovered, nil
+}
+
+// ImportProviderModels creates AI models linked to a provider for the given
+// identifiers, skipping any that are already imported. Capabilities come only
+// from the provider's listing or the model catalog; models whose context
+// window is unknown are imported inactive until an admin confirms them.
+func (s *AdminService) ImportProviderModels(ctx context.Context, adminUserID, providerID uuid.UUID, identifiers []string) ([]*model.AIModel, error) {
+	provider, err := s.providerRepo.GetByID(ctx, providerID)
+	if err != nil {
+		return nil, fmt.Errorf("provider not found")
+	}
+
+	discovered, err := s.DiscoverProviderModels(ctx, providerID)
+	if err != nil {
+		return nil, err
+	}
+	available := make(map[string]model.DiscoveredModel, len(discovered))
+	for _, d := range discovered {
+		available[d.ModelIdentifier] = d
+	}
+
+	created := make([]*model.AIModel, 0, len(identifiers))
+	for _, identifier := range identifiers {
+		d, ok := available[identifier]
+		if !ok {
+			return created, fmt.Errorf("model %q is not offered by this provider", identifier)
+		}
+		if d.AlreadyImported {
+			continue
+		}
+
+		displayName := d.DisplayName
+		if displayName == "" {
+			displayName = identifier
+		}
+		// A placeholder limit keeps the row valid; the model stays off until
+		// an admin has set the real one
+		maxTokens, confirmed := d.MaxTokens, d.MaxTokens > 0
+		description := fmt.Sprintf("Imported from %s", provider.DisplayName)
+		if !confirmed {
+			maxTokens = 4096
+			description += "; confirm its context window and capabilities before enabling it"
+		}
+
+		aiModel := &model.AIModel{
+			ID:                uuid.New(),
+			Name:              identifier,
+			DisplayName:       displayName,
+			Provider:          provider.ProviderType,
+			ModelIdentifier:   identifier,
+			ProviderID:        &provider.ID,
+			SupportsStreaming: d.SupportsStreaming,
+			SupportsFunctions: d.SupportsFunctions,
+			MaxTokens:         maxTokens,
+			InputPricePer1k:   d.InputPricePer1k,
+			OutputPricePer1k:  d.OutputPricePer1k,
+			IsActive:          confirmed,
+			Description:       description,
+			CreatedBy:         &adminUserID,
+		}
+
+		if err := s.modelRepo.Create(ctx, aiModel); err != nil {
+			return created, fmt.Errorf("failed to create AI model %s: %w", identifier, err)
+		}
+		available[identifier] = model.DiscoveredModel{ModelIdentifier: identifier, AlreadyImported: true}
+		created = append(created, aiModel)
+	}
+
+	return created, nil
+}
+
 // GetTokenLeaderboard retrieves the token usage leaderboard
 func (s *AdminService) GetTokenLeaderboard(ctx context.Context, limit int) ([]*model.TokenLeaderboard, error) {
 	return s.tokenUsageRepo.GetLeaderboard(ctx, limit)
@@ -1,17 +1,29 @@
 package service
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
+	"math/rand"
+	"net"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/ai-chat/backend/internal/model"
+	"github.com/ai-chat/backend/internal/pkg/circuitbreaker"
 	"github.com/ai-chat/backend/internal/pkg/crypto"
+	"github.com/ai-chat/backend/internal/pkg/llm"
+	"github.com/ai-chat/backend/internal/pkg/tokenizer"
 	"github.com/ai-chat/backend/internal/repository"
 )
 
@@ -19,104 +31,626 @@ import (
 type AIProxyService struct {
 	modelRepo    *repository.AIModelRepository
 	providerRepo *repository.AIProviderRepository
+	keyRepo      *repository.ProviderAPIKeyRepository
 	encryptionKey string
 	httpClient   *http.Client
+	breakers     *circuitbreaker.Registry
+	keyCursors   sync.Map // provider ID -> *uint64, round-robin position in the key pool
+	keyCooldowns sync.Map // key ID -> time.Time, until when a rate-limited pooled key is rested
 }
 
 // NewAIProxyService creates a new AI proxy service
-func NewAIProxyService(modelRepo *repository.AIModelRepository, providerRepo *repository.AIProviderRepository, encryptionKey string) *AIProxyService {
+func NewAIProxyService(modelRepo *repository.AIModelRepository, providerRepo *repository.AIProviderRepository, keyRepo *repository.ProviderAPIKeyRepository, encryptionKey string) *AIProxyService {
 	return &AIProxyService{
 		modelRepo:    modelRepo,
 		providerRepo: providerRepo,
+		keyRepo:      keyRepo,
 		encryptionKey: encryptionKey,
+		// No overall client timeout: streams can legitimately run for minutes.
+		// Waiting for headers is bounded here, and whole non-streaming
+		// exchanges by the retry policy's total deadline.
 		httpClient: &http.Client{
-			Timeout: 60 * time.Second,
+			Transport: &http.Transport{
+				Proxy:                 http.ProxyFromEnvironment,
+				ResponseHeaderTimeout: 60 * time.Second,
+				TLSHandshakeTimeout:   10 * time.Second,
+				IdleConnTimeout:       90 * time.Second,
+				MaxIdleConnsPerHost:   10,
+			},
 		},
+		breakers: circuitbreaker.NewRegistry(circuitbreaker.DefaultConfig()),
 	}
 }
 
-// resolveCredentials returns the API endpoint and decrypted key for a model,
-// falling back to the linked provider if the model itself has no credentials.
-func (s *AIProxyService) resolveCredentials(ctx context.Context, aiModel *model.AIModel) (endpoint, apiKey string, err error) {
-	if aiModel.ProviderID != nil && (aiModel.APIEndpoint == "" || aiModel.APIKeyEncrypted == "") {
-		// Resolve from provider
-		provider, pErr := s.providerRepo.GetByID(ctx, *aiModel.ProviderID)
+// upstream is everything needed to call a model's provider
+type upstream struct {
+	target   *llm.Target
+	retry    model.RetryPolicy
+	breaker  *circuitbreaker.Breaker
+	provider *model.AIProvider
+	key      *model.ProviderAPIKey // pooled key in use, nil for a provider's or model's own key
+}
+
+// providerBreakerKey identifies a provider's circuit breaker
+func providerBreakerKey(providerID uuid.UUID) string {
+	return "provider:" + providerID.String()
+}
+
+// ProviderHealth reports the live circuit state, error rate and latency of a provider
+func (s *AIProxyService) ProviderHealth(providerID uuid.UUID) *model.ProviderHealth {
+	snap := s.breakers.Snapshot(providerBreakerKey(providerID))
+	return &model.ProviderHealth{
+		State:          string(snap.State),
+		RecentRequests: snap.RecentRequests,
+		ErrorRate:      snap.ErrorRate,
+		P95LatencyMs:   snap.P95LatencyMs,
+		OpenedAt:       snap.OpenedAt,
+	}
+}
+
+// resolveCredentials returns the upstream (provider type, endpoint and
+// decrypted key) for a model, falling back to the linked provider if the model
+// itself has no credentials. Retry policy and circuit breaker are per provider;
+// models without one get the default policy and a breaker of their own.
+func (s *AIProxyService) resolveCredentials(ctx context.Context, aiModel *model.AIModel) (*upstream, error) {
+	var provider *model.AIProvider
+	if aiModel.ProviderID != nil {
+		var pErr error
+		provider, pErr = s.providerRepo.GetByID(ctx, *aiModel.ProviderID)
 		if pErr != nil {
-			return "", "", fmt.Errorf("failed to get provider: %w", pErr)
+			return nil, fmt.Errorf("failed to get provider: %w", pErr)
 		}
-		decrypted, dErr := crypto.Decrypt(provider.APIKeyEncrypted, s.encryptionKey)
-		if dErr != nil {
-			return "", "", fmt.Errorf("failed to decrypt provider API key: %w", dErr)
+	}
+
+	up := &upstream{provider: provider}
+	if provider != nil {
+		up.retry = provider.RetryPolicy
+		up.breaker = s.breakers.Get(providerBreakerKey(provider.ID))
+	} else {
+		up.breaker = s.breakers.Get("model:" + aiModel.ID.String())
+	}
+
+	if provider != nil && (aiModel.APIEndpoint == "" || aiModel.APIKeyEncrypted == "") {
+		// Resolve from provider
+		target, key, err := s.providerTarget(ctx, provider)
+		if err != nil {
+			return nil, err
 		}
-		return provider.APIEndpoint, decrypted, nil
+		up.target = target
+		up.key = key
+		return up, nil
 	}
 
 	// Use model's own credentials
 	decrypted, dErr := crypto.Decrypt(aiModel.APIKeyEncrypted, s.encryptionKey)
 	if dErr != nil {
-		return "", "", fmt.Errorf("failed to decrypt API key: %w", dErr)
+		return nil, fmt.Errorf("failed to decrypt API key: %w", dErr)
+	}
+	up.target = &llm.Target{
+		ProviderType: aiModel.Provider,
+		Endpoint:     aiModel.APIEndpoint,
+		APIKey:       decrypted,
 	}
-	return aiModel.APIEndpoint, decrypted, nil
+	return up, nil
 }
 
-// SendChatCompletion sends a chat completion request to an AI model
-func (s *AIProxyService) SendChatCompletion(ctx context.Context, modelID uuid.UUID, request *model.ChatCompletionRequest) (*model.ChatCompletionResponse, error) {
-	// Get model configuration
-	aiModel, err := s.modelRepo.GetByID(ctx, modelID)
+// providerTarget builds the upstream target for a provider, taking a key from
+// its pool when it has active pooled keys and its own key otherwise. Providers
+// without any key (local runtimes) are called unauthenticated.
+func (s *AIProxyService) providerTarget(ctx context.Context, provider *model.AIProvider) (*llm.Target, *model.ProviderAPIKey, error) {
+	target := &llm.Target{
+		ProviderType: provider.ProviderType,
+		Endpoint:     provider.APIEndpoint,
+		Resource:     provider.AzureResource,
+		APIVersion:   provider.AzureAPIVersion,
+		Deployments:  provider.AzureDeployments,
+	}
+
+	key, err := s.pickKey(ctx, provider)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get model: %w", err)
+		return nil, nil, err
+	}
+	if key != nil {
+		decrypted, err := crypto.Decrypt(key.APIKeyEncrypted, s.encryptionKey)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to decrypt provider API key %s: %w", key.Name, err)
+		}
+		target.APIKey = decrypted
+		return target, key, nil
 	}
 
-	if !aiModel.IsActive {
-		return nil, fmt.Errorf("model is not active")
+	if provider.APIKeyEncrypted != "" {
+		decrypted, err := crypto.Decrypt(provider.APIKeyEncrypted, s.encryptionKey)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to decrypt provider API key: %w", err)
+		}
+		target.APIKey = decrypted
 	}
 
-	endpoint, apiKey, err := s.resolveCredentials(ctx, aiModel)
+	return target, nil, nil
+}
+
+// pickKey selects an active key from a provider's pool using the provider's
+// selection strategy, passing over rate-limited keys while others are
+// available. It returns nil when the pool is empty.
+func (s *AIProxyService) pickKey(ctx context.Context, provider *model.AIProvider) (*model.ProviderAPIKey, error) {
+	keys, err := s.keyRepo.ListByProvider(ctx, provider.ID, true)
 	if err != nil {
 		return nil, err
 	}
+	return s.chooseKey(provider, keys), nil
+}
 
-	// Prepare request
-	requestBody, err := json.Marshal(request)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+// chooseKey applies the provider's selection strategy to its active keys
+func (s *AIProxyService) chooseKey(provider *model.AIProvider, keys []*model.ProviderAPIKey) *model.ProviderAPIKey {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	rested := make([]*model.ProviderAPIKey, 0, len(keys))
+	for _, k := range keys {
+		if !s.coolingDown(k.ID) {
+			rested = append(rested, k)
+		}
+	}
+	if len(rested) > 0 {
+		keys = rested
+	}
+
+	if provider.KeySelection == model.KeySelectionLeastUsed {
+		least := keys[0]
+		for _, k := range keys[1:] {
+			if k.RequestCount < least.RequestCount {
+				least = k
+			}
+		}
+		return least
+	}
+
+	cursor, _ := s.keyCursors.LoadOrStore(provider.ID, new(uint64))
+	next := atomic.AddUint64(cursor.(*uint64), 1)
+	return keys[(next-1)%uint64(len(keys))]
+}
+
+// keyFailure is what an upstream error says about the key that was used
+type keyFailure int
+
+const (
+	keyFine        keyFailure = iota
+	keyRejected               // invalid, revoked or out of quota: disable the key
+	keyRateLimited            // throttled for now: rest the key for a while
+)
+
+// keyRejectedCodes are the error codes with which providers reject a key
+// itself: OpenAI (and Azure) error codes and types, Anthropic error types and
+// Gemini error reasons
+var keyRejectedCodes = map[string]bool{
+	"invalid_api_key":      true,
+	"insufficient_quota":   true,
+	"account_deactivated":  true,
+	"authentication_error": true,
+	"API_KEY_INVALID":      true,
+}
+
+// defaultKeyCooldown rests a rate-limited key whose upstream gave no Retry-After
+const defaultKeyCooldown = time.Minute
+
+// classifyKeyError decides from an upstream error whether the key should be
+// disabled or rested. Only authentication statuses and provider-specific
+// codes disable a key; a plain 429 is a temporary limit.
+func classifyKeyError(err error) keyFailure {
+	var upErr *upstreamError
+	if !errors.As(err, &upErr) {
+		return keyFine
+	}
+	switch upErr.StatusCode {
+	case http.StatusUnauthorized, http.StatusPaymentRequired:
+		return keyRejected
+	}
+	for _, code := range providerErrorCodes(upErr.Body) {
+		if keyRejectedCodes[code] {
+			return keyRejected
+		}
+	}
+	if upErr.StatusCode == http.StatusTooManyRequests {
+		return keyRateLimited
+	}
+	return keyFine
+}
+
+// providerErrorCodes extracts the machine-readable codes from an upstream
+// error body: error.code, error.type and error.status, plus Gemini's
+// error.details[].reason
+func providerErrorCodes(body string) []string {
+	var payload struct {
+		Error struct {
+			Code    json.RawMessage `json:"code"` // a string, except Gemini's numeric status
+			Type    string          `json:"type"`
+			Status  string          `json:"status"`
+			Details []struct {
+				Reason string `json:"reason"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	if json.Unmarshal([]byte(body), &payload) != nil {
+		return nil
+	}
+
+	codes := []string{payload.Error.Type, payload.Error.Status}
+	var code string
+	if json.Unmarshal(payload.Error.Code, &code) == nil {
+		codes = append(codes, code)
+	}
+	for _, detail := range payload.Error.Details {
+		codes = append(codes, detail.Reason)
+	}
+	return codes
+}
+
+// keyCooldown is how long to rest a rate-limited key: as long as the upstream
+// asked, or defaultKeyCooldown
+func keyCooldown(cause error) time.Duration {
+	var upErr *upstreamError
+	if errors.As(cause, &upErr) && upErr.RetryAfter > 0 {
+		return upErr.RetryAfter
+	}
+	return defaultKeyCooldown
+}
+
+// coolingDown reports whether a pooled key is resting after a rate limit
+func (s *AIProxyService) coolingDown(keyID uuid.UUID) bool {
+	until, ok := s.keyCooldowns.Load(keyID)
+	if !ok {
+		return false
+	}
+	if time.Now().Before(until.(time.Time)) {
+		return true
+	}
+	s.keyCooldowns.Delete(keyID)
+	return false
+}
+
+// rotateKey takes the pooled key out of use, disabling it if it was rejected
+// or resting it if it was rate limited, and switches to another key from the
+// pool. It reports false when no other usable key is available.
+func (s *AIProxyService) rotateKey(ctx context.Context, up *upstream, failure keyFailure, cause error) bool {
+	if up.key == nil || up.provider == nil {
+		return false
+	}
+
+	if failure == keyRateLimited {
+		wait := keyCooldown(cause)
+		s.keyCooldowns.Store(up.key.ID, time.Now().Add(wait))
+		log.Printf("Resting rate-limited API key %s of provider %s for %s", up.key.Name, up.provider.Name, wait)
+	} else {
+		reason := cause.Error()
+		if len(reason) > 500 {
+			reason = reason[:500]
+		}
+		if err := s.keyRepo.Disable(ctx, up.key.ID, reason); err != nil {
+			log.Printf("Failed to disable API key %s of provider %s: %v", up.key.Name, up.provider.Name, err)
+			return false
+		}
+		log.Printf("Disabled API key %s of provider %s: %s", up.key.Name, up.provider.Name, reason)
 	}
 
-	// Create HTTP request
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(requestBody))
+	target, key, err := s.providerTarget(ctx, up.provider)
+	if err != nil || key == nil || key.ID == up.key.ID || s.coolingDown(key.ID) {
+		return false
+	}
+	up.target.APIKey = target.APIKey
+	up.key = key
+	return true
+}
+
+// recordKeyUsage adds a request and its tokens to the pooled key's counters
+func (s *AIProxyService) recordKeyUsage(ctx context.Context, key *model.ProviderAPIKey, requests int, usage *model.ChatCompletionUsage) {
+	if key == nil {
+		return
+	}
+	var input, output int
+	if usage != nil {
+		input, output = usage.PromptTokens, usage.CompletionTokens
+	}
+	_ = s.keyRepo.RecordUsage(ctx, key.ID, requests, input, output)
+}
+
+// ListProviderModels asks a provider's upstream API which models it offers
+func (s *AIProxyService) ListProviderModels(ctx context.Context, provider *model.AIProvider) ([]model.DiscoveredModel, error) {
+	target, _, err := s.providerTarget(ctx, provider)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, err
+	}
+
+	lister, ok := llm.ForProvider(provider.ProviderType).(llm.ModelLister)
+	if !ok {
+		return nil, fmt.Errorf("model discovery is not supported for provider type %q", provider.ProviderType)
 	}
 
-	// Set headers
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiKey))
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
 
-	// Send request
-	resp, err := s.httpClient.Do(httpReq)
+	models, err := lister.ListModels(ctx, s.httpClient, target)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	// Check status code
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+	// Fill in context window, capabilities and pricing for well-known models
+	for i := range models {
+		info, known := llm.LookupModel(models[i].ModelIdentifier)
+		if !known {
+			continue
+		}
+		if models[i].MaxTokens == 0 {
+			models[i].MaxTokens = info.ContextWindow
+		}
+		models[i].SupportsStreaming = true
+		models[i].SupportsFunctions = info.Functions
+		inputPrice, outputPrice := info.InputPricePer1k, info.OutputPricePer1k
+		models[i].InputPricePer1k = &inputPrice
+		models[i].OutputPricePer1k = &outputPrice
 	}
 
-	// Parse response
-	var response model.ChatCompletionResponse
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	return models, nil
+}
+
+// ErrUpstream marks a failed exchange with a provider's API: an error
+// response, a transport failure or a malformed or interrupted response. Such
+// errors can carry provider error bodies and request details, so they are
+// logged in full but shown to end users only as ClientErrorMessage.
+var ErrUpstream = errors.New("AI service request failed")
+
+// upstreamFailure marks err as an ErrUpstream, keeping it inspectable
+func upstreamFailure(err error) error {
+	if errors.Is(err, ErrUpstream) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrUpstream, err)
+}
+
+// ClientErrorMessage returns the text of an error to show to an end user.
+// Upstream failures are summarized; other errors are our own and passed on.
+func ClientErrorMessage(err error) string {
+	if !errors.Is(err, ErrUpstream) {
+		return err.Error()
 	}
 
-	return &response, nil
+	var upErr *upstreamError
+	switch {
+	case errors.Is(err, llm.ErrImageURLUnsupported):
+		return llm.ErrImageURLUnsupported.Error()
+	case errors.As(err, &upErr) && upErr.StatusCode == http.StatusTooManyRequests:
+		return "AI service is busy, please try again later"
+	case errors.As(err, &upErr):
+		return fmt.Sprintf("AI service returned an error (status %d)", upErr.StatusCode)
+	case errors.Is(err, circuitbreaker.ErrOpen):
+		return "AI service is temporarily unavailable, please try again later"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "AI service timed out, please try again later"
+	}
+	return ErrUpstream.Error()
 }
 
-// SendStreamingChatCompletion sends a streaming chat completion request
-func (s *AIProxyService) SendStreamingChatCompletion(ctx context.Context, modelID uuid.UUID, request *model.ChatCompletionRequest) (io.ReadCloser, error) {
-	// Get model configuration
+// redactURLError drops the query from the URL a transport error quotes, so
+// credentials passed as query parameters never reach logs
+func redactURLError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		if i := strings.IndexByte(urlErr.URL, '?'); i >= 0 {
+			urlErr.URL = urlErr.URL[:i]
+		}
+	}
+	return err
+}
+
+// upstreamError is a non-200 response from a provider
+type upstreamError struct {
+	StatusCode int
+	Body       string
+	RetryAfter time.Duration // from the Retry-After header, if any
+}
+
+func (e *upstreamError) Error() string {
+	return fmt.Sprintf("API returned status %d: %s", e.StatusCode, e.Body)
+}
+
+// isFailoverError reports whether an error means the upstream is unavailable
+// (5xx, 429, timeout or connection failure) rather than the request being bad
+func isFailoverError(err error) bool {
+	var upErr *upstreamError
+	if errors.As(err, &upErr) {
+		return upErr.StatusCode == http.StatusTooManyRequests || upErr.StatusCode >= 500
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var ctxErr *ContextLengthError
+	if errors.As(err, &ctxErr) {
+		return true // a fallback with a larger context window may still fit
+	}
+	var visionErr *VisionUnsupportedError
+	if errors.As(err, &visionErr) {
+		return true // a later fallback may accept images
+	}
+	if errors.Is(err, llm.ErrImageURLUnsupported) {
+		return true // a fallback on another provider may accept image links
+	}
+	return errors.Is(err, context.DeadlineExceeded) || errors.Is(err, circuitbreaker.ErrOpen)
+}
+
+// Retry policy defaults, used for any field a provider leaves unset
+const (
+	defaultRetryMaxAttempts  = 3
+	defaultRetryInitialDelay = 500 * time.Millisecond
+	defaultRetryMaxDelay     = 8 * time.Second
+	defaultRetryJitter       = 0.2
+	defaultRetryTotalTimeout = 120 * time.Second
+)
+
+// retrySettings is a RetryPolicy with defaults applied
+type retrySettings struct {
+	maxAttempts  int
+	initialDelay time.Duration
+	maxDelay     time.Duration
+	jitter       float64
+	totalTimeout time.Duration
+}
+
+func newRetrySettings(policy model.RetryPolicy) retrySettings {
+	rs := retrySettings{
+		maxAttempts:  defaultRetryMaxAttempts,
+		initialDelay: defaultRetryInitialDelay,
+		maxDelay:     defaultRetryMaxDelay,
+		jitter:       defaultRetryJitter,
+		totalTimeout: defaultRetryTotalTimeout,
+	}
+	if policy.MaxAttempts > 0 {
+		rs.maxAttempts = policy.MaxAttempts
+	}
+	if policy.InitialBackoffMs > 0 {
+		rs.initialDelay = time.Duration(policy.InitialBackoffMs) * time.Millisecond
+	}
+	if policy.MaxBackoffMs > 0 {
+		rs.maxDelay = time.Duration(policy.MaxBackoffMs) * time.Millisecond
+	}
+	if policy.Jitter > 0 {
+		rs.jitter = policy.Jitter
+	}
+	if policy.TotalTimeoutSeconds > 0 {
+		rs.totalTimeout = time.Duration(policy.TotalTimeoutSeconds) * time.Second
+	}
+	return rs
+}
+
+// backoff returns the delay before the given retry (1-based): exponential,
+// capped at maxDelay, with a random jitter fraction taken off
+func (rs retrySettings) backoff(retry int) time.Duration {
+	delay := rs.initialDelay
+	for i := 1; i < retry && delay < rs.maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > rs.maxDelay {
+		delay = rs.maxDelay
+	}
+	return delay - time.Duration(rand.Float64()*rs.jitter*float64(delay))
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if d := time.Until(at); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// isRetryableError reports whether a failed attempt is worth repeating
+// against the same upstream: connection failures, 429 and 502/503/504
+func isRetryableError(err error) bool {
+	var upErr *upstreamError
+	if errors.As(err, &upErr) {
+		switch upErr.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET)
+}
+
+// doWithRetry sends the request produced by newRequest, retrying transient
+// failures with backoff until the policy's attempts or deadline run out.
+// Every attempt goes through the upstream's circuit breaker, so a provider
+// that keeps failing is skipped instead of waited on.
+// Only a 200 response is returned; anything else becomes an error.
+func (s *AIProxyService) doWithRetry(ctx context.Context, up *upstream, rs retrySettings, deadline time.Time, newRequest func() (*http.Request, error)) (*http.Response, error) {
+	var lastErr error
+	for attempt := 1; ; attempt++ {
+		if err := up.breaker.Allow(); err != nil {
+			if lastErr != nil {
+				return nil, fmt.Errorf("%w (after: %v)", err, lastErr)
+			}
+			return nil, fmt.Errorf("provider unavailable: %w", err)
+		}
+
+		httpReq, err := newRequest()
+		if err != nil {
+			up.breaker.Abandon()
+			return nil, err
+		}
+
+		started := time.Now()
+		resp, err := s.httpClient.Do(httpReq)
+		latency := time.Since(started)
+		if err == nil && resp.StatusCode == http.StatusOK {
+			up.breaker.Record(true, latency)
+			s.recordKeyUsage(ctx, up.key, 1, nil)
+			return resp, nil
+		}
+		if err != nil {
+			err = fmt.Errorf("failed to send request: %w", redactURLError(err))
+		} else {
+			body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+			resp.Body.Close()
+			err = &upstreamError{
+				StatusCode: resp.StatusCode,
+				Body:       string(body),
+				RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+			}
+		}
+		lastErr = err
+
+		// A rejected or rate-limited pooled key is taken out of use and the
+		// request repeated with another key, without using up an attempt
+		if failure := classifyKeyError(err); failure != keyFine && s.rotateKey(ctx, up, failure, err) {
+			up.breaker.Record(true, latency)
+			attempt--
+			continue
+		}
+
+		// Client errors mean the upstream is healthy; a caller that went away says nothing
+		switch {
+		case ctx.Err() != nil:
+			up.breaker.Abandon()
+		default:
+			up.breaker.Record(!isFailoverError(err), latency)
+		}
+
+		if attempt >= rs.maxAttempts || !isRetryableError(err) || ctx.Err() != nil {
+			return nil, err
+		}
+
+		wait := rs.backoff(attempt)
+		var upErr *upstreamError
+		if errors.As(err, &upErr) && upErr.RetryAfter > 0 {
+			wait = upErr.RetryAfter
+		}
+		if time.Now().Add(wait).After(deadline) {
+			return nil, err
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		}
+	}
+}
+
+// modelChain returns the requested model followed by its active fallbacks, in
+// order and without repeats
+func (s *AIProxyService) modelChain(ctx context.Context, modelID uuid.UUID) ([]*model.AIModel, error) {
 	aiModel, err := s.modelRepo.GetByID(ctx, modelID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get model: %w", err)
@@ -126,49 +660,307 @@ func (s *AIProxyService) SendStreamingChatCompletion(ctx context.Context, modelI
 		return nil, fmt.Errorf("model is not active")
 	}
 
-	if !aiModel.SupportsStreaming {
-		return nil, fmt.Errorf("model does not support streaming")
+	chain := []*model.AIModel{aiModel}
+	seen := map[uuid.UUID]bool{aiModel.ID: true}
+	for _, fallbackID := range aiModel.FallbackModelIDs {
+		if seen[fallbackID] {
+			continue
+		}
+		seen[fallbackID] = true
+
+		fallback, err := s.modelRepo.GetByID(ctx, fallbackID)
+		if err != nil || !fallback.IsActive {
+			continue
+		}
+		chain = append(chain, fallback)
 	}
 
-	endpoint, apiKey, err := s.resolveCredentials(ctx, aiModel)
+	return chain, nil
+}
+
+// logFailover records that a model in a failover chain failed and the next is being tried
+func logFailover(failed, next *model.AIModel, err error) {
+	log.Printf("AI failover: model %s (%s) failed: %v; trying %s (%s)",
+		failed.Name, failed.ID, err, next.Name, next.ID)
+}
+
+// SendChatCompletion sends a chat completion request to an AI model, failing
+// over to the model's fallbacks when its upstream is unavailable. It returns
+// the model that actually answered.
+func (s *AIProxyService) SendChatCompletion(ctx context.Context, modelID uuid.UUID, request *model.ChatCompletionRequest) (*model.ChatCompletionResponse, *model.AIModel, error) {
+	chain, err := s.modelChain(ctx, modelID)
 	if err != nil {
+		return nil, nil, err
+	}
+
+	for i, aiModel := range chain {
+		response, err := s.sendChatCompletion(ctx, aiModel, request)
+		if err == nil {
+			return response, aiModel, nil
+		}
+		if i == len(chain)-1 || !isFailoverError(err) || ctx.Err() != nil {
+			return nil, nil, err
+		}
+		logFailover(aiModel, chain[i+1], err)
+	}
+
+	return nil, nil, fmt.Errorf("no model available")
+}
+
+// sendChatCompletion sends a chat completion request to a single model
+func (s *AIProxyService) sendChatCompletion(ctx context.Context, aiModel *model.AIModel, request *model.ChatCompletionRequest) (*model.ChatCompletionResponse, error) {
+	if err := s.checkContextBudget(aiModel, request); err != nil {
 		return nil, err
 	}
 
-	// Ensure streaming is enabled
-	request.Stream = true
+	up, err := s.resolveCredentials(ctx, aiModel)
+	if err != nil {
+		return nil, err
+	}
 
-	// Prepare request
-	requestBody, err := json.Marshal(request)
+	// Address the request to this model, which may be a fallback
+	modelRequest := *request
+	modelRequest.Model = aiModel.ModelIdentifier
+	if !aiModel.SupportsFunctions {
+		withoutTools(&modelRequest)
+	}
+	if !aiModel.SupportsVision {
+		if err := withoutImages(&modelRequest, aiModel); err != nil {
+			return nil, err
+		}
+	}
+
+	// The total deadline covers every attempt and reading the response
+	rs := newRetrySettings(up.retry)
+	ctx, cancel := context.WithTimeout(ctx, rs.totalTimeout)
+	defer cancel()
+	deadline, _ := ctx.Deadline()
+
+	// Build provider-specific HTTP request and send it, retrying transient failures
+	adapter := llm.ForProvider(up.target.ProviderType)
+	resp, err := s.doWithRetry(ctx, up, rs, deadline, func() (*http.Request, error) {
+		return adapter.NewChatRequest(ctx, up.target, &modelRequest)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+		return nil, upstreamFailure(err)
+	}
+	defer resp.Body.Close()
+
+	// Parse response
+	response, err := adapter.DecodeChatResponse(resp.Body)
+	if err != nil {
+		return nil, upstreamFailure(err)
+	}
+	s.recordKeyUsage(ctx, up.key, 0, &response.Usage)
+
+	return response, nil
+}
+
+// SendStreamingChatCompletion sends a streaming chat completion request,
+// failing over to streaming-capable fallbacks until a stream is established.
+// It returns the model that is answering.
+func (s *AIProxyService) SendStreamingChatCompletion(ctx context.Context, modelID uuid.UUID, request *model.ChatCompletionRequest) (*llm.Stream, *model.AIModel, error) {
+	chain, err := s.modelChain(ctx, modelID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !chain[0].SupportsStreaming {
+		return nil, nil, fmt.Errorf("model does not support streaming")
+	}
+
+	// Fallbacks that cannot stream are skipped
+	streaming := chain[:0]
+	for _, aiModel := range chain {
+		if aiModel.SupportsStreaming {
+			streaming = append(streaming, aiModel)
+		}
 	}
 
-	// Create HTTP request
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(requestBody))
+	for i, aiModel := range streaming {
+		stream, err := s.sendStreamingChatCompletion(ctx, aiModel, request)
+		if err == nil {
+			return stream, aiModel, nil
+		}
+		if i == len(streaming)-1 || !isFailoverError(err) || ctx.Err() != nil {
+			return nil, nil, err
+		}
+		logFailover(aiModel, streaming[i+1], err)
+	}
+
+	return nil, nil, fmt.Errorf("no model available")
+}
+
+// sendStreamingChatCompletion opens a streaming completion against a single model
+func (s *AIProxyService) sendStreamingChatCompletion(ctx context.Context, aiModel *model.AIModel, request *model.ChatCompletionRequest) (*llm.Stream, error) {
+	if err := s.checkContextBudget(aiModel, request); err != nil {
+		return nil, err
+	}
+
+	up, err := s.resolveCredentials(ctx, aiModel)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, err
+	}
+
+	// Ensure streaming is enabled and the request is addressed to this model
+	modelRequest := *request
+	modelRequest.Model = aiModel.ModelIdentifier
+	modelRequest.Stream = true
+	if !aiModel.SupportsFunctions {
+		withoutTools(&modelRequest)
+	}
+	if !aiModel.SupportsVision {
+		if err := withoutImages(&modelRequest, aiModel); err != nil {
+			return nil, err
+		}
 	}
 
-	// Set headers
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiKey))
-	httpReq.Header.Set("Accept", "text/event-stream")
+	// The total deadline only bounds retries here; once the stream is
+	// established it may run as long as the model keeps generating
+	rs := newRetrySettings(up.retry)
+	deadline := time.Now().Add(rs.totalTimeout)
 
-	// Send request
-	resp, err := s.httpClient.Do(httpReq)
+	// Build provider-specific HTTP request and send it, retrying transient failures
+	adapter := llm.ForProvider(up.target.ProviderType)
+	resp, err := s.doWithRetry(ctx, up, rs, deadline, func() (*http.Request, error) {
+		return adapter.NewChatRequest(ctx, up.target, &modelRequest)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		return nil, upstreamFailure(err)
 	}
 
-	// Check status code
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+	stream := llm.NewStream(resp.Body, adapter.NewStreamDecoder())
+	if key := up.key; key != nil {
+		// Token usage only arrives with the final chunks
+		stream.OnUsage(func(usage model.ChatCompletionUsage) {
+			usageCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			s.recordKeyUsage(usageCtx, key, 0, &usage)
+		})
 	}
 
-	return resp.Body, nil
+	return stream, nil
+}
+
+// withoutTools adapts a request for a model that cannot call tools, e.g. a
+// fallback: tool definitions are dropped and past tool exchanges are reduced
+// to their text
+func withoutTools(request *model.ChatCompletionRequest) {
+	request.Tools = nil
+	request.ToolChoice = nil
+
+	messages := make([]model.ChatMessage, 0, len(request.Messages))
+	for _, msg := range request.Messages {
+		if msg.Role == "tool" {
+			continue
+		}
+		if len(msg.ToolCalls) > 0 {
+			if msg.Content == "" {
+				continue
+			}
+			msg.ToolCalls = nil
+		}
+		messages = append(messages, msg)
+	}
+	request.Messages = messages
+}
+
+// VisionUnsupportedError reports images sent to a model that cannot read them
+type VisionUnsupportedError struct {
+	Model string
+}
+
+func (e *VisionUnsupportedError) Error() string {
+	return fmt.Sprintf("model %s does not support image input", e.Model)
+}
+
+// withoutImages adapts a request for a model without vision. Images in
+// earlier turns are replaced by a placeholder so the history still reads
+// naturally; images in the turn being answered cannot be dropped silently.
+func withoutImages(request *model.ChatCompletionRequest, aiModel *model.AIModel) error {
+	last := -1
+	for i, msg := range request.Messages {
+		if msg.Role == "user" {
+			last = i
+		}
+	}
+	if last >= 0 && request.Messages[last].HasImages() {
+		return &VisionUnsupportedError{Model: aiModel.Name}
+	}
+
+	messages := make([]model.ChatMessage, len(request.Messages))
+	for i, msg := range request.Messages {
+		if msg.HasImages() {
+			msg.Content = strings.TrimSpace(msg.Content + "\n[image]")
+			msg.Parts = nil
+		}
+		messages[i] = msg
+	}
+	request.Messages = messages
+	return nil
+}
+
+// CreateEmbeddings embeds texts with a model, returning one vector per text in
+// input order and the tokens consumed. There is no failover: vectors from
+// different models cannot be compared.
+func (s *AIProxyService) CreateEmbeddings(ctx context.Context, aiModel *model.AIModel, texts []string) ([][]float32, *model.ChatCompletionUsage, error) {
+	if len(texts) == 0 {
+		return nil, &model.ChatCompletionUsage{}, nil
+	}
+	if !aiModel.SupportsEmbeddings {
+		return nil, nil, fmt.Errorf("model %s does not support embeddings", aiModel.Name)
+	}
+
+	up, err := s.resolveCredentials(ctx, aiModel)
+	if err != nil {
+		return nil, nil, err
+	}
+	embedder, ok := llm.ForProvider(up.target.ProviderType).(llm.Embedder)
+	if !ok {
+		return nil, nil, fmt.Errorf("embeddings are not supported for provider type %q", up.target.ProviderType)
+	}
+
+	rs := newRetrySettings(up.retry)
+	ctx, cancel := context.WithTimeout(ctx, rs.totalTimeout)
+	defer cancel()
+	deadline, _ := ctx.Deadline()
+
+	request := &model.EmbeddingRequest{Model: aiModel.ModelIdentifier, Input: texts}
+	resp, err := s.doWithRetry(ctx, up, rs, deadline, func() (*http.Request, error) {
+		return embedder.NewEmbeddingRequest(ctx, up.target, request)
+	})
+	if err != nil {
+		return nil, nil, upstreamFailure(err)
+	}
+	defer resp.Body.Close()
+
+	response, err := embedder.DecodeEmbeddingResponse(resp.Body)
+	if err != nil {
+		return nil, nil, upstreamFailure(err)
+	}
+
+	// Not every provider reports usage for embeddings
+	usage := response.Usage
+	if usage.PromptTokens == 0 {
+		for _, text := range texts {
+			usage.PromptTokens += s.CountTokens(aiModel, text)
+		}
+		usage.TotalTokens = usage.PromptTokens
+	}
+	s.recordKeyUsage(ctx, up.key, 0, &usage)
+
+	vectors := make([][]float32, len(texts))
+	for _, d := range response.Data {
+		if d.Index >= 0 && d.Index < len(vectors) {
+			vectors[d.Index] = d.Embedding
+		}
+	}
+	for i, v := range vectors {
+		if len(v) == 0 {
+			return nil, nil, fmt.Errorf("embedding response is missing input %d", i)
+		}
+	}
+	return vectors, &usage, nil
 }
 
 // EstimateCost estimates the cost of a completion based on token usage
@@ -189,19 +981,101 @@ func (s *AIProxyService) EstimateCost(ctx context.Context, modelID uuid.UUID, in
 	return &totalCost, nil
 }
 
-// CountTokens estimates token count for a text (simple approximation)
-// In production, use a proper tokenizer like tiktoken
-func (s *AIProxyService) CountTokens(text string) int {
-	// Simple approximation: ~4 characters per token
-	return len(text) / 4
+// Token accounting for the chat message format: every message carries a few
+// tokens of role and delimiter framing, and the reply is primed with a few more
+const (
+	tokensPerMessage = 4
+	tokensReplyPrime = 3
+
+	// Images are billed by size, which is not known here: low detail is a
+	// flat rate, anything else is estimated as a 1024x1024 image
+	tokensPerImageLow = 85
+	tokensPerImage    = 765
+)
+
+// CountTokens counts the tokens of a text with the model's tokenizer
+func (s *AIProxyService) CountTokens(aiModel *model.AIModel, text string) int {
+	return tokenizer.ForModel(aiModel.ModelIdentifier, aiModel.Tokenizer).Count(text)
+}
+
+// CountMessageTokens counts the tokens a single message adds to a prompt
+func (s *AIProxyService) CountMessageTokens(aiModel *model.AIModel, msg model.ChatMessage) int {
+	tok := tokenizer.ForModel(aiModel.ModelIdentifier, aiModel.Tokenizer)
+	total := tokensPerMessage + tok.Count(msg.Role) + tok.Count(msg.Content)
+	for _, part := range msg.Parts {
+		if part.Type != model.ContentPartImageURL {
+			continue
+		}
+		if part.ImageURL != nil && part.ImageURL.Detail == "low" {
+			total += tokensPerImageLow
+		} else {
+			total += tokensPerImage
+		}
+	}
+	for _, call := range msg.ToolCalls {
+		total += tok.Count(call.Function.Name) + tok.Count(call.Function.Arguments)
+	}
+	return total
+}
+
+// CountToolsTokens counts the prompt tokens spent on tool definitions
+func (s *AIProxyService) CountToolsTokens(aiModel *model.AIModel, tools []model.Tool) int {
+	if len(tools) == 0 {
+		return 0
+	}
+	data, err := json.Marshal(tools)
+	if err != nil {
+		return 0
+	}
+	return tokenizer.ForModel(aiModel.ModelIdentifier, aiModel.Tokenizer).Count(string(data))
 }
 
-// CountMessagesTokens estimates total tokens for a list of messages
-func (s *AIProxyService) CountMessagesTokens(messages []model.ChatMessage) int {
-	total := 0
+// CountMessagesTokens counts the prompt tokens a list of messages costs on a model
+func (s *AIProxyService) CountMessagesTokens(aiModel *model.AIModel, messages []model.ChatMessage) int {
+	total := tokensReplyPrime
 	for _, msg := range messages {
-		total += s.CountTokens(msg.Content)
-		total += 4 // overhead per message
+		total += s.CountMessageTokens(aiModel, msg)
 	}
 	return total
 }
+
+// ContextLengthError reports a request that cannot fit a model's context window
+type ContextLengthError struct {
+	Model        string
+	PromptTokens int
+	OutputTokens int
+	Limit        int
+}
+
+func (e *ContextLengthError) Error() string {
+	return fmt.Sprintf("request exceeds the context window of %s: %d prompt + %d output tokens > %d",
+		e.Model, e.PromptTokens, e.OutputTokens, e.Limit)
+}
+
+// checkContextBudget rejects a request before it is sent if its prompt and
+// requested output cannot fit the model's context window
+func (s *AIProxyService) checkContextBudget(aiModel *model.AIModel, request *model.ChatCompletionRequest) error {
+	if aiModel.MaxTokens <= 0 {
+		return nil
+	}
+
+	outputTokens := 0
+	if request.MaxTokens != nil {
+		outputTokens = *request.MaxTokens
+	}
+
+	promptTokens := s.CountMessagesTokens(aiModel, request.Messages)
+	if aiModel.SupportsFunctions {
+		promptTokens += s.CountToolsTokens(aiModel, request.Tools)
+	}
+	if promptTokens+outputTokens > aiModel.MaxTokens {
+		return &ContextLengthError{
+			Model:        aiModel.Name,
+			PromptTokens: promptTokens,
+			OutputTokens: outputTokens,
+			Limit:        aiModel.MaxTokens,
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ai-chat/backend/internal/model"
+)
+
+func TestClassifyKeyError(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   keyFailure
+	}{
+		{"unauthorized", http.StatusUnauthorized, `{}`, keyRejected},
+		{"payment required", http.StatusPaymentRequired, ``, keyRejected},
+		{"openai invalid key", http.StatusUnauthorized, `{"error": {"code": "invalid_api_key", "type": "invalid_request_error"}}`, keyRejected},
+		{"openai out of quota", http.StatusTooManyRequests, `{"error": {"message": "You exceeded your current quota", "type": "insufficient_quota", "code": "insufficient_quota"}}`, keyRejected},
+		{"openai rate limit", http.StatusTooManyRequests, `{"error": {"message": "Rate limit reached for requests; check your api key usage", "type": "requests", "code": "rate_limit_exceeded"}}`, keyRateLimited},
+		{"anthropic authentication", http.StatusForbidden, `{"type": "error", "error": {"type": "authentication_error", "message": "invalid x-api-key"}}`, keyRejected},
+		{"anthropic rate limit", http.StatusTooManyRequests, `{"type": "error", "error": {"type": "rate_limit_error", "message": "credit balance"}}`, keyRateLimited},
+		{"anthropic overloaded", 529, `{"type": "error", "error": {"type": "overloaded_error"}}`, keyFine},
+		{"gemini invalid key", http.StatusBadRequest, `{"error": {"code": 400, "status": "INVALID_ARGUMENT", "details": [{"reason": "API_KEY_INVALID"}]}}`, keyRejected},
+		{"gemini exhausted", http.StatusTooManyRequests, `{"error": {"code": 429, "status": "RESOURCE_EXHAUSTED"}}`, keyRateLimited},
+		{"forbidden mentioning api key", http.StatusForbidden, `{"error": {"message": "this api key cannot use the model", "type": "permission_error"}}`, keyFine},
+		{"plain 429", http.StatusTooManyRequests, `Too Many Requests`, keyRateLimited},
+		{"bad request", http.StatusBadRequest, `{"error": {"type": "invalid_request_error"}}`, keyFine},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := fmt.Errorf("%w: %w", ErrUpstream, &upstreamError{StatusCode: tt.status, Body: tt.body})
+			if got := classifyKeyError(err); got != tt.want {
+				t.Errorf("classifyKeyError() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	if got := classifyKeyError(errors.New("connection refused")); got != keyFine {
+		t.Errorf("classifyKeyError(transport error) = %d, want keyFine", got)
+	}
+}
+
+func TestChooseKey(t *testing.T) {
+	keys := []*model.ProviderAPIKey{
+		{ID: uuid.New(), Name: "a", RequestCount: 30},
+		{ID: uuid.New(), Name: "b", RequestCount: 10},
+		{ID: uuid.New(), Name: "c", RequestCount: 20},
+	}
+	picks := func(s *AIProxyService, provider *model.AIProvider, n int) string {
+		var out string
+		for i := 0; i < n; i++ {
+			out += s.chooseKey(provider, keys).Name
+		}
+		return out
+	}
+
+	roundRobin := &model.AIProvider{ID: uuid.New(), KeySelection: model.KeySelectionRoundRobin}
+	leastUsed := &model.AIProvider{ID: uuid.New(), KeySelection: model.KeySelectionLeastUsed}
+
+	s := &AIProxyService{}
+	if got := picks(s, roundRobin, 4); got != "abca" {
+		t.Errorf("round robin picks = %q, want %q", got, "abca")
+	}
+	if got := picks(s, leastUsed, 2); got != "bb" {
+		t.Errorf("least used picks = %q, want %q", got, "bb")
+	}
+	if got := s.chooseKey(roundRobin, nil); got != nil {
+		t.Errorf("chooseKey() of an empty pool = %v, want nil", got)
+	}
+
+	// Keys resting after a rate limit are passed over while others are available
+	s = &AIProxyService{}
+	s.keyCooldowns.Store(keys[1].ID, time.Now().Add(time.Minute))
+	if got := picks(s, roundRobin, 3); got != "aca" {
+		t.Errorf("round robin picks with b resting = %q, want %q", got, "aca")
+	}
+	if got := picks(s, leastUsed, 1); got != "c" {
+		t.Errorf("least used pick with b resting = %q, want %q", got, "c")
+	}
+
+	// When every key is resting, one is used anyway
+	s.keyCooldowns.Store(keys[0].ID, time.Now().Add(time.Minute))
+	s.keyCooldowns.Store(keys[2].ID, time.Now().Add(time.Minute))
+	if got := picks(s, leastUsed, 1); got != "b" {
+		t.Errorf("least used pick with all resting = %q, want %q", got, "b")
+	}
+
+	// An expired cooldown is cleared
+	s.keyCooldowns.Store(keys[1].ID, time.Now().Add(-time.Second))
+	if s.coolingDown(keys[1].ID) {
+		t.Error("coolingDown() after the cooldown = true, want false")
+	}
+	if _, ok := s.keyCooldowns.Load(keys[1].ID); ok {
+		t.Error("expired cooldown was not cleared")
+	}
+}
+
+func TestKeyCooldown(t *testing.T) {
+	tests := []struct {
+		name  string
+		cause error
+		want  time.Duration
+	}{
+		{"retry after", &upstreamError{StatusCode: http.StatusTooManyRequests, RetryAfter: 20 * time.Second}, 20 * time.Second},
+		{"wrapped retry after", fmt.Errorf("stream: %w", &upstreamError{StatusCode: http.StatusTooManyRequests, RetryAfter: 5 * time.Minute}), 5 * time.Minute},
+		{"no retry after", &upstreamError{StatusCode: http.StatusTooManyRequests}, defaultKeyCooldown},
+		{"other error", errors.New("rate limited"), defaultKeyCooldown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := keyCooldown(tt.cause); got != tt.want {
+				t.Errorf("keyCooldown() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
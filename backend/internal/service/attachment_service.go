@@ -0,0 +1,449 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/ai-chat/backend/internal/model"
+	"github.com/ai-chat/backend/internal/pkg/docparse"
+	"github.com/ai-chat/backend/internal/pkg/storage"
+	"github.com/ai-chat/backend/internal/repository"
+)
+
+const (
+	// unlinkedAttachmentTTL is how long an upload may wait to be sent with a
+	// message before it is treated as abandoned
+	unlinkedAttachmentTTL = 24 * time.Hour
+	// attachmentCleanupBatch bounds the work of a single cleanup pass
+	attachmentCleanupBatch = 100
+	// maxFilenameLength matches attachments.filename
+	maxFilenameLength = 255
+)
+
+// attachmentTypes are the accepted file types, by sniffed content type
+var attachmentTypes = map[string]bool{
+	"image/png":       true,
+	"image/jpeg":      true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+	"text/plain":      true,
+	docparse.TypeDOCX: true,
+}
+
+// textSubtypes refine a sniffed text/plain file by its extension
+var textSubtypes = map[string]string{
+	".md":       "text/markdown",
+	".markdown": "text/markdown",
+	".csv":      "text/csv",
+	".json":     "application/json",
+}
+
+// ErrAttachmentNotFound is returned for attachments that do not exist, belong
+// to another user or whose file is gone
+var ErrAttachmentNotFound = errors.New("attachment not found")
+
+// AttachmentRejectedError is an upload or attachment request that fails
+// validation; its reason is meant for the client
+type AttachmentRejectedError struct {
+	Reason string
+}
+
+func (e *AttachmentRejectedError) Error() string {
+	return e.Reason
+}
+
+// rejectAttachment returns an AttachmentRejectedError with a formatted reason
+func rejectAttachment(format string, args ...interface{}) error {
+	return &AttachmentRejectedError{Reason: fmt.Sprintf(format, args...)}
+}
+
+// UploadedFile is a file received for upload. The content must be seekable so
+// it can be sniffed, hashed and then stored.
+type UploadedFile struct {
+	Filename string
+	Size     int64
+	Content  io.ReadSeeker
+}
+
+// AttachmentService stores user files and tracks them against per-user quotas
+type AttachmentService struct {
+	attachmentRepo *repository.AttachmentRepository
+	convRepo       *repository.ConversationRepository
+	store          storage.Storage
+	maxFileSize    int64
+	userQuota      int64
+	purge          chan struct{} // wakes the cleanup worker to remove deleted files
+}
+
+// NewAttachmentService creates a new attachment service
+func NewAttachmentService(
+	attachmentRepo *repository.AttachmentRepository,
+	convRepo *repository.ConversationRepository,
+	store storage.Storage,
+	maxFileSize int64,
+	userQuota int64,
+) *AttachmentService {
+	return &AttachmentService{
+		attachmentRepo: attachmentRepo,
+		convRepo:       convRepo,
+		store:          store,
+		maxFileSize:    maxFileSize,
+		userQuota:      userQuota,
+		purge:          make(chan struct{}, 1),
+	}
+}
+
+// Upload validates and stores a file for the user. conversationID optionally
+// scopes the upload to one of the user's conversations.
+func (s *AttachmentService) Upload(ctx context.Context, userID uuid.UUID, conversationID *uuid.UUID, file *UploadedFile) (*model.Attachment, error) {
+	if file.Size <= 0 {
+		return nil, rejectAttachment("file is empty")
+	}
+	if file.Size > s.maxFileSize {
+		return nil, rejectAttachment("file exceeds the %d MB limit", s.maxFileSize>>20)
+	}
+
+	if conversationID != nil {
+		conv, err := s.convRepo.GetByID(ctx, *conversationID)
+		if err != nil || conv.UserID != userID {
+			return nil, rejectAttachment("conversation not found")
+		}
+	}
+
+	// Early check so an upload that cannot fit is not stored; the quota is
+	// enforced atomically when the attachment is recorded
+	_, used, err := s.attachmentRepo.UsageByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if used+file.Size > s.userQuota {
+		return nil, s.quotaExceeded(used)
+	}
+
+	filename := sanitizeFilename(file.Filename)
+	contentType, err := detectContentType(file.Content, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file.Content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	if size != file.Size {
+		return nil, rejectAttachment("file size mismatch")
+	}
+	if _, err := file.Content.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	attachment := &model.Attachment{
+		ID:             uuid.New(),
+		UserID:         userID,
+		ConversationID: conversationID,
+		Filename:       filename,
+		ContentType:    contentType,
+		SizeBytes:      size,
+		Checksum:       hex.EncodeToString(hash.Sum(nil)),
+	}
+	attachment.StorageKey = fmt.Sprintf("attachments/%s/%s", userID, attachment.ID)
+
+	if err := s.store.Put(ctx, attachment.StorageKey, file.Content, size, contentType); err != nil {
+		return nil, fmt.Errorf("failed to store file: %w", err)
+	}
+	if err := s.attachmentRepo.Create(ctx, attachment, s.userQuota); err != nil {
+		// No record points at the file, so remove it rather than leak it
+		if delErr := s.store.Delete(context.Background(), attachment.StorageKey); delErr != nil {
+			log.Printf("Failed to remove unrecorded attachment %s: %v", attachment.StorageKey, delErr)
+		}
+		if errors.Is(err, repository.ErrQuotaExceeded) {
+			// A concurrent upload took the remaining space
+			_, used, _ := s.attachmentRepo.UsageByUser(ctx, userID)
+			return nil, s.quotaExceeded(used)
+		}
+		return nil, err
+	}
+
+	return attachment, nil
+}
+
+// quotaExceeded rejects an upload that does not fit the user's quota
+func (s *AttachmentService) quotaExceeded(used int64) error {
+	return rejectAttachment("attachment quota exceeded (%d of %d MB used)", used>>20, s.userQuota>>20)
+}
+
+// GetAttachment retrieves an attachment owned by the user
+func (s *AttachmentService) GetAttachment(ctx context.Context, userID, attachmentID uuid.UUID) (*model.Attachment, error) {
+	attachment, err := s.attachmentRepo.GetByID(ctx, attachmentID)
+	if err != nil {
+		return nil, ErrAttachmentNotFound
+	}
+
+	// Other users' attachments are reported as missing
+	if attachment.UserID != userID {
+		return nil, ErrAttachmentNotFound
+	}
+
+	return attachment, nil
+}
+
+// OpenAttachment returns an attachment owned by the user and a reader for its
+// content; the caller closes the reader
+func (s *AttachmentService) OpenAttachment(ctx context.Context, userID, attachmentID uuid.UUID) (*model.Attachment, io.ReadCloser, error) {
+	attachment, err := s.GetAttachment(ctx, userID, attachmentID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	content, err := s.store.Get(ctx, attachment.StorageKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		log.Printf("Attachment %s has no file at %s", attachment.ID, attachment.StorageKey)
+		return nil, nil, ErrAttachmentNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read attachment: %w", err)
+	}
+
+	return attachment, content, nil
+}
+
+// ReadContent reads an attachment's whole content, up to the upload size limit
+func (s *AttachmentService) ReadContent(ctx context.Context, attachment *model.Attachment) ([]byte, error) {
+	content, err := s.store.Get(ctx, attachment.StorageKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("attachment file is missing")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attachment: %w", err)
+	}
+	defer content.Close()
+
+	data, err := io.ReadAll(io.LimitReader(content, s.maxFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attachment: %w", err)
+	}
+	if int64(len(data)) > s.maxFileSize {
+		return nil, fmt.Errorf("attachment exceeds the %d MB limit", s.maxFileSize>>20)
+	}
+	return data, nil
+}
+
+// ListAttachments lists the user's attachments
+func (s *AttachmentService) ListAttachments(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*model.Attachment, error) {
+	return s.attachmentRepo.ListByUser(ctx, userID, limit, offset)
+}
+
+// GetUsage reports the user's storage use against their quota
+func (s *AttachmentService) GetUsage(ctx context.Context, userID uuid.UUID) (*model.AttachmentUsage, error) {
+	count, used, err := s.attachmentRepo.UsageByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &model.AttachmentUsage{Count: count, UsedBytes: used, QuotaBytes: s.userQuota}, nil
+}
+
+// DeleteAttachment deletes an attachment owned by the user and its file
+func (s *AttachmentService) DeleteAttachment(ctx context.Context, userID, attachmentID uuid.UUID) error {
+	attachment, err := s.GetAttachment(ctx, userID, attachmentID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.attachmentRepo.Delete(ctx, attachment.ID); err != nil {
+		return fmt.Errorf("failed to delete attachment: %w", err)
+	}
+
+	// The delete trigger queued the file; remove it now rather than waiting
+	// for the next cleanup pass
+	if err := s.store.Delete(ctx, attachment.StorageKey); err != nil {
+		log.Printf("Failed to delete attachment file %s: %v", attachment.StorageKey, err)
+		return nil
+	}
+	if err := s.attachmentRepo.CompleteDeletion(ctx, attachment.StorageKey); err != nil {
+		log.Printf("Failed to dequeue attachment file %s: %v", attachment.StorageKey, err)
+	}
+	return nil
+}
+
+// LinkToMessage attaches the user's uploaded files to a message they sent
+func (s *AttachmentService) LinkToMessage(ctx context.Context, userID, conversationID, messageID uuid.UUID, attachmentIDs []uuid.UUID) error {
+	return s.attachmentRepo.LinkToMessage(ctx, userID, conversationID, messageID, attachmentIDs)
+}
+
+// CheckAttachable verifies that every ID is an unlinked upload the user owns
+// that may be sent in the conversation, before a message references them
+func (s *AttachmentService) CheckAttachable(ctx context.Context, userID, conversationID uuid.UUID, attachmentIDs []uuid.UUID) error {
+	seen := make(map[uuid.UUID]bool, len(attachmentIDs))
+	for _, id := range attachmentIDs {
+		if seen[id] {
+			return rejectAttachment("duplicate attachment: %s", id)
+		}
+		seen[id] = true
+
+		attachment, err := s.GetAttachment(ctx, userID, id)
+		if err != nil {
+			return err
+		}
+		if attachment.MessageID != nil {
+			return rejectAttachment("attachment %s is already attached to a message", id)
+		}
+		if attachment.ConversationID != nil && *attachment.ConversationID != conversationID {
+			return rejectAttachment("attachment %s belongs to another conversation", id)
+		}
+	}
+	return nil
+}
+
+// AttachToMessages fills in the Attachments of each message
+func (s *AttachmentService) AttachToMessages(ctx context.Context, messages []*model.Message) error {
+	ids := make([]uuid.UUID, 0, len(messages))
+	for _, msg := range messages {
+		ids = append(ids, msg.ID)
+	}
+
+	attachments, err := s.attachmentRepo.ListByMessages(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	byMessage := make(map[uuid.UUID][]*model.Attachment)
+	for _, a := range attachments {
+		byMessage[*a.MessageID] = append(byMessage[*a.MessageID], a)
+	}
+	for _, msg := range messages {
+		msg.Attachments = byMessage[msg.ID]
+	}
+	return nil
+}
+
+// Cleanup deletes uploads that were never sent with a message, then removes
+// the files of all deleted attachments
+func (s *AttachmentService) Cleanup(ctx context.Context) {
+	stale, err := s.attachmentRepo.ListUnlinkedBefore(ctx, time.Now().Add(-unlinkedAttachmentTTL), attachmentCleanupBatch)
+	if err != nil {
+		log.Printf("Failed to list abandoned attachments: %v", err)
+	}
+	for _, a := range stale {
+		if err := s.attachmentRepo.Delete(ctx, a.ID); err != nil {
+			log.Printf("Failed to delete abandoned attachment %s: %v", a.ID, err)
+		}
+	}
+
+	s.PurgeDeletedFiles(ctx)
+}
+
+// PurgeDeletedFiles removes the files of deleted attachment records. Records
+// removed by cascades (conversation, message and user deletion) are queued
+// by a database trigger, so no caller has to collect their storage keys.
+func (s *AttachmentService) PurgeDeletedFiles(ctx context.Context) {
+	keys, err := s.attachmentRepo.ListPendingDeletions(ctx, attachmentCleanupBatch)
+	if err != nil {
+		log.Printf("Failed to list orphaned attachment files: %v", err)
+		return
+	}
+	for _, key := range keys {
+		if err := s.store.Delete(ctx, key); err != nil {
+			log.Printf("Failed to delete orphaned attachment file %s: %v", key, err)
+			continue
+		}
+		if err := s.attachmentRepo.CompleteDeletion(ctx, key); err != nil {
+			log.Printf("Failed to dequeue attachment file %s: %v", key, err)
+		}
+	}
+}
+
+// RequestPurge asks the cleanup worker to remove the files of deleted
+// attachments now rather than at its next pass. Requests made while one is
+// pending are merged into it.
+func (s *AttachmentService) RequestPurge() {
+	select {
+	case s.purge <- struct{}{}:
+	default:
+	}
+}
+
+// StartCleanup runs Cleanup every interval, and PurgeDeletedFiles whenever a
+// purge is requested, until ctx is cancelled
+func (s *AttachmentService) StartCleanup(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		s.Cleanup(ctx)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.Cleanup(ctx)
+			case <-s.purge:
+				s.PurgeDeletedFiles(ctx)
+			}
+		}
+	}()
+}
+
+// detectContentType sniffs the file's type from its first bytes; the client's
+// declared type is never trusted
+func detectContentType(content io.ReadSeeker, filename string) (string, error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(content, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(head[:n]))
+	ext := strings.ToLower(filepath.Ext(filename))
+	if contentType == "application/zip" && ext == ".docx" {
+		// DOCX is a zip container; whether it holds a document is only
+		// checked when its text is extracted
+		contentType = docparse.TypeDOCX
+	}
+	if !attachmentTypes[contentType] {
+		return "", rejectAttachment("unsupported file type: %s", contentType)
+	}
+	if contentType == "text/plain" {
+		if subtype, ok := textSubtypes[ext]; ok {
+			return subtype, nil
+		}
+	}
+	return contentType, nil
+}
+
+// sanitizeFilename keeps the base name of a client-supplied filename, without
+// control characters and within the column length
+func sanitizeFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.ToValidUTF8(name, "")
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == "/" {
+		return "file"
+	}
+	for len(name) > maxFilenameLength {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
+}
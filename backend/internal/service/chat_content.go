@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+
+	"github.com/google/uuid"
+	"github.com/ai-chat/backend/internal/model"
+)
+
+const (
+	// maxImagesPerMessage caps the images a single user message can carry
+	maxImagesPerMessage = 4
+
+	// maxImageBytes caps the decoded size of an inline (data URL) image
+	maxImageBytes = 5 << 20
+)
+
+// allowedImageTypes are the inline image formats vision providers accept
+var allowedImageTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// checkMessageContent validates a new message's content parts and
+// attachments, and makes sure the model that will answer it can read any
+// images it carries
+func (s *ChatService) checkMessageContent(ctx context.Context, userID, conversationID, modelID uuid.UUID, req *model.MessageCreateRequest) error {
+	if err := validateContentParts(req.Parts); err != nil {
+		return err
+	}
+	if err := s.attachmentService.CheckAttachable(ctx, userID, conversationID, req.AttachmentIDs); err != nil {
+		return err
+	}
+	if !req.HasImages() {
+		return nil
+	}
+
+	aiModel, err := s.modelRepo.GetByID(ctx, modelID)
+	if err != nil {
+		return fmt.Errorf("failed to get model: %w", err)
+	}
+	if !aiModel.SupportsVision {
+		return &VisionUnsupportedError{Model: aiModel.Name}
+	}
+	return nil
+}
+
+// validateContentParts checks part types, image count, and that every image
+// is an http(s) URL or an allowed, size-limited base64 data URL
+func validateContentParts(parts []model.ContentPart) error {
+	images := 0
+	for _, part := range parts {
+		switch part.Type {
+		case model.ContentPartText:
+		case model.ContentPartImageURL:
+			images++
+			if images > maxImagesPerMessage {
+				return fmt.Errorf("a message can carry at most %d images", maxImagesPerMessage)
+			}
+			if part.ImageURL == nil || part.ImageURL.URL == "" {
+				return fmt.Errorf("image_url part is missing its URL")
+			}
+			switch part.ImageURL.Detail {
+			case "", "auto", "low", "high":
+			default:
+				return fmt.Errorf("invalid image detail: %s", part.ImageURL.Detail)
+			}
+			if err := validateImageURL(part.ImageURL); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unsupported content part type: %s", part.Type)
+		}
+	}
+	return nil
+}
+
+// validateImageURL checks a single image reference
+func validateImageURL(image *model.ImageURL) error {
+	if mediaType, data, ok := image.DataURL(); ok {
+		if !allowedImageTypes[mediaType] {
+			return fmt.Errorf("unsupported image type: %s", mediaType)
+		}
+		if len(data) > base64.StdEncoding.EncodedLen(maxImageBytes) {
+			return fmt.Errorf("image exceeds the %d MB limit", maxImageBytes>>20)
+		}
+		if _, err := base64.StdEncoding.DecodeString(data); err != nil {
+			return fmt.Errorf("image data is not valid base64")
+		}
+		return nil
+	}
+
+	u, err := url.Parse(image.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("image URL must be an http(s) URL or a base64 data URL")
+	}
+	return nil
+}
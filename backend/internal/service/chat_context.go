@@ -0,0 +1,241 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/ai-chat/backend/internal/model"
+	"github.com/ai-chat/backend/internal/pkg/tools"
+)
+
+const (
+	// historyFetchLimit caps how many recent messages are considered for the
+	// context window before token budgeting trims them
+	historyFetchLimit = 200
+
+	// Output tokens kept free for the reply: a quarter of the context window,
+	// but never more than maxReservedOutputTokens
+	maxReservedOutputTokens = 4096
+
+	// customInstructionsKey is the advanced_settings entry holding a user's
+	// custom instructions
+	customInstructionsKey = "custom_instructions"
+)
+
+// chatContext is the prompt assembled for one turn
+type chatContext struct {
+	system  []model.ChatMessage // memory, document, instruction and summary system messages
+	history []model.ChatMessage // conversation turns that fit the budget, oldest first
+	tools   []model.Tool        // tools offered to the model
+	dropped int                 // older turns left out for lack of room
+}
+
+// messages returns the prompt in send order
+func (c *chatContext) messages() []model.ChatMessage {
+	out := make([]model.ChatMessage, 0, len(c.system)+len(c.history))
+	out = append(out, c.system...)
+	return append(out, c.history...)
+}
+
+// reservedOutputTokens is how much of a model's context window is kept for the reply
+func reservedOutputTokens(aiModel *model.AIModel) int {
+	reserved := aiModel.MaxTokens / 4
+	if reserved > maxReservedOutputTokens {
+		reserved = maxReservedOutputTokens
+	}
+	return reserved
+}
+
+// buildChatRequest builds the AI request from memory context, relevant
+// document passages, custom instructions, the rolling summary and as much
+// conversation history as fits the model's context window
+func (s *ChatService) buildChatRequest(ctx context.Context, userID uuid.UUID, conv *model.Conversation, modelID uuid.UUID) (*model.ChatCompletionRequest, error) {
+	aiModel, err := s.modelRepo.GetByID(ctx, modelID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get model: %w", err)
+	}
+
+	cc, err := s.buildChatContext(ctx, userID, conv, aiModel)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.ChatCompletionRequest{
+		Model:    aiModel.ModelIdentifier,
+		Messages: cc.messages(),
+		Tools:    cc.tools,
+	}, nil
+}
+
+// buildChatContext collects the system messages, then fills the remaining
+// token budget with history, newest turns first. Turns that do not fit are
+// represented by the conversation's rolling summary.
+func (s *ChatService) buildChatContext(ctx context.Context, userID uuid.UUID, conv *model.Conversation, aiModel *model.AIModel) (*chatContext, error) {
+	messages, err := s.msgRepo.GetRecentMessages(ctx, conv.ID, historyFetchLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversation history: %w", err)
+	}
+
+	cc := &chatContext{}
+
+	// The user's latest message is the query for memories and documents
+	query := ""
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "user" {
+			query = messages[i].Content
+			break
+		}
+	}
+
+	// The message is embedded once, for both memories and documents
+	searchDocuments := query != "" && s.documentService.Searchable(ctx, userID, conv.ID)
+	var queryEmbedding *QueryEmbedding
+	if conv.MemoryMode.ReadsMemory() || searchDocuments {
+		queryEmbedding, err = s.embeddingService.EmbedQuery(ctx, userID, query)
+		if err != nil {
+			log.Printf("Failed to embed the message in conversation %s: %v", conv.ID, err)
+		}
+	}
+
+	// Add memory context if available and the conversation reads memory
+	if conv.MemoryMode.ReadsMemory() {
+		memoryContext, err := s.memoryService.BuildMemoryContext(ctx, userID, queryEmbedding)
+		if err == nil && memoryContext != "" {
+			cc.system = append(cc.system, model.ChatMessage{
+				Role:    "system",
+				Content: memoryContext,
+			})
+		}
+	}
+
+	// Passages of the user's documents relevant to the current turn
+	if searchDocuments {
+		documentContext, err := s.documentService.BuildDocumentContext(ctx, userID, conv.ID, queryEmbedding)
+		if err != nil {
+			log.Printf("Document retrieval failed for conversation %s: %v", conv.ID, err)
+		} else if documentContext != "" {
+			cc.system = append(cc.system, model.ChatMessage{
+				Role:    "system",
+				Content: documentContext,
+			})
+		}
+	}
+
+	if instructions := s.customInstructions(ctx, userID); instructions != "" {
+		cc.system = append(cc.system, model.ChatMessage{
+			Role:    "system",
+			Content: instructions,
+		})
+	}
+
+	if aiModel.SupportsFunctions {
+		disabled := aiModel.DisabledTools
+		if !conv.MemoryMode.ReadsMemory() {
+			// Searching memories and past conversations is reading memory
+			disabled = append(append([]string{}, disabled...), tools.SearchToolName)
+		}
+		cc.tools = s.toolRegistry.Definitions(disabled)
+	}
+
+	// A model without a known context size gets the full fetched history
+	if aiModel.MaxTokens <= 0 {
+		for _, msg := range messages {
+			cc.history = append(cc.history, historyMessage(msg))
+		}
+		return cc, nil
+	}
+
+	budget := aiModel.MaxTokens - reservedOutputTokens(aiModel) - s.aiProxyService.CountMessagesTokens(aiModel, cc.system) -
+		s.aiProxyService.CountToolsTokens(aiModel, cc.tools)
+
+	var summary *model.ChatMessage
+	if msg, ok := s.summaryService.SummaryMessage(conv); ok {
+		summary = &msg
+	}
+	start, withSummary := fitHistory(messages, budget, summary, func(msg model.ChatMessage) int {
+		return s.aiProxyService.CountMessageTokens(aiModel, msg)
+	})
+	if withSummary {
+		cc.system = append(cc.system, *summary)
+	}
+
+	// Fold everything that was dropped into the summary for later turns
+	if start > 0 {
+		s.summaryService.ScheduleRefresh(conv, messages[start-1].CreatedAt)
+	}
+
+	cc.dropped = start
+	for _, msg := range messages[start:] {
+		cc.history = append(cc.history, historyMessage(msg))
+	}
+
+	return cc, nil
+}
+
+// fitHistory picks the oldest message to keep so that the history, and the
+// rolling summary standing in for the turns before it, fit the budget. It
+// reports whether the summary is to be sent.
+func fitHistory(messages []*model.Message, budget int, summary *model.ChatMessage, cost func(model.ChatMessage) int) (int, bool) {
+	// Walk back from the newest message. The newest message is the user's
+	// current turn and is always kept; if it alone overflows, the proxy's
+	// pre-flight check reports it.
+	costs := make([]int, len(messages))
+	start := len(messages)
+	for i := len(messages) - 1; i >= 0; i-- {
+		costs[i] = cost(historyMessage(messages[i]))
+		if costs[i] > budget && i < len(messages)-1 {
+			break
+		}
+		budget -= costs[i]
+		start = i
+	}
+
+	// Anything older than the fetched window is left to the summary as well
+	if start == 0 && len(messages) == historyFetchLimit {
+		budget += costs[0]
+		start = 1
+	}
+
+	withSummary := false
+	if start > 0 && summary != nil {
+		// Make room for the summary of the dropped turns by giving up more of
+		// the oldest kept ones
+		summaryCost := cost(*summary)
+		for budget < summaryCost && start < len(messages)-1 {
+			budget += costs[start]
+			start++
+		}
+		withSummary = budget >= summaryCost
+	}
+
+	// Some providers require the history to open with a user turn
+	for start < len(messages)-1 && messages[start].Role != "user" {
+		start++
+	}
+	return start, withSummary
+}
+
+// historyMessage converts a stored message, including any tool exchange, into a prompt message
+func historyMessage(msg *model.Message) model.ChatMessage {
+	return model.ChatMessage{
+		Role:       msg.Role,
+		Content:    msg.Content,
+		Parts:      msg.Parts,
+		ToolCalls:  msg.ToolCalls,
+		ToolCallID: msg.ToolCallID,
+	}
+}
+
+// customInstructions returns the user's custom instructions from their settings, if any
+func (s *ChatService) customInstructions(ctx context.Context, userID uuid.UUID) string {
+	settings, err := s.settingsRepo.GetByUserID(ctx, userID)
+	if err != nil || settings.AdvancedSettings == nil {
+		return ""
+	}
+
+	instructions, _ := settings.AdvancedSettings[customInstructionsKey].(string)
+	return strings.TrimSpace(instructions)
+}
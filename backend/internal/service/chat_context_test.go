@@ -0,0 +1,68 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ai-chat/backend/internal/model"
+)
+
+func TestFitHistory(t *testing.T) {
+	// Turns alternate user/assistant and cost 10 each; the summary costs 12
+	turns := func(n int) []*model.Message {
+		messages := make([]*model.Message, n)
+		for i := range messages {
+			role := "user"
+			if i%2 == 1 {
+				role = "assistant"
+			}
+			messages[i] = &model.Message{Role: role, Content: strings.Repeat("x", 10)}
+		}
+		return messages
+	}
+	cost := func(msg model.ChatMessage) int { return len(msg.Content) }
+	summary := &model.ChatMessage{Role: "system", Content: strings.Repeat("s", 12)}
+
+	tests := []struct {
+		name        string
+		messages    []*model.Message
+		budget      int
+		summary     *model.ChatMessage
+		wantStart   int
+		wantSummary bool
+	}{
+		{"everything fits", turns(5), 100, summary, 0, false},
+		{"oldest turns dropped", turns(5), 35, nil, 2, false},
+		// Room for the summary costs the oldest kept turn
+		{"summary replaces a turn", turns(5), 42, summary, 2, true},
+		// ... and the history then has to open with a user turn
+		{"summary and a user turn first", turns(5), 35, summary, 4, true},
+		{"newest turn always kept", turns(5), 5, summary, 4, false},
+		{"full window leaves the oldest to the summary", turns(historyFetchLimit), 1 << 20, summary, 2, true},
+		{"empty", nil, 100, summary, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, withSummary := fitHistory(tt.messages, tt.budget, tt.summary, cost)
+			if start != tt.wantStart || withSummary != tt.wantSummary {
+				t.Errorf("fitHistory() = (%d, %v), want (%d, %v)", start, withSummary, tt.wantStart, tt.wantSummary)
+			}
+		})
+	}
+}
+
+func TestReservedOutputTokens(t *testing.T) {
+	tests := []struct {
+		maxTokens int
+		want      int
+	}{
+		{8000, 2000},
+		{128000, maxReservedOutputTokens},
+		{0, 0},
+	}
+	for _, tt := range tests {
+		if got := reservedOutputTokens(&model.AIModel{MaxTokens: tt.maxTokens}); got != tt.want {
+			t.Errorf("reservedOutputTokens(%d) = %d, want %d", tt.maxTokens, got, tt.want)
+		}
+	}
+}
@@ -8,17 +8,29 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/ai-chat/backend/internal/model"
+	"github.com/ai-chat/backend/internal/pkg/tools"
 	"github.com/ai-chat/backend/internal/repository"
 )
 
+// ephemeralConversationIdle is how long an ephemeral conversation is kept
+// after its last message
+const ephemeralConversationIdle = time.Hour
+
 // ChatService handles chat-related business logic
 type ChatService struct {
-	convRepo         *repository.ConversationRepository
-	msgRepo          *repository.MessageRepository
-	modelRepo        *repository.AIModelRepository
-	tokenUsageRepo   *repository.TokenUsageRepository
-	aiProxyService   *AIProxyService
-	memoryService    *MemoryService
+	convRepo          *repository.ConversationRepository
+	msgRepo           *repository.MessageRepository
+	modelRepo         *repository.AIModelRepository
+	tokenUsageRepo    *repository.TokenUsageRepository
+	settingsRepo      *repository.UserSettingsRepository
+	aiProxyService    *AIProxyService
+	memoryService     *MemoryService
+	memoryJobService  *MemoryJobService
+	summaryService    *SummaryService
+	toolRegistry      *tools.Registry
+	attachmentService *AttachmentService
+	documentService   *DocumentService
+	embeddingService  *EmbeddingService
 }
 
 // NewChatService creates a new chat service
@@ -27,16 +39,30 @@ func NewChatService(
 	msgRepo *repository.MessageRepository,
 	modelRepo *repository.AIModelRepository,
 	tokenUsageRepo *repository.TokenUsageRepository,
+	settingsRepo *repository.UserSettingsRepository,
 	aiProxyService *AIProxyService,
 	memoryService *MemoryService,
+	memoryJobService *MemoryJobService,
+	summaryService *SummaryService,
+	toolRegistry *tools.Registry,
+	attachmentService *AttachmentService,
+	documentService *DocumentService,
+	embeddingService *EmbeddingService,
 ) *ChatService {
 	return &ChatService{
-		convRepo:       convRepo,
-		msgRepo:        msgRepo,
-		modelRepo:      modelRepo,
-		tokenUsageRepo: tokenUsageRepo,
-		aiProxyService: aiProxyService,
-		memoryService:  memoryService,
+		convRepo:          convRepo,
+		msgRepo:           msgRepo,
+		modelRepo:         modelRepo,
+		tokenUsageRepo:    tokenUsageRepo,
+		settingsRepo:      settingsRepo,
+		aiProxyService:    aiProxyService,
+		memoryService:     memoryService,
+		memoryJobService:  memoryJobService,
+		summaryService:    summaryService,
+		toolRegistry:      toolRegistry,
+		attachmentService: attachmentService,
+		documentService:   documentService,
+		embeddingService:  embeddingService,
 	}
 }
 
@@ -47,11 +73,17 @@ func (s *ChatService) CreateConversation(ctx context.Context, userID uuid.UUID,
 		title = req.Title
 	}
 
+	memoryMode := model.MemoryModeFull
+	if req.MemoryMode != "" {
+		memoryMode = req.MemoryMode
+	}
+
 	conv := &model.Conversation{
-		ID:      uuid.New(),
-		UserID:  userID,
-		Title:   title,
-		ModelID: req.ModelID,
+		ID:         uuid.New(),
+		UserID:     userID,
+		Title:      title,
+		ModelID:    req.ModelID,
+		MemoryMode: memoryMode,
 	}
 
 	if err := s.convRepo.Create(ctx, conv); err != nil {
@@ -92,10 +124,26 @@ func (s *ChatService) UpdateConversation(ctx context.Context, userID, conversati
 		conv.Title = *req.Title
 	}
 
+	previousMode := conv.MemoryMode
+	if req.MemoryMode != nil && *req.MemoryMode != conv.MemoryMode {
+		if conv.MemoryMode == model.MemoryModeEphemeral {
+			return nil, fmt.Errorf("memory mode of an ephemeral conversation cannot be changed")
+		}
+		conv.MemoryMode = *req.MemoryMode
+	}
+
 	if err := s.convRepo.Update(ctx, conv); err != nil {
 		return nil, fmt.Errorf("failed to update conversation: %w", err)
 	}
 
+	// Turns from before the switch are neither extracted after memory is
+	// turned off nor later, once it is turned back on
+	if previousMode.WritesMemory() != conv.MemoryMode.WritesMemory() {
+		if err := s.memoryJobService.Skip(ctx, userID, conversationID); err != nil {
+			return nil, err
+		}
+	}
+
 	return conv, nil
 }
 
@@ -110,7 +158,14 @@ func (s *ChatService) DeleteConversation(ctx context.Context, userID, conversati
 		return fmt.Errorf("unauthorized")
 	}
 
-	return s.convRepo.Delete(ctx, conversationID)
+	if err := s.convRepo.Delete(ctx, conversationID); err != nil {
+		return err
+	}
+
+	// Deleting the conversation cascaded to its attachments; have their files removed
+	s.attachmentService.RequestPurge()
+
+	return nil
 }
 
 // GetMessages retrieves messages for a conversation
@@ -121,7 +176,16 @@ func (s *ChatService) GetMessages(ctx context.Context, userID, conversationID uu
 		return nil, err
 	}
 
-	return s.msgRepo.ListByConversation(ctx, conversationID, limit, offset)
+	messages, err := s.msgRepo.ListByConversation(ctx, conversationID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.attachmentService.AttachToMessages(ctx, messages); err != nil {
+		return nil, err
+	}
+
+	return messages, nil
 }
 
 // SendMessage sends a message and gets AI response
@@ -132,200 +196,287 @@ func (s *ChatService) SendMessage(ctx context.Context, userID, conversationID uu
 		return nil, nil, err
 	}
 
-	// Determine which model to use
-	modelID := conv.ModelID
-	if req.ModelID != nil {
-		modelID = req.ModelID
-	}
-
-	if modelID == nil {
-		// Get default model
-		defaultModel, err := s.modelRepo.GetDefault(ctx)
-		if err != nil {
-			return nil, nil, fmt.Errorf("no model specified and no default model configured")
-		}
-		modelID = &defaultModel.ID
+	modelID, err := s.resolveModelID(ctx, conv, req)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	// Save user message
-	userMsg := &model.Message{
-		ID:             uuid.New(),
-		ConversationID: conversationID,
-		Role:           "user",
-		Content:        req.Content,
-		ModelID:        modelID,
+	if err := s.checkMessageContent(ctx, userID, conversationID, *modelID, req); err != nil {
+		return nil, nil, err
 	}
 
-	if err := s.msgRepo.Create(ctx, userMsg); err != nil {
-		return nil, nil, fmt.Errorf("failed to save user message: %w", err)
+	userMsg, err := s.saveUserMessage(ctx, userID, conversationID, modelID, req)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	// Get conversation history
-	messages, err := s.msgRepo.GetRecentMessages(ctx, conversationID, 20)
+	aiRequest, err := s.buildChatRequest(ctx, userID, conv, *modelID)
 	if err != nil {
-		return userMsg, nil, fmt.Errorf("failed to get conversation history: %w", err)
+		return userMsg, nil, err
 	}
 
-	// Build chat messages for AI
-	chatMessages := make([]model.ChatMessage, 0, len(messages))
+	// Send to AI, running any tools it calls until it answers
+	var assistantMsg *model.Message
+	for round := 0; ; round++ {
+		aiResponse, answeredBy, err := s.aiProxyService.SendChatCompletion(ctx, *modelID, aiRequest)
+		if err != nil {
+			return userMsg, nil, fmt.Errorf("failed to get AI response: %w", err)
+		}
+
+		if len(aiResponse.Choices) == 0 {
+			return userMsg, nil, fmt.Errorf("no response from AI")
+		}
 
-	// Add memory context if available
-	memoryContext, err := s.memoryService.BuildMemoryContext(ctx, userID)
-	if err == nil && memoryContext != "" {
-		chatMessages = append(chatMessages, model.ChatMessage{
-			Role:    "system",
-			Content: memoryContext,
-		})
-	}
+		reply := aiResponse.Choices[0].Message
+		reply.Role = "assistant"
+		if round >= maxToolRounds {
+			reply.ToolCalls = nil
+		}
 
-	// Add conversation history
-	for _, msg := range messages {
-		chatMessages = append(chatMessages, model.ChatMessage{
-			Role:    msg.Role,
-			Content: msg.Content,
-		})
+		// Attribute the reply to the model that produced it, which may be a fallback
+		assistantMsg, err = s.saveAssistantMessage(ctx, userID, conversationID, &answeredBy.ID, reply, aiResponse.Usage)
+		if err != nil {
+			return userMsg, nil, err
+		}
+		if len(reply.ToolCalls) == 0 {
+			break
+		}
+
+		results, err := s.runToolCalls(ctx, userID, conversationID, &answeredBy.ID, reply.ToolCalls, aiRequest.Tools)
+		if err != nil {
+			return userMsg, nil, err
+		}
+		continueWithToolResults(aiRequest, reply, results, round)
 	}
 
-	// Prepare AI request
-	aiModel, err := s.modelRepo.GetByID(ctx, *modelID)
+	s.scheduleMemoryExtraction(conv)
+
+	return userMsg, assistantMsg, nil
+}
+
+// GetStreamingResponse gets a streaming response from AI
+// Returns a channel that emits response chunks. The channel is closed once the
+// stream ends and the assistant message has been saved; any failure is sent on
+// the error channel before that happens.
+func (s *ChatService) GetStreamingResponse(ctx context.Context, userID, conversationID uuid.UUID, req *model.MessageCreateRequest) (<-chan *model.ChatCompletionStreamResponse, <-chan error, error) {
+	// Verify conversation ownership
+	conv, err := s.GetConversation(ctx, userID, conversationID)
 	if err != nil {
-		return userMsg, nil, fmt.Errorf("failed to get model: %w", err)
+		return nil, nil, err
 	}
 
-	aiRequest := &model.ChatCompletionRequest{
-		Model:    aiModel.ModelIdentifier,
-		Messages: chatMessages,
+	modelID, err := s.resolveModelID(ctx, conv, req)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	// Send to AI
-	aiResponse, err := s.aiProxyService.SendChatCompletion(ctx, *modelID, aiRequest)
-	if err != nil {
-		return userMsg, nil, fmt.Errorf("failed to get AI response: %w", err)
+	if err := s.checkMessageContent(ctx, userID, conversationID, *modelID, req); err != nil {
+		return nil, nil, err
 	}
 
-	if len(aiResponse.Choices) == 0 {
-		return userMsg, nil, fmt.Errorf("no response from AI")
+	if _, err := s.saveUserMessage(ctx, userID, conversationID, modelID, req); err != nil {
+		return nil, nil, err
 	}
 
-	// Save AI response
-	assistantMsg := &model.Message{
-		ID:             uuid.New(),
-		ConversationID: conversationID,
-		Role:           "assistant",
-		Content:        aiResponse.Choices[0].Message.Content,
-		InputTokens:    &aiResponse.Usage.PromptTokens,
-		OutputTokens:   &aiResponse.Usage.CompletionTokens,
-		TotalTokens:    &aiResponse.Usage.TotalTokens,
-		ModelID:        modelID,
+	aiRequest, err := s.buildChatRequest(ctx, userID, conv, *modelID)
+	if err != nil {
+		return nil, nil, err
 	}
+	aiRequest.Stream = true
+	aiRequest.StreamOptions = &model.ChatCompletionStreamOptions{IncludeUsage: true}
 
-	if err := s.msgRepo.Create(ctx, assistantMsg); err != nil {
-		return userMsg, nil, fmt.Errorf("failed to save assistant message: %w", err)
+	stream, answeredBy, err := s.aiProxyService.SendStreamingChatCompletion(ctx, *modelID, aiRequest)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get AI response: %w", err)
 	}
 
-	// Record token usage
-	cost, _ := s.aiProxyService.EstimateCost(ctx, *modelID, aiResponse.Usage.PromptTokens, aiResponse.Usage.CompletionTokens)
-	_ = s.tokenUsageRepo.RecordUsage(
-		ctx,
-		userID,
-		modelID,
-		aiResponse.Usage.PromptTokens,
-		aiResponse.Usage.CompletionTokens,
-		cost,
-	)
+	responseChan := make(chan *model.ChatCompletionStreamResponse)
+	errorChan := make(chan error, 1)
 
-	// Extract memories from conversation (async)
 	go func() {
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-		defer cancel()
-		if err := s.memoryService.ExtractMemoriesFromConversation(ctx, userID, conversationID); err != nil {
-			log.Printf("Memory extraction failed for conversation %s: %v", conversationID, err)
+		defer close(errorChan)
+		defer close(responseChan)
+
+		var streamErr error
+		saved := false
+
+		for round := 0; ; round++ {
+			reply, usage, err := relayStream(ctx, stream, responseChan)
+			stream.Close()
+			streamErr = err
+
+			// An interrupted round keeps its text but not half-received tool calls
+			if streamErr != nil || round >= maxToolRounds {
+				reply.ToolCalls = nil
+			}
+			if reply.Content == "" && len(reply.ToolCalls) == 0 {
+				break
+			}
+
+			// Persist whatever was generated, even if the client went away mid-stream
+			saveCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+
+			if usage == nil {
+				// Upstream did not report usage, fall back to an estimate
+				promptTokens := s.aiProxyService.CountMessagesTokens(answeredBy, aiRequest.Messages)
+				completionTokens := s.aiProxyService.CountMessageTokens(answeredBy, reply) - tokensPerMessage
+				usage = &model.ChatCompletionUsage{
+					PromptTokens:     promptTokens,
+					CompletionTokens: completionTokens,
+					TotalTokens:      promptTokens + completionTokens,
+				}
+			}
+
+			_, err = s.saveAssistantMessage(saveCtx, userID, conversationID, &answeredBy.ID, reply, *usage)
+			cancel()
+			if err != nil {
+				if streamErr == nil {
+					streamErr = err
+				}
+				break
+			}
+			saved = true
+
+			if len(reply.ToolCalls) == 0 {
+				break
+			}
+
+			results, err := s.runToolCalls(ctx, userID, conversationID, &answeredBy.ID, reply.ToolCalls, aiRequest.Tools)
+			if err != nil {
+				streamErr = err
+				break
+			}
+			continueWithToolResults(aiRequest, reply, results, round)
+
+			stream, answeredBy, err = s.aiProxyService.SendStreamingChatCompletion(ctx, *modelID, aiRequest)
+			if err != nil {
+				streamErr = fmt.Errorf("failed to get AI response: %w", err)
+				break
+			}
+		}
+
+		if saved {
+			s.scheduleMemoryExtraction(conv)
+		}
+
+		if streamErr != nil {
+			errorChan <- streamErr
 		}
 	}()
 
-	return userMsg, assistantMsg, nil
+	return responseChan, errorChan, nil
 }
 
-// GetStreamingResponse gets a streaming response from AI
-// Returns a channel that emits response chunks
-func (s *ChatService) GetStreamingResponse(ctx context.Context, userID, conversationID uuid.UUID, req *model.MessageCreateRequest) (<-chan *model.ChatCompletionStreamResponse, <-chan error, error) {
-	// Verify conversation ownership
-	conv, err := s.GetConversation(ctx, userID, conversationID)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	// Determine which model to use
+// resolveModelID determines which model to use: request override, conversation model, then default
+func (s *ChatService) resolveModelID(ctx context.Context, conv *model.Conversation, req *model.MessageCreateRequest) (*uuid.UUID, error) {
 	modelID := conv.ModelID
 	if req.ModelID != nil {
 		modelID = req.ModelID
 	}
 
 	if modelID == nil {
+		// Get default model
 		defaultModel, err := s.modelRepo.GetDefault(ctx)
 		if err != nil {
-			return nil, nil, fmt.Errorf("no model specified and no default model configured")
+			return nil, fmt.Errorf("no model specified and no default model configured")
 		}
 		modelID = &defaultModel.ID
 	}
 
-	// Save user message
+	return modelID, nil
+}
+
+// saveUserMessage persists the user's message
+func (s *ChatService) saveUserMessage(ctx context.Context, userID, conversationID uuid.UUID, modelID *uuid.UUID, req *model.MessageCreateRequest) (*model.Message, error) {
 	userMsg := &model.Message{
 		ID:             uuid.New(),
 		ConversationID: conversationID,
 		Role:           "user",
 		Content:        req.Content,
+		Parts:          req.Parts,
 		ModelID:        modelID,
 	}
 
 	if err := s.msgRepo.Create(ctx, userMsg); err != nil {
-		return nil, nil, fmt.Errorf("failed to save user message: %w", err)
+		return nil, fmt.Errorf("failed to save user message: %w", err)
 	}
 
-	// Get conversation history
-	messages, err := s.msgRepo.GetRecentMessages(ctx, conversationID, 20)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to get conversation history: %w", err)
+	if err := s.attachmentService.LinkToMessage(ctx, userID, conversationID, userMsg.ID, req.AttachmentIDs); err != nil {
+		// Don't leave a message behind that is missing the files it was sent with
+		if delErr := s.msgRepo.Delete(ctx, userMsg.ID); delErr != nil {
+			log.Printf("Failed to remove message %s after attachment error: %v", userMsg.ID, delErr)
+		}
+		return nil, err
 	}
 
-	// Build chat messages
-	chatMessages := make([]model.ChatMessage, 0, len(messages))
-
-	memoryContext, err := s.memoryService.BuildMemoryContext(ctx, userID)
-	if err == nil && memoryContext != "" {
-		chatMessages = append(chatMessages, model.ChatMessage{
-			Role:    "system",
-			Content: memoryContext,
-		})
-	}
+	return userMsg, nil
+}
 
-	for _, msg := range messages {
-		chatMessages = append(chatMessages, model.ChatMessage{
-			Role:    msg.Role,
-			Content: msg.Content,
-		})
+// saveAssistantMessage persists the AI response, including any tool calls,
+// and records its token usage and cost
+func (s *ChatService) saveAssistantMessage(ctx context.Context, userID, conversationID uuid.UUID, modelID *uuid.UUID, reply model.ChatMessage, usage model.ChatCompletionUsage) (*model.Message, error) {
+	assistantMsg := &model.Message{
+		ID:             uuid.New(),
+		ConversationID: conversationID,
+		Role:           "assistant",
+		Content:        reply.Content,
+		ToolCalls:      reply.ToolCalls,
+		InputTokens:    &usage.PromptTokens,
+		OutputTokens:   &usage.CompletionTokens,
+		TotalTokens:    &usage.TotalTokens,
+		ModelID:        modelID,
 	}
 
-	// Prepare AI request
-	aiModel, err := s.modelRepo.GetByID(ctx, *modelID)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to get model: %w", err)
+	if err := s.msgRepo.Create(ctx, assistantMsg); err != nil {
+		return nil, fmt.Errorf("failed to save assistant message: %w", err)
 	}
 
-	_ = &model.ChatCompletionRequest{
-		Model:    aiModel.ModelIdentifier,
-		Messages: chatMessages,
-		Stream:   true,
-	}
+	// Record token usage
+	cost, _ := s.aiProxyService.EstimateCost(ctx, *modelID, usage.PromptTokens, usage.CompletionTokens)
+	_ = s.tokenUsageRepo.RecordUsage(
+		ctx,
+		userID,
+		modelID,
+		usage.PromptTokens,
+		usage.CompletionTokens,
+		cost,
+	)
 
-	// This would return channels for streaming
-	// Implementation details depend on WebSocket/SSE setup in handlers
-	responseChan := make(chan *model.ChatCompletionStreamResponse)
-	errorChan := make(chan error, 1)
+	return assistantMsg, nil
+}
 
-	// TODO: Implement actual streaming logic in Stage 4 with WebSocket handler
+// scheduleMemoryExtraction queues the finished turn for memory extraction
+// unless the conversation does not write memories. It does not use the
+// request's context, which a closed stream cancels.
+func (s *ChatService) scheduleMemoryExtraction(conv *model.Conversation) {
+	if !conv.MemoryMode.WritesMemory() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	s.memoryJobService.Enqueue(ctx, conv.UserID, conv.ID)
+}
 
-	return responseChan, errorChan, nil
+// StartEphemeralCleanup deletes ephemeral conversations idle for longer than
+// ephemeralConversationIdle, checking every interval until ctx is cancelled
+func (s *ChatService) StartEphemeralCleanup(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if n, err := s.convRepo.DeleteIdleEphemeral(ctx, ephemeralConversationIdle); err != nil {
+				log.Printf("Failed to clean up ephemeral conversations: %v", err)
+			} else if n > 0 {
+				// The deletions cascaded to attachments; remove their files
+				s.attachmentService.PurgeDeletedFiles(ctx)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
 }
 
 // AvailableModel is a user-safe view of an AI model (no API keys or internal URLs)
@@ -336,10 +487,11 @@ type AvailableModel struct {
 	Description      string `json:"description,omitempty"`
 	IsDefault        bool   `json:"is_default"`
 	SupportsStreaming bool   `json:"supports_streaming"`
+	SupportsVision   bool   `json:"supports_vision"`
 	MaxTokens        int    `json:"max_tokens,omitempty"`
 }
 
-// ListAvailableModels returns active models with only user-safe fields
+// ListAvailableModels returns active chat models with only user-safe fields
 func (s *ChatService) ListAvailableModels(ctx context.Context) ([]AvailableModel, error) {
 	models, err := s.modelRepo.List(ctx, true) // activeOnly=true
 	if err != nil {
@@ -347,6 +499,9 @@ func (s *ChatService) ListAvailableModels(ctx context.Context) ([]AvailableModel
 	}
 	result := make([]AvailableModel, 0, len(models))
 	for _, m := range models {
+		if m.SupportsEmbeddings {
+			continue // embedding models cannot chat
+		}
 		result = append(result, AvailableModel{
 			ID:               m.ID.String(),
 			Name:             m.Name,
@@ -354,6 +509,7 @@ func (s *ChatService) ListAvailableModels(ctx context.Context) ([]AvailableModel
 			Description:      m.Description,
 			IsDefault:        m.IsDefault,
 			SupportsStreaming: m.SupportsStreaming,
+			SupportsVision:   m.SupportsVision,
 			MaxTokens:        m.MaxTokens,
 		})
 	}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ai-chat/backend/internal/model"
+	"github.com/ai-chat/backend/internal/pkg/llm"
+	"github.com/ai-chat/backend/internal/pkg/tools"
+)
+
+const (
+	// maxToolRounds caps how many times a model may call tools within one
+	// turn; the last round is asked to answer without tools
+	maxToolRounds = 5
+
+	// toolCallTimeout bounds a single tool execution
+	toolCallTimeout = 30 * time.Second
+)
+
+// runToolCalls executes the tools an assistant turn asked for, persists each
+// result as a "tool" message and returns the results in call order. Only the
+// tools offered in the request may run. A failing tool does not fail the
+// turn: its error is reported back to the model.
+func (s *ChatService) runToolCalls(ctx context.Context, userID, conversationID uuid.UUID, modelID *uuid.UUID, calls []model.ToolCall, offered []model.Tool) ([]model.ChatMessage, error) {
+	results := make([]model.ChatMessage, 0, len(calls))
+	for _, call := range calls {
+		result := "Error: tool " + call.Function.Name + " is not available"
+		if toolOffered(offered, call.Function.Name) {
+			result = s.executeToolCall(ctx, userID, call)
+		}
+
+		toolMsg := &model.Message{
+			ID:             uuid.New(),
+			ConversationID: conversationID,
+			Role:           "tool",
+			Content:        result,
+			ToolCallID:     call.ID,
+			ModelID:        modelID,
+		}
+		if err := s.msgRepo.Create(ctx, toolMsg); err != nil {
+			return nil, fmt.Errorf("failed to save tool message: %w", err)
+		}
+
+		results = append(results, historyMessage(toolMsg))
+	}
+	return results, nil
+}
+
+// executeToolCall runs one tool call and returns the text to hand back to the model
+func (s *ChatService) executeToolCall(ctx context.Context, userID uuid.UUID, call model.ToolCall) string {
+	arguments := strings.TrimSpace(call.Function.Arguments)
+	if arguments == "" {
+		arguments = "{}"
+	}
+	if !json.Valid([]byte(arguments)) {
+		return "Error: arguments are not valid JSON"
+	}
+
+	toolCtx, cancel := context.WithTimeout(ctx, toolCallTimeout)
+	defer cancel()
+
+	result, err := s.toolRegistry.Execute(toolCtx, call.Function.Name, &tools.Call{
+		UserID:    userID,
+		Arguments: json.RawMessage(arguments),
+	})
+	if err != nil {
+		log.Printf("Tool %s failed: %v", call.Function.Name, err)
+		return "Error: " + err.Error()
+	}
+	return result
+}
+
+// toolOffered reports whether a tool was offered to the model
+func toolOffered(offered []model.Tool, name string) bool {
+	for _, tool := range offered {
+		if tool.Function.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// continueWithToolResults appends an assistant tool-call turn and its results
+// to the request. Once the rounds are used up the model must answer in text.
+func continueWithToolResults(request *model.ChatCompletionRequest, reply model.ChatMessage, results []model.ChatMessage, round int) {
+	request.Messages = append(request.Messages, reply)
+	request.Messages = append(request.Messages, results...)
+	if round+1 >= maxToolRounds {
+		request.ToolChoice = &model.ToolChoice{Mode: model.ToolChoiceNone}
+	}
+}
+
+// relayStream forwards one upstream stream to the client and collects the
+// assistant reply, joining tool call fragments by their index
+func relayStream(ctx context.Context, stream *llm.Stream, out chan<- *model.ChatCompletionStreamResponse) (model.ChatMessage, *model.ChatCompletionUsage, error) {
+	reply := model.ChatMessage{Role: "assistant"}
+	var content strings.Builder
+	var usage *model.ChatCompletionUsage
+	calls := make(map[int]*model.ToolCall)
+
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			reply.Content = content.String()
+			return reply, usage, upstreamFailure(err)
+		}
+
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+		if len(chunk.Choices) > 0 {
+			delta := chunk.Choices[0].Delta
+			content.WriteString(delta.Content)
+
+			for _, fragment := range delta.ToolCalls {
+				index := 0
+				if fragment.Index != nil {
+					index = *fragment.Index
+				}
+				call, ok := calls[index]
+				if !ok {
+					call = &model.ToolCall{Type: "function"}
+					calls[index] = call
+				}
+				if fragment.ID != "" {
+					call.ID = fragment.ID
+				}
+				if fragment.Function.Name != "" {
+					call.Function.Name = fragment.Function.Name
+				}
+				call.Function.Arguments += fragment.Function.Arguments
+			}
+		}
+
+		select {
+		case out <- chunk:
+		case <-ctx.Done():
+			reply.Content = content.String()
+			return reply, usage, ctx.Err()
+		}
+	}
+
+	reply.Content = content.String()
+
+	indexes := make([]int, 0, len(calls))
+	for index := range calls {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	for _, index := range indexes {
+		reply.ToolCalls = append(reply.ToolCalls, *calls[index])
+	}
+
+	return reply, usage, nil
+}
@@ -0,0 +1,319 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/ai-chat/backend/internal/model"
+	"github.com/ai-chat/backend/internal/pkg/docparse"
+	"github.com/ai-chat/backend/internal/repository"
+)
+
+const (
+	// Chunks are sized in runes: about 250-300 English words, fewer for CJK
+	documentChunkRunes   = 1500
+	documentChunkOverlap = 200
+	// maxDocumentChunks bounds the embedding work for a single document
+	maxDocumentChunks = 2000
+	// embeddingBatchSize is how many chunks are embedded per request
+	embeddingBatchSize = 64
+	// documentProcessTimeout bounds extracting and embedding one document
+	documentProcessTimeout = 10 * time.Minute
+	// documentWorkers is how many documents are processed at once
+	documentWorkers = 2
+
+	// Retrieval: the best documentExcerptLimit chunks scoring at least
+	// documentMinScore, within documentCharBudget characters
+	documentExcerptLimit = 5
+	documentMinScore     = 0.2
+	documentCharBudget   = 6000
+)
+
+// DocumentService indexes uploaded files for retrieval and finds the passages
+// relevant to a message
+type DocumentService struct {
+	documentRepo      *repository.DocumentRepository
+	convRepo          *repository.ConversationRepository
+	attachmentService *AttachmentService
+	embeddingService  *EmbeddingService
+	workers           chan struct{} // semaphore bounding concurrent processing
+	inflight          sync.Map      // document ID -> struct{}, processing in progress
+}
+
+// NewDocumentService creates a new document service. Without an embedding
+// model, documents cannot be indexed.
+func NewDocumentService(
+	documentRepo *repository.DocumentRepository,
+	convRepo *repository.ConversationRepository,
+	attachmentService *AttachmentService,
+	embeddingService *EmbeddingService,
+) *DocumentService {
+	return &DocumentService{
+		documentRepo:      documentRepo,
+		convRepo:          convRepo,
+		attachmentService: attachmentService,
+		embeddingService:  embeddingService,
+		workers:           make(chan struct{}, documentWorkers),
+	}
+}
+
+// CreateDocument indexes one of the user's attachments. Text extraction and
+// embedding run in the background; the document's status reports progress.
+func (s *DocumentService) CreateDocument(ctx context.Context, userID uuid.UUID, req *model.DocumentCreateRequest) (*model.Document, error) {
+	if _, err := s.embeddingService.Model(ctx); err != nil {
+		return nil, err
+	}
+
+	attachment, err := s.attachmentService.GetAttachment(ctx, userID, req.AttachmentID)
+	if err != nil {
+		return nil, err
+	}
+	if !docparse.Supported(attachment.ContentType) {
+		return nil, fmt.Errorf("unsupported document type: %s", attachment.ContentType)
+	}
+
+	if req.ConversationID != nil {
+		conv, err := s.convRepo.GetByID(ctx, *req.ConversationID)
+		if err != nil {
+			return nil, fmt.Errorf("conversation not found")
+		}
+		if conv.UserID != userID {
+			return nil, fmt.Errorf("unauthorized")
+		}
+		if attachment.ConversationID != nil && *attachment.ConversationID != conv.ID {
+			return nil, fmt.Errorf("attachment belongs to another conversation")
+		}
+	} else if attachment.ConversationID != nil {
+		// The file would be deleted along with its conversation
+		return nil, fmt.Errorf("attachment belongs to a conversation and cannot be added to the knowledge base")
+	}
+
+	doc := &model.Document{
+		ID:             uuid.New(),
+		UserID:         userID,
+		AttachmentID:   attachment.ID,
+		ConversationID: req.ConversationID,
+		Filename:       attachment.Filename,
+		Status:         model.DocumentStatusPending,
+	}
+	if err := s.documentRepo.Create(ctx, doc); err != nil {
+		return nil, err
+	}
+
+	s.scheduleProcessing(doc)
+	return doc, nil
+}
+
+// GetDocument retrieves a document owned by the user
+func (s *DocumentService) GetDocument(ctx context.Context, userID, documentID uuid.UUID) (*model.Document, error) {
+	doc, err := s.documentRepo.GetByID(ctx, documentID)
+	if err != nil {
+		return nil, fmt.Errorf("document not found")
+	}
+
+	// Check ownership
+	if doc.UserID != userID {
+		return nil, fmt.Errorf("unauthorized")
+	}
+
+	return doc, nil
+}
+
+// ListDocuments lists the user's documents, optionally only those of one conversation
+func (s *DocumentService) ListDocuments(ctx context.Context, userID uuid.UUID, conversationID *uuid.UUID) ([]*model.Document, error) {
+	return s.documentRepo.ListByUser(ctx, userID, conversationID)
+}
+
+// DeleteDocument removes a document and its chunks. Its file is deleted too
+// unless a message was sent with it.
+func (s *DocumentService) DeleteDocument(ctx context.Context, userID, documentID uuid.UUID) error {
+	doc, err := s.GetDocument(ctx, userID, documentID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.documentRepo.Delete(ctx, doc.ID); err != nil {
+		return fmt.Errorf("failed to delete document: %w", err)
+	}
+
+	attachment, err := s.attachmentService.GetAttachment(ctx, userID, doc.AttachmentID)
+	if err != nil || attachment.MessageID != nil {
+		return nil
+	}
+	return s.attachmentService.DeleteAttachment(ctx, userID, attachment.ID)
+}
+
+// ResumeProcessing schedules documents left unfinished, e.g. by a restart
+func (s *DocumentService) ResumeProcessing(ctx context.Context) {
+	docs, err := s.documentRepo.ListUnfinished(ctx)
+	if err != nil {
+		log.Printf("Failed to list unfinished documents: %v", err)
+		return
+	}
+	for _, doc := range docs {
+		s.scheduleProcessing(doc)
+	}
+}
+
+// scheduleProcessing processes a document in the background, at most once at
+// a time and with a bounded number of documents in flight
+func (s *DocumentService) scheduleProcessing(doc *model.Document) {
+	if _, running := s.inflight.LoadOrStore(doc.ID, struct{}{}); running {
+		return
+	}
+
+	go func() {
+		defer s.inflight.Delete(doc.ID)
+
+		s.workers <- struct{}{}
+		defer func() { <-s.workers }()
+
+		ctx, cancel := context.WithTimeout(context.Background(), documentProcessTimeout)
+		defer cancel()
+		if err := s.processSafely(ctx, doc); err != nil {
+			log.Printf("Failed to index document %s: %v", doc.ID, err)
+			if updateErr := s.documentRepo.UpdateStatus(context.Background(), doc.ID, model.DocumentStatusFailed, ClientErrorMessage(err)); updateErr != nil {
+				log.Printf("Failed to mark document %s failed: %v", doc.ID, updateErr)
+			}
+		}
+	}()
+}
+
+// processSafely runs process, turning a panic, e.g. in a parser handed a
+// malformed file, into an error so one document cannot bring the server down
+func (s *DocumentService) processSafely(ctx context.Context, doc *model.Document) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Panic while processing document %s: %v\n%s", doc.ID, r, debug.Stack())
+			err = fmt.Errorf("failed to read document")
+		}
+	}()
+	return s.process(ctx, doc)
+}
+
+// process extracts, chunks and embeds a document, then stores its chunks
+func (s *DocumentService) process(ctx context.Context, doc *model.Document) error {
+	if err := s.documentRepo.UpdateStatus(ctx, doc.ID, model.DocumentStatusProcessing, ""); err != nil {
+		return err
+	}
+
+	embeddingModel, err := s.embeddingService.Model(ctx)
+	if err != nil {
+		return err
+	}
+
+	attachment, err := s.attachmentService.GetAttachment(ctx, doc.UserID, doc.AttachmentID)
+	if err != nil {
+		return err
+	}
+	data, err := s.attachmentService.ReadContent(ctx, attachment)
+	if err != nil {
+		return err
+	}
+
+	text, err := docparse.Extract(attachment.ContentType, data)
+	if err != nil {
+		return err
+	}
+	pieces := docparse.Chunk(text, documentChunkRunes, documentChunkOverlap)
+	if len(pieces) > maxDocumentChunks {
+		return fmt.Errorf("document is too long (%d chunks, at most %d)", len(pieces), maxDocumentChunks)
+	}
+
+	chunks := make([]*model.DocumentChunk, 0, len(pieces))
+	for start := 0; start < len(pieces); start += embeddingBatchSize {
+		end := start + embeddingBatchSize
+		if end > len(pieces) {
+			end = len(pieces)
+		}
+
+		// Prefix each chunk with its source so passages embed with their context
+		inputs := make([]string, 0, end-start)
+		for _, piece := range pieces[start:end] {
+			inputs = append(inputs, doc.Filename+"\n\n"+piece)
+		}
+		vectors, err := s.embeddingService.Embed(ctx, doc.UserID, embeddingModel, inputs)
+		if err != nil {
+			return fmt.Errorf("failed to embed document: %w", err)
+		}
+
+		for i, vector := range vectors {
+			chunks = append(chunks, &model.DocumentChunk{
+				ChunkIndex: start + i,
+				Content:    pieces[start+i],
+				Embedding:  vector,
+			})
+		}
+	}
+
+	return s.documentRepo.SaveChunks(ctx, doc, embeddingModel.ModelIdentifier, chunks)
+}
+
+// Searchable reports whether the user has documents that a search in the
+// conversation would cover, so the query is only embedded when it is needed
+func (s *DocumentService) Searchable(ctx context.Context, userID, conversationID uuid.UUID) bool {
+	// Without an embedding model nothing can have been indexed
+	embeddingModel, err := s.embeddingService.Model(ctx)
+	if err != nil {
+		return false
+	}
+	searchable, err := s.documentRepo.HasSearchable(ctx, userID, conversationID, embeddingModel.ModelIdentifier)
+	if err != nil {
+		log.Printf("Failed to check documents of user %s: %v", userID, err)
+		return false
+	}
+	return searchable
+}
+
+// Retrieve finds the passages of the user's knowledge base and of the
+// conversation's documents most relevant to the query
+func (s *DocumentService) Retrieve(ctx context.Context, userID, conversationID uuid.UUID, query *QueryEmbedding) ([]*model.DocumentExcerpt, error) {
+	if query == nil {
+		return nil, nil
+	}
+
+	excerpts, err := s.documentRepo.Search(ctx, userID, conversationID, query.Model, query.Vector, documentExcerptLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	relevant := excerpts[:0]
+	for _, e := range excerpts {
+		if e.Score >= documentMinScore {
+			relevant = append(relevant, e)
+		}
+	}
+	return relevant, nil
+}
+
+// BuildDocumentContext returns a system message with the passages relevant to
+// the user's message, numbered so the answer can cite them, or "" when no
+// document matches
+func (s *DocumentService) BuildDocumentContext(ctx context.Context, userID, conversationID uuid.UUID, query *QueryEmbedding) (string, error) {
+	excerpts, err := s.Retrieve(ctx, userID, conversationID, query)
+	if err != nil || len(excerpts) == 0 {
+		return "", err
+	}
+
+	var body strings.Builder
+	used := 0
+	for i, e := range excerpts {
+		entry := fmt.Sprintf("[%d] 来源：%s（片段 %d）\n%s\n\n", i+1, e.Filename, e.ChunkIndex+1, e.Content)
+		entryLen := utf8.RuneCountInString(entry)
+		if used+entryLen > documentCharBudget && used > 0 {
+			break
+		}
+		body.WriteString(entry)
+		used += entryLen
+	}
+
+	return "以下是从用户文档中检索到的相关内容。回答时请优先依据这些内容，并用 [编号] 标注引用的来源；" +
+		"若内容与问题无关则忽略：\n\n" + strings.TrimSpace(body.String()), nil
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/ai-chat/backend/internal/model"
+	"github.com/ai-chat/backend/internal/repository"
+)
+
+// queryRunes truncates a message embedded as a search query
+const queryRunes = 2000
+
+// QueryEmbedding is the embedding of a search query. Vectors of different
+// models cannot be compared, so it names the model it was computed with.
+type QueryEmbedding struct {
+	Model  string // model identifier
+	Vector []float32
+}
+
+// EmbeddingService embeds text with the model chosen by the administrator and
+// bills the tokens to the user the text belongs to
+type EmbeddingService struct {
+	modelRepo      *repository.AIModelRepository
+	tokenUsageRepo *repository.TokenUsageRepository
+	aiProxyService *AIProxyService
+	embeddingModel string
+}
+
+// NewEmbeddingService creates a new embedding service. embeddingModel names
+// the model by name or identifier; when empty, the first active model that
+// supports embeddings is used.
+func NewEmbeddingService(
+	modelRepo *repository.AIModelRepository,
+	tokenUsageRepo *repository.TokenUsageRepository,
+	aiProxyService *AIProxyService,
+	embeddingModel string,
+) *EmbeddingService {
+	return &EmbeddingService{
+		modelRepo:      modelRepo,
+		tokenUsageRepo: tokenUsageRepo,
+		aiProxyService: aiProxyService,
+		embeddingModel: embeddingModel,
+	}
+}
+
+// Model returns the embedding model. Unlike utility models there is no
+// fallback to a chat model, which cannot produce embeddings.
+func (s *EmbeddingService) Model(ctx context.Context) (*model.AIModel, error) {
+	models, err := s.modelRepo.List(ctx, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list models: %w", err)
+	}
+
+	for _, m := range models {
+		if !m.SupportsEmbeddings {
+			continue
+		}
+		if s.embeddingModel == "" || m.ModelIdentifier == s.embeddingModel || m.Name == s.embeddingModel {
+			return m, nil
+		}
+	}
+
+	if s.embeddingModel == "" {
+		return nil, fmt.Errorf("no embedding model is configured")
+	}
+	return nil, fmt.Errorf("embedding model %s is not available", s.embeddingModel)
+}
+
+// Embed embeds texts on behalf of a user, one vector per text in input order,
+// and records the tokens as the user's usage
+func (s *EmbeddingService) Embed(ctx context.Context, userID uuid.UUID, aiModel *model.AIModel, texts []string) ([][]float32, error) {
+	vectors, usage, err := s.aiProxyService.CreateEmbeddings(ctx, aiModel, texts)
+	if err != nil {
+		return nil, err
+	}
+
+	if usage.PromptTokens > 0 {
+		cost, _ := s.aiProxyService.EstimateCost(ctx, aiModel.ID, usage.PromptTokens, 0)
+		_ = s.tokenUsageRepo.RecordUsage(ctx, userID, &aiModel.ID, usage.PromptTokens, 0, cost)
+	}
+
+	return vectors, nil
+}
+
+// EmbedQuery embeds a message as the query for memories and documents. It
+// returns nil when the message is empty or no embedding model is configured.
+func (s *EmbeddingService) EmbedQuery(ctx context.Context, userID uuid.UUID, query string) (*QueryEmbedding, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, nil
+	}
+	aiModel, err := s.Model(ctx)
+	if err != nil {
+		return nil, nil
+	}
+
+	if utf8.RuneCountInString(query) > queryRunes {
+		query = string([]rune(query)[:queryRunes])
+	}
+	vectors, err := s.Embed(ctx, userID, aiModel, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+	return &QueryEmbedding{Model: aiModel.ModelIdentifier, Vector: vectors[0]}, nil
+}
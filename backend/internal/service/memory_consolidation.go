@@ -0,0 +1,379 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/ai-chat/backend/internal/model"
+	"github.com/ai-chat/backend/internal/pkg/vector"
+)
+
+const (
+	// Each extracted memory is compared with up to memoryConsolidationNeighbors
+	// stored memories at least memoryRelatedSimilarity similar to it. Without
+	// embeddings, the memoryConsolidationContext most important memories are
+	// compared instead.
+	memoryConsolidationNeighbors = 5
+	memoryRelatedSimilarity      = 0.5
+	memoryConsolidationContext   = 30
+
+	// memoryDuplicateSimilarity marks a stored memory as saying the same thing
+	// when the model's verdict is unavailable
+	memoryDuplicateSimilarity = 0.92
+)
+
+// consolidateMemories reconciles newly extracted memories with the stored ones.
+// The extraction model decides per memory whether to add it, update or delete
+// a related stored memory, or drop it as already known; when it cannot be
+// asked, near duplicates are dropped and the rest added.
+func (s *MemoryService) consolidateMemories(ctx context.Context, userID, conversationID uuid.UUID, extracted []model.MemoryExtractionResult) error {
+	facts := normalizeExtractedMemories(extracted)
+	if len(facts) == 0 {
+		return nil
+	}
+
+	// Embeddings find the related memories and are stored with the results
+	var vectors [][]float32
+	modelIdentifier := ""
+	embeddingModel, err := s.embeddingService.Model(ctx)
+	if err != nil {
+		embeddingModel = nil
+	} else {
+		modelIdentifier = embeddingModel.ModelIdentifier
+		texts := make([]string, len(facts))
+		for i, fact := range facts {
+			texts[i] = fact.Content
+		}
+		if vectors, err = s.embeddingService.Embed(ctx, userID, embeddingModel, texts); err != nil {
+			log.Printf("Failed to embed extracted memories for user %s: %v", userID, err)
+			vectors = nil
+		}
+	}
+
+	existing, err := s.memoryRepo.ListForRanking(ctx, userID, modelIdentifier, maxRankedMemories)
+	if err != nil {
+		return err
+	}
+
+	candidates := relatedMemories(facts, vectors, existing)
+	decisions := fallbackDecisions(facts, vectors, candidates)
+	if len(candidates) > 0 {
+		judged, err := s.judgeMemories(ctx, facts, candidates)
+		if err != nil {
+			log.Printf("Memory consolidation for user %s fell back to duplicate detection: %v", userID, err)
+		}
+		for i, d := range judged {
+			decisions[i] = d
+		}
+	}
+
+	touched := make(map[uuid.UUID]bool)
+	var unembedded []*model.Memory
+	for i, fact := range facts {
+		d := decisions[i]
+		var factVector []float32
+		if vectors != nil {
+			factVector = vectors[i]
+		}
+
+		// A stored memory is changed at most once per extraction
+		if (d.Action == model.MemoryActionUpdate || d.Action == model.MemoryActionDelete) && touched[candidates[d.Target].ID] {
+			d.Action = model.MemoryActionAdd
+		}
+
+		switch d.Action {
+		case model.MemoryActionNoop:
+			continue
+
+		case model.MemoryActionDelete:
+			target := candidates[d.Target]
+			touched[target.ID] = true
+			if err := s.memoryRepo.Delete(ctx, target.ID); err != nil {
+				log.Printf("Failed to delete memory %s: %v", target.ID, err)
+				continue
+			}
+			s.recordVersion(ctx, target, model.MemoryChangeDelete, model.MemoryChangedByConsolidation)
+
+		case model.MemoryActionUpdate:
+			target := candidates[d.Target]
+			touched[target.ID] = true
+
+			content := strings.TrimSpace(d.Content)
+			if content == "" {
+				content = fact.Content
+			}
+			target.Content = content
+			target.Category = fact.Category
+			if fact.Importance > target.Importance {
+				target.Importance = fact.Importance
+			}
+			// The memory now rests on the newer message
+			if fact.SourceMessageID != nil {
+				target.SourceConversationID = &conversationID
+				target.SourceMessageID = fact.SourceMessageID
+			}
+			if err := s.memoryRepo.Update(ctx, target); err != nil {
+				log.Printf("Failed to update memory %s: %v", target.ID, err)
+				continue
+			}
+			s.recordVersion(ctx, target, model.MemoryChangeUpdate, model.MemoryChangedByConsolidation)
+			s.storeEmbedding(ctx, target, embeddingModel, fact.Content, factVector, &unembedded)
+
+		default:
+			memory := &model.Memory{
+				ID:                   uuid.New(),
+				UserID:               userID,
+				Content:              fact.Content,
+				Category:             fact.Category,
+				Importance:           fact.Importance,
+				SourceConversationID: &conversationID,
+				SourceMessageID:      fact.SourceMessageID,
+			}
+			if err := s.memoryRepo.Create(ctx, memory); err != nil {
+				// Log error but continue with other memories
+				log.Printf("Failed to create memory for user %s: %v", userID, err)
+				continue
+			}
+			s.recordVersion(ctx, memory, model.MemoryChangeCreate, model.MemoryChangedByExtraction)
+			s.storeEmbedding(ctx, memory, embeddingModel, fact.Content, factVector, &unembedded)
+		}
+	}
+
+	// Merged contents differ from what was embedded above
+	if embeddingModel != nil {
+		s.embedMemories(ctx, userID, embeddingModel, unembedded)
+	}
+
+	return nil
+}
+
+// storeEmbedding saves the fact's embedding with a memory holding exactly the
+// fact's content; other memories are queued to be embedded
+func (s *MemoryService) storeEmbedding(ctx context.Context, memory *model.Memory, embeddingModel *model.AIModel, fact string, factVector []float32, unembedded *[]*model.Memory) {
+	if embeddingModel == nil {
+		return
+	}
+	if factVector == nil || memory.Content != fact {
+		*unembedded = append(*unembedded, memory)
+		return
+	}
+	if err := s.memoryRepo.SetEmbedding(ctx, memory.ID, embeddingModel.ModelIdentifier, factVector); err != nil {
+		log.Printf("Failed to save embedding of memory %s: %v", memory.ID, err)
+	}
+}
+
+// judgeMemories asks the extraction model how each fact relates to the
+// candidate memories. Facts it gives no valid verdict for are left out of the
+// result, which is keyed by fact index.
+func (s *MemoryService) judgeMemories(ctx context.Context, facts []model.MemoryExtractionResult, candidates []*model.Memory) (map[int]model.MemoryConsolidationDecision, error) {
+	var existingList, factList strings.Builder
+	for i, mem := range candidates {
+		existingList.WriteString(fmt.Sprintf("%d. [%s] %s\n", i+1, mem.Category, mem.Content))
+	}
+	for i, fact := range facts {
+		factList.WriteString(fmt.Sprintf("%d. [%s] %s\n", i+1, fact.Category, fact.Content))
+	}
+
+	prompt := fmt.Sprintf(`已有记忆：
+%s
+新提取的记忆：
+%s
+为每条新记忆选择一个操作，输出JSON数组：
+[{"fact":新记忆编号,"action":"ADD|UPDATE|DELETE|NOOP","target":已有记忆编号,"content":"更新后的内容"}]
+ADD：已有记忆中没有的新信息
+UPDATE：与某条已有记忆说的是同一件事，但信息有变化或可以合并；content为合并后的内容（≤40字）
+DELETE：新信息表明某条已有记忆已不再成立，且新信息本身无需保存
+NOOP：已有记忆已包含该信息`, existingList.String(), factList.String())
+
+	judgeModel, err := selectUtilityModel(ctx, s.modelRepo, s.defaultModel)
+	if err != nil {
+		return nil, err
+	}
+
+	maxTokens := 60*len(facts) + 100
+	request := &model.ChatCompletionRequest{
+		Model: judgeModel.ModelIdentifier,
+		Messages: []model.ChatMessage{
+			{
+				Role:    "system",
+				Content: "你负责维护用户的记忆库，使其准确且不重复。新信息与旧记忆矛盾时以新信息为准。只返回JSON数组。",
+			},
+			{
+				Role:    "user",
+				Content: prompt,
+			},
+		},
+		Temperature: func() *float64 { t := 0.0; return &t }(),
+		MaxTokens:   &maxTokens,
+	}
+
+	response, _, err := s.aiProxyService.SendChatCompletion(ctx, judgeModel.ID, request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call AI: %w", err)
+	}
+	if len(response.Choices) == 0 {
+		return nil, fmt.Errorf("no response from AI")
+	}
+
+	var decisions []model.MemoryConsolidationDecision
+	if err := parseJSONReply(response.Choices[0].Message.Content, &decisions); err != nil {
+		return nil, fmt.Errorf("failed to parse AI response: %w", err)
+	}
+
+	return judgedDecisions(decisions, len(facts), len(candidates)), nil
+}
+
+// judgedDecisions keeps the model's first valid verdict per fact, converted
+// to 0-based indexes and keyed by fact
+func judgedDecisions(decisions []model.MemoryConsolidationDecision, facts, candidates int) map[int]model.MemoryConsolidationDecision {
+	judged := make(map[int]model.MemoryConsolidationDecision)
+	for _, d := range decisions {
+		d.Fact--
+		d.Target--
+		d.Action = model.MemoryAction(strings.ToUpper(string(d.Action)))
+		if d.Fact < 0 || d.Fact >= facts {
+			continue
+		}
+		if _, seen := judged[d.Fact]; seen {
+			continue
+		}
+
+		switch d.Action {
+		case model.MemoryActionAdd, model.MemoryActionNoop:
+		case model.MemoryActionUpdate, model.MemoryActionDelete:
+			if d.Target < 0 || d.Target >= candidates {
+				continue
+			}
+		default:
+			continue
+		}
+		judged[d.Fact] = d
+	}
+
+	return judged
+}
+
+// relatedMemories picks the stored memories the facts are compared with:
+// each fact's nearest neighbours, or the most important memories when there
+// are no embeddings, plus any memory containing a fact or contained in one
+func relatedMemories(facts []model.MemoryExtractionResult, vectors [][]float32, existing []*model.Memory) []*model.Memory {
+	picked := make(map[uuid.UUID]bool)
+	var related []*model.Memory
+	add := func(mem *model.Memory) {
+		if !picked[mem.ID] {
+			picked[mem.ID] = true
+			related = append(related, mem)
+		}
+	}
+
+	if vectors == nil {
+		for i, mem := range existing {
+			if i >= memoryConsolidationContext {
+				break
+			}
+			add(mem)
+		}
+	} else {
+		type neighbour struct {
+			memory     *model.Memory
+			similarity float64
+		}
+		for i := range facts {
+			var neighbours []neighbour
+			for _, mem := range existing {
+				if len(mem.Embedding) != len(vectors[i]) {
+					continue
+				}
+				if similarity := vector.Cosine(vectors[i], mem.Embedding); similarity >= memoryRelatedSimilarity {
+					neighbours = append(neighbours, neighbour{memory: mem, similarity: similarity})
+				}
+			}
+			sort.Slice(neighbours, func(a, b int) bool { return neighbours[a].similarity > neighbours[b].similarity })
+			for j, n := range neighbours {
+				if j >= memoryConsolidationNeighbors {
+					break
+				}
+				add(n.memory)
+			}
+		}
+	}
+
+	for _, fact := range facts {
+		for _, mem := range existing {
+			if isSimilarMemory(mem.Content, fact.Content) {
+				add(mem)
+			}
+		}
+	}
+
+	return related
+}
+
+// fallbackDecisions drops facts a candidate memory already states and adds
+// the rest
+func fallbackDecisions(facts []model.MemoryExtractionResult, vectors [][]float32, candidates []*model.Memory) []model.MemoryConsolidationDecision {
+	decisions := make([]model.MemoryConsolidationDecision, len(facts))
+	for i, fact := range facts {
+		decisions[i] = model.MemoryConsolidationDecision{Fact: i, Action: model.MemoryActionAdd}
+		var factVector []float32
+		if vectors != nil {
+			factVector = vectors[i]
+		}
+		for _, mem := range candidates {
+			if isDuplicateMemory(mem, fact.Content, factVector) {
+				decisions[i].Action = model.MemoryActionNoop
+				break
+			}
+		}
+	}
+	return decisions
+}
+
+// isDuplicateMemory reports whether a stored memory already states the fact,
+// going by the text or, given the fact's embedding, by near identical meaning
+func isDuplicateMemory(mem *model.Memory, fact string, factVector []float32) bool {
+	if isSimilarMemory(mem.Content, fact) {
+		return true
+	}
+	return factVector != nil && len(mem.Embedding) == len(factVector) &&
+		vector.Cosine(factVector, mem.Embedding) >= memoryDuplicateSimilarity
+}
+
+// normalizeExtractedMemories trims extracted memories, fixes out-of-range
+// categories and importance, and drops empty ones and repeats within the batch
+func normalizeExtractedMemories(extracted []model.MemoryExtractionResult) []model.MemoryExtractionResult {
+	facts := make([]model.MemoryExtractionResult, 0, len(extracted))
+	for _, mem := range extracted {
+		mem.Content = strings.TrimSpace(mem.Content)
+		if mem.Content == "" {
+			continue
+		}
+
+		switch mem.Category {
+		case model.MemoryCategoryPreference, model.MemoryCategoryFact, model.MemoryCategoryContext:
+		default:
+			mem.Category = model.MemoryCategoryContext
+		}
+		if mem.Importance < 1 {
+			mem.Importance = 1
+		} else if mem.Importance > 10 {
+			mem.Importance = 10
+		}
+
+		repeated := false
+		for _, fact := range facts {
+			if isSimilarMemory(fact.Content, mem.Content) {
+				repeated = true
+				break
+			}
+		}
+		if !repeated {
+			facts = append(facts, mem)
+		}
+	}
+	return facts
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/ai-chat/backend/internal/model"
+)
+
+func contents(memories []*model.Memory) []string {
+	var out []string
+	for _, mem := range memories {
+		out = append(out, mem.Content)
+	}
+	return out
+}
+
+func TestRelatedMemories(t *testing.T) {
+	memory := func(content string, embedding ...float32) *model.Memory {
+		return &model.Memory{ID: uuid.New(), Content: content, Embedding: embedding}
+	}
+	facts := []model.MemoryExtractionResult{{Content: "Has a dog"}}
+
+	// The nearest neighbours at least memoryRelatedSimilarity similar, closest
+	// first, then memories sharing the text
+	existing := []*model.Memory{
+		memory("far", 0, 1),
+		memory("n3", 1, 0.3),
+		memory("n1", 1, 0),
+		memory("other dimensions", 1, 0, 0),
+		memory("n5", 1, 0.5),
+		memory("n2", 1, 0.2),
+		memory("n6", 1, 0.6),
+		memory("n4", 1, 0.4),
+		memory("Has a dog named Rex", 0, 1),
+	}
+	got := contents(relatedMemories(facts, [][]float32{{1, 0}}, existing))
+	want := []string{"n1", "n2", "n3", "n4", "n5", "Has a dog named Rex"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("relatedMemories() with embeddings = %v, want %v", got, want)
+	}
+
+	// Without embeddings, the first (most important) memories are compared
+	existing = nil
+	for i := 0; i < memoryConsolidationContext+5; i++ {
+		existing = append(existing, memory(fmt.Sprintf("memory %d", i)))
+	}
+	existing = append(existing, memory("has a dog"))
+	related := relatedMemories(facts, nil, existing)
+	if len(related) != memoryConsolidationContext+1 || related[len(related)-1].Content != "has a dog" {
+		t.Errorf("relatedMemories() without embeddings = %v, want the first %d and %q", contents(related), memoryConsolidationContext, "has a dog")
+	}
+
+	// A memory matching several facts is listed once
+	facts = append(facts, model.MemoryExtractionResult{Content: "a dog"})
+	existing = []*model.Memory{memory("has a dog", 1, 0)}
+	if got := relatedMemories(facts, [][]float32{{1, 0}, {1, 0}}, existing); len(got) != 1 {
+		t.Errorf("relatedMemories() = %v, want only %q", contents(got), "has a dog")
+	}
+}
+
+func TestFallbackDecisions(t *testing.T) {
+	facts := []model.MemoryExtractionResult{
+		{Content: "Has a dog"},
+		{Content: "Lives in Berlin"},
+		{Content: "Likes tea"},
+	}
+	candidates := []*model.Memory{
+		{Content: "User has a dog named Rex", Embedding: []float32{1, 0}},
+		{Content: "Resides in Berlin, Germany", Embedding: []float32{0, 1}},
+	}
+	actions := func(decisions []model.MemoryConsolidationDecision) []model.MemoryAction {
+		var out []model.MemoryAction
+		for i, d := range decisions {
+			if d.Fact != i {
+				t.Errorf("decision %d is for fact %d", i, d.Fact)
+			}
+			out = append(out, d.Action)
+		}
+		return out
+	}
+
+	// Duplicates are found by text, and by meaning given embeddings
+	got := actions(fallbackDecisions(facts, [][]float32{{0.7, 0.7}, {0.05, 1}, {0.7, 0.7}}, candidates))
+	want := []model.MemoryAction{model.MemoryActionNoop, model.MemoryActionNoop, model.MemoryActionAdd}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("fallbackDecisions() with embeddings = %v, want %v", got, want)
+	}
+
+	got = actions(fallbackDecisions(facts, nil, candidates))
+	want = []model.MemoryAction{model.MemoryActionNoop, model.MemoryActionAdd, model.MemoryActionAdd}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("fallbackDecisions() without embeddings = %v, want %v", got, want)
+	}
+}
+
+func TestJudgedDecisions(t *testing.T) {
+	decisions := []model.MemoryConsolidationDecision{
+		{Fact: 1, Action: "update", Target: 2, Content: "Has two dogs"},
+		{Fact: 1, Action: model.MemoryActionAdd},               // second verdict for fact 1
+		{Fact: 2, Action: model.MemoryActionDelete},            // no target
+		{Fact: 3, Action: model.MemoryActionUpdate, Target: 3}, // target out of range
+		{Fact: 4, Action: "MERGE"},
+		{Fact: 5, Action: model.MemoryActionNoop},
+		{Fact: 0, Action: model.MemoryActionAdd},
+		{Fact: 7, Action: model.MemoryActionAdd},
+	}
+
+	got := judgedDecisions(decisions, 6, 2)
+	want := map[int]model.MemoryConsolidationDecision{
+		0: {Fact: 0, Action: model.MemoryActionUpdate, Target: 1, Content: "Has two dogs"},
+		4: {Fact: 4, Action: model.MemoryActionNoop, Target: -1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("judgedDecisions() = %+v, want %+v", got, want)
+	}
+}
+
+func TestNormalizeExtractedMemories(t *testing.T) {
+	extracted := []model.MemoryExtractionResult{
+		{Content: "  Likes tea  ", Category: model.MemoryCategoryPreference, Importance: 12},
+		{Content: "   ", Category: model.MemoryCategoryFact, Importance: 5},
+		{Content: "likes tea a lot", Category: model.MemoryCategoryPreference, Importance: 6},
+		{Content: "Has a dog", Category: "pets", Importance: 0},
+	}
+
+	got := normalizeExtractedMemories(extracted)
+	want := []model.MemoryExtractionResult{
+		{Content: "Likes tea", Category: model.MemoryCategoryPreference, Importance: 10},
+		{Content: "Has a dog", Category: model.MemoryCategoryContext, Importance: 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("normalizeExtractedMemories() = %+v, want %+v", got, want)
+	}
+}
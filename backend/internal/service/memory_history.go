@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ai-chat/backend/internal/model"
+)
+
+// recordVersion adds the memory's current state to its history. A failure is
+// logged; the change itself has already been made.
+func (s *MemoryService) recordVersion(ctx context.Context, memory *model.Memory, changeType model.MemoryChangeType, changedBy model.MemoryChangeSource) {
+	if err := s.versionRepo.Record(ctx, memory, changeType, changedBy); err != nil {
+		log.Printf("Failed to record version of memory %s: %v", memory.ID, err)
+	}
+}
+
+// GetMemoryHistory lists a memory's versions, newest first. The history of a
+// deleted memory remains available.
+func (s *MemoryService) GetMemoryHistory(ctx context.Context, userID, memoryID uuid.UUID) ([]*model.MemoryVersion, error) {
+	versions, err := s.versionRepo.ListByMemory(ctx, userID, memoryID)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("memory not found")
+	}
+	return versions, nil
+}
+
+// RollbackMemory returns a memory to the state of an earlier version,
+// restoring it if it has been deleted. The rollback is itself a new version.
+func (s *MemoryService) RollbackMemory(ctx context.Context, userID, memoryID uuid.UUID, version int) (*model.Memory, error) {
+	target, err := s.versionRepo.Get(ctx, userID, memoryID, version)
+	if err != nil {
+		return nil, err
+	}
+	if target.ChangeType == model.MemoryChangeDelete {
+		return nil, fmt.Errorf("cannot roll back to a deletion")
+	}
+
+	changeType := model.MemoryChangeUpdate
+	memory, err := s.memoryRepo.GetByID(ctx, memoryID)
+	if err != nil {
+		// Deleted since; bring it back under its old ID
+		memory = &model.Memory{ID: memoryID, UserID: userID}
+		changeType = model.MemoryChangeRestore
+	} else if memory.UserID != userID {
+		return nil, fmt.Errorf("unauthorized")
+	}
+
+	memory.Content = target.Content
+	memory.Category = target.Category
+	memory.Importance = target.Importance
+	memory.SourceConversationID, memory.SourceMessageID = nil, nil
+
+	// Keep the source only while the message still exists
+	if target.SourceMessageID != nil {
+		if msg, err := s.messageRepo.GetByID(ctx, *target.SourceMessageID); err == nil {
+			memory.SourceConversationID = &msg.ConversationID
+			memory.SourceMessageID = &msg.ID
+		}
+	}
+
+	if changeType == model.MemoryChangeRestore {
+		err = s.memoryRepo.Create(ctx, memory)
+	} else {
+		err = s.memoryRepo.Update(ctx, memory)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to roll back memory: %w", err)
+	}
+	s.recordVersion(ctx, memory, changeType, model.MemoryChangedByRollback)
+
+	s.cache.Delete(userID.String())
+	return memory, nil
+}
+
+// GetMemorySource returns the message a memory was extracted from
+func (s *MemoryService) GetMemorySource(ctx context.Context, userID, memoryID uuid.UUID) (*model.MemorySource, error) {
+	memory, err := s.memoryRepo.GetByID(ctx, memoryID)
+	if err != nil {
+		return nil, fmt.Errorf("memory not found")
+	}
+	if memory.UserID != userID {
+		return nil, fmt.Errorf("unauthorized")
+	}
+	if memory.SourceMessageID == nil {
+		return nil, fmt.Errorf("memory has no source message")
+	}
+
+	msg, err := s.messageRepo.GetByID(ctx, *memory.SourceMessageID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.MemorySource{
+		MemoryID:       memory.ID,
+		ConversationID: msg.ConversationID,
+		Message:        msg,
+	}, nil
+}
+
+// StartVersionCleanup prunes, every interval, the memory history older than
+// retention. A zero retention keeps the history forever.
+func (s *MemoryService) StartVersionCleanup(ctx context.Context, retention, interval time.Duration) {
+	if retention <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if n, err := s.versionRepo.DeleteBefore(ctx, time.Now().Add(-retention)); err != nil {
+				log.Printf("Failed to prune memory history: %v", err)
+			} else if n > 0 {
+				log.Printf("Pruned %d old memory versions", n)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ai-chat/backend/internal/database/dbtest"
+	"github.com/ai-chat/backend/internal/model"
+	"github.com/ai-chat/backend/internal/repository"
+)
+
+func newHistoryTestService(db *sql.DB) *MemoryService {
+	return NewMemoryService(repository.NewMemoryRepository(db), repository.NewMemoryVersionRepository(db),
+		repository.NewMessageRepository(db), nil, nil, nil, false, "")
+}
+
+// versionSummary lists a memory's history, newest first, as change type and content
+func versionSummary(t *testing.T, s *MemoryService, userID, memoryID uuid.UUID) []string {
+	t.Helper()
+	versions, err := s.GetMemoryHistory(context.Background(), userID, memoryID)
+	if err != nil {
+		t.Fatalf("GetMemoryHistory() error = %v", err)
+	}
+	var out []string
+	for _, v := range versions {
+		out = append(out, string(v.ChangeType)+" "+string(v.ChangedBy)+": "+v.Content)
+	}
+	return out
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRollbackMemory(t *testing.T) {
+	db := dbtest.Open(t)
+	s := newHistoryTestService(db)
+	ctx := context.Background()
+	userID := dbtest.CreateUser(t, db)
+
+	memory, err := s.CreateMemory(ctx, userID, &model.MemoryCreateRequest{Content: "Likes tea", Category: model.MemoryCategoryPreference, Importance: 5})
+	if err != nil {
+		t.Fatalf("CreateMemory() error = %v", err)
+	}
+	coffee := "Likes coffee"
+	if _, err := s.UpdateMemory(ctx, userID, memory.ID, &model.MemoryUpdateRequest{Content: &coffee}); err != nil {
+		t.Fatalf("UpdateMemory() error = %v", err)
+	}
+	if err := s.DeleteMemory(ctx, userID, memory.ID); err != nil {
+		t.Fatalf("DeleteMemory() error = %v", err)
+	}
+
+	// The history of a deleted memory remains
+	got := versionSummary(t, s, userID, memory.ID)
+	want := []string{"delete user: Likes coffee", "update user: Likes coffee", "create user: Likes tea"}
+	if !equalStrings(got, want) {
+		t.Fatalf("history = %v, want %v", got, want)
+	}
+
+	if _, err := s.RollbackMemory(ctx, userID, memory.ID, 3); err == nil {
+		t.Error("RollbackMemory() to a deletion succeeded")
+	}
+	other := dbtest.CreateUser(t, db)
+	if _, err := s.RollbackMemory(ctx, other, memory.ID, 1); err == nil {
+		t.Error("RollbackMemory() of another user's memory succeeded")
+	}
+
+	// Rolling back a deleted memory restores it under its ID
+	restored, err := s.RollbackMemory(ctx, userID, memory.ID, 1)
+	if err != nil {
+		t.Fatalf("RollbackMemory() error = %v", err)
+	}
+	if restored.ID != memory.ID || restored.Content != "Likes tea" {
+		t.Errorf("restored memory = %+v, want %q under %s", restored, "Likes tea", memory.ID)
+	}
+	stored, err := repository.NewMemoryRepository(db).GetByID(ctx, memory.ID)
+	if err != nil || stored.Content != "Likes tea" || stored.Importance != 5 {
+		t.Fatalf("stored memory = %+v, %v; want it restored", stored, err)
+	}
+
+	// Rolling back an existing memory updates it
+	if _, err := s.RollbackMemory(ctx, userID, memory.ID, 2); err != nil {
+		t.Fatalf("RollbackMemory() error = %v", err)
+	}
+	got = versionSummary(t, s, userID, memory.ID)
+	want = append([]string{"update rollback: Likes coffee", "restore rollback: Likes tea"}, want...)
+	if !equalStrings(got, want) {
+		t.Errorf("history = %v, want %v", got, want)
+	}
+}
+
+func TestRollbackMemorySource(t *testing.T) {
+	db := dbtest.Open(t)
+	s := newHistoryTestService(db)
+	ctx := context.Background()
+	userID := dbtest.CreateUser(t, db)
+	convID := dbtest.CreateConversation(t, db, userID)
+	msgID := uuid.New()
+	dbtest.Exec(t, db, `INSERT INTO messages (id, conversation_id, role, content) VALUES ($1, $2, 'user', 'I have a dog')`, msgID, convID)
+
+	memory := &model.Memory{
+		ID: uuid.New(), UserID: userID, Content: "Has a dog", Category: model.MemoryCategoryFact, Importance: 5,
+		SourceConversationID: &convID, SourceMessageID: &msgID,
+	}
+	if err := s.memoryRepo.Create(ctx, memory); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	s.recordVersion(ctx, memory, model.MemoryChangeCreate, model.MemoryChangedByExtraction)
+
+	// The source is kept while the message exists...
+	rolledBack, err := s.RollbackMemory(ctx, userID, memory.ID, 1)
+	if err != nil {
+		t.Fatalf("RollbackMemory() error = %v", err)
+	}
+	if rolledBack.SourceMessageID == nil || *rolledBack.SourceMessageID != msgID {
+		t.Errorf("source after rollback = %v, want %s", rolledBack.SourceMessageID, msgID)
+	}
+
+	// ... and dropped once it is gone
+	dbtest.Exec(t, db, `DELETE FROM messages WHERE id = $1`, msgID)
+	rolledBack, err = s.RollbackMemory(ctx, userID, memory.ID, 1)
+	if err != nil {
+		t.Fatalf("RollbackMemory() error = %v", err)
+	}
+	if rolledBack.SourceMessageID != nil || rolledBack.SourceConversationID != nil {
+		t.Errorf("source after the message was deleted = %v, want none", rolledBack.SourceMessageID)
+	}
+}
+
+func TestMemoryVersionCleanup(t *testing.T) {
+	db := dbtest.Open(t)
+	s := newHistoryTestService(db)
+	ctx := context.Background()
+	userID := dbtest.CreateUser(t, db)
+
+	create := func(content string, category model.MemoryCategory) *model.Memory {
+		t.Helper()
+		memory, err := s.CreateMemory(ctx, userID, &model.MemoryCreateRequest{Content: content, Category: category, Importance: 5})
+		if err != nil {
+			t.Fatalf("CreateMemory() error = %v", err)
+		}
+		return memory
+	}
+	kept := create("Likes tea", model.MemoryCategoryPreference)
+	coffee := "Likes coffee"
+	if _, err := s.UpdateMemory(ctx, userID, kept.ID, &model.MemoryUpdateRequest{Content: &coffee}); err != nil {
+		t.Fatalf("UpdateMemory() error = %v", err)
+	}
+	deleted := create("Has a dog", model.MemoryCategoryFact)
+
+	// Bulk deletion records a version per memory
+	if n, err := s.DeleteMemories(ctx, userID, model.MemoryCategoryFact, nil); err != nil || n != 1 {
+		t.Fatalf("DeleteMemories() = %d, %v, want 1", n, err)
+	}
+	got := versionSummary(t, s, userID, deleted.ID)
+	if want := []string{"delete user: Has a dog", "create user: Has a dog"}; !equalStrings(got, want) {
+		t.Fatalf("history of the bulk deleted memory = %v, want %v", got, want)
+	}
+
+	// Pruning keeps only the current state of existing memories
+	n, err := s.versionRepo.DeleteBefore(ctx, time.Now().Add(48*time.Hour))
+	if err != nil {
+		t.Fatalf("DeleteBefore() error = %v", err)
+	}
+	if n != 3 {
+		t.Errorf("DeleteBefore() deleted %d versions, want 3", n)
+	}
+	got = versionSummary(t, s, userID, kept.ID)
+	if want := []string{"update user: Likes coffee"}; !equalStrings(got, want) {
+		t.Errorf("history after pruning = %v, want %v", got, want)
+	}
+	if _, err := s.GetMemoryHistory(ctx, userID, deleted.ID); err == nil {
+		t.Error("history of the deleted memory survived pruning")
+	}
+}
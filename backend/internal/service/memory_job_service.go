@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ai-chat/backend/internal/model"
+	"github.com/ai-chat/backend/internal/repository"
+)
+
+const (
+	// Workers look for due jobs every memoryJobPollInterval and give a run
+	// memoryJobTimeout; a job still running after memoryJobStaleAfter is
+	// assumed abandoned and claimed again
+	memoryJobPollInterval = 2 * time.Second
+	memoryJobTimeout      = 2 * time.Minute
+	memoryJobStaleAfter   = 10 * time.Minute
+
+	// A failing job is retried after memoryJobBackoff, doubled per attempt,
+	// until memoryJobMaxAttempts attempts have failed
+	memoryJobMaxAttempts = 5
+	memoryJobBackoff     = 30 * time.Second
+)
+
+// MemoryJobService queues memory extraction per conversation and runs it in
+// a fixed pool of workers. Jobs live in the database, so pending work survives
+// restarts and each message is read for memories once.
+type MemoryJobService struct {
+	jobRepo       *repository.MemoryJobRepository
+	memoryService *MemoryService
+	turns         int
+	idle          time.Duration
+	workers       int
+}
+
+// NewMemoryJobService creates a new memory job service. A conversation's job
+// becomes due after `turns` new turns or once it has been idle for `idle`.
+func NewMemoryJobService(
+	jobRepo *repository.MemoryJobRepository,
+	memoryService *MemoryService,
+	turns int,
+	idle time.Duration,
+	workers int,
+) *MemoryJobService {
+	return &MemoryJobService{
+		jobRepo:       jobRepo,
+		memoryService: memoryService,
+		turns:         turns,
+		idle:          idle,
+		workers:       workers,
+	}
+}
+
+// Enqueue records a completed turn in a conversation
+func (s *MemoryJobService) Enqueue(ctx context.Context, userID, conversationID uuid.UUID) {
+	if !s.memoryService.Enabled() {
+		return
+	}
+	if err := s.jobRepo.Enqueue(ctx, userID, conversationID, s.turns, s.idle); err != nil {
+		log.Printf("Failed to queue memory extraction for conversation %s: %v", conversationID, err)
+	}
+}
+
+// Skip excludes the conversation's messages so far from memory extraction,
+// e.g. when it stops or starts writing memories
+func (s *MemoryJobService) Skip(ctx context.Context, userID, conversationID uuid.UUID) error {
+	return s.jobRepo.Skip(ctx, userID, conversationID)
+}
+
+// Start runs the workers until ctx is cancelled
+func (s *MemoryJobService) Start(ctx context.Context) {
+	for i := 0; i < s.workers; i++ {
+		go func() {
+			ticker := time.NewTicker(memoryJobPollInterval)
+			defer ticker.Stop()
+			for {
+				// Drain due jobs before waiting for the next poll
+				for s.runNext(ctx) {
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}()
+	}
+}
+
+// runNext claims and runs one due job. It reports whether there was one.
+func (s *MemoryJobService) runNext(ctx context.Context) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	job, err := s.jobRepo.Claim(ctx, memoryJobStaleAfter)
+	if err != nil {
+		log.Printf("Failed to claim memory extraction job: %v", err)
+		return false
+	}
+	if job == nil {
+		return false
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, memoryJobTimeout)
+	defer cancel()
+
+	// Messages up to the claim are covered by the turns counted so far;
+	// later ones belong to turns counted during the run
+	until := time.Now()
+	if job.LockedAt != nil {
+		until = *job.LockedAt
+	}
+
+	watermark, more, err := s.memoryService.ExtractMemoriesFromConversation(runCtx, job.UserID, job.ConversationID, job.Watermark(), until)
+	if err != nil {
+		log.Printf("Memory extraction failed for conversation %s (attempt %d): %v", job.ConversationID, job.Attempts+1, err)
+		if err := s.jobRepo.Fail(ctx, job.ID, err.Error(), memoryJobMaxAttempts, memoryJobBackoff); err != nil {
+			log.Printf("Failed to record memory extraction failure: %v", err)
+		}
+		return true
+	}
+
+	if err := s.jobRepo.Complete(ctx, job.ID, job.PendingTurns, watermark, more, s.turns, s.idle); err != nil {
+		log.Printf("Failed to complete memory extraction job %s: %v", job.ID, err)
+	}
+	return true
+}
+
+// ListJobs lists jobs, optionally only those with a status, with the number
+// of jobs per status
+func (s *MemoryJobService) ListJobs(ctx context.Context, status model.MemoryJobStatus, limit, offset int) ([]*model.MemoryExtractionJob, map[model.MemoryJobStatus]int, error) {
+	jobs, err := s.jobRepo.List(ctx, status, limit, offset)
+	if err != nil {
+		return nil, nil, err
+	}
+	counts, err := s.jobRepo.CountByStatus(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return jobs, counts, nil
+}
+
+// RetryJob makes a failed job due immediately
+func (s *MemoryJobService) RetryJob(ctx context.Context, id uuid.UUID) error {
+	retried, err := s.jobRepo.Retry(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !retried {
+		return fmt.Errorf("job not found or not failed")
+	}
+	return nil
+}
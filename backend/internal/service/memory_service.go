@@ -4,136 +4,194 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log"
+	"math"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/ai-chat/backend/internal/model"
+	"github.com/ai-chat/backend/internal/pkg/vector"
 	"github.com/ai-chat/backend/internal/repository"
 )
 
-const memoryCharBudget = 1200
+const (
+	memoryCharBudget = 1200
 
+	// Ranking: up to maxRankedMemories candidates are scored by a weighted
+	// sum of similarity to the current message, importance and recency
+	maxRankedMemories      = 300
+	memorySimilarityWeight = 0.6
+	memoryImportanceWeight = 0.25
+	memoryRecencyWeight    = 0.15
+	memoryRecencyHalfLife  = 30 * 24 * time.Hour
+
+	// Memories less similar to the message than memoryMinSimilarity are left
+	// out unless their importance is at least memoryPinnedImportance
+	memoryMinSimilarity    = 0.2
+	memoryPinnedImportance = 9
+
+	// memoryEmbedBatch bounds how many missing embeddings are computed at once
+	memoryEmbedBatch = 32
+
+	// memoryExtractionBatch bounds how many new messages one extraction reads
+	memoryExtractionBatch = 20
+
+	// maxMemorySearchTerms caps how many words a memory search matches on
+	maxMemorySearchTerms = 5
+)
+
+// memoryCacheEntry holds a user's ranking candidates. embeddingModel is nil
+// when no embedding model is available and memories are ranked without
+// similarity.
 type memoryCacheEntry struct {
-	context   string
-	expiresAt time.Time
+	memories       []*model.Memory
+	embeddingModel *model.AIModel
+	expiresAt      time.Time
 }
 
 // MemoryService handles memory extraction and management
 type MemoryService struct {
-	memoryRepo     *repository.MemoryRepository
-	messageRepo    *repository.MessageRepository
-	aiProxyService *AIProxyService
-	modelRepo      *repository.AIModelRepository
-	enabled        bool
-	defaultModel   string
-	cache          sync.Map      // key: uuid.UUID string → *memoryCacheEntry
-	cacheTTL       time.Duration // 5 minutes
+	memoryRepo       *repository.MemoryRepository
+	versionRepo      *repository.MemoryVersionRepository
+	messageRepo      *repository.MessageRepository
+	aiProxyService   *AIProxyService
+	embeddingService *EmbeddingService
+	modelRepo        *repository.AIModelRepository
+	enabled          bool
+	defaultModel     string
+	cache            sync.Map      // key: uuid.UUID string → *memoryCacheEntry
+	cacheTTL         time.Duration // 5 minutes
 }
 
 // NewMemoryService creates a new memory service
 func NewMemoryService(
 	memoryRepo *repository.MemoryRepository,
+	versionRepo *repository.MemoryVersionRepository,
 	messageRepo *repository.MessageRepository,
 	aiProxyService *AIProxyService,
+	embeddingService *EmbeddingService,
 	modelRepo *repository.AIModelRepository,
 	enabled bool,
 	defaultModel string,
 ) *MemoryService {
 	return &MemoryService{
-		memoryRepo:     memoryRepo,
-		messageRepo:    messageRepo,
-		aiProxyService: aiProxyService,
-		modelRepo:      modelRepo,
-		enabled:        enabled,
-		defaultModel:   defaultModel,
-		cacheTTL:       5 * time.Minute,
+		memoryRepo:       memoryRepo,
+		versionRepo:      versionRepo,
+		messageRepo:      messageRepo,
+		aiProxyService:   aiProxyService,
+		embeddingService: embeddingService,
+		modelRepo:        modelRepo,
+		enabled:          enabled,
+		defaultModel:     defaultModel,
+		cacheTTL:         5 * time.Minute,
 	}
 }
 
-// ExtractMemoriesFromConversation extracts memories from recent conversation
-func (s *MemoryService) ExtractMemoriesFromConversation(ctx context.Context, userID, conversationID uuid.UUID) error {
+// Enabled reports whether memories are extracted from conversations
+func (s *MemoryService) Enabled() bool {
+	return s.enabled
+}
+
+// ExtractMemoriesFromConversation extracts memories from the conversation's
+// messages after the cursor (from the start when nil) created up to `until`,
+// reading at most memoryExtractionBatch of them. It returns the position of
+// the last message read, nil when there was none, and whether more messages
+// remain.
+func (s *MemoryService) ExtractMemoriesFromConversation(ctx context.Context, userID, conversationID uuid.UUID, after *model.MessageCursor, until time.Time) (*model.MessageCursor, bool, error) {
 	if !s.enabled {
-		return nil
+		return nil, false, nil
 	}
 
-	// Get recent messages (last 10)
-	messages, err := s.messageRepo.GetRecentMessages(ctx, conversationID, 10)
+	messages, err := s.messageRepo.ListRange(ctx, conversationID, after, until, memoryExtractionBatch+1)
 	if err != nil {
-		return fmt.Errorf("failed to get recent messages: %w", err)
+		return nil, false, fmt.Errorf("failed to get new messages: %w", err)
 	}
 
-	if len(messages) < 2 {
-		return nil // Not enough context for memory extraction
+	more := len(messages) > memoryExtractionBatch
+	if more {
+		messages = messages[:memoryExtractionBatch]
 	}
+	if len(messages) == 0 {
+		return nil, false, nil
+	}
+	watermark := messages[len(messages)-1].Cursor()
 
 	// Build conversation context
-	conversationText := s.buildConversationText(messages)
+	conversationText, numbered := s.buildConversationText(messages)
+	if strings.TrimSpace(conversationText) == "" {
+		return &watermark, more, nil
+	}
 
 	// Extract memories using small model
 	extractedMemories, err := s.extractMemoriesWithAI(ctx, conversationText)
 	if err != nil {
-		return fmt.Errorf("failed to extract memories: %w", err)
+		return nil, false, fmt.Errorf("failed to extract memories: %w", err)
 	}
+	attributeSources(extractedMemories, numbered)
 
-	// Save extracted memories
-	for _, mem := range extractedMemories {
-		// Check for duplicates
-		existingMemories, err := s.memoryRepo.GetRelevantMemories(ctx, userID, 100)
-		if err != nil {
-			continue
-		}
-
-		isDuplicate := false
-		for _, existing := range existingMemories {
-			if s.isSimilar(existing.Content, mem.Content) {
-				isDuplicate = true
-				break
-			}
-		}
-
-		if isDuplicate {
-			continue
-		}
-
-		// Create new memory
-		memory := &model.Memory{
-			ID:                   uuid.New(),
-			UserID:               userID,
-			Content:              mem.Content,
-			Category:             mem.Category,
-			Importance:           mem.Importance,
-			SourceConversationID: &conversationID,
-		}
-
-		if err := s.memoryRepo.Create(ctx, memory); err != nil {
-			// Log error but continue with other memories
-			continue
-		}
+	// Reconcile them with what is already known
+	if err := s.consolidateMemories(ctx, userID, conversationID, extractedMemories); err != nil {
+		return nil, false, fmt.Errorf("failed to save memories: %w", err)
 	}
 
 	// Invalidate cache for this user so next BuildMemoryContext reloads
 	s.cache.Delete(userID.String())
 
-	return nil
+	return &watermark, more, nil
 }
 
-// buildConversationText builds a text representation of the conversation
-func (s *MemoryService) buildConversationText(messages []*model.Message) string {
+// buildConversationText builds a text representation of the conversation,
+// numbering the messages from 1 so extracted memories can cite their source.
+// It returns the numbered messages in order.
+func (s *MemoryService) buildConversationText(messages []*model.Message) (string, []*model.Message) {
 	var builder strings.Builder
+	var numbered []*model.Message
 
 	for _, msg := range messages {
+		// Tool results and bare tool calls are scaffolding, not conversation
+		if msg.Role == "tool" || msg.Content == "" {
+			continue
+		}
 		role := msg.Role
 		if role == "user" {
 			role = "用户"
 		} else if role == "assistant" {
 			role = "助手"
 		}
-		builder.WriteString(fmt.Sprintf("%s: %s\n", role, msg.Content))
+		numbered = append(numbered, msg)
+		builder.WriteString(fmt.Sprintf("[%d] %s: %s\n", len(numbered), role, msg.Content))
 	}
 
-	return builder.String()
+	return builder.String(), numbered
+}
+
+// attributeSources resolves each extracted memory's cited message number. A
+// memory citing no valid number is attributed to the batch's only user
+// message, if there is just one, and otherwise left without a source.
+func attributeSources(extracted []model.MemoryExtractionResult, numbered []*model.Message) {
+	var onlyUserMessage *model.Message
+	for _, msg := range numbered {
+		if msg.Role != "user" {
+			continue
+		}
+		if onlyUserMessage != nil {
+			onlyUserMessage = nil
+			break
+		}
+		onlyUserMessage = msg
+	}
+
+	for i := range extracted {
+		source := extracted[i].Source
+		if source >= 1 && source <= len(numbered) {
+			extracted[i].SourceMessageID = &numbered[source-1].ID
+		} else if onlyUserMessage != nil {
+			extracted[i].SourceMessageID = &onlyUserMessage.ID
+		}
+	}
 }
 
 // extractMemoriesWithAI uses AI to extract memories from conversation
@@ -142,34 +200,19 @@ func (s *MemoryService) extractMemoriesWithAI(ctx context.Context, conversationT
 	prompt := fmt.Sprintf(`对话内容：
 %s
 
-提取用户明确说明的信息（每条≤40字），输出JSON数组：
-[{"content":"内容","category":"preference|fact|context","importance":1-10}]
+提取用户明确说明的信息（每条≤40字），输出JSON数组，source为信息出处的消息编号：
+[{"content":"内容","category":"preference|fact|context","importance":1-10,"source":消息编号}]
 无信息时返回空数组[]`, conversationText)
 
 	// Get memory extraction model
-	var modelID uuid.UUID
-	var modelIdentifier string
-	models, err := s.modelRepo.List(ctx, true)
-	if err != nil || len(models) == 0 {
-		return nil, fmt.Errorf("no active models available")
-	}
-
-	// Find the default memory model or use first available
-	for _, m := range models {
-		if m.ModelIdentifier == s.defaultModel || m.Name == s.defaultModel {
-			modelID = m.ID
-			modelIdentifier = m.ModelIdentifier
-			break
-		}
-	}
-	if modelID == uuid.Nil {
-		modelID = models[0].ID // Use first available model
-		modelIdentifier = models[0].ModelIdentifier
+	extractionModel, err := selectUtilityModel(ctx, s.modelRepo, s.defaultModel)
+	if err != nil {
+		return nil, err
 	}
 
 	// Prepare chat request
 	request := &model.ChatCompletionRequest{
-		Model: modelIdentifier,
+		Model: extractionModel.ModelIdentifier,
 		Messages: []model.ChatMessage{
 			{
 				Role:    "system",
@@ -181,11 +224,11 @@ func (s *MemoryService) extractMemoriesWithAI(ctx context.Context, conversationT
 			},
 		},
 		Temperature: func() *float64 { t := 0.3; return &t }(),
-		MaxTokens:   func() *int { t := 400; return &t }(),
+		MaxTokens:   func() *int { t := 500; return &t }(),
 	}
 
 	// Send request to AI
-	response, err := s.aiProxyService.SendChatCompletion(ctx, modelID, request)
+	response, _, err := s.aiProxyService.SendChatCompletion(ctx, extractionModel.ID, request)
 	if err != nil {
 		return nil, fmt.Errorf("failed to call AI: %w", err)
 	}
@@ -195,7 +238,17 @@ func (s *MemoryService) extractMemoriesWithAI(ctx context.Context, conversationT
 	}
 
 	// Parse JSON response
-	content := response.Choices[0].Message.Content
+	var memories []model.MemoryExtractionResult
+	if err := parseJSONReply(response.Choices[0].Message.Content, &memories); err != nil {
+		return nil, fmt.Errorf("failed to parse AI response: %w", err)
+	}
+
+	return memories, nil
+}
+
+// parseJSONReply decodes a model's JSON reply, which may be wrapped in a
+// markdown code block
+func parseJSONReply(content string, v interface{}) error {
 	content = strings.TrimSpace(content)
 
 	// Extract JSON from markdown code blocks if present
@@ -209,16 +262,36 @@ func (s *MemoryService) extractMemoriesWithAI(ctx context.Context, conversationT
 		content = strings.TrimSpace(content)
 	}
 
-	var memories []model.MemoryExtractionResult
-	if err := json.Unmarshal([]byte(content), &memories); err != nil {
-		return nil, fmt.Errorf("failed to parse AI response: %w", err)
+	return json.Unmarshal([]byte(content), v)
+}
+
+// selectUtilityModel picks the model for background tasks such as memory
+// extraction: the configured model matched by identifier or name, otherwise
+// the first active chat model
+func selectUtilityModel(ctx context.Context, modelRepo *repository.AIModelRepository, preferred string) (*model.AIModel, error) {
+	models, err := modelRepo.List(ctx, true)
+	if err != nil || len(models) == 0 {
+		return nil, fmt.Errorf("no active models available")
 	}
 
-	return memories, nil
+	var fallback *model.AIModel
+	for _, m := range models {
+		if m.ModelIdentifier == preferred || m.Name == preferred {
+			return m, nil
+		}
+		if fallback == nil && !m.SupportsEmbeddings {
+			fallback = m
+		}
+	}
+
+	if fallback == nil {
+		return nil, fmt.Errorf("no active chat models available")
+	}
+	return fallback, nil // Use first available chat model
 }
 
-// isSimilar checks if two memory contents are similar (simple check)
-func (s *MemoryService) isSimilar(a, b string) bool {
+// isSimilarMemory checks if two memory contents are similar (simple check)
+func isSimilarMemory(a, b string) bool {
 	a = strings.ToLower(strings.TrimSpace(a))
 	b = strings.ToLower(strings.TrimSpace(b))
 
@@ -235,21 +308,6 @@ func (s *MemoryService) GetUserMemories(ctx context.Context, userID uuid.UUID, l
 	return s.memoryRepo.ListByUser(ctx, userID, limit, offset)
 }
 
-// GetRelevantMemories gets the most relevant memories for context
-func (s *MemoryService) GetRelevantMemories(ctx context.Context, userID uuid.UUID, limit int) ([]*model.Memory, error) {
-	memories, err := s.memoryRepo.GetRelevantMemories(ctx, userID, limit)
-	if err != nil {
-		return nil, err
-	}
-
-	// Increment usage count for retrieved memories
-	for _, mem := range memories {
-		_ = s.memoryRepo.IncrementUsage(ctx, mem.ID)
-	}
-
-	return memories, nil
-}
-
 // CreateMemory creates a new memory manually
 func (s *MemoryService) CreateMemory(ctx context.Context, userID uuid.UUID, req *model.MemoryCreateRequest) (*model.Memory, error) {
 	memory := &model.Memory{
@@ -263,7 +321,9 @@ func (s *MemoryService) CreateMemory(ctx context.Context, userID uuid.UUID, req
 	if err := s.memoryRepo.Create(ctx, memory); err != nil {
 		return nil, fmt.Errorf("failed to create memory: %w", err)
 	}
+	s.recordVersion(ctx, memory, model.MemoryChangeCreate, model.MemoryChangedByUser)
 
+	s.cache.Delete(userID.String())
 	return memory, nil
 }
 
@@ -281,6 +341,7 @@ func (s *MemoryService) UpdateMemory(ctx context.Context, userID, memoryID uuid.
 	}
 
 	// Update fields
+	before := *memory
 	if req.Content != nil {
 		memory.Content = *req.Content
 	}
@@ -290,11 +351,16 @@ func (s *MemoryService) UpdateMemory(ctx context.Context, userID, memoryID uuid.
 	if req.Importance != nil {
 		memory.Importance = *req.Importance
 	}
+	if memory.Content == before.Content && memory.Category == before.Category && memory.Importance == before.Importance {
+		return memory, nil
+	}
 
 	if err := s.memoryRepo.Update(ctx, memory); err != nil {
 		return nil, fmt.Errorf("failed to update memory: %w", err)
 	}
+	s.recordVersion(ctx, memory, model.MemoryChangeUpdate, model.MemoryChangedByUser)
 
+	s.cache.Delete(userID.String())
 	return memory, nil
 }
 
@@ -314,21 +380,66 @@ func (s *MemoryService) DeleteMemory(ctx context.Context, userID, memoryID uuid.
 	if err := s.memoryRepo.Delete(ctx, memoryID); err != nil {
 		return err
 	}
+	s.recordVersion(ctx, memory, model.MemoryChangeDelete, model.MemoryChangedByUser)
 
 	// Invalidate cache
 	s.cache.Delete(userID.String())
 	return nil
 }
 
+// SearchMemories finds a user's memories containing every word of the query
+func (s *MemoryService) SearchMemories(ctx context.Context, userID uuid.UUID, query string, limit int) ([]*model.Memory, error) {
+	terms := strings.Fields(query)
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("query is required")
+	}
+	if len(terms) > maxMemorySearchTerms {
+		terms = terms[:maxMemorySearchTerms]
+	}
+	return s.memoryRepo.SearchByUser(ctx, userID, terms, limit)
+}
+
+// DeleteMemories deletes a user's memories of a category and/or extracted
+// from a conversation. At least one filter is required.
+func (s *MemoryService) DeleteMemories(ctx context.Context, userID uuid.UUID, category model.MemoryCategory, conversationID *uuid.UUID) (int64, error) {
+	if category == "" && conversationID == nil {
+		return 0, fmt.Errorf("category or conversation_id is required")
+	}
+
+	deleted, err := s.memoryRepo.DeleteByUser(ctx, userID, category, conversationID)
+	if err != nil {
+		return 0, err
+	}
+	for _, memory := range deleted {
+		s.recordVersion(ctx, memory, model.MemoryChangeDelete, model.MemoryChangedByUser)
+	}
+
+	s.cache.Delete(userID.String())
+	return int64(len(deleted)), nil
+}
+
 // CleanupOldMemories removes old low-importance memories
 func (s *MemoryService) CleanupOldMemories(ctx context.Context, userID uuid.UUID) error {
 	// Delete memories with importance <= 3 that are older than 30 days and unused
-	return s.memoryRepo.DeleteLowImportance(ctx, userID, 30, 3)
+	deleted, err := s.memoryRepo.DeleteLowImportance(ctx, userID, 30, 3)
+	if err != nil {
+		return err
+	}
+	for _, memory := range deleted {
+		s.recordVersion(ctx, memory, model.MemoryChangeDelete, model.MemoryChangedByCleanup)
+	}
+
+	if len(deleted) > 0 {
+		s.cache.Delete(userID.String())
+	}
+	return nil
 }
 
-// buildBudgetedContext builds memory context within ~1200 character budget
-func (s *MemoryService) buildBudgetedContext(memories []*model.Memory) string {
+// buildBudgetedContext builds memory context within ~1200 character budget,
+// returning it with the memories it includes
+func (s *MemoryService) buildBudgetedContext(memories []*model.Memory) (string, []*model.Memory) {
 	var builder strings.Builder
+	var used []*model.Memory
 	total := 0
 
 	for _, mem := range memories {
@@ -337,46 +448,157 @@ func (s *MemoryService) buildBudgetedContext(memories []*model.Memory) string {
 			break
 		}
 		builder.WriteString(line)
+		used = append(used, mem)
 		total += len(line)
 	}
 
-	return builder.String()
+	return builder.String(), used
+}
+
+// BuildMemoryContext builds a context string from the user's memories most
+// relevant to the query, the embedding of the message being answered
+func (s *MemoryService) BuildMemoryContext(ctx context.Context, userID uuid.UUID, query *QueryEmbedding) (string, error) {
+	entry, err := s.loadCandidates(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if len(entry.memories) == 0 {
+		return "", nil
+	}
+
+	// Without a query embedding comparable to the memories' own, memories are
+	// ranked by importance and recency
+	var queryEmbedding []float32
+	if query != nil && entry.embeddingModel != nil && query.Model == entry.embeddingModel.ModelIdentifier {
+		queryEmbedding = query.Vector
+	}
+
+	body, used := s.buildBudgetedContext(rankMemories(entry.memories, queryEmbedding, time.Now()))
+	if body == "" {
+		return "", nil
+	}
+
+	ids := make([]uuid.UUID, len(used))
+	for i, mem := range used {
+		ids[i] = mem.ID
+	}
+	_ = s.memoryRepo.IncrementUsage(ctx, ids...)
+
+	return "关于用户的记忆：\n" + body, nil
 }
 
-// BuildMemoryContext builds a context string from relevant memories (with cache)
-func (s *MemoryService) BuildMemoryContext(ctx context.Context, userID uuid.UUID) (string, error) {
-	// Check cache
+// loadCandidates returns the user's ranking candidates, from the cache when
+// fresh. Missing embeddings are computed a batch at a time.
+func (s *MemoryService) loadCandidates(ctx context.Context, userID uuid.UUID) (*memoryCacheEntry, error) {
 	cacheKey := userID.String()
 	if v, ok := s.cache.Load(cacheKey); ok {
 		entry := v.(*memoryCacheEntry)
 		if time.Now().Before(entry.expiresAt) {
-			return entry.context, nil
+			return entry, nil
 		}
 		s.cache.Delete(cacheKey)
 	}
 
-	// Fetch memories (limit 20, budget will trim)
-	memories, err := s.GetRelevantMemories(ctx, userID, 20)
+	entry := &memoryCacheEntry{expiresAt: time.Now().Add(s.cacheTTL)}
+	modelIdentifier := ""
+	if embeddingModel, err := s.embeddingService.Model(ctx); err == nil {
+		entry.embeddingModel = embeddingModel
+		modelIdentifier = embeddingModel.ModelIdentifier
+	}
+
+	memories, err := s.memoryRepo.ListForRanking(ctx, userID, modelIdentifier, maxRankedMemories)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
+	entry.memories = memories
 
+	if entry.embeddingModel != nil {
+		var missing []*model.Memory
+		for _, mem := range memories {
+			if mem.Embedding == nil && len(missing) < memoryEmbedBatch {
+				missing = append(missing, mem)
+			}
+		}
+		s.embedMemories(ctx, userID, entry.embeddingModel, missing)
+	}
+
+	s.cache.Store(cacheKey, entry)
+	return entry, nil
+}
+
+// embedMemories computes and stores the embeddings of memories. Failures are
+// logged; the memories are retried the next time candidates are loaded.
+func (s *MemoryService) embedMemories(ctx context.Context, userID uuid.UUID, embeddingModel *model.AIModel, memories []*model.Memory) {
 	if len(memories) == 0 {
-		return "", nil
+		return
 	}
 
-	body := s.buildBudgetedContext(memories)
-	if body == "" {
-		return "", nil
+	texts := make([]string, len(memories))
+	for i, mem := range memories {
+		texts[i] = mem.Content
+	}
+	vectors, err := s.embeddingService.Embed(ctx, userID, embeddingModel, texts)
+	if err != nil {
+		log.Printf("Failed to embed memories for user %s: %v", userID, err)
+		return
 	}
 
-	result := "关于用户的记忆：\n" + body
+	for i, mem := range memories {
+		if err := s.memoryRepo.SetEmbedding(ctx, mem.ID, embeddingModel.ModelIdentifier, vectors[i]); err != nil {
+			log.Printf("Failed to save embedding of memory %s: %v", mem.ID, err)
+			continue
+		}
+		mem.Embedding = vectors[i]
+	}
+}
+
+// rankMemories orders memories by relevance, best first. With a query
+// embedding, memories unrelated to the query are dropped unless they are
+// very important; a memory not yet embedded counts as barely related.
+func rankMemories(memories []*model.Memory, queryEmbedding []float32, now time.Time) []*model.Memory {
+	type scored struct {
+		memory *model.Memory
+		score  float64
+	}
+
+	ranked := make([]scored, 0, len(memories))
+	for _, mem := range memories {
+		score := memoryImportanceWeight*float64(mem.Importance)/10 + memoryRecencyWeight*memoryRecency(mem, now)
+
+		if queryEmbedding != nil {
+			similarity := memoryMinSimilarity
+			if len(mem.Embedding) == len(queryEmbedding) {
+				similarity = vector.Cosine(queryEmbedding, mem.Embedding)
+			}
+			if similarity < memoryMinSimilarity && mem.Importance < memoryPinnedImportance {
+				continue
+			}
+			score += memorySimilarityWeight * similarity
+		}
 
-	// Store in cache
-	s.cache.Store(cacheKey, &memoryCacheEntry{
-		context:   result,
-		expiresAt: time.Now().Add(s.cacheTTL),
-	})
+		ranked = append(ranked, scored{memory: mem, score: score})
+	}
 
-	return result, nil
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].score > ranked[j].score })
+
+	result := make([]*model.Memory, len(ranked))
+	for i, r := range ranked {
+		result[i] = r.memory
+	}
+	return result
+}
+
+// memoryRecency decays from 1 for a memory created or changed just now,
+// halving every memoryRecencyHalfLife. Being injected does not count, or the
+// memories injected once would keep crowding out the rest.
+func memoryRecency(mem *model.Memory, now time.Time) float64 {
+	last := mem.CreatedAt
+	if mem.UpdatedAt.After(last) {
+		last = mem.UpdatedAt
+	}
+	age := now.Sub(last)
+	if age < 0 {
+		age = 0
+	}
+	return math.Pow(0.5, float64(age)/float64(memoryRecencyHalfLife))
 }
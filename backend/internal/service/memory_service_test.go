@@ -0,0 +1,81 @@
+package service
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ai-chat/backend/internal/model"
+)
+
+func TestRankMemories(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	memory := func(content string, importance int, age time.Duration, embedding []float32) *model.Memory {
+		return &model.Memory{Content: content, Importance: importance, CreatedAt: now.Add(-age), UpdatedAt: now.Add(-age), Embedding: embedding}
+	}
+
+	related := memory("related", 5, 0, []float32{1, 0})
+	unrelated := memory("unrelated", 5, 0, []float32{0, 1})
+	pinned := memory("pinned", 9, 0, []float32{0, 1})
+	unembedded := memory("unembedded", 8, 0, nil)
+	old := memory("old", 5, 365*24*time.Hour, []float32{1, 0})
+
+	names := func(memories []*model.Memory) []string {
+		var out []string
+		for _, mem := range memories {
+			out = append(out, mem.Content)
+		}
+		return out
+	}
+	equal := func(got, want []string) bool {
+		if len(got) != len(want) {
+			return false
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	// With a query, similarity dominates and unrelated memories are dropped
+	// unless pinned by their importance; an unembedded one counts as barely related
+	got := names(rankMemories([]*model.Memory{unrelated, old, pinned, unembedded, related}, []float32{1, 0}, now))
+	want := []string{"related", "old", "unembedded", "pinned"}
+	if !equal(got, want) {
+		t.Errorf("rankMemories() with a query = %v, want %v", got, want)
+	}
+
+	// Without one, importance and then recency decide
+	got = names(rankMemories([]*model.Memory{old, related, pinned}, nil, now))
+	want = []string{"pinned", "related", "old"}
+	if !equal(got, want) {
+		t.Errorf("rankMemories() without a query = %v, want %v", got, want)
+	}
+}
+
+func TestMemoryRecency(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	used := now
+
+	tests := []struct {
+		name string
+		mem  *model.Memory
+		want float64
+	}{
+		{"new", &model.Memory{CreatedAt: now, UpdatedAt: now}, 1},
+		{"one half-life", &model.Memory{CreatedAt: now.Add(-memoryRecencyHalfLife), UpdatedAt: now.Add(-memoryRecencyHalfLife)}, 0.5},
+		{"changed recently", &model.Memory{CreatedAt: now.Add(-2 * memoryRecencyHalfLife), UpdatedAt: now.Add(-memoryRecencyHalfLife)}, 0.5},
+		// Being injected into a conversation does not make a memory recent
+		{"used recently", &model.Memory{CreatedAt: now.Add(-memoryRecencyHalfLife), UpdatedAt: now.Add(-memoryRecencyHalfLife), LastUsedAt: &used}, 0.5},
+		{"clock skew", &model.Memory{CreatedAt: now.Add(time.Hour), UpdatedAt: now.Add(time.Hour)}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := memoryRecency(tt.mem, now); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("memoryRecency() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,331 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/ai-chat/backend/internal/model"
+)
+
+const (
+	// memoryExportVersion is written to and accepted from JSON exports
+	memoryExportVersion = 1
+
+	// maxMemoryTransfer bounds how many memories are imported at once
+	maxMemoryTransfer = 5000
+
+	// memoryPageSize is how many stored memories are read at a time
+	memoryPageSize = 500
+
+	// memoryMaxRunes matches the limit on manually created memories
+	memoryMaxRunes = 500
+
+	// defaultImportImportance is used for Markdown items without an importance
+	defaultImportImportance = 5
+)
+
+// Memory export formats
+const (
+	MemoryFormatJSON     = "json"
+	MemoryFormatMarkdown = "markdown"
+)
+
+// memoryCategories lists the categories in the order they are exported
+var memoryCategories = []model.MemoryCategory{
+	model.MemoryCategoryPreference,
+	model.MemoryCategoryFact,
+	model.MemoryCategoryContext,
+}
+
+// ExportMemories renders all of a user's memories in the given format, most
+// important first
+func (s *MemoryService) ExportMemories(ctx context.Context, userID uuid.UUID, format string) ([]byte, error) {
+	memories, err := s.listAllMemories(ctx, userID, "")
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(memories, func(i, j int) bool { return memories[i].Importance > memories[j].Importance })
+
+	switch format {
+	case MemoryFormatJSON:
+		export := model.MemoryExport{
+			Version:    memoryExportVersion,
+			ExportedAt: time.Now(),
+			Memories:   make([]model.MemoryExportItem, 0, len(memories)),
+		}
+		for _, mem := range memories {
+			createdAt := mem.CreatedAt
+			export.Memories = append(export.Memories, model.MemoryExportItem{
+				Content:    mem.Content,
+				Category:   mem.Category,
+				Importance: mem.Importance,
+				CreatedAt:  &createdAt,
+			})
+		}
+		return json.MarshalIndent(export, "", "  ")
+
+	case MemoryFormatMarkdown:
+		return formatMemoriesMarkdown(memories), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported format: %s", format)
+	}
+}
+
+// ImportMemories adds memories from a JSON or Markdown export. Memories that
+// repeat each other or one the user already has are skipped, comparing
+// embeddings as consolidation does when there is an embedding model. If
+// storing fails midway, the memories imported so far are kept and reported
+// along with the error.
+func (s *MemoryService) ImportMemories(ctx context.Context, userID uuid.UUID, data []byte) (*model.MemoryImportResult, error) {
+	items, err := parseMemoryImport(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) > maxMemoryTransfer {
+		return nil, fmt.Errorf("too many memories (max %d)", maxMemoryTransfer)
+	}
+
+	result := &model.MemoryImportResult{}
+	candidates := make([]model.MemoryExtractionResult, 0, len(items))
+	for _, item := range items {
+		if utf8.RuneCountInString(strings.TrimSpace(item.Content)) > memoryMaxRunes {
+			result.Skipped++
+			continue
+		}
+		candidates = append(candidates, item)
+	}
+
+	facts := normalizeExtractedMemories(candidates)
+	result.Skipped += len(candidates) - len(facts)
+	if len(facts) == 0 {
+		return result, nil
+	}
+
+	var embeddingModel *model.AIModel
+	modelIdentifier := ""
+	if m, err := s.embeddingService.Model(ctx); err == nil {
+		embeddingModel, modelIdentifier = m, m.ModelIdentifier
+	}
+
+	existing, err := s.listAllMemories(ctx, userID, modelIdentifier)
+	if err != nil {
+		return nil, err
+	}
+	vectors := s.embedImport(ctx, userID, embeddingModel, facts, existing)
+
+	defer func() {
+		if result.Imported > 0 {
+			s.cache.Delete(userID.String())
+		}
+	}()
+
+	var unembedded []*model.Memory
+	for i, fact := range facts {
+		var factVector []float32
+		if vectors != nil {
+			factVector = vectors[i]
+		}
+
+		known := false
+		for _, mem := range existing {
+			if isDuplicateMemory(mem, fact.Content, factVector) {
+				known = true
+				break
+			}
+		}
+		if known {
+			result.Skipped++
+			continue
+		}
+
+		memory := &model.Memory{
+			ID:         uuid.New(),
+			UserID:     userID,
+			Content:    fact.Content,
+			Category:   fact.Category,
+			Importance: fact.Importance,
+		}
+		if err := s.memoryRepo.Create(ctx, memory); err != nil {
+			return result, fmt.Errorf("import stopped after %d memories: %w", result.Imported, err)
+		}
+		s.recordVersion(ctx, memory, model.MemoryChangeCreate, model.MemoryChangedByImport)
+		s.storeEmbedding(ctx, memory, embeddingModel, fact.Content, factVector, &unembedded)
+		memory.Embedding = factVector
+		existing = append(existing, memory)
+		result.Imported++
+	}
+
+	return result, nil
+}
+
+// listAllMemories reads all of a user's memories, a page at a time, with their
+// embeddings from the given model
+func (s *MemoryService) listAllMemories(ctx context.Context, userID uuid.UUID, embeddingModel string) ([]*model.Memory, error) {
+	var all []*model.Memory
+	var after *model.Memory
+	for {
+		page, err := s.memoryRepo.ListPage(ctx, userID, embeddingModel, after, memoryPageSize)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page...)
+		if len(page) < memoryPageSize {
+			return all, nil
+		}
+		after = page[len(page)-1]
+	}
+}
+
+// embedImport embeds the facts to import, and any stored memories not yet
+// embedded so they can be compared. It returns nil without an embedding model
+// or when embedding fails, leaving duplicates to be found by their text.
+func (s *MemoryService) embedImport(ctx context.Context, userID uuid.UUID, embeddingModel *model.AIModel, facts []model.MemoryExtractionResult, existing []*model.Memory) [][]float32 {
+	if embeddingModel == nil {
+		return nil
+	}
+
+	var missing []*model.Memory
+	for _, mem := range existing {
+		if mem.Embedding == nil {
+			missing = append(missing, mem)
+		}
+	}
+	for start := 0; start < len(missing); start += embeddingBatchSize {
+		s.embedMemories(ctx, userID, embeddingModel, missing[start:min(start+embeddingBatchSize, len(missing))])
+	}
+
+	vectors := make([][]float32, 0, len(facts))
+	for start := 0; start < len(facts); start += embeddingBatchSize {
+		batch := facts[start:min(start+embeddingBatchSize, len(facts))]
+		texts := make([]string, len(batch))
+		for i, fact := range batch {
+			texts[i] = fact.Content
+		}
+		embedded, err := s.embeddingService.Embed(ctx, userID, embeddingModel, texts)
+		if err != nil {
+			log.Printf("Failed to embed imported memories for user %s: %v", userID, err)
+			return nil
+		}
+		vectors = append(vectors, embedded...)
+	}
+	return vectors
+}
+
+// formatMemoriesMarkdown lists memories under a heading per category, each
+// prefixed with its importance, e.g. "- [8] Prefers dark mode"
+func formatMemoriesMarkdown(memories []*model.Memory) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("# Memories\n")
+
+	for _, category := range memoryCategories {
+		first := true
+		for _, mem := range memories {
+			if mem.Category != category {
+				continue
+			}
+			if first {
+				fmt.Fprintf(&buf, "\n## %s\n\n", category)
+				first = false
+			}
+			// Keep each memory on one line
+			content := strings.Join(strings.Fields(mem.Content), " ")
+			fmt.Fprintf(&buf, "- [%d] %s\n", mem.Importance, content)
+		}
+	}
+
+	return buf.Bytes()
+}
+
+// parseMemoryImport reads memories from a JSON export, a bare JSON array of
+// memories, or Markdown in the format formatMemoriesMarkdown writes
+func parseMemoryImport(data []byte) ([]model.MemoryExtractionResult, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil, fmt.Errorf("import is empty")
+	}
+
+	var items []model.MemoryExportItem
+	switch trimmed[0] {
+	case '{':
+		var export model.MemoryExport
+		if err := json.Unmarshal(trimmed, &export); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		if export.Version > memoryExportVersion {
+			return nil, fmt.Errorf("unsupported export version %d", export.Version)
+		}
+		items = export.Memories
+	case '[':
+		if err := json.Unmarshal(trimmed, &items); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+	default:
+		return parseMemoryMarkdown(trimmed), nil
+	}
+
+	results := make([]model.MemoryExtractionResult, 0, len(items))
+	for _, item := range items {
+		if item.Importance == 0 {
+			item.Importance = defaultImportImportance
+		}
+		results = append(results, model.MemoryExtractionResult{
+			Content:    item.Content,
+			Category:   item.Category,
+			Importance: item.Importance,
+		})
+	}
+	return results, nil
+}
+
+// parseMemoryMarkdown takes list items as memories, categorized by the
+// closest "## <category>" heading above them. Items outside a known category
+// are imported as context.
+func parseMemoryMarkdown(data []byte) []model.MemoryExtractionResult {
+	var results []model.MemoryExtractionResult
+	category := model.MemoryCategoryContext
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if heading := strings.TrimPrefix(line, "## "); heading != line {
+			category = model.MemoryCategory(strings.ToLower(strings.TrimSpace(heading)))
+			continue
+		}
+
+		item := strings.TrimPrefix(strings.TrimPrefix(line, "- "), "* ")
+		if item == line {
+			continue
+		}
+
+		importance := defaultImportImportance
+		if strings.HasPrefix(item, "[") {
+			if end := strings.Index(item, "]"); end > 0 {
+				if n, err := strconv.Atoi(item[1:end]); err == nil {
+					importance = n
+					item = item[end+1:]
+				}
+			}
+		}
+
+		results = append(results, model.MemoryExtractionResult{
+			Content:    item,
+			Category:   category,
+			Importance: importance,
+		})
+	}
+
+	return results
+}
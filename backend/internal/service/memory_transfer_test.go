@@ -0,0 +1,85 @@
+package service
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ai-chat/backend/internal/model"
+)
+
+func TestMemoryMarkdownRoundTrip(t *testing.T) {
+	memories := []*model.Memory{
+		{Content: "Prefers dark mode", Category: model.MemoryCategoryPreference, Importance: 8},
+		{Content: "Lives in\nBerlin", Category: model.MemoryCategoryFact, Importance: 6},
+		{Content: "Works on a Go backend", Category: model.MemoryCategoryContext, Importance: 4},
+		{Content: "Likes short answers", Category: model.MemoryCategoryPreference, Importance: 7},
+	}
+
+	got, err := parseMemoryImport(formatMemoriesMarkdown(memories))
+	if err != nil {
+		t.Fatalf("parseMemoryImport() error = %v", err)
+	}
+	// Memories come back grouped by category, each on one line
+	want := []model.MemoryExtractionResult{
+		{Content: "Prefers dark mode", Category: model.MemoryCategoryPreference, Importance: 8},
+		{Content: "Likes short answers", Category: model.MemoryCategoryPreference, Importance: 7},
+		{Content: "Lives in Berlin", Category: model.MemoryCategoryFact, Importance: 6},
+		{Content: "Works on a Go backend", Category: model.MemoryCategoryContext, Importance: 4},
+	}
+	for i := range got {
+		got[i].Content = strings.TrimSpace(got[i].Content) // normalized on import
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseMemoryImport(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    []model.MemoryExtractionResult
+		wantErr bool
+	}{
+		{
+			name: "JSON export",
+			data: `{"version": 1, "memories": [{"content": "Prefers tea", "category": "preference", "importance": 7}, {"content": "Has a dog", "category": "fact"}]}`,
+			want: []model.MemoryExtractionResult{
+				{Content: "Prefers tea", Category: model.MemoryCategoryPreference, Importance: 7},
+				{Content: "Has a dog", Category: model.MemoryCategoryFact, Importance: defaultImportImportance},
+			},
+		},
+		{
+			name: "bare JSON array",
+			data: `[{"content": "Has a dog", "category": "fact", "importance": 3}]`,
+			want: []model.MemoryExtractionResult{
+				{Content: "Has a dog", Category: model.MemoryCategoryFact, Importance: 3},
+			},
+		},
+		{
+			name: "Markdown outside a known category",
+			data: "Some notes\n\n- Has a dog\n* [9] Allergic to nuts\n\n## Fact\n\n- [x] not an importance\n",
+			want: []model.MemoryExtractionResult{
+				{Content: "Has a dog", Category: model.MemoryCategoryContext, Importance: defaultImportImportance},
+				{Content: " Allergic to nuts", Category: model.MemoryCategoryContext, Importance: 9},
+				{Content: "[x] not an importance", Category: model.MemoryCategoryFact, Importance: defaultImportImportance},
+			},
+		},
+		{name: "newer export version", data: `{"version": 2, "memories": []}`, wantErr: true},
+		{name: "invalid JSON", data: `{"version": 1,`, wantErr: true},
+		{name: "empty", data: " \n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseMemoryImport([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseMemoryImport() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseMemoryImport() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
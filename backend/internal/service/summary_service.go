@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ai-chat/backend/internal/model"
+	"github.com/ai-chat/backend/internal/repository"
+)
+
+const (
+	// summaryBatchMessages caps how many new turns are folded into a summary
+	// per refresh; a long backlog catches up over several turns
+	summaryBatchMessages = 60
+	// summaryMaxTokens caps the length of a generated summary
+	summaryMaxTokens = 600
+	// summaryMessageRunes truncates single oversized turns before summarizing
+	summaryMessageRunes = 4000
+)
+
+// SummaryService maintains rolling summaries of conversation turns that no
+// longer fit a model's context window
+type SummaryService struct {
+	convRepo       *repository.ConversationRepository
+	messageRepo    *repository.MessageRepository
+	modelRepo      *repository.AIModelRepository
+	tokenUsageRepo *repository.TokenUsageRepository
+	aiProxyService *AIProxyService
+	summaryModel   string
+	inflight       sync.Map // conversation ID -> struct{}, refreshes in progress
+}
+
+// NewSummaryService creates a new summary service
+func NewSummaryService(
+	convRepo *repository.ConversationRepository,
+	messageRepo *repository.MessageRepository,
+	modelRepo *repository.AIModelRepository,
+	tokenUsageRepo *repository.TokenUsageRepository,
+	aiProxyService *AIProxyService,
+	summaryModel string,
+) *SummaryService {
+	return &SummaryService{
+		convRepo:       convRepo,
+		messageRepo:    messageRepo,
+		modelRepo:      modelRepo,
+		tokenUsageRepo: tokenUsageRepo,
+		aiProxyService: aiProxyService,
+		summaryModel:   summaryModel,
+	}
+}
+
+// SummaryMessage returns the system message that carries a conversation's summary
+func (s *SummaryService) SummaryMessage(conv *model.Conversation) (model.ChatMessage, bool) {
+	if strings.TrimSpace(conv.Summary) == "" {
+		return model.ChatMessage{}, false
+	}
+	return model.ChatMessage{
+		Role:    "system",
+		Content: "以下是本次对话较早内容的摘要，供参考：\n" + conv.Summary,
+	}, true
+}
+
+// ScheduleRefresh folds turns up to and including `until` into the
+// conversation's summary in the background. Turns already covered are
+// skipped, and only one refresh runs per conversation at a time.
+func (s *SummaryService) ScheduleRefresh(conv *model.Conversation, until time.Time) {
+	if conv.SummaryUpTo != nil && !until.After(*conv.SummaryUpTo) {
+		return
+	}
+	if _, running := s.inflight.LoadOrStore(conv.ID, struct{}{}); running {
+		return
+	}
+
+	go func() {
+		defer s.inflight.Delete(conv.ID)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+		defer cancel()
+		if err := s.refresh(ctx, conv.ID, until); err != nil {
+			log.Printf("Summary refresh failed for conversation %s: %v", conv.ID, err)
+		}
+	}()
+}
+
+// refresh extends the stored summary with the next batch of unsummarized turns
+func (s *SummaryService) refresh(ctx context.Context, conversationID uuid.UUID, until time.Time) error {
+	// Reload: the summary may have moved on since the caller read it
+	conv, err := s.convRepo.GetByID(ctx, conversationID)
+	if err != nil {
+		return err
+	}
+
+	messages, err := s.messageRepo.ListRange(ctx, conversationID, conv.SummaryCursor(), until, summaryBatchMessages)
+	if err != nil {
+		return err
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+
+	summaryModel, err := selectUtilityModel(ctx, s.modelRepo, s.summaryModel)
+	if err != nil {
+		return err
+	}
+
+	// Keep the batch to half of the summary model's window; the rest is
+	// for the previous summary, the instructions and the reply
+	if summaryModel.MaxTokens > 0 {
+		budget := summaryModel.MaxTokens / 2
+		for i, msg := range messages {
+			budget -= s.aiProxyService.CountTokens(summaryModel, truncateRunes(msg.Content, summaryMessageRunes))
+			if budget < 0 && i > 0 {
+				messages = messages[:i]
+				break
+			}
+		}
+	}
+
+	summary, err := s.summarize(ctx, conv.UserID, summaryModel, conv.Summary, messages)
+	if err != nil {
+		return err
+	}
+
+	return s.convRepo.UpdateSummary(ctx, conversationID, summary, messages[len(messages)-1].Cursor())
+}
+
+// summarize asks the summary model to merge new turns into the previous
+// summary, recording the tokens as the conversation owner's usage
+func (s *SummaryService) summarize(ctx context.Context, userID uuid.UUID, summaryModel *model.AIModel, previous string, messages []*model.Message) (string, error) {
+	var transcript strings.Builder
+	for _, msg := range messages {
+		// Tool results and bare tool calls are scaffolding, not conversation
+		if msg.Role == "tool" || msg.Content == "" {
+			continue
+		}
+		role := msg.Role
+		if role == "user" {
+			role = "用户"
+		} else if role == "assistant" {
+			role = "助手"
+		}
+		transcript.WriteString(fmt.Sprintf("%s: %s\n", role, truncateRunes(msg.Content, summaryMessageRunes)))
+	}
+
+	if previous == "" {
+		previous = "（无）"
+	}
+
+	prompt := fmt.Sprintf(`已有摘要：
+%s
+
+新增对话：
+%s
+
+将新增对话合并进已有摘要，输出更新后的完整摘要（≤400字）。保留用户的目标、关键事实、已做出的决定和未解决的问题，省略寒暄与重复内容。只输出摘要正文。`, previous, transcript.String())
+
+	request := &model.ChatCompletionRequest{
+		Model: summaryModel.ModelIdentifier,
+		Messages: []model.ChatMessage{
+			{
+				Role:    "system",
+				Content: "你负责为长对话维护简洁、准确的摘要。只依据给出的内容，不推断，不虚构。",
+			},
+			{
+				Role:    "user",
+				Content: prompt,
+			},
+		},
+		Temperature: func() *float64 { t := 0.2; return &t }(),
+		MaxTokens:   func() *int { t := summaryMaxTokens; return &t }(),
+	}
+
+	response, servedBy, err := s.aiProxyService.SendChatCompletion(ctx, summaryModel.ID, request)
+	if err != nil {
+		return "", fmt.Errorf("failed to call AI: %w", err)
+	}
+
+	// A fallback model may have answered; bill the one that did
+	usage := response.Usage
+	cost, _ := s.aiProxyService.EstimateCost(ctx, servedBy.ID, usage.PromptTokens, usage.CompletionTokens)
+	_ = s.tokenUsageRepo.RecordUsage(ctx, userID, &servedBy.ID, usage.PromptTokens, usage.CompletionTokens, cost)
+
+	if len(response.Choices) == 0 {
+		return "", fmt.Errorf("no response from AI")
+	}
+
+	summary := strings.TrimSpace(response.Choices[0].Message.Content)
+	if summary == "" {
+		return "", fmt.Errorf("empty summary from AI")
+	}
+
+	return summary, nil
+}
+
+// truncateRunes shortens text to at most n runes
+func truncateRunes(text string, n int) string {
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	return string(runes[:n]) + "…"
+}
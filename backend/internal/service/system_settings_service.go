@@ -99,6 +99,10 @@ func (s *SystemSettingsService) GetSettings(ctx context.Context) (*model.SystemS
 			dto.AIDefaultMemoryModel = setting.SettingValue
 		case "ai_memory_extraction_enabled":
 			dto.AIMemoryExtractionEnabled = setting.SettingValue == "true"
+		case "ai_summary_model":
+			dto.AISummaryModel = setting.SettingValue
+		case "ai_embedding_model":
+			dto.AIEmbeddingModel = setting.SettingValue
 		}
 	}
 
@@ -177,6 +181,12 @@ func (s *SystemSettingsService) UpdateSettings(ctx context.Context, dto *model.S
 		updates["ai_default_memory_model"] = dto.AIDefaultMemoryModel
 	}
 	updates["ai_memory_extraction_enabled"] = strconv.FormatBool(dto.AIMemoryExtractionEnabled)
+	if dto.AISummaryModel != "" {
+		updates["ai_summary_model"] = dto.AISummaryModel
+	}
+	if dto.AIEmbeddingModel != "" {
+		updates["ai_embedding_model"] = dto.AIEmbeddingModel
+	}
 
 	return s.settingsRepo.UpdateMultiple(ctx, updates)
 }
@@ -10,6 +10,9 @@ import (
 	"github.com/ai-chat/backend/internal/repository"
 )
 
+// timezoneKey is the advanced_settings entry holding a user's IANA timezone
+const timezoneKey = "timezone"
+
 // UserSettingsService handles user settings and multi-device sync
 type UserSettingsService struct {
 	settingsRepo *repository.UserSettingsRepository
@@ -71,6 +74,11 @@ func (s *UserSettingsService) UpdateSettings(ctx context.Context, userID uuid.UU
 		settings.ShowTokenCount = *req.ShowTokenCount
 	}
 	if req.AdvancedSettings != nil {
+		if tz, ok := req.AdvancedSettings[timezoneKey].(string); ok && tz != "" {
+			if _, err := time.LoadLocation(tz); err != nil {
+				return nil, fmt.Errorf("invalid timezone: %s", tz)
+			}
+		}
 		settings.AdvancedSettings = req.AdvancedSettings
 	}
 	if req.DeviceID != nil {
@@ -85,6 +93,21 @@ func (s *UserSettingsService) UpdateSettings(ctx context.Context, userID uuid.UU
 	return settings, nil
 }
 
+// Timezone returns the user's IANA timezone from their settings, or "" when
+// unset or no longer valid
+func (s *UserSettingsService) Timezone(ctx context.Context, userID uuid.UUID) string {
+	settings, err := s.settingsRepo.GetByUserID(ctx, userID)
+	if err != nil || settings.AdvancedSettings == nil {
+		return ""
+	}
+
+	tz, _ := settings.AdvancedSettings[timezoneKey].(string)
+	if _, err := time.LoadLocation(tz); tz == "" || err != nil {
+		return ""
+	}
+	return tz
+}
+
 // SyncSettings syncs settings across devices
 func (s *UserSettingsService) SyncSettings(ctx context.Context, userID uuid.UUID, localSettings *model.UserSettings) (*model.UserSettings, bool, error) {
 	// Get server settings
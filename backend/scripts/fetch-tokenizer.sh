@@ -0,0 +1,36 @@
+#!/bin/sh
+# Downloads the BPE rank files the token counter needs and verifies them
+# against the checksums tiktoken pins. Usage: fetch-tokenizer.sh [dir]
+set -eu
+
+DIR="${1:-./data/tokenizer}"
+BASE_URL="https://openaipublic.blob.core.windows.net/encodings"
+
+mkdir -p "$DIR"
+
+fetch() {
+	name="$1"
+	sum="$2"
+	file="$DIR/$name.tiktoken"
+
+	if [ -f "$file" ] && echo "$sum  $file" | sha256sum -c >/dev/null 2>&1; then
+		echo "$name: up to date"
+		return
+	fi
+
+	echo "$name: downloading"
+	if command -v curl >/dev/null 2>&1; then
+		curl -fsSL -o "$file.tmp" "$BASE_URL/$name.tiktoken"
+	else
+		wget -q -O "$file.tmp" "$BASE_URL/$name.tiktoken"
+	fi
+	if ! echo "$sum  $file.tmp" | sha256sum -c >/dev/null 2>&1; then
+		rm -f "$file.tmp"
+		echo "$name: checksum mismatch" >&2
+		exit 1
+	fi
+	mv "$file.tmp" "$file"
+}
+
+fetch cl100k_base 223921b76ee99bde995b7ff738513eef100fb51d18c93597a113bcffe865b2a7
+fetch o200k_base 446a9538cb6c348e3516120d7c08b09f57c36495e2acfffe59a5bf8b0cfb1a2d
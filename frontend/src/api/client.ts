@@ -234,8 +234,8 @@ class APIClient {
       return response.data;
     },
 
-    create: async (title?: string, modelId?: string) => {
-      const response = await this.client.post('/conversations', { title, model_id: modelId });
+    create: async (title?: string, modelId?: string, memoryMode?: 'full' | 'read_only' | 'off' | 'ephemeral') => {
+      const response = await this.client.post('/conversations', { title, model_id: modelId, memory_mode: memoryMode });
       return response.data;
     },
 
@@ -293,6 +293,45 @@ class APIClient {
       const response = await this.client.delete(`/memories/${id}`);
       return response.data;
     },
+
+    search: async (q: string, limit = 50) => {
+      const response = await this.client.get('/memories/search', { params: { q, limit } });
+      return response.data;
+    },
+
+    bulkDelete: async (filter: { category?: string; conversationId?: string }) => {
+      const response = await this.client.delete('/memories', {
+        params: { category: filter.category, conversation_id: filter.conversationId },
+      });
+      return response.data;
+    },
+
+    export: async (format: 'json' | 'markdown') => {
+      const response = await this.client.get('/memories/export', { params: { format }, responseType: 'blob' });
+      return response.data as Blob;
+    },
+
+    import: async (file: File) => {
+      const form = new FormData();
+      form.append('file', file);
+      const response = await this.client.post('/memories/import', form);
+      return response.data;
+    },
+
+    source: async (id: string) => {
+      const response = await this.client.get(`/memories/${id}/source`);
+      return response.data;
+    },
+
+    history: async (id: string) => {
+      const response = await this.client.get(`/memories/${id}/history`);
+      return response.data;
+    },
+
+    rollback: async (id: string, version: number) => {
+      const response = await this.client.post(`/memories/${id}/rollback`, { version });
+      return response.data;
+    },
   };
 
   // Settings endpoints
@@ -382,6 +421,16 @@ class APIClient {
       const response = await this.client.delete(`/admin/providers/${id}`);
       return response.data;
     },
+
+    discoverModels: async (id: string) => {
+      const response = await this.client.get(`/admin/providers/${id}/models/discover`);
+      return response.data;
+    },
+
+    importModels: async (id: string, modelIdentifiers: string[]) => {
+      const response = await this.client.post(`/admin/providers/${id}/models/import`, { model_identifiers: modelIdentifiers });
+      return response.data;
+    },
   };
 }
 
@@ -7,6 +7,7 @@ import UserManagement from './UserManagement';
 import ModelManagement from './ModelManagement';
 import TokenLeaderboard from './TokenLeaderboard';
 import AuditLogs from './AuditLogs';
+import MemoryJobs from './MemoryJobs';
 import { GlassPanel } from '../../styles/glass';
 
 const Container = styled.div`
@@ -119,7 +120,7 @@ const Subtitle = styled.p`
   font-size: 14px;
 `;
 
-type TabType = 'overview' | 'settings' | 'users' | 'models' | 'tokens' | 'audit';
+type TabType = 'overview' | 'settings' | 'users' | 'models' | 'tokens' | 'audit' | 'memoryJobs';
 
 const Admin: React.FC = () => {
   const [activeTab, setActiveTab] = useState<TabType>('overview');
@@ -133,6 +134,7 @@ const Admin: React.FC = () => {
     { id: 'models' as TabType, label: '模型管理', icon: '🤖' },
     { id: 'tokens' as TabType, label: 'Token 排行榜', icon: '📊' },
     { id: 'audit' as TabType, label: '审计日志', icon: '📋' },
+    { id: 'memoryJobs' as TabType, label: '记忆任务', icon: '🧠' },
   ];
 
   const renderContent = () => {
@@ -149,6 +151,8 @@ const Admin: React.FC = () => {
         return <TokenLeaderboard />;
       case 'audit':
         return <AuditLogs />;
+      case 'memoryJobs':
+        return <MemoryJobs />;
       default:
         return null;
     }
@@ -167,6 +171,7 @@ const Admin: React.FC = () => {
       models: '配置 AI 模型和 API 密钥',
       tokens: '查看用户 Token 使用统计',
       audit: '查看系统操作审计日志',
+      memoryJobs: '查看记忆提取队列，重试失败的任务',
     };
     return subtitles[activeTab];
   };
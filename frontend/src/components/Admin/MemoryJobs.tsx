@@ -0,0 +1,284 @@
+import React, { useState, useEffect } from 'react';
+import styled from 'styled-components';
+import apiClient from '../../api/client';
+import useAutoRefresh from '../../hooks/useAutoRefresh';
+import { ensureArray, ensureNumber } from '../../utils/safe';
+
+const Table = styled.table`
+  width: 100%;
+  border-collapse: collapse;
+  background: var(--bg-secondary);
+  border-radius: 12px;
+  overflow: hidden;
+`;
+
+const Th = styled.th`
+  padding: 16px;
+  text-align: left;
+  background: var(--bg-elevated);
+  color: var(--text-secondary);
+  font-weight: 600;
+  font-size: 13px;
+  text-transform: uppercase;
+  letter-spacing: 0.5px;
+`;
+
+const Td = styled.td`
+  padding: 16px;
+  border-top: 1px solid var(--border-primary);
+  color: var(--text-primary);
+  font-size: 14px;
+`;
+
+const statusColors: Record<string, string> = {
+  pending: '#667eea',
+  running: '#ecc94b',
+  done: '#48bb78',
+  failed: '#fc8181',
+};
+
+const Badge = styled.span<{ status: string }>`
+  padding: 4px 12px;
+  border-radius: 12px;
+  font-size: 12px;
+  font-weight: 500;
+  background: ${props => `${statusColors[props.status] || '#667eea'}33`};
+  color: ${props => statusColors[props.status] || '#667eea'};
+`;
+
+const Filters = styled.div`
+  display: flex;
+  gap: 8px;
+  flex-wrap: wrap;
+`;
+
+const PageButton = styled.button<{ active?: boolean }>`
+  padding: 8px 16px;
+  background: ${props => props.active ? 'linear-gradient(135deg, #667eea 0%, #764ba2 100%)' : 'var(--bg-secondary)'};
+  color: ${props => props.active ? 'white' : 'var(--text-secondary)'};
+  border: 1px solid ${props => props.active ? 'transparent' : 'var(--border-primary)'};
+  border-radius: 6px;
+  cursor: pointer;
+  transition: all 0.2s;
+
+  &:hover {
+    background: ${props => props.active ? 'linear-gradient(135deg, #667eea 0%, #764ba2 100%)' : '#2a3441'};
+  }
+
+  &:disabled {
+    opacity: 0.5;
+    cursor: not-allowed;
+  }
+`;
+
+const Pagination = styled.div`
+  display: flex;
+  justify-content: center;
+  gap: 12px;
+  margin-top: 24px;
+`;
+
+const Header = styled.div`
+  display: flex;
+  justify-content: space-between;
+  align-items: center;
+  margin-bottom: 20px;
+`;
+
+const RefreshButton = styled.button`
+  padding: 10px 20px;
+  background: linear-gradient(135deg, #667eea 0%, #764ba2 100%);
+  color: white;
+  border: none;
+  border-radius: 8px;
+  font-size: 14px;
+  font-weight: 500;
+  cursor: pointer;
+  transition: all 0.2s;
+
+  &:hover {
+    transform: translateY(-2px);
+    box-shadow: 0 4px 12px rgba(102, 126, 234, 0.3);
+  }
+
+  &:disabled {
+    opacity: 0.5;
+    cursor: not-allowed;
+    transform: none;
+  }
+`;
+
+const ErrorText = styled.div`
+  color: #fc8181;
+  font-size: 12px;
+  max-width: 320px;
+  overflow: hidden;
+  text-overflow: ellipsis;
+  white-space: nowrap;
+`;
+
+interface MemoryJob {
+  id: string;
+  user_id: string;
+  conversation_id: string;
+  status: string;
+  pending_turns: number;
+  run_after: string;
+  attempts: number;
+  last_error?: string;
+  watermark_at?: string;
+  finished_at?: string;
+  updated_at: string;
+}
+
+const statusLabels: Record<string, string> = {
+  '': '全部',
+  pending: '等待中',
+  running: '运行中',
+  done: '已完成',
+  failed: '失败',
+};
+
+const MemoryJobs: React.FC = () => {
+  const [jobs, setJobs] = useState<MemoryJob[]>([]);
+  const [counts, setCounts] = useState<Record<string, number>>({});
+  const [status, setStatus] = useState('');
+  const [loading, setLoading] = useState(false);
+  const [page, setPage] = useState(1);
+  const [total, setTotal] = useState(0);
+  const pageSize = 20;
+
+  const loadJobs = async () => {
+    setLoading(true);
+    try {
+      const response = await apiClient.get('/admin/memory-jobs', {
+        params: {
+          status: status || undefined,
+          limit: pageSize,
+          offset: (page - 1) * pageSize,
+        },
+      });
+      setJobs(ensureArray<MemoryJob>(response.data?.jobs));
+      setCounts(response.data?.counts || {});
+      setTotal(ensureNumber(response.data?.total));
+    } catch (error) {
+      console.error('Failed to load memory jobs:', error);
+      setJobs([]);
+      setTotal(0);
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  useEffect(() => {
+    loadJobs();
+  }, [page, status]);
+
+  // Auto-refresh every 10 seconds
+  useAutoRefresh(loadJobs, 10000);
+
+  const retryJob = async (id: string) => {
+    try {
+      await apiClient.post(`/admin/memory-jobs/${id}/retry`);
+      loadJobs();
+    } catch (error: any) {
+      alert(error.response?.data?.error || '重试失败');
+    }
+  };
+
+  const formatDate = (dateString?: string) => {
+    if (!dateString) return '-';
+    return new Date(dateString).toLocaleString('zh-CN', {
+      month: '2-digit',
+      day: '2-digit',
+      hour: '2-digit',
+      minute: '2-digit',
+      second: '2-digit',
+    });
+  };
+
+  const totalPages = Math.ceil(total / pageSize);
+
+  return (
+    <div>
+      <Header>
+        <Filters>
+          {Object.keys(statusLabels).map(key => (
+            <PageButton
+              key={key || 'all'}
+              active={status === key}
+              onClick={() => {
+                setStatus(key);
+                setPage(1);
+              }}
+            >
+              {statusLabels[key]}
+              {key && ` (${ensureNumber(counts[key])})`}
+            </PageButton>
+          ))}
+        </Filters>
+        <RefreshButton onClick={loadJobs} disabled={loading}>
+          {loading ? '刷新中...' : '刷新数据'}
+        </RefreshButton>
+      </Header>
+      <Table>
+        <thead>
+          <tr>
+            <Th>对话</Th>
+            <Th>状态</Th>
+            <Th>待处理轮数</Th>
+            <Th>下次运行</Th>
+            <Th>处理至</Th>
+            <Th>重试次数</Th>
+            <Th>错误</Th>
+            <Th></Th>
+          </tr>
+        </thead>
+        <tbody>
+          {jobs.map(job => (
+            <tr key={job.id}>
+              <Td title={job.conversation_id}>{job.conversation_id.slice(0, 8)}</Td>
+              <Td>
+                <Badge status={job.status}>{statusLabels[job.status] || job.status}</Badge>
+              </Td>
+              <Td>{job.pending_turns}</Td>
+              <Td>{job.status === 'pending' ? formatDate(job.run_after) : '-'}</Td>
+              <Td>{formatDate(job.watermark_at)}</Td>
+              <Td>{job.attempts}</Td>
+              <Td>
+                <ErrorText title={job.last_error}>{job.last_error || '-'}</ErrorText>
+              </Td>
+              <Td>
+                {job.status === 'failed' && (
+                  <PageButton onClick={() => retryJob(job.id)}>重试</PageButton>
+                )}
+              </Td>
+            </tr>
+          ))}
+        </tbody>
+      </Table>
+
+      {totalPages > 1 && (
+        <Pagination>
+          <PageButton
+            disabled={page === 1}
+            onClick={() => setPage(page - 1)}
+          >
+            上一页
+          </PageButton>
+          <PageButton active>
+            {page} / {totalPages}
+          </PageButton>
+          <PageButton
+            disabled={page === totalPages}
+            onClick={() => setPage(page + 1)}
+          >
+            下一页
+          </PageButton>
+        </Pagination>
+      )}
+    </div>
+  );
+};
+
+export default MemoryJobs;
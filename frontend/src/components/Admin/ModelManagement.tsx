@@ -285,6 +285,31 @@ interface Provider {
   is_active: boolean;
   description: string;
   model_count: number;
+  health?: ProviderHealth;
+}
+
+interface ProviderHealth {
+  state: 'closed' | 'open' | 'half-open';
+  recent_requests: number;
+  error_rate: number;
+  p95_latency_ms: number;
+}
+
+const HEALTH_LABELS: Record<ProviderHealth['state'], { label: string; color: string }> = {
+  closed: { label: '正常', color: '52,199,89' },
+  'half-open': { label: '探测中', color: '255,159,10' },
+  open: { label: '熔断', color: '255,59,48' },
+};
+
+interface ProviderKey {
+  id: string;
+  name: string;
+  key_hint: string;
+  is_active: boolean;
+  disabled_reason?: string;
+  request_count: number;
+  input_tokens: number;
+  output_tokens: number;
 }
 
 interface Model {
@@ -297,19 +322,31 @@ interface Model {
   max_tokens: number;
   is_default: boolean;
   provider_id?: string;
+  fallback_model_ids?: string[];
+  tokenizer?: string;
+  supports_functions?: boolean;
+  supports_vision?: boolean;
+  supports_embeddings?: boolean;
+  disabled_tools?: string[];
+}
+
+interface ToolInfo {
+  function: { name: string; description: string };
 }
 
 type ModalKind =
   | { type: 'addProvider' }
   | { type: 'editProvider'; provider: Provider }
   | { type: 'addModel'; provider: Provider }
-  | { type: 'editModel'; model: Model };
+  | { type: 'editModel'; model: Model }
+  | { type: 'providerKeys'; provider: Provider };
 
 // ─── Component ────────────────────────────────────────────────────────────────
 
 const ModelManagement: React.FC = () => {
   const [providers, setProviders] = useState<Provider[]>([]);
   const [models, setModels] = useState<Model[]>([]);
+  const [tools, setTools] = useState<ToolInfo[]>([]);
   const [expanded, setExpanded] = useState<Record<string, boolean>>({});
   const [modal, setModal] = useState<ModalKind | null>(null);
   const [saveError, setSaveError] = useState('');
@@ -320,11 +357,17 @@ const ModelManagement: React.FC = () => {
     name: '', display_name: '', provider_type: 'openai', api_endpoint: '', api_key: '', description: '',
   });
 
+  // Provider key pool
+  const [providerKeys, setProviderKeys] = useState<ProviderKey[]>([]);
+  const [keyForm, setKeyForm] = useState({ name: '', api_key: '' });
+
   // Model form
   const [modelForm, setModelForm] = useState({
     name: '', display_name: '', model_identifier: '', provider: 'openai',
     api_endpoint: '', api_key: '', max_tokens: 4096,
-    supports_streaming: true, provider_id: '',
+    supports_streaming: true, provider_id: '', fallback_model_ids: [] as string[],
+    tokenizer: '', supports_functions: false, disabled_tools: [] as string[],
+    supports_vision: false, supports_embeddings: false,
   });
 
   useEffect(() => {
@@ -333,12 +376,14 @@ const ModelManagement: React.FC = () => {
 
   const loadAll = async () => {
     try {
-      const [pRes, mRes] = await Promise.all([
+      const [pRes, mRes, tRes] = await Promise.all([
         apiClient.get('/admin/providers'),
         apiClient.get('/admin/models'),
+        apiClient.get('/admin/tools'),
       ]);
       setProviders(ensureArray<Provider>(pRes.data?.providers));
       setModels(ensureArray<Model>(mRes.data?.models));
+      setTools(ensureArray<ToolInfo>(tRes.data?.tools));
     } catch (e) {
       console.error('Failed to load data:', e);
     }
@@ -400,13 +445,84 @@ const ModelManagement: React.FC = () => {
     }
   };
 
+  const syncProviderModels = async (p: Provider) => {
+    try {
+      const res = await apiClient.get(`/admin/providers/${p.id}/models/discover`);
+      const pending = ensureArray<{ model_identifier: string; already_imported: boolean }>(res.data?.models)
+        .filter(m => !m.already_imported)
+        .map(m => m.model_identifier);
+      if (pending.length === 0) {
+        alert('没有发现新的模型');
+        return;
+      }
+      if (!confirm(`发现 ${pending.length} 个新模型：\n${pending.join('\n')}\n\n是否全部导入？无法识别上下文长度的模型将以停用状态导入，请确认参数后再启用。`)) return;
+      await apiClient.post(`/admin/providers/${p.id}/models/import`, { model_identifiers: pending });
+      await loadAll();
+    } catch (e: any) {
+      alert(e?.response?.data?.error || '获取模型列表失败');
+    }
+  };
+
+  // ── Provider key pool actions ──
+
+  const loadProviderKeys = async (providerID: string) => {
+    const res = await apiClient.get(`/admin/providers/${providerID}/keys`);
+    setProviderKeys(ensureArray<ProviderKey>(res.data?.keys));
+  };
+
+  const openProviderKeys = async (p: Provider) => {
+    setKeyForm({ name: '', api_key: '' });
+    setSaveError('');
+    try {
+      await loadProviderKeys(p.id);
+      setModal({ type: 'providerKeys', provider: p });
+    } catch (e: any) {
+      alert(e?.response?.data?.error || '加载密钥失败');
+    }
+  };
+
+  const addProviderKey = async (providerID: string) => {
+    setSaving(true);
+    setSaveError('');
+    try {
+      await apiClient.post(`/admin/providers/${providerID}/keys`, keyForm);
+      setKeyForm({ name: '', api_key: '' });
+      await loadProviderKeys(providerID);
+    } catch (e: any) {
+      setSaveError(e?.response?.data?.error || '添加失败，请重试');
+    } finally {
+      setSaving(false);
+    }
+  };
+
+  const toggleProviderKey = async (providerID: string, k: ProviderKey) => {
+    try {
+      await apiClient.put(`/admin/providers/${providerID}/keys/${k.id}`, { is_active: !k.is_active });
+      await loadProviderKeys(providerID);
+    } catch (e: any) {
+      setSaveError(e?.response?.data?.error || '操作失败');
+    }
+  };
+
+  const deleteProviderKey = async (providerID: string, id: string) => {
+    if (!confirm('确定删除这个密钥吗？')) return;
+    try {
+      await apiClient.delete(`/admin/providers/${providerID}/keys/${id}`);
+      await loadProviderKeys(providerID);
+    } catch (e: any) {
+      setSaveError(e?.response?.data?.error || '删除失败');
+    }
+  };
+
   // ── Model actions ──
 
   const openAddModel = (provider: Provider) => {
     setModelForm({
       name: '', display_name: '', model_identifier: '', provider: provider.provider_type,
       api_endpoint: '', api_key: '', max_tokens: 4096, supports_streaming: true,
-      provider_id: provider.id,
+      provider_id: provider.id, fallback_model_ids: [], tokenizer: '',
+      supports_functions: false, disabled_tools: [], supports_vision: false,
+      supports_embeddings: false,
     });
     setSaveError('');
     setModal({ type: 'addModel', provider });
@@ -417,7 +533,10 @@ const ModelManagement: React.FC = () => {
       name: m.name, display_name: m.display_name || '', model_identifier: m.model_identifier,
       provider: m.provider, api_endpoint: m.api_endpoint || '', api_key: '',
       max_tokens: m.max_tokens, supports_streaming: true,
-      provider_id: m.provider_id || '',
+      provider_id: m.provider_id || '', fallback_model_ids: m.fallback_model_ids || [],
+      tokenizer: m.tokenizer || '',
+      supports_functions: !!m.supports_functions, disabled_tools: m.disabled_tools || [],
+      supports_vision: !!m.supports_vision, supports_embeddings: !!m.supports_embeddings,
     });
     setSaveError('');
     setModal({ type: 'editModel', model: m });
@@ -434,6 +553,12 @@ const ModelManagement: React.FC = () => {
           api_endpoint: modelForm.api_endpoint || undefined,
           api_key: modelForm.api_key || undefined,
           supports_streaming: modelForm.supports_streaming,
+          fallback_model_ids: modelForm.fallback_model_ids,
+          tokenizer: modelForm.tokenizer,
+          supports_functions: modelForm.supports_functions,
+          disabled_tools: modelForm.disabled_tools,
+          supports_vision: modelForm.supports_vision,
+          supports_embeddings: modelForm.supports_embeddings,
         });
       } else {
         await apiClient.post('/admin/models', {
@@ -446,6 +571,12 @@ const ModelManagement: React.FC = () => {
           api_key: modelForm.api_key || undefined,
           max_tokens: modelForm.max_tokens,
           supports_streaming: modelForm.supports_streaming,
+          fallback_model_ids: modelForm.fallback_model_ids,
+          tokenizer: modelForm.tokenizer,
+          supports_functions: modelForm.supports_functions,
+          disabled_tools: modelForm.disabled_tools,
+          supports_vision: modelForm.supports_vision,
+          supports_embeddings: modelForm.supports_embeddings,
         });
       }
       setModal(null);
@@ -491,6 +622,7 @@ const ModelManagement: React.FC = () => {
             {modal.type === 'editProvider' && '编辑供应商'}
             {modal.type === 'addModel' && `在「${(modal as any).provider.display_name}」下添加模型`}
             {modal.type === 'editModel' && '编辑模型'}
+            {modal.type === 'providerKeys' && `「${modal.provider.display_name}」的 API 密钥池`}
           </ModalTitle>
 
           {saveError && <ErrorMsg>{saveError}</ErrorMsg>}
@@ -523,6 +655,9 @@ const ModelManagement: React.FC = () => {
                 >
                   <option value="openai">OpenAI 兼容</option>
                   <option value="anthropic">Anthropic</option>
+                  <option value="gemini">Google Gemini</option>
+                  <option value="azure">Azure OpenAI</option>
+                  <option value="local">本地运行时 (Ollama / vLLM)</option>
                   <option value="custom">自定义</option>
                 </Select>
               </FormGroup>
@@ -592,6 +727,67 @@ const ModelManagement: React.FC = () => {
                   onChange={e => setModelForm({ ...modelForm, max_tokens: parseInt(e.target.value) || 4096 })}
                 />
               </FormGroup>
+              <FormGroup>
+                <Label>Tokenizer（用于 Token 计数）</Label>
+                <Select
+                  value={modelForm.tokenizer}
+                  onChange={e => setModelForm({ ...modelForm, tokenizer: e.target.value })}
+                >
+                  <option value="">自动（按模型标识判断）</option>
+                  <option value="o200k_base">o200k_base（GPT-4o / o 系列）</option>
+                  <option value="cl100k_base">cl100k_base（GPT-4 / GPT-3.5）</option>
+                  <option value="heuristic">估算（其他模型）</option>
+                </Select>
+              </FormGroup>
+              <FormGroup>
+                <Label>
+                  <input
+                    type="checkbox"
+                    checked={modelForm.supports_functions}
+                    onChange={e => setModelForm({ ...modelForm, supports_functions: e.target.checked })}
+                    style={{ marginRight: '8px' }}
+                  />
+                  支持工具调用（Function Calling）
+                </Label>
+                {modelForm.supports_functions && tools.map(t => (
+                  <Label key={t.function.name} title={t.function.description}>
+                    <input
+                      type="checkbox"
+                      checked={!modelForm.disabled_tools.includes(t.function.name)}
+                      onChange={e => setModelForm({
+                        ...modelForm,
+                        disabled_tools: e.target.checked
+                          ? modelForm.disabled_tools.filter(n => n !== t.function.name)
+                          : [...modelForm.disabled_tools, t.function.name],
+                      })}
+                      style={{ marginRight: '8px', marginLeft: '16px' }}
+                    />
+                    {t.function.name}
+                  </Label>
+                ))}
+              </FormGroup>
+              <FormGroup>
+                <Label>
+                  <input
+                    type="checkbox"
+                    checked={modelForm.supports_vision}
+                    onChange={e => setModelForm({ ...modelForm, supports_vision: e.target.checked })}
+                    style={{ marginRight: '8px' }}
+                  />
+                  支持图片输入（Vision）
+                </Label>
+              </FormGroup>
+              <FormGroup>
+                <Label>
+                  <input
+                    type="checkbox"
+                    checked={modelForm.supports_embeddings}
+                    onChange={e => setModelForm({ ...modelForm, supports_embeddings: e.target.checked })}
+                    style={{ marginRight: '8px' }}
+                  />
+                  向量嵌入模型（Embeddings，不用于对话）
+                </Label>
+              </FormGroup>
               <FormGroup>
                 <Label>API 端点（覆盖供应商，可选）</Label>
                 <Input
@@ -609,14 +805,86 @@ const ModelManagement: React.FC = () => {
                   placeholder="留空使用供应商配置"
                 />
               </FormGroup>
+              <FormGroup>
+                <Label>备用模型（上游不可用时按顺序切换）</Label>
+                {modelForm.fallback_model_ids.map((id, i) => {
+                  const fm = models.find(m => m.id === id);
+                  return (
+                    <ModelRow key={id}>
+                      <ModelName>{i + 1}. {fm ? (fm.display_name || fm.name) : id}</ModelName>
+                      <IconBtn danger onClick={() => setModelForm({
+                        ...modelForm,
+                        fallback_model_ids: modelForm.fallback_model_ids.filter(f => f !== id),
+                      })}>移除</IconBtn>
+                    </ModelRow>
+                  );
+                })}
+                <Select
+                  value=""
+                  onChange={e => e.target.value && setModelForm({
+                    ...modelForm,
+                    fallback_model_ids: [...modelForm.fallback_model_ids, e.target.value],
+                  })}
+                >
+                  <option value="">+ 添加备用模型</option>
+                  {models
+                    .filter(m => m.id !== (modal?.type === 'editModel' ? modal.model.id : '') && !modelForm.fallback_model_ids.includes(m.id))
+                    .map(m => <option key={m.id} value={m.id}>{m.display_name || m.name}</option>)}
+                </Select>
+              </FormGroup>
+            </>
+          )}
+
+          {modal.type === 'providerKeys' && (
+            <>
+              {providerKeys.length === 0 && (
+                <EmptyModels>密钥池为空，将使用供应商自身的 API Key</EmptyModels>
+              )}
+              {providerKeys.map(k => (
+                <ModelRow key={k.id}>
+                  <ModelName>{k.name}</ModelName>
+                  <ModelMeta title={k.disabled_reason}>
+                    {k.key_hint} · {k.request_count} 次 · {k.input_tokens + k.output_tokens} tokens
+                  </ModelMeta>
+                  {k.is_active
+                    ? <Badge color="52,199,89">启用</Badge>
+                    : <Badge color="255,59,48" title={k.disabled_reason}>已停用</Badge>}
+                  <IconBtn onClick={() => toggleProviderKey(modal.provider.id, k)}>
+                    {k.is_active ? '停用' : '启用'}
+                  </IconBtn>
+                  <IconBtn danger onClick={() => deleteProviderKey(modal.provider.id, k.id)}>删除</IconBtn>
+                </ModelRow>
+              ))}
+              <FormGroup>
+                <Label>名称</Label>
+                <Input
+                  value={keyForm.name}
+                  onChange={e => setKeyForm({ ...keyForm, name: e.target.value })}
+                  placeholder="如: org-2"
+                />
+              </FormGroup>
+              <FormGroup>
+                <Label>API Key</Label>
+                <Input
+                  type="password"
+                  value={keyForm.api_key}
+                  onChange={e => setKeyForm({ ...keyForm, api_key: e.target.value })}
+                />
+              </FormGroup>
             </>
           )}
 
           <ModalActions>
-            <CancelBtn onClick={() => setModal(null)}>取消</CancelBtn>
-            <SaveBtn onClick={isProviderModal ? saveProvider : saveModel} disabled={saving}>
-              {saving ? '保存中…' : '保存'}
-            </SaveBtn>
+            <CancelBtn onClick={() => setModal(null)}>{modal.type === 'providerKeys' ? '关闭' : '取消'}</CancelBtn>
+            {modal.type === 'providerKeys' ? (
+              <SaveBtn onClick={() => addProviderKey(modal.provider.id)} disabled={saving || !keyForm.name || !keyForm.api_key}>
+                {saving ? '添加中…' : '添加密钥'}
+              </SaveBtn>
+            ) : (
+              <SaveBtn onClick={isProviderModal ? saveProvider : saveModel} disabled={saving}>
+                {saving ? '保存中…' : '保存'}
+              </SaveBtn>
+            )}
           </ModalActions>
         </ModalContent>
       </Backdrop>
@@ -644,7 +912,18 @@ const ModelManagement: React.FC = () => {
               <ProviderName>{p.display_name}</ProviderName>
               <ProviderEndpoint title={p.api_endpoint}>{p.api_endpoint}</ProviderEndpoint>
               <Badge>{pModels.length} 个模型</Badge>
+              {p.health && (
+                <Badge
+                  color={HEALTH_LABELS[p.health.state].color}
+                  title={`近期请求 ${p.health.recent_requests} · 错误率 ${(p.health.error_rate * 100).toFixed(1)}% · P95 ${p.health.p95_latency_ms}ms`}
+                >
+                  {HEALTH_LABELS[p.health.state].label}
+                  {p.health.recent_requests > 0 && ` · ${(p.health.error_rate * 100).toFixed(0)}% · ${p.health.p95_latency_ms}ms`}
+                </Badge>
+              )}
               <ProviderActions onClick={e => e.stopPropagation()}>
+                <IconBtn onClick={() => syncProviderModels(p)}>同步模型</IconBtn>
+                <IconBtn onClick={() => openProviderKeys(p)}>密钥</IconBtn>
                 <IconBtn onClick={() => openEditProvider(p)}>编辑</IconBtn>
                 <IconBtn danger onClick={() => deleteProvider(p.id)}>删除</IconBtn>
               </ProviderActions>
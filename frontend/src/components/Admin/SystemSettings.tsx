@@ -22,6 +22,8 @@ interface SystemSettingsDTO {
   email_resend_api_key: string;
   ai_default_memory_model: string;
   ai_memory_extraction_enabled: boolean;
+  ai_summary_model: string;
+  ai_embedding_model: string;
 }
 
 type MessageType = 'success' | 'error';
@@ -46,6 +48,8 @@ const DEFAULT_SETTINGS: SystemSettingsDTO = {
   email_resend_api_key: '',
   ai_default_memory_model: 'gpt-3.5-turbo',
   ai_memory_extraction_enabled: true,
+  ai_summary_model: 'gpt-3.5-turbo',
+  ai_embedding_model: '',
 };
 
 const DEFAULT_EXPANDED_STATE: Record<SectionKey, boolean> = {
@@ -418,6 +422,8 @@ const SystemSettingsPage: React.FC = () => {
       email_from_name: settings.email_from_name.trim(),
       email_resend_api_key: normalizeSensitiveValue(settings.email_resend_api_key),
       ai_default_memory_model: settings.ai_default_memory_model.trim(),
+      ai_summary_model: settings.ai_summary_model.trim(),
+      ai_embedding_model: settings.ai_embedding_model.trim(),
     };
 
     try {
@@ -760,6 +766,28 @@ const SystemSettingsPage: React.FC = () => {
               <HelpText>Enter a model identifier manually to override the dropdown selection.</HelpText>
             </FormGroup>
 
+            <FormGroup>
+              <Label>Conversation Summary Model</Label>
+              <Input
+                type="text"
+                value={settings.ai_summary_model}
+                placeholder="gpt-3.5-turbo"
+                onChange={e => setSettings(prev => ({ ...prev, ai_summary_model: e.target.value }))}
+              />
+              <HelpText>Small model used to summarize older turns of long conversations.</HelpText>
+            </FormGroup>
+
+            <FormGroup>
+              <Label>Embedding Model</Label>
+              <Input
+                type="text"
+                value={settings.ai_embedding_model}
+                placeholder="text-embedding-3-small"
+                onChange={e => setSettings(prev => ({ ...prev, ai_embedding_model: e.target.value }))}
+              />
+              <HelpText>Model that embeds documents and other text for semantic search. It must be marked as an embedding model; leave empty to use the first one. Takes effect after a restart.</HelpText>
+            </FormGroup>
+
             <FormGroup>
               <SwitchLabel>
                 <Checkbox
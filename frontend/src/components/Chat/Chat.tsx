@@ -121,6 +121,24 @@ const NewChatBtn = styled.button`
   &:active { transform: translateY(0); }
 `;
 
+const IncognitoBtn = styled.button`
+  width: 100%;
+  margin-top: 8px;
+  padding: 8px 16px;
+  background: transparent;
+  color: var(--text-secondary);
+  border: 1px dashed var(--border-primary);
+  border-radius: 20px;
+  font-size: 13px;
+  cursor: pointer;
+  transition: all 0.2s;
+
+  &:hover {
+    border-color: #667eea;
+    color: #667eea;
+  }
+`;
+
 // ─── Conversation List ────────────────────────────────────────────────────────
 const ConvList = styled.div`
   flex: 1;
@@ -264,6 +282,15 @@ const ModelSelector = styled.select`
   option { background: var(--bg-primary); }
 `;
 
+const IncognitoBadge = styled.span`
+  padding: 4px 10px;
+  border-radius: 20px;
+  border: 1px dashed var(--border-primary);
+  color: var(--text-muted);
+  font-size: 12px;
+  white-space: nowrap;
+`;
+
 // ─── Messages ─────────────────────────────────────────────────────────────────
 const Messages = styled.div`
   flex: 1;
@@ -324,6 +351,15 @@ const Bubble = styled.div<{ $user?: boolean }>`
   border: ${p => p.$user ? 'none' : '1px solid var(--border-primary)'};
 `;
 
+const BubbleImage = styled.img`
+  display: block;
+  max-width: 240px;
+  max-height: 240px;
+  border-radius: 10px;
+  margin-bottom: 6px;
+  object-fit: cover;
+`;
+
 const BubbleTime = styled.div<{ $user?: boolean }>`
   font-size: 11px;
   color: var(--text-muted);
@@ -381,6 +417,58 @@ const TextInput = styled.textarea`
   &::placeholder { color: var(--text-muted); }
 `;
 
+const AttachBtn = styled.button`
+  width: 44px;
+  height: 44px;
+  border-radius: 50%;
+  background: var(--bg-elevated, var(--bg-tertiary));
+  border: none;
+  color: var(--text-secondary, var(--text-muted));
+  cursor: pointer;
+  display: flex;
+  align-items: center;
+  justify-content: center;
+  flex-shrink: 0;
+
+  &:disabled { cursor: not-allowed; opacity: 0.5; }
+`;
+
+const PendingImages = styled.div`
+  display: flex;
+  gap: 8px;
+  padding: 10px 16px 0;
+  background: var(--bg-secondary);
+  border-top: 1px solid var(--border-primary);
+`;
+
+const PendingImage = styled.div`
+  position: relative;
+
+  img {
+    width: 56px;
+    height: 56px;
+    border-radius: 8px;
+    object-fit: cover;
+    display: block;
+  }
+
+  button {
+    position: absolute;
+    top: -6px;
+    right: -6px;
+    width: 18px;
+    height: 18px;
+    border-radius: 50%;
+    border: none;
+    background: rgba(0,0,0,0.6);
+    color: #fff;
+    font-size: 11px;
+    line-height: 18px;
+    padding: 0;
+    cursor: pointer;
+  }
+`;
+
 const SendBtn = styled.button<{ $active?: boolean }>`
   width: 44px;
   height: 44px;
@@ -433,17 +521,33 @@ const WelcomeSub = styled.p`
 `;
 
 // ─── Types ────────────────────────────────────────────────────────────────────
+type MemoryMode = 'full' | 'read_only' | 'off' | 'ephemeral';
+
 interface Conversation {
   id: string;
   title: string;
   last_message?: string;
+  memory_mode?: MemoryMode;
   updated_at: string;
 }
 
+const memoryModeLabels: Record<Exclude<MemoryMode, 'ephemeral'>, string> = {
+  full: '记忆：读写',
+  read_only: '记忆：只读',
+  off: '记忆：关闭',
+};
+
+interface ContentPart {
+  type: 'text' | 'image_url';
+  text?: string;
+  image_url?: { url: string; detail?: string };
+}
+
 interface Message {
   id: string;
-  role: 'user' | 'assistant';
+  role: 'user' | 'assistant' | 'tool';
   content: string;
+  content_parts?: ContentPart[];
   created_at: string;
 }
 
@@ -453,8 +557,24 @@ interface AIModel {
   display_name: string;
   description?: string;
   is_default: boolean;
+  supports_vision?: boolean;
 }
 
+// 与后端 maxImagesPerMessage / maxImageBytes 保持一致
+const MAX_IMAGES = 4;
+const MAX_IMAGE_BYTES = 5 * 1024 * 1024;
+
+const readAsDataURL = (file: File) =>
+  new Promise<string>((resolve, reject) => {
+    const reader = new FileReader();
+    reader.onload = () => resolve(reader.result as string);
+    reader.onerror = () => reject(reader.error);
+    reader.readAsDataURL(file);
+  });
+
+const messageImages = (m: Message) =>
+  (m.content_parts || []).filter(p => p.type === 'image_url' && p.image_url).map(p => p.image_url!.url);
+
 const ensureArray = <T,>(v: unknown): T[] => (Array.isArray(v) ? (v as T[]) : []);
 
 // ─── Component ────────────────────────────────────────────────────────────────
@@ -472,9 +592,11 @@ const Chat: React.FC = () => {
   const [sidebarOpen, setSidebarOpen] = useState(false);
   const [models, setModels] = useState<AIModel[]>([]);
   const [selectedModel, setSelectedModel] = useState<string>('');
+  const [images, setImages] = useState<string[]>([]);
 
   const messagesEndRef = useRef<HTMLDivElement>(null);
   const textareaRef = useRef<HTMLTextAreaElement>(null);
+  const fileInputRef = useRef<HTMLInputElement>(null);
 
   // Load conversations & models on mount
   useEffect(() => {
@@ -491,6 +613,21 @@ const Chat: React.FC = () => {
     if (activeConv) loadMessages(activeConv);
   }, [activeConv]);
 
+  // 无痕对话离开即删除（服务端也会清理闲置的无痕对话）
+  const conversationsRef = useRef<Conversation[]>([]);
+  conversationsRef.current = conversations;
+  useEffect(() => {
+    const id = activeConv;
+    return () => {
+      const conv = conversationsRef.current.find(c => c.id === id);
+      if (conv?.memory_mode === 'ephemeral') {
+        apiClient.delete(`/conversations/${conv.id}`)
+          .then(() => setConversations(prev => prev.filter(c => c.id !== conv.id)))
+          .catch(() => {});
+      }
+    };
+  }, [activeConv]);
+
   useEffect(() => {
     messagesEndRef.current?.scrollIntoView({ behavior: 'smooth' });
   }, [messages]);
@@ -515,7 +652,8 @@ const Chat: React.FC = () => {
   const loadMessages = async (id: string) => {
     try {
       const r = await apiClient.get(`/conversations/${id}/messages`);
-      setMessages(ensureArray<Message>(r.data?.messages));
+      // Tool results and bare tool-call turns are not shown in the transcript
+      setMessages(ensureArray<Message>(r.data?.messages).filter(m => m.role !== 'tool' && (m.content || messageImages(m).length > 0)));
     } catch {
       setMessages([]);
     }
@@ -535,11 +673,12 @@ const Chat: React.FC = () => {
     }
   };
 
-  const handleNewChat = async () => {
+  const handleNewChat = async (memoryMode: MemoryMode = 'full') => {
     try {
       const r = await apiClient.post('/conversations', {
-        title: '新对话',
+        title: memoryMode === 'ephemeral' ? '无痕对话' : '新对话',
         model_id: selectedModel || undefined,
+        memory_mode: memoryMode,
       });
       await loadConversations();
       setActiveConv(r.data.id);
@@ -549,24 +688,42 @@ const Chat: React.FC = () => {
     }
   };
 
+  const handleAttach = async (e: React.ChangeEvent<HTMLInputElement>) => {
+    const files = Array.from(e.target.files || []);
+    e.target.value = '';
+    const accepted = files
+      .filter(f => f.type.startsWith('image/') && f.size <= MAX_IMAGE_BYTES)
+      .slice(0, MAX_IMAGES - images.length);
+    const urls = await Promise.all(accepted.map(readAsDataURL));
+    setImages(prev => [...prev, ...urls].slice(0, MAX_IMAGES));
+  };
+
   const handleSend = async () => {
-    if (!input.trim() || !activeConv || loading) return;
+    if ((!input.trim() && images.length === 0) || !activeConv || loading) return;
 
     const text = input.trim();
+    const parts: ContentPart[] | undefined = images.length > 0
+      ? [
+          ...(text ? [{ type: 'text' as const, text }] : []),
+          ...images.map(url => ({ type: 'image_url' as const, image_url: { url } })),
+        ]
+      : undefined;
     setInput('');
+    setImages([]);
     setLoading(true);
 
     const tempMsg: Message = {
       id: `tmp-${Date.now()}`,
       role: 'user',
       content: text,
+      content_parts: parts,
       created_at: new Date().toISOString(),
     };
     setMessages(prev => [...prev, tempMsg]);
 
     try {
       await apiClient.post(`/conversations/${activeConv}/messages`, {
-        content: text,
+        content: parts || text,
         model_id: selectedModel || undefined,
       });
       await loadMessages(activeConv);
@@ -585,6 +742,16 @@ const Chat: React.FC = () => {
     }
   };
 
+  const handleMemoryModeChange = async (memoryMode: MemoryMode) => {
+    if (!activeConv) return;
+    try {
+      await apiClient.put(`/conversations/${activeConv}`, { memory_mode: memoryMode });
+      setConversations(prev => prev.map(c => (c.id === activeConv ? { ...c, memory_mode: memoryMode } : c)));
+    } catch {
+      // ignore
+    }
+  };
+
   const handleModelChange = (modelId: string) => {
     setSelectedModel(modelId);
     // Persist as user default
@@ -599,7 +766,11 @@ const Chat: React.FC = () => {
   const fmt = (d: string) =>
     new Date(d).toLocaleTimeString('zh-CN', { hour: '2-digit', minute: '2-digit' });
 
-  const activeTitle = conversations.find(c => c.id === activeConv)?.title ?? '对话';
+  const canAttach = !!models.find(m => m.id === selectedModel)?.supports_vision;
+  const canSend = (!!input.trim() || images.length > 0) && !loading;
+
+  const activeConversation = conversations.find(c => c.id === activeConv);
+  const activeTitle = activeConversation?.title ?? '对话';
   const canAdmin = user?.role === 'admin' || user?.role === 'super_admin';
 
   return (
@@ -614,12 +785,15 @@ const Chat: React.FC = () => {
             <BrandAvatar>A</BrandAvatar>
             <BrandName>AI Chat</BrandName>
           </AppBrand>
-          <NewChatBtn onClick={handleNewChat}>
+          <NewChatBtn onClick={() => handleNewChat()}>
             <svg width="14" height="14" viewBox="0 0 24 24" fill="none" stroke="currentColor" strokeWidth="2.5">
               <line x1="12" y1="5" x2="12" y2="19"/><line x1="5" y1="12" x2="19" y2="12"/>
             </svg>
             新对话
           </NewChatBtn>
+          <IncognitoBtn onClick={() => handleNewChat('ephemeral')} title="不读取也不写入长期记忆，离开后删除">
+            🕶️ 无痕对话
+          </IncognitoBtn>
         </SidebarTop>
 
         <ConvList>
@@ -669,6 +843,20 @@ const Chat: React.FC = () => {
 
           <ChatTitle>{activeConv ? activeTitle : 'AI Chat'}</ChatTitle>
 
+          {activeConversation && (activeConversation.memory_mode === 'ephemeral' ? (
+            <IncognitoBadge title="不读取也不写入长期记忆，离开后删除">🕶️ 无痕</IncognitoBadge>
+          ) : (
+            <ModelSelector
+              value={activeConversation.memory_mode || 'full'}
+              onChange={e => handleMemoryModeChange(e.target.value as MemoryMode)}
+              title="本对话如何使用长期记忆"
+            >
+              {Object.entries(memoryModeLabels).map(([mode, label]) => (
+                <option key={mode} value={mode}>{label}</option>
+              ))}
+            </ModelSelector>
+          ))}
+
           {models.length > 0 && (
             <ModelSelector
               value={selectedModel}
@@ -691,7 +879,10 @@ const Chat: React.FC = () => {
                 <MessageRow key={msg.id} $user={msg.role === 'user'}>
                   {msg.role !== 'user' && <AIAvatar>✦</AIAvatar>}
                   <BubbleWrap $user={msg.role === 'user'}>
-                    <Bubble $user={msg.role === 'user'}>{msg.content}</Bubble>
+                    <Bubble $user={msg.role === 'user'}>
+                      {messageImages(msg).map((url, i) => <BubbleImage key={i} src={url} alt="" />)}
+                      {msg.content}
+                    </Bubble>
                     <BubbleTime $user={msg.role === 'user'}>{fmt(msg.created_at)}</BubbleTime>
                   </BubbleWrap>
                 </MessageRow>
@@ -713,7 +904,41 @@ const Chat: React.FC = () => {
               <div ref={messagesEndRef} />
             </Messages>
 
+            {images.length > 0 && (
+              <PendingImages>
+                {images.map((url, i) => (
+                  <PendingImage key={i}>
+                    <img src={url} alt="" />
+                    <button onClick={() => setImages(prev => prev.filter((_, j) => j !== i))} title="移除">×</button>
+                  </PendingImage>
+                ))}
+              </PendingImages>
+            )}
+
             <InputArea>
+              {canAttach && (
+                <>
+                  <input
+                    ref={fileInputRef}
+                    type="file"
+                    accept="image/png,image/jpeg,image/gif,image/webp"
+                    multiple
+                    hidden
+                    onChange={handleAttach}
+                  />
+                  <AttachBtn
+                    onClick={() => fileInputRef.current?.click()}
+                    disabled={loading || images.length >= MAX_IMAGES}
+                    title="添加图片"
+                  >
+                    <svg width="18" height="18" viewBox="0 0 24 24" fill="none" stroke="currentColor" strokeWidth="2">
+                      <rect x="3" y="3" width="18" height="18" rx="2"/>
+                      <circle cx="8.5" cy="8.5" r="1.5"/>
+                      <polyline points="21 15 16 10 5 21"/>
+                    </svg>
+                  </AttachBtn>
+                </>
+              )}
               <TextInput
                 ref={textareaRef}
                 rows={1}
@@ -723,7 +948,7 @@ const Chat: React.FC = () => {
                 placeholder="输入消息… (Shift+Enter 换行)"
                 disabled={loading}
               />
-              <SendBtn $active={!!input.trim() && !loading} onClick={handleSend} disabled={!input.trim() || loading}>
+              <SendBtn $active={canSend} onClick={handleSend} disabled={!canSend}>
                 <svg width="20" height="20" viewBox="0 0 24 24" fill="none" stroke="currentColor" strokeWidth="2">
                   <line x1="22" y1="2" x2="11" y2="13"/>
                   <polygon points="22 2 15 22 11 13 2 9 22 2"/>
@@ -1,4 +1,4 @@
-import React, { useState, useEffect } from 'react';
+import React, { useState, useEffect, useRef } from 'react';
 import styled, { keyframes } from 'styled-components';
 import { useNavigate } from 'react-router-dom';
 import apiClient from '../../api/client';
@@ -105,6 +105,38 @@ const FilterChip = styled.button<{ $active?: boolean }>`
   }
 `;
 
+const Toolbar = styled.div`
+  display: flex;
+  gap: 8px;
+  margin-bottom: 14px;
+  flex-wrap: wrap;
+  align-items: center;
+`;
+
+const SearchInput = styled.input`
+  flex: 1;
+  min-width: 180px;
+  padding: 8px 14px;
+  background: var(--bg-secondary);
+  border: 1.5px solid var(--border-primary);
+  border-radius: 20px;
+  color: var(--text-primary);
+  font-size: 13px;
+  transition: border-color 0.18s;
+
+  &:focus {
+    outline: none;
+    border-color: #667eea;
+  }
+  &::placeholder { color: var(--text-muted); }
+`;
+
+const Notice = styled.div`
+  font-size: 13px;
+  color: var(--text-secondary);
+  margin-bottom: 14px;
+`;
+
 // ─── Memory Card ──────────────────────────────────────────────────────────────
 const CardGrid = styled.div`
   display: flex;
@@ -319,6 +351,63 @@ const SaveBtn = styled.button`
   &:disabled { opacity: 0.5; cursor: not-allowed; }
 `;
 
+// ─── Source & History ─────────────────────────────────────────────────────────
+const WideModal = styled(Modal)`
+  max-width: 600px;
+  max-height: 80vh;
+  overflow-y: auto;
+`;
+
+const SourceMeta = styled.div`
+  font-size: 12px;
+  color: var(--text-muted);
+  margin-bottom: 8px;
+`;
+
+const SourceMessage = styled.div`
+  padding: 12px 14px;
+  background: var(--bg-primary);
+  border: 1px solid var(--border-primary);
+  border-radius: 12px;
+  font-size: 14px;
+  line-height: 1.6;
+  white-space: pre-wrap;
+  word-break: break-word;
+  max-height: 320px;
+  overflow-y: auto;
+`;
+
+const VersionList = styled.div`
+  display: flex;
+  flex-direction: column;
+  gap: 10px;
+`;
+
+const VersionItem = styled.div`
+  padding: 12px 14px;
+  background: var(--bg-primary);
+  border: 1px solid var(--border-primary);
+  border-radius: 12px;
+`;
+
+const VersionHead = styled.div`
+  display: flex;
+  align-items: center;
+  gap: 8px;
+  margin-bottom: 6px;
+  font-size: 12px;
+  color: var(--text-muted);
+`;
+
+const VersionContent = styled.div<{ $deleted?: boolean }>`
+  font-size: 13px;
+  line-height: 1.6;
+  color: var(--text-primary);
+  white-space: pre-wrap;
+  word-break: break-word;
+  ${p => p.$deleted && 'text-decoration: line-through; opacity: 0.6;'}
+`;
+
 // ─── Empty State ──────────────────────────────────────────────────────────────
 const Empty = styled.div`
   text-align: center;
@@ -339,9 +428,33 @@ interface MemoryItem {
   content: string;
   category: Category;
   importance: number;
+  source_conversation_id?: string;
+  source_message_id?: string;
   created_at?: string;
 }
 
+interface MemorySource {
+  memory_id: string;
+  conversation_id: string;
+  message: {
+    id: string;
+    role: string;
+    content: string;
+    created_at: string;
+  };
+}
+
+interface MemoryVersion {
+  id: string;
+  version: number;
+  change_type: 'create' | 'update' | 'delete' | 'restore';
+  changed_by: string;
+  content: string;
+  category: Category;
+  importance: number;
+  created_at: string;
+}
+
 const CATEGORY_LABELS: Record<Category | 'all', string> = {
   all:        '全部',
   preference: '偏好',
@@ -349,6 +462,24 @@ const CATEGORY_LABELS: Record<Category | 'all', string> = {
   context:    '上下文',
 };
 
+const CHANGE_LABELS: Record<string, string> = {
+  create:  '创建',
+  update:  '修改',
+  delete:  '删除',
+  restore: '恢复',
+};
+
+const CHANGED_BY_LABELS: Record<string, string> = {
+  user:          '手动',
+  extraction:    '自动提取',
+  consolidation: '自动整合',
+  import:        '导入',
+  rollback:      '回滚',
+  cleanup:       '清理',
+};
+
+const formatTime = (s?: string) => (s ? new Date(s).toLocaleString('zh-CN') : '');
+
 const stars = (n: number) => '★'.repeat(n) + '☆'.repeat(10 - n);
 
 // ─── Component ────────────────────────────────────────────────────────────────
@@ -359,6 +490,13 @@ const Memory: React.FC = () => {
   const [showModal, setShowModal] = useState(false);
   const [editTarget, setEditTarget] = useState<MemoryItem | null>(null);
   const [loading, setLoading] = useState(false);
+  const [query, setQuery] = useState('');
+  const [searchResults, setSearchResults] = useState<MemoryItem[] | null>(null);
+  const [notice, setNotice] = useState('');
+  const importInputRef = useRef<HTMLInputElement>(null);
+  const [sourceView, setSourceView] = useState<MemorySource | null>(null);
+  const [historyTarget, setHistoryTarget] = useState<MemoryItem | null>(null);
+  const [versions, setVersions] = useState<MemoryVersion[]>([]);
 
   // Form state
   const [formContent, setFormContent] = useState('');
@@ -377,6 +515,69 @@ const Memory: React.FC = () => {
     }
   };
 
+  // 按文本搜索（服务端匹配全部关键词），清空后回到完整列表
+  const runSearch = async (q: string) => {
+    if (!q) {
+      setSearchResults(null);
+      return;
+    }
+    try {
+      const r = await apiClient.memories.search(q);
+      setSearchResults(Array.isArray(r?.memories) ? r.memories : []);
+    } catch {
+      setSearchResults([]);
+    }
+  };
+
+  useEffect(() => {
+    const timer = setTimeout(() => runSearch(query.trim()), 300);
+    return () => clearTimeout(timer);
+  }, [query]);
+
+  const refresh = async () => {
+    await fetchMemories();
+    await runSearch(query.trim());
+  };
+
+  const handleExport = async (format: 'json' | 'markdown') => {
+    try {
+      const blob = await apiClient.memories.export(format);
+      const url = URL.createObjectURL(blob);
+      const a = document.createElement('a');
+      a.href = url;
+      a.download = `memories.${format === 'json' ? 'json' : 'md'}`;
+      a.click();
+      URL.revokeObjectURL(url);
+    } catch (e) {
+      console.error(e);
+    }
+  };
+
+  const handleImport = async (e: React.ChangeEvent<HTMLInputElement>) => {
+    const file = e.target.files?.[0];
+    e.target.value = '';
+    if (!file) return;
+    try {
+      const r = await apiClient.memories.import(file);
+      setNotice(`已导入 ${r?.imported ?? 0} 条记忆，跳过 ${r?.skipped ?? 0} 条（重复或无效）`);
+      await refresh();
+    } catch (err: any) {
+      setNotice(err?.response?.data?.error || '导入失败');
+    }
+  };
+
+  const handleBulkDelete = async () => {
+    if (filter === 'all') return;
+    if (!window.confirm(`确认删除全部「${CATEGORY_LABELS[filter]}」类型的记忆？`)) return;
+    try {
+      const r = await apiClient.memories.bulkDelete({ category: filter });
+      setNotice(`已删除 ${r?.deleted ?? 0} 条记忆`);
+      await refresh();
+    } catch (e) {
+      console.error(e);
+    }
+  };
+
   const openCreate = () => {
     setEditTarget(null);
     setFormContent('');
@@ -403,7 +604,7 @@ const Memory: React.FC = () => {
         await apiClient.memories.create(formContent.trim(), formCategory, formImportance);
       }
       setShowModal(false);
-      await fetchMemories();
+      await refresh();
     } catch (e) {
       console.error(e);
     } finally {
@@ -416,12 +617,45 @@ const Memory: React.FC = () => {
     try {
       await apiClient.memories.delete(id);
       setMemories(prev => prev.filter(m => m.id !== id));
+      setSearchResults(prev => prev && prev.filter(m => m.id !== id));
     } catch (e) {
       console.error(e);
     }
   };
 
-  const filtered = filter === 'all' ? memories : memories.filter(m => m.category === filter);
+  const openSource = async (m: MemoryItem) => {
+    try {
+      setSourceView(await apiClient.memories.source(m.id));
+    } catch (err: any) {
+      setNotice(err?.response?.data?.error || '来源消息已不存在');
+    }
+  };
+
+  const openHistory = async (m: MemoryItem) => {
+    setHistoryTarget(m);
+    setVersions([]);
+    try {
+      const r = await apiClient.memories.history(m.id);
+      setVersions(Array.isArray(r?.versions) ? r.versions : []);
+    } catch (e) {
+      console.error(e);
+    }
+  };
+
+  const handleRollback = async (v: MemoryVersion) => {
+    if (!historyTarget) return;
+    if (!window.confirm(`确认回滚到版本 ${v.version}？`)) return;
+    try {
+      await apiClient.memories.rollback(historyTarget.id, v.version);
+      setHistoryTarget(null);
+      await refresh();
+    } catch (err: any) {
+      setNotice(err?.response?.data?.error || '回滚失败');
+    }
+  };
+
+  const source = searchResults ?? memories;
+  const filtered = filter === 'all' ? source : source.filter(m => m.category === filter);
 
   return (
     <Page>
@@ -436,6 +670,29 @@ const Memory: React.FC = () => {
       </Header>
 
       <Content>
+        <Toolbar>
+          <SearchInput
+            value={query}
+            onChange={e => setQuery(e.target.value)}
+            placeholder="搜索记忆…"
+          />
+          <ActionBtn onClick={() => handleExport('json')}>导出 JSON</ActionBtn>
+          <ActionBtn onClick={() => handleExport('markdown')}>导出 Markdown</ActionBtn>
+          <ActionBtn onClick={() => importInputRef.current?.click()}>导入</ActionBtn>
+          <input
+            ref={importInputRef}
+            type="file"
+            accept=".json,.md,.markdown,.txt,application/json,text/markdown,text/plain"
+            hidden
+            onChange={handleImport}
+          />
+          {filter !== 'all' && (
+            <ActionBtn $danger onClick={handleBulkDelete}>删除全部{CATEGORY_LABELS[filter]}</ActionBtn>
+          )}
+        </Toolbar>
+
+        {notice && <Notice>{notice}</Notice>}
+
         {/* Category filter */}
         <FilterRow>
           {(['all', 'preference', 'fact', 'context'] as const).map(cat => (
@@ -446,7 +703,7 @@ const Memory: React.FC = () => {
             >
               {CATEGORY_LABELS[cat]}
               {cat !== 'all' && (
-                <> ({memories.filter(m => m.category === cat).length})</>
+                <> ({source.filter(m => m.category === cat).length})</>
               )}
             </FilterChip>
           ))}
@@ -457,7 +714,9 @@ const Memory: React.FC = () => {
           <Empty>
             <EmptyIcon>🧠</EmptyIcon>
             <div style={{ fontSize: 15, marginBottom: 6 }}>
-              {filter === 'all' ? '还没有记忆' : `没有「${CATEGORY_LABELS[filter]}」类型的记忆`}
+              {searchResults
+                ? '没有匹配的记忆'
+                : filter === 'all' ? '还没有记忆' : `没有「${CATEGORY_LABELS[filter]}」类型的记忆`}
             </div>
             <div style={{ fontSize: 13 }}>AI 会在对话中自动提取和保存记忆</div>
           </Empty>
@@ -474,6 +733,10 @@ const Memory: React.FC = () => {
                 <CardContent>{m.content}</CardContent>
                 <CardActions>
                   <ActionBtn onClick={() => openEdit(m)}>编辑</ActionBtn>
+                  {m.source_message_id && (
+                    <ActionBtn onClick={() => openSource(m)}>来源</ActionBtn>
+                  )}
+                  <ActionBtn onClick={() => openHistory(m)}>历史</ActionBtn>
                   <ActionBtn $danger onClick={() => handleDelete(m.id)}>删除</ActionBtn>
                 </CardActions>
               </Card>
@@ -533,6 +796,56 @@ const Memory: React.FC = () => {
           </Modal>
         </Backdrop>
       )}
+
+      {/* Source message modal */}
+      {sourceView && (
+        <Backdrop onClick={e => { if (e.target === e.currentTarget) setSourceView(null); }}>
+          <WideModal>
+            <ModalTitle>记忆来源</ModalTitle>
+            <SourceMeta>
+              {sourceView.message.role === 'user' ? '用户' : 'AI'} · {formatTime(sourceView.message.created_at)}
+            </SourceMeta>
+            <SourceMessage>{sourceView.message.content}</SourceMessage>
+            <ModalActions>
+              <CancelBtn onClick={() => setSourceView(null)}>关闭</CancelBtn>
+              <SaveBtn onClick={() => navigate(`/chat/${sourceView.conversation_id}`)}>打开对话</SaveBtn>
+            </ModalActions>
+          </WideModal>
+        </Backdrop>
+      )}
+
+      {/* Version history modal */}
+      {historyTarget && (
+        <Backdrop onClick={e => { if (e.target === e.currentTarget) setHistoryTarget(null); }}>
+          <WideModal>
+            <ModalTitle>修改历史</ModalTitle>
+            <VersionList>
+              {versions.map((v, i) => (
+                <VersionItem key={v.id}>
+                  <VersionHead>
+                    <span>v{v.version}</span>
+                    <span>{CHANGE_LABELS[v.change_type] || v.change_type}</span>
+                    <span>· {CHANGED_BY_LABELS[v.changed_by] || v.changed_by}</span>
+                    <span>· {formatTime(v.created_at)}</span>
+                    <CategoryBadge $cat={v.category} style={{ marginLeft: 'auto' }}>
+                      {CATEGORY_LABELS[v.category]}
+                    </CategoryBadge>
+                  </VersionHead>
+                  <VersionContent $deleted={v.change_type === 'delete'}>{v.content}</VersionContent>
+                  {i > 0 && v.change_type !== 'delete' && (
+                    <CardActions>
+                      <ActionBtn onClick={() => handleRollback(v)}>回滚到此版本</ActionBtn>
+                    </CardActions>
+                  )}
+                </VersionItem>
+              ))}
+            </VersionList>
+            <ModalActions>
+              <CancelBtn onClick={() => setHistoryTarget(null)}>关闭</CancelBtn>
+            </ModalActions>
+          </WideModal>
+        </Backdrop>
+      )}
     </Page>
   );
 };